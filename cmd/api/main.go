@@ -43,6 +43,11 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
+	// Background Jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go srv.RunBackgroundJobs(jobsCtx)
+
 	// Start Server
 	go func() {
 		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -61,5 +66,8 @@ func main() {
 	if err := httpSrv.Shutdown(ctx); err != nil {
 		log.Fatalf("server forced shutdown: %v", err)
 	}
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("stop server resources: %v", err)
+	}
 	log.Println("server existing")
 }
@@ -5,7 +5,10 @@ import (
 	"errors"
 
 	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func GetUserFromContext(ctx context.Context) (*jwttoken.UserClaims, error) {
@@ -26,4 +29,71 @@ func GetUserIDFromContext(ctx context.Context) (string, error) {
 
 func SetContextUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, config.ContextUserIDKey, userID)
-}
\ No newline at end of file
+}
+
+func SetContextUserClaims(ctx context.Context, claims *jwttoken.UserClaims) context.Context {
+	ctx = context.WithValue(ctx, config.ContextUserClaimsKey, claims)
+	ctx = context.WithValue(ctx, config.ContextUserIDKey, claims.UserID)
+	return ctx
+}
+
+// SetContextAPIKey : the request acts as the key creator but without a role,
+// only RequirePermission with the key scopes lets it through
+func SetContextAPIKey(ctx context.Context, key *user.APIKey) context.Context {
+	ctx = SetContextUserClaims(ctx, &jwttoken.UserClaims{
+		UserID:           key.CreatedBy,
+		RegisteredClaims: &jwt.RegisteredClaims{},
+	})
+	return context.WithValue(ctx, config.ContextAPIKeyKey, key)
+}
+
+// GetAPIKeyFromContext : ok is false for users signed in with a token
+func GetAPIKeyFromContext(ctx context.Context) (*user.APIKey, bool) {
+	key, ok := ctx.Value(config.ContextAPIKeyKey).(*user.APIKey)
+	return key, ok
+}
+
+// IsImpersonated : true when an admin is acting as the user
+func IsImpersonated(ctx context.Context) bool {
+	claims, err := GetUserFromContext(ctx)
+	return err == nil && claims.IsImpersonated()
+}
+
+// RequireRole : caller must have one of roles
+func RequireRole(ctx context.Context, roles ...user.Role) error {
+	claims, err := GetUserFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+
+	for _, r := range roles {
+		if claims.Role == r {
+			return nil
+		}
+	}
+	return errs.ErrForbidden
+}
+
+// RequirePermission : caller role, or API key scopes, must grant all perms
+func RequirePermission(ctx context.Context, perms ...user.Permission) error {
+	claims, err := GetUserFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+
+	if key, ok := GetAPIKeyFromContext(ctx); ok {
+		for _, p := range perms {
+			if !key.HasScope(p) {
+				return errs.ErrForbidden
+			}
+		}
+		return nil
+	}
+
+	for _, p := range perms {
+		if !claims.Role.HasPermission(p) {
+			return errs.ErrForbidden
+		}
+	}
+	return nil
+}
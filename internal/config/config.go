@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -19,20 +20,29 @@ const (
 	// Context keys
 	ContextUserClaimsKey contextKey = "ctx-user-claims"
 	ContextUserIDKey     contextKey = "ctx-user-id"
+	ContextAPIKeyKey     contextKey = "ctx-api-key"
 
 	ContextTimeout = time.Second * 10
 )
 
 type EnvConfig struct {
-	APP AppConfig `envPrefix:"APP_"`
-	DB  DBConfig  `envPrefix:"DB_"`
-	JWT JWTConfig `envPrefix:"JWT_"`
+	APP      AppConfig      `envPrefix:"APP_"`
+	DB       DBConfig       `envPrefix:"DB_"`
+	JWT      JWTConfig      `envPrefix:"JWT_"`
+	Admin    AdminConfig    `envPrefix:"ADMIN_"`
+	Payment  PaymentConfig  `envPrefix:"PAYMENT_"`
+	Auth     AuthConfig     `envPrefix:"AUTH_"`
+	Mail     MailConfig     `envPrefix:"MAIL_"`
+	OIDC     OIDCConfig     `envPrefix:"OIDC_"`
+	Password PasswordConfig `envPrefix:"PASSWORD_"`
 }
 
 type AppConfig struct {
 	Host   string `env:"HOST" envDefault:"localhost"`
 	Port   int    `env:"PORT" envDefault:"8080"`
 	Prefix string `env:"PREFIX" envDefault:"/api/v1"`
+	// Idempotency-Key replay window
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
 }
 
 type DBConfig struct {
@@ -44,10 +54,107 @@ type DBConfig struct {
 	SSLMode  string `env:"SSL_MODE" envDefault:"disable"`
 }
 
+// JWTConfig : SecretKey signs HS256 access tokens, RS256/EdDSA read keys from KeysDir
 type JWTConfig struct {
-	AppName    string `env:"APP_NAME" envDefault:"Go Starter Kit"`
-	SecretKey  string `env:"SECRET_KEY" validate:"required"`
-	RefreshKey string `env:"REFRESH_KEY" validate:"required"`
+	AppName     string `env:"APP_NAME" envDefault:"Go Starter Kit"`
+	Algorithm   string `env:"ALGORITHM" envDefault:"HS256" validate:"oneof=HS256 RS256 EdDSA"`
+	SecretKey   string `env:"SECRET_KEY" validate:"required_if=Algorithm HS256"`
+	RefreshKey  string `env:"REFRESH_KEY" validate:"required"`
+	KeysDir     string `env:"KEYS_DIR" validate:"required_unless=Algorithm HS256"`
+	ActiveKeyID string `env:"ACTIVE_KEY_ID"`
+}
+
+// AdminConfig : bootstrap the first admin, skipped when an admin already exists
+type AdminConfig struct {
+	Email    string `env:"EMAIL"`
+	Password string `env:"PASSWORD"`
+}
+
+// PaymentConfig : provider "fake" starts an in-process gateway on FakeGatewayAddr.
+// Empty WebhookSecret with the fake provider = random secret per process.
+type PaymentConfig struct {
+	Provider        string `env:"PROVIDER" envDefault:"fake"`
+	Currency        string `env:"CURRENCY" envDefault:"THB"`
+	WebhookSecret   string `env:"WEBHOOK_SECRET"`
+	FakeGatewayAddr string `env:"FAKE_GATEWAY_ADDR" envDefault:"127.0.0.1:9090"`
+}
+
+// AuthConfig : what unverified users may do, and email links. VerifyEmailURL, ConfirmEmailURL and PasswordResetURL
+// are pages that post the token back to the API, the token is added as ?token=
+type AuthConfig struct {
+	AllowUnverifiedLogin    bool          `env:"ALLOW_UNVERIFIED_LOGIN" envDefault:"true"`
+	AllowUnverifiedCheckout bool          `env:"ALLOW_UNVERIFIED_CHECKOUT" envDefault:"false"`
+	VerificationTTL         time.Duration `env:"VERIFICATION_TTL" envDefault:"24h"`
+	VerifyEmailURL          string        `env:"VERIFY_EMAIL_URL" envDefault:"http://localhost:3000/verify-email"`
+	ConfirmEmailURL         string        `env:"CONFIRM_EMAIL_URL" envDefault:"http://localhost:3000/confirm-email"`
+	// Verification resend: at most VerificationResendLimit emails per address in VerificationResendWindow
+	VerificationResendLimit  int           `env:"VERIFICATION_RESEND_LIMIT" envDefault:"3"`
+	VerificationResendWindow time.Duration `env:"VERIFICATION_RESEND_WINDOW" envDefault:"1h"`
+	// Password reset: at most PasswordResetLimit emails per address in PasswordResetWindow
+	PasswordResetTTL    time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"30m"`
+	PasswordResetURL    string        `env:"PASSWORD_RESET_URL" envDefault:"http://localhost:3000/reset-password"`
+	PasswordResetLimit  int           `env:"PASSWORD_RESET_LIMIT" envDefault:"3"`
+	PasswordResetWindow time.Duration `env:"PASSWORD_RESET_WINDOW" envDefault:"1h"`
+	// Two-factor: TOTP secrets are encrypted with MFAEncryptionKey (base64, 32 bytes), empty = 2FA unavailable
+	MFAEncryptionKey string        `env:"MFA_ENCRYPTION_KEY"`
+	MFAIssuer        string        `env:"MFA_ISSUER" envDefault:"Go Starter Kit"`
+	MFAChallengeTTL  time.Duration `env:"MFA_CHALLENGE_TTL" envDefault:"5m"`
+	// Brute-force lockout: limit failures per account / IP within LoginWindow,
+	// the lock starts at LoginLockoutBase and doubles per failure up to LoginLockoutMax
+	LoginWindow       time.Duration `env:"LOGIN_WINDOW" envDefault:"1h"`
+	LoginAccountLimit int           `env:"LOGIN_ACCOUNT_LIMIT" envDefault:"5"`
+	LoginIPLimit      int           `env:"LOGIN_IP_LIMIT" envDefault:"20"`
+	LoginLockoutBase  time.Duration `env:"LOGIN_LOCKOUT_BASE" envDefault:"1m"`
+	LoginLockoutMax   time.Duration `env:"LOGIN_LOCKOUT_MAX" envDefault:"30m"`
+	// Revoked access tokens are reloaded from the database at most this often,
+	// a logout on another instance takes effect within this delay
+	DenylistRefresh time.Duration `env:"DENYLIST_REFRESH" envDefault:"5s"`
+	// Admin impersonation: access token lifetime, there is no refresh token
+	ImpersonationTTL time.Duration `env:"IMPERSONATION_TTL" envDefault:"15m"`
+}
+
+// MailConfig : driver "file" writes .eml files to FileDir, "smtp" sends through SMTPHost
+type MailConfig struct {
+	Driver       string `env:"DRIVER" envDefault:"file" validate:"oneof=file smtp"`
+	From         string `env:"FROM" envDefault:"Go Starter Kit <no-reply@localhost>"`
+	FileDir      string `env:"FILE_DIR" envDefault:"./tmp/mail"`
+	SMTPHost     string `env:"SMTP_HOST" envDefault:"localhost"`
+	SMTPPort     int    `env:"SMTP_PORT" envDefault:"1025"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+}
+
+// OIDCConfig : social login providers, each one is read from OIDC_<NAME>_*.
+// Provider "fake" starts an in-process identity provider on FakeAddr and needs no issuer or client.
+type OIDCConfig struct {
+	Providers []string      `env:"PROVIDERS" envSeparator:","`
+	StateTTL  time.Duration `env:"STATE_TTL" envDefault:"10m"`
+	FakeAddr  string        `env:"FAKE_ADDR" envDefault:"127.0.0.1:9091"`
+	// Filled by LoadConfig from Providers
+	Clients []OIDCClientConfig `env:"-" validate:"dive"`
+}
+
+// OIDCClientConfig : RedirectURL is the frontend page that posts code and state back to the API
+type OIDCClientConfig struct {
+	Name         string
+	Issuer       string   `env:"ISSUER" validate:"required_unless=Name fake"`
+	ClientID     string   `env:"CLIENT_ID" validate:"required_unless=Name fake"`
+	ClientSecret string   `env:"CLIENT_SECRET" validate:"required_unless=Name fake"`
+	RedirectURL  string   `env:"REDIRECT_URL" envDefault:"http://localhost:3000/oidc/callback"`
+	Scopes       []string `env:"SCOPES" envSeparator:","`
+}
+
+// PasswordConfig : new hashes use Algorithm, older bcrypt or argon2id hashes are upgraded on login.
+// Argon2Memory is in KiB. BreachedFile lists passwords refused on register and change, one per line.
+type PasswordConfig struct {
+	Algorithm         string `env:"ALGORITHM" envDefault:"argon2id" validate:"oneof=argon2id bcrypt"`
+	Argon2Memory      uint32 `env:"ARGON2_MEMORY" envDefault:"19456" validate:"min=8"`
+	Argon2Iterations  uint32 `env:"ARGON2_ITERATIONS" envDefault:"2" validate:"min=1"`
+	Argon2Parallelism uint8  `env:"ARGON2_PARALLELISM" envDefault:"1" validate:"min=1"`
+	BcryptCost        int    `env:"BCRYPT_COST" envDefault:"10" validate:"min=4,max=31"`
+	MinLength         int    `env:"MIN_LENGTH" envDefault:"8" validate:"min=1"`
+	MaxLength         int    `env:"MAX_LENGTH" envDefault:"128" validate:"gtefield=MinLength"`
+	BreachedFile      string `env:"BREACHED_FILE"`
 }
 
 func LoadConfig(path string) (*EnvConfig, error) {
@@ -62,6 +169,20 @@ func LoadConfig(path string) (*EnvConfig, error) {
 		return nil, fmt.Errorf("parse env failed: %w", err)
 	}
 
+	// Parse OIDC providers: OIDC_<NAME>_*
+	for _, name := range cfg.OIDC.Providers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		client := OIDCClientConfig{Name: name}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		if err := env.ParseWithOptions(&client, env.Options{Prefix: prefix}); err != nil {
+			return nil, fmt.Errorf("parse env failed: %w", err)
+		}
+		cfg.OIDC.Clients = append(cfg.OIDC.Clients, client)
+	}
+
 	// Validate config
 	if err := validate.Struct(cfg); err != nil {
 		return nil, fmt.Errorf("validate env failed: %w", err)
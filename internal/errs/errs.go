@@ -7,10 +7,55 @@ var (
 	ErrUserNotFound           = errors.New("user not found")
 	ErrEmailAlreadyExists     = errors.New("email already exists")
 	ErrInvalidEmailOrPassword = errors.New("invalid email or password")
+	ErrTooManyLoginAttempts   = errors.New("too many failed login attempts, try again later")
 	ErrTokenNotFound          = errors.New("token not found")
+	ErrInvalidToken           = errors.New("invalid token")
 	ErrTokenRevoked           = errors.New("token revoked")
 	ErrTokenExpires           = errors.New("token expires")
+	ErrTokenReused            = errors.New("refresh token reused, please login again")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrEmailNotVerified       = errors.New("email not verified")
+	ErrEmailAlreadyVerified   = errors.New("email already verified")
+	ErrInvalidUserToken       = errors.New("invalid or expired token")
+	ErrInvalidPassword        = errors.New("invalid current password")
+	ErrSamePassword           = errors.New("new password must differ from current password")
+	ErrPasswordTooShort       = errors.New("password is too short")
+	ErrPasswordTooLong        = errors.New("password is too long")
+	ErrPasswordBreached       = errors.New("password appears in a list of breached passwords, choose another")
+	ErrPasswordSet            = errors.New("account has a password, confirm with it instead")
+	ErrMFAAlreadyEnabled      = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled          = errors.New("two-factor authentication not enabled")
+	ErrMFANotEnrolled         = errors.New("two-factor enrollment not started")
+	ErrInvalidMFACode         = errors.New("invalid two-factor code")
+	ErrMFAUnavailable         = errors.New("two-factor authentication is not configured on this server")
+	ErrOIDCProviderNotFound   = errors.New("unknown identity provider")
+	ErrInvalidOIDCState       = errors.New("invalid or expired sign-in state")
+	ErrOIDCLoginFailed        = errors.New("identity provider sign-in failed")
+	ErrOIDCEmailNotVerified   = errors.New("email not verified by the identity provider")
+	ErrOIDCLinkConflict       = errors.New("email belongs to an account that cannot be linked, verify it or sign in with your password")
 	ErrUnauthorized           = errors.New("unauthorized")
+	ErrForbidden              = errors.New("forbidden")
+	ErrInvalidRole            = errors.New("invalid role")
+	ErrCannotChangeOwnRole    = errors.New("cannot change own role")
+	ErrCannotDisableSelf      = errors.New("cannot disable own account")
+	ErrAccountDisabled        = errors.New("account disabled")
+)
+
+// Error Impersonation
+var (
+	ErrCannotImpersonate       = errors.New("only customer accounts can be impersonated")
+	ErrImpersonationNotAllowed = errors.New("not allowed while impersonating a customer")
+	ErrImpersonationReason     = errors.New("impersonation reason is required")
+	ErrAuditUnavailable        = errors.New("audit log unavailable, try again later")
+)
+
+// Error API Keys
+var (
+	ErrInvalidAPIKey       = errors.New("invalid or expired api key")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrAPIKeyNotAllowed    = errors.New("api keys cannot be used for this request")
+	ErrInvalidAPIKeyScope  = errors.New("invalid api key scope")
+	ErrInvalidAPIKeyExpiry = errors.New("api key expiry must be in the future")
 )
 
 // Error Products
@@ -24,4 +69,54 @@ var (
 var (
 	ErrCartEmpty     = errors.New("cart empty")
 	ErrOrderNotFound = errors.New("order not found")
+
+	ErrInvalidOrderStatus      = errors.New("invalid order status")
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+
+	ErrOrderNotRefundable = errors.New("order has no captured payment to refund")
+	ErrInvalidRefundItem  = errors.New("invalid refund item")
+	ErrRefundExceedsPaid  = errors.New("refund exceeds refundable amount")
+	ErrNothingToRefund    = errors.New("nothing to refund")
+	ErrRefundConflict     = errors.New("order changed during refund, try again")
+
+	ErrReturnNotFound           = errors.New("return not found")
+	ErrOrderNotReturnable       = errors.New("order is not eligible for return")
+	ErrInvalidReturnItem        = errors.New("invalid return item")
+	ErrInvalidReturnStatus      = errors.New("invalid return status")
+	ErrInvalidReturnTransition  = errors.New("invalid return status transition")
+	ErrReturnResolutionRequired = errors.New("every return item needs a resolution")
+
+	ErrShipmentNotFound         = errors.New("shipment not found")
+	ErrOrderNotShippable        = errors.New("order is not ready to ship")
+	ErrInvalidShipmentItem      = errors.New("invalid shipment item")
+	ErrNothingToShip            = errors.New("nothing to ship")
+	ErrTrackingNumberExists     = errors.New("tracking number already used")
+	ErrShipmentAlreadyDelivered = errors.New("shipment already delivered")
+)
+
+// Error Addresses
+var (
+	ErrAddressNotFound    = errors.New("address not found")
+	ErrAddressRequired    = errors.New("shipping address required")
+	ErrAddressAmbiguous   = errors.New("use either address_id or address, not both")
+	ErrInvalidAddress     = errors.New("recipient name, line1 and city are required")
+	ErrUnsupportedCountry = errors.New("country not supported for shipping")
+	ErrProvinceRequired   = errors.New("province is required for this country")
+	ErrInvalidPostalCode  = errors.New("invalid postal code for this country")
+	ErrInvalidPhone       = errors.New("invalid phone number")
+)
+
+// Error Payments
+var (
+	ErrPaymentNotFound         = errors.New("payment not found")
+	ErrOrderNotPayable         = errors.New("order is not awaiting payment")
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrPaymentProviderNotFound = errors.New("payment provider not found")
+)
+
+// Error Idempotency
+var (
+	ErrIdempotencyKeyInvalid    = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key reused with different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is in progress")
 )
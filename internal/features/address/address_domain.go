@@ -0,0 +1,115 @@
+package address
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+)
+
+// countryRule : postal code format and whether province/state is part of the address
+type countryRule struct {
+	postalCode       *regexp.Regexp
+	provinceRequired bool
+}
+
+// countryRules : supported shipping countries, ISO 3166-1 alpha-2
+var countryRules = map[string]countryRule{
+	"TH": {postalCode: regexp.MustCompile(`^[1-9][0-9]{4}$`), provinceRequired: true},
+	"US": {postalCode: regexp.MustCompile(`^[0-9]{5}(-[0-9]{4})?$`), provinceRequired: true},
+	"JP": {postalCode: regexp.MustCompile(`^[0-9]{3}-?[0-9]{4}$`), provinceRequired: true},
+	"SG": {postalCode: regexp.MustCompile(`^[0-9]{6}$`), provinceRequired: false},
+	"GB": {postalCode: regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`), provinceRequired: false},
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 -]{6,18}[0-9]$`)
+
+// Address : postal address, stored in address book and snapshotted on orders
+type Address struct {
+	RecipientName string `json:"recipient_name" db:"recipient_name"`
+	Phone         string `json:"phone" db:"phone"`
+	Line1         string `json:"line1" db:"line1"`
+	Line2         string `json:"line2,omitempty" db:"line2"`
+	City          string `json:"city" db:"city"`
+	Province      string `json:"province,omitempty" db:"province"`
+	PostalCode    string `json:"postal_code" db:"postal_code"`
+	Country       string `json:"country" db:"country"`
+}
+
+// Normalize : trim fields, upper-case country and postal code
+func (a *Address) Normalize() {
+	a.RecipientName = strings.TrimSpace(a.RecipientName)
+	a.Phone = strings.TrimSpace(a.Phone)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.Province = strings.TrimSpace(a.Province)
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(a.PostalCode))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Validate : call after Normalize
+func (a *Address) Validate() error {
+	rule, ok := countryRules[a.Country]
+	if !ok {
+		return errs.ErrUnsupportedCountry
+	}
+	if a.RecipientName == "" || a.Line1 == "" || a.City == "" {
+		return errs.ErrInvalidAddress
+	}
+	if rule.provinceRequired && a.Province == "" {
+		return errs.ErrProvinceRequired
+	}
+	if !rule.postalCode.MatchString(a.PostalCode) {
+		return errs.ErrInvalidPostalCode
+	}
+	if !phonePattern.MatchString(a.Phone) {
+		return errs.ErrInvalidPhone
+	}
+	return nil
+}
+
+// String : one line format, kept in orders.address for old clients
+func (a Address) String() string {
+	parts := []string{a.RecipientName, a.Line1, a.Line2, a.City, a.Province, a.PostalCode, a.Country}
+
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+type UserAddress struct {
+	ID                int64     `json:"id" db:"id"`
+	UserID            string    `json:"user_id" db:"user_id"`
+	Label             string    `json:"label" db:"label"` // e.g. Home, Office
+	IsDefaultShipping bool      `json:"is_default_shipping" db:"is_default_shipping"`
+	IsDefaultBilling  bool      `json:"is_default_billing" db:"is_default_billing"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+
+	Address
+}
+
+// ============ Address DTO =================
+
+type AddressInput struct {
+	Label             string
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+
+	Address
+}
+
+type AddressResponse struct {
+	ID                int64  `json:"id"`
+	Label             string `json:"label,omitempty"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+
+	Address
+}
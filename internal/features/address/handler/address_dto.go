@@ -0,0 +1,40 @@
+package addresshandler
+
+import "github.com/codepnw/go-starter-kit/internal/features/address"
+
+const (
+	ParamAddressID = "address_id"
+)
+
+// AddressReq : country rules (postal code, province) are checked by the service
+type AddressReq struct {
+	RecipientName string `json:"recipient_name" binding:"required,max=100"`
+	Phone         string `json:"phone" binding:"required,max=20"`
+	Line1         string `json:"line1" binding:"required,max=255"`
+	Line2         string `json:"line2" binding:"max=255"`
+	City          string `json:"city" binding:"required,max=100"`
+	Province      string `json:"province" binding:"max=100"`
+	PostalCode    string `json:"postal_code" binding:"required,max=20"`
+	Country       string `json:"country" binding:"required,len=2"` // ISO 3166-1 alpha-2, e.g. TH
+}
+
+func (r AddressReq) ToAddress() address.Address {
+	return address.Address{
+		RecipientName: r.RecipientName,
+		Phone:         r.Phone,
+		Line1:         r.Line1,
+		Line2:         r.Line2,
+		City:          r.City,
+		Province:      r.Province,
+		PostalCode:    r.PostalCode,
+		Country:       r.Country,
+	}
+}
+
+type SaveAddressReq struct {
+	Label             string `json:"label" binding:"max=50"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+
+	AddressReq
+}
@@ -0,0 +1,146 @@
+package addresshandler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/address"
+	addressservice "github.com/codepnw/go-starter-kit/internal/features/address/service"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AddressHandler struct {
+	service addressservice.AddressService
+}
+
+func NewAddressHandler(service addressservice.AddressService) *AddressHandler {
+	return &AddressHandler{service: service}
+}
+
+func (h *AddressHandler) ListAddresses(c *gin.Context) {
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	resp, err := h.service.ListAddresses(c.Request.Context(), userID)
+	if err != nil {
+		response.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *AddressHandler) GetAddress(c *gin.Context) {
+	userID, addressID, ok := h.params(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetAddress(c.Request.Context(), userID, addressID)
+	if err != nil {
+		addressError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	req := new(SaveAddressReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.CreateAddress(c.Request.Context(), userID, saveInput(req))
+	if err != nil {
+		addressError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	userID, addressID, ok := h.params(c)
+	if !ok {
+		return
+	}
+
+	req := new(SaveAddressReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.UpdateAddress(c.Request.Context(), userID, addressID, saveInput(req))
+	if err != nil {
+		addressError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	userID, addressID, ok := h.params(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAddress(c.Request.Context(), userID, addressID); err != nil {
+		addressError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+// addressError : shared by address actions
+func addressError(c *gin.Context, err error) {
+	switch err {
+	case errs.ErrAddressNotFound:
+		response.ResponseError(c, http.StatusNotFound, err)
+	case errs.ErrAddressRequired, errs.ErrAddressAmbiguous, errs.ErrInvalidAddress, errs.ErrUnsupportedCountry,
+		errs.ErrProvinceRequired, errs.ErrInvalidPostalCode, errs.ErrInvalidPhone:
+		response.ResponseError(c, http.StatusBadRequest, err)
+	default:
+		response.ResponseError(c, http.StatusInternalServerError, err)
+	}
+}
+
+func (h *AddressHandler) params(c *gin.Context) (string, int64, bool) {
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return "", 0, false
+	}
+
+	addressID, err := strconv.ParseInt(c.Param(ParamAddressID), 10, 64)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return "", 0, false
+	}
+	return userID, addressID, true
+}
+
+func saveInput(req *SaveAddressReq) address.AddressInput {
+	return address.AddressInput{
+		Label:             req.Label,
+		IsDefaultShipping: req.IsDefaultShipping,
+		IsDefaultBilling:  req.IsDefaultBilling,
+		Address:           req.ToAddress(),
+	}
+}
@@ -0,0 +1,248 @@
+package addressrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/address"
+)
+
+//go:generate mockgen -source=address_repository.go -destination=address_repository_mock.go -package=addressrepository
+type AddressRepository interface {
+	FindAddresses(ctx context.Context, userID string) ([]*address.UserAddress, error)
+	FindAddress(ctx context.Context, userID string, addressID int64) (*address.UserAddress, error)
+	FindDefaultShippingAddress(ctx context.Context, userID string) (*address.UserAddress, error)
+	DeleteAddress(ctx context.Context, userID string, addressID int64) error
+
+	// Transaction
+	CountAddressesTx(ctx context.Context, tx *sql.Tx, userID string) (int, error)
+	ClearDefaultsTx(ctx context.Context, tx *sql.Tx, userID string, shipping, billing bool) error
+	InsertAddressTx(ctx context.Context, tx *sql.Tx, addr *address.UserAddress) error
+	UpdateAddressTx(ctx context.Context, tx *sql.Tx, addr *address.UserAddress) error
+}
+
+type addressRepository struct {
+	db *sql.DB
+}
+
+func NewAddressRepository(db *sql.DB) AddressRepository {
+	return &addressRepository{db: db}
+}
+
+const addressColumns = `
+	id,
+	user_id,
+	COALESCE(label, ''),
+	recipient_name,
+	phone,
+	line1,
+	COALESCE(line2, ''),
+	city,
+	COALESCE(province, ''),
+	postal_code,
+	country,
+	is_default_shipping,
+	is_default_billing,
+	created_at,
+	updated_at
+`
+
+func (r *addressRepository) FindAddresses(ctx context.Context, userID string) ([]*address.UserAddress, error) {
+	query := `
+		SELECT ` + addressColumns + ` FROM user_addresses
+		WHERE user_id = $1
+		ORDER BY is_default_shipping DESC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addrs []*address.UserAddress
+
+	for rows.Next() {
+		addr, err := scanAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan address failed: %w", err)
+		}
+		addrs = append(addrs, addr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return addrs, nil
+}
+
+func (r *addressRepository) FindAddress(ctx context.Context, userID string, addressID int64) (*address.UserAddress, error) {
+	query := `SELECT ` + addressColumns + ` FROM user_addresses WHERE id = $1 AND user_id = $2`
+	return r.findAddress(ctx, query, addressID, userID)
+}
+
+func (r *addressRepository) FindDefaultShippingAddress(ctx context.Context, userID string) (*address.UserAddress, error) {
+	query := `SELECT ` + addressColumns + ` FROM user_addresses WHERE user_id = $1 AND is_default_shipping`
+	return r.findAddress(ctx, query, userID)
+}
+
+func (r *addressRepository) findAddress(ctx context.Context, query string, args ...any) (*address.UserAddress, error) {
+	addr, err := scanAddress(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrAddressNotFound
+		}
+		return nil, err
+	}
+	return addr, nil
+}
+
+func (r *addressRepository) DeleteAddress(ctx context.Context, userID string, addressID int64) error {
+	query := `DELETE FROM user_addresses WHERE id = $1 AND user_id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, addressID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errs.ErrAddressNotFound
+	}
+	return nil
+}
+
+func (r *addressRepository) CountAddressesTx(ctx context.Context, tx *sql.Tx, userID string) (int, error) {
+	var total int
+	query := `SELECT COUNT(*) FROM user_addresses WHERE user_id = $1`
+
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// ClearDefaultsTx : unset current default before a new one is saved
+func (r *addressRepository) ClearDefaultsTx(ctx context.Context, tx *sql.Tx, userID string, shipping, billing bool) error {
+	query := `
+		UPDATE user_addresses SET
+			is_default_shipping = is_default_shipping AND NOT $2,
+			is_default_billing = is_default_billing AND NOT $3,
+			updated_at = NOW()
+		WHERE user_id = $1 AND ((is_default_shipping AND $2) OR (is_default_billing AND $3))
+	`
+	_, err := tx.ExecContext(ctx, query, userID, shipping, billing)
+	return err
+}
+
+func (r *addressRepository) InsertAddressTx(ctx context.Context, tx *sql.Tx, addr *address.UserAddress) error {
+	query := `
+		INSERT INTO user_addresses (
+			user_id, label, recipient_name, phone, line1, line2, city, province, postal_code, country,
+			is_default_shipping, is_default_billing
+		)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		addr.UserID,
+		addr.Label,
+		addr.RecipientName,
+		addr.Phone,
+		addr.Line1,
+		addr.Line2,
+		addr.City,
+		addr.Province,
+		addr.PostalCode,
+		addr.Country,
+		addr.IsDefaultShipping,
+		addr.IsDefaultBilling,
+	).Scan(
+		&addr.ID,
+		&addr.CreatedAt,
+		&addr.UpdatedAt,
+	)
+}
+
+func (r *addressRepository) UpdateAddressTx(ctx context.Context, tx *sql.Tx, addr *address.UserAddress) error {
+	query := `
+		UPDATE user_addresses SET
+			label = NULLIF($3, ''),
+			recipient_name = $4,
+			phone = $5,
+			line1 = $6,
+			line2 = NULLIF($7, ''),
+			city = $8,
+			province = NULLIF($9, ''),
+			postal_code = $10,
+			country = $11,
+			is_default_shipping = $12,
+			is_default_billing = $13,
+			updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING created_at, updated_at
+	`
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		addr.ID,
+		addr.UserID,
+		addr.Label,
+		addr.RecipientName,
+		addr.Phone,
+		addr.Line1,
+		addr.Line2,
+		addr.City,
+		addr.Province,
+		addr.PostalCode,
+		addr.Country,
+		addr.IsDefaultShipping,
+		addr.IsDefaultBilling,
+	).Scan(
+		&addr.CreatedAt,
+		&addr.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrAddressNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAddress(row rowScanner) (*address.UserAddress, error) {
+	addr := new(address.UserAddress)
+
+	err := row.Scan(
+		&addr.ID,
+		&addr.UserID,
+		&addr.Label,
+		&addr.RecipientName,
+		&addr.Phone,
+		&addr.Line1,
+		&addr.Line2,
+		&addr.City,
+		&addr.Province,
+		&addr.PostalCode,
+		&addr.Country,
+		&addr.IsDefaultShipping,
+		&addr.IsDefaultBilling,
+		&addr.CreatedAt,
+		&addr.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
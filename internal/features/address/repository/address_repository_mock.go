@@ -0,0 +1,194 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: address_repository.go
+
+// Package addressrepository is a generated GoMock package.
+package addressrepository
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+
+	address "github.com/codepnw/go-starter-kit/internal/features/address"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAddressRepository is a mock of AddressRepository interface.
+type MockAddressRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAddressRepositoryMockRecorder
+}
+
+// MockAddressRepositoryMockRecorder is the mock recorder for MockAddressRepository.
+type MockAddressRepositoryMockRecorder struct {
+	mock *MockAddressRepository
+}
+
+// NewMockAddressRepository creates a new mock instance.
+func NewMockAddressRepository(ctrl *gomock.Controller) *MockAddressRepository {
+	mock := &MockAddressRepository{ctrl: ctrl}
+	mock.recorder = &MockAddressRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAddressRepository) EXPECT() *MockAddressRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClearDefaultsTx mocks base method.
+func (m *MockAddressRepository) ClearDefaultsTx(ctx context.Context, tx *sql.Tx, userID string, shipping, billing bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearDefaultsTx", ctx, tx, userID, shipping, billing)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearDefaultsTx indicates an expected call of ClearDefaultsTx.
+func (mr *MockAddressRepositoryMockRecorder) ClearDefaultsTx(ctx, tx, userID, shipping, billing interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearDefaultsTx", reflect.TypeOf((*MockAddressRepository)(nil).ClearDefaultsTx), ctx, tx, userID, shipping, billing)
+}
+
+// CountAddressesTx mocks base method.
+func (m *MockAddressRepository) CountAddressesTx(ctx context.Context, tx *sql.Tx, userID string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAddressesTx", ctx, tx, userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAddressesTx indicates an expected call of CountAddressesTx.
+func (mr *MockAddressRepositoryMockRecorder) CountAddressesTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAddressesTx", reflect.TypeOf((*MockAddressRepository)(nil).CountAddressesTx), ctx, tx, userID)
+}
+
+// DeleteAddress mocks base method.
+func (m *MockAddressRepository) DeleteAddress(ctx context.Context, userID string, addressID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAddress", ctx, userID, addressID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAddress indicates an expected call of DeleteAddress.
+func (mr *MockAddressRepositoryMockRecorder) DeleteAddress(ctx, userID, addressID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAddress", reflect.TypeOf((*MockAddressRepository)(nil).DeleteAddress), ctx, userID, addressID)
+}
+
+// FindAddress mocks base method.
+func (m *MockAddressRepository) FindAddress(ctx context.Context, userID string, addressID int64) (*address.UserAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAddress", ctx, userID, addressID)
+	ret0, _ := ret[0].(*address.UserAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAddress indicates an expected call of FindAddress.
+func (mr *MockAddressRepositoryMockRecorder) FindAddress(ctx, userID, addressID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAddress", reflect.TypeOf((*MockAddressRepository)(nil).FindAddress), ctx, userID, addressID)
+}
+
+// FindAddresses mocks base method.
+func (m *MockAddressRepository) FindAddresses(ctx context.Context, userID string) ([]*address.UserAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAddresses", ctx, userID)
+	ret0, _ := ret[0].([]*address.UserAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAddresses indicates an expected call of FindAddresses.
+func (mr *MockAddressRepositoryMockRecorder) FindAddresses(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAddresses", reflect.TypeOf((*MockAddressRepository)(nil).FindAddresses), ctx, userID)
+}
+
+// FindDefaultShippingAddress mocks base method.
+func (m *MockAddressRepository) FindDefaultShippingAddress(ctx context.Context, userID string) (*address.UserAddress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDefaultShippingAddress", ctx, userID)
+	ret0, _ := ret[0].(*address.UserAddress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDefaultShippingAddress indicates an expected call of FindDefaultShippingAddress.
+func (mr *MockAddressRepositoryMockRecorder) FindDefaultShippingAddress(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDefaultShippingAddress", reflect.TypeOf((*MockAddressRepository)(nil).FindDefaultShippingAddress), ctx, userID)
+}
+
+// InsertAddressTx mocks base method.
+func (m *MockAddressRepository) InsertAddressTx(ctx context.Context, tx *sql.Tx, addr *address.UserAddress) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAddressTx", ctx, tx, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertAddressTx indicates an expected call of InsertAddressTx.
+func (mr *MockAddressRepositoryMockRecorder) InsertAddressTx(ctx, tx, addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAddressTx", reflect.TypeOf((*MockAddressRepository)(nil).InsertAddressTx), ctx, tx, addr)
+}
+
+// UpdateAddressTx mocks base method.
+func (m *MockAddressRepository) UpdateAddressTx(ctx context.Context, tx *sql.Tx, addr *address.UserAddress) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAddressTx", ctx, tx, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAddressTx indicates an expected call of UpdateAddressTx.
+func (mr *MockAddressRepositoryMockRecorder) UpdateAddressTx(ctx, tx, addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAddressTx", reflect.TypeOf((*MockAddressRepository)(nil).UpdateAddressTx), ctx, tx, addr)
+}
+
+// MockrowScanner is a mock of rowScanner interface.
+type MockrowScanner struct {
+	ctrl     *gomock.Controller
+	recorder *MockrowScannerMockRecorder
+}
+
+// MockrowScannerMockRecorder is the mock recorder for MockrowScanner.
+type MockrowScannerMockRecorder struct {
+	mock *MockrowScanner
+}
+
+// NewMockrowScanner creates a new mock instance.
+func NewMockrowScanner(ctrl *gomock.Controller) *MockrowScanner {
+	mock := &MockrowScanner{ctrl: ctrl}
+	mock.recorder = &MockrowScannerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrowScanner) EXPECT() *MockrowScannerMockRecorder {
+	return m.recorder
+}
+
+// Scan mocks base method.
+func (m *MockrowScanner) Scan(dest ...any) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range dest {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Scan", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockrowScannerMockRecorder) Scan(dest ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockrowScanner)(nil).Scan), dest...)
+}
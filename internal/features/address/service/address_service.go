@@ -0,0 +1,198 @@
+package addressservice
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/address"
+	addressrepository "github.com/codepnw/go-starter-kit/internal/features/address/repository"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+)
+
+//go:generate mockgen -source=address_service.go -destination=address_service_mock.go -package=addressservice
+type AddressService interface {
+	ListAddresses(ctx context.Context, userID string) ([]*address.AddressResponse, error)
+	GetAddress(ctx context.Context, userID string, addressID int64) (*address.AddressResponse, error)
+	CreateAddress(ctx context.Context, userID string, input address.AddressInput) (*address.AddressResponse, error)
+	UpdateAddress(ctx context.Context, userID string, addressID int64, input address.AddressInput) (*address.AddressResponse, error)
+	DeleteAddress(ctx context.Context, userID string, addressID int64) error
+
+	// Checkout: saved address id, inline address, or default shipping address when both empty
+	ResolveShippingAddress(ctx context.Context, userID string, addressID int64, inline *address.Address) (*address.Address, error)
+}
+
+type addressService struct {
+	tx   database.TxManager
+	repo addressrepository.AddressRepository
+}
+
+func NewAddressService(tx database.TxManager, repo addressrepository.AddressRepository) AddressService {
+	return &addressService{
+		tx:   tx,
+		repo: repo,
+	}
+}
+
+// ListAddresses implements AddressService.
+func (s *addressService) ListAddresses(ctx context.Context, userID string) ([]*address.AddressResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	addrs, err := s.repo.FindAddresses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*address.AddressResponse, 0, len(addrs))
+	for _, a := range addrs {
+		resp = append(resp, addressResponse(a))
+	}
+	return resp, nil
+}
+
+// GetAddress implements AddressService.
+func (s *addressService) GetAddress(ctx context.Context, userID string, addressID int64) (*address.AddressResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	addr, err := s.repo.FindAddress(ctx, userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+	return addressResponse(addr), nil
+}
+
+// CreateAddress implements AddressService.
+// First address of a user becomes default shipping and billing.
+func (s *addressService) CreateAddress(ctx context.Context, userID string, input address.AddressInput) (*address.AddressResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	input.Normalize()
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	addr := &address.UserAddress{
+		UserID:            userID,
+		Label:             input.Label,
+		IsDefaultShipping: input.IsDefaultShipping,
+		IsDefaultBilling:  input.IsDefaultBilling,
+		Address:           input.Address,
+	}
+
+	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		total, err := s.repo.CountAddressesTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if total == 0 {
+			addr.IsDefaultShipping = true
+			addr.IsDefaultBilling = true
+		}
+
+		if err := s.repo.ClearDefaultsTx(ctx, tx, userID, addr.IsDefaultShipping, addr.IsDefaultBilling); err != nil {
+			return err
+		}
+		return s.repo.InsertAddressTx(ctx, tx, addr)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addressResponse(addr), nil
+}
+
+// UpdateAddress implements AddressService.
+// Orders keep their own snapshot, editing here does not change past orders.
+func (s *addressService) UpdateAddress(ctx context.Context, userID string, addressID int64, input address.AddressInput) (*address.AddressResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	input.Normalize()
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	addr := &address.UserAddress{
+		ID:                addressID,
+		UserID:            userID,
+		Label:             input.Label,
+		IsDefaultShipping: input.IsDefaultShipping,
+		IsDefaultBilling:  input.IsDefaultBilling,
+		Address:           input.Address,
+	}
+
+	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.ClearDefaultsTx(ctx, tx, userID, addr.IsDefaultShipping, addr.IsDefaultBilling); err != nil {
+			return err
+		}
+		return s.repo.UpdateAddressTx(ctx, tx, addr)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addressResponse(addr), nil
+}
+
+// DeleteAddress implements AddressService.
+func (s *addressService) DeleteAddress(ctx context.Context, userID string, addressID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	return s.repo.DeleteAddress(ctx, userID, addressID)
+}
+
+// ResolveShippingAddress implements AddressService.
+func (s *addressService) ResolveShippingAddress(ctx context.Context, userID string, addressID int64, inline *address.Address) (*address.Address, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if addressID > 0 && inline != nil {
+		return nil, errs.ErrAddressAmbiguous
+	}
+
+	// Inline Address
+	if inline != nil {
+		addr := *inline
+		addr.Normalize()
+		if err := addr.Validate(); err != nil {
+			return nil, err
+		}
+		return &addr, nil
+	}
+
+	// Saved Address
+	var saved *address.UserAddress
+	var err error
+
+	if addressID > 0 {
+		saved, err = s.repo.FindAddress(ctx, userID, addressID)
+	} else {
+		saved, err = s.repo.FindDefaultShippingAddress(ctx, userID)
+		if err == errs.ErrAddressNotFound {
+			return nil, errs.ErrAddressRequired
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	addr := saved.Address
+	return &addr, nil
+}
+
+// -------- HELPER ------------
+
+func addressResponse(a *address.UserAddress) *address.AddressResponse {
+	return &address.AddressResponse{
+		ID:                a.ID,
+		Label:             a.Label,
+		IsDefaultShipping: a.IsDefaultShipping,
+		IsDefaultBilling:  a.IsDefaultBilling,
+		Address:           a.Address,
+	}
+}
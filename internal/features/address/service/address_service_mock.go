@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: address_service.go
+
+// Package addressservice is a generated GoMock package.
+package addressservice
+
+import (
+	context "context"
+	reflect "reflect"
+
+	address "github.com/codepnw/go-starter-kit/internal/features/address"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAddressService is a mock of AddressService interface.
+type MockAddressService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAddressServiceMockRecorder
+}
+
+// MockAddressServiceMockRecorder is the mock recorder for MockAddressService.
+type MockAddressServiceMockRecorder struct {
+	mock *MockAddressService
+}
+
+// NewMockAddressService creates a new mock instance.
+func NewMockAddressService(ctrl *gomock.Controller) *MockAddressService {
+	mock := &MockAddressService{ctrl: ctrl}
+	mock.recorder = &MockAddressServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAddressService) EXPECT() *MockAddressServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateAddress mocks base method.
+func (m *MockAddressService) CreateAddress(ctx context.Context, userID string, input address.AddressInput) (*address.AddressResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAddress", ctx, userID, input)
+	ret0, _ := ret[0].(*address.AddressResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAddress indicates an expected call of CreateAddress.
+func (mr *MockAddressServiceMockRecorder) CreateAddress(ctx, userID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAddress", reflect.TypeOf((*MockAddressService)(nil).CreateAddress), ctx, userID, input)
+}
+
+// DeleteAddress mocks base method.
+func (m *MockAddressService) DeleteAddress(ctx context.Context, userID string, addressID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAddress", ctx, userID, addressID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAddress indicates an expected call of DeleteAddress.
+func (mr *MockAddressServiceMockRecorder) DeleteAddress(ctx, userID, addressID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAddress", reflect.TypeOf((*MockAddressService)(nil).DeleteAddress), ctx, userID, addressID)
+}
+
+// GetAddress mocks base method.
+func (m *MockAddressService) GetAddress(ctx context.Context, userID string, addressID int64) (*address.AddressResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAddress", ctx, userID, addressID)
+	ret0, _ := ret[0].(*address.AddressResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAddress indicates an expected call of GetAddress.
+func (mr *MockAddressServiceMockRecorder) GetAddress(ctx, userID, addressID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAddress", reflect.TypeOf((*MockAddressService)(nil).GetAddress), ctx, userID, addressID)
+}
+
+// ListAddresses mocks base method.
+func (m *MockAddressService) ListAddresses(ctx context.Context, userID string) ([]*address.AddressResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAddresses", ctx, userID)
+	ret0, _ := ret[0].([]*address.AddressResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAddresses indicates an expected call of ListAddresses.
+func (mr *MockAddressServiceMockRecorder) ListAddresses(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAddresses", reflect.TypeOf((*MockAddressService)(nil).ListAddresses), ctx, userID)
+}
+
+// ResolveShippingAddress mocks base method.
+func (m *MockAddressService) ResolveShippingAddress(ctx context.Context, userID string, addressID int64, inline *address.Address) (*address.Address, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveShippingAddress", ctx, userID, addressID, inline)
+	ret0, _ := ret[0].(*address.Address)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveShippingAddress indicates an expected call of ResolveShippingAddress.
+func (mr *MockAddressServiceMockRecorder) ResolveShippingAddress(ctx, userID, addressID, inline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveShippingAddress", reflect.TypeOf((*MockAddressService)(nil).ResolveShippingAddress), ctx, userID, addressID, inline)
+}
+
+// UpdateAddress mocks base method.
+func (m *MockAddressService) UpdateAddress(ctx context.Context, userID string, addressID int64, input address.AddressInput) (*address.AddressResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAddress", ctx, userID, addressID, input)
+	ret0, _ := ret[0].(*address.AddressResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAddress indicates an expected call of UpdateAddress.
+func (mr *MockAddressServiceMockRecorder) UpdateAddress(ctx, userID, addressID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAddress", reflect.TypeOf((*MockAddressService)(nil).UpdateAddress), ctx, userID, addressID, input)
+}
@@ -0,0 +1,282 @@
+package addressservice_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/address"
+	addressrepository "github.com/codepnw/go-starter-kit/internal/features/address/repository"
+	addressservice "github.com/codepnw/go-starter-kit/internal/features/address/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const mockUserID = "mock-uuid-1"
+
+var ErrDB = errors.New("database error")
+
+func mockAddress() address.Address {
+	return address.Address{
+		RecipientName: "John Doe",
+		Phone:         "+66 81 234 5678",
+		Line1:         "99 Sukhumvit Rd",
+		City:          "Bangkok",
+		Province:      "Bangkok",
+		PostalCode:    "10110",
+		Country:       "th",
+	}
+}
+
+func TestCreateAddress(t *testing.T) {
+	type testCase struct {
+		name          string
+		input         address.AddressInput
+		mockFn        func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository)
+		expectDefault bool
+		expectedErr   error
+	}
+
+	withTx := func(mockTx *database.MockTxManager) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success first address becomes default",
+			input: address.AddressInput{Label: "Home", Address: mockAddress()},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {
+				withTx(mockTx)
+				mockRepo.EXPECT().CountAddressesTx(gomock.Any(), gomock.Any(), mockUserID).Return(0, nil).Times(1)
+				mockRepo.EXPECT().ClearDefaultsTx(gomock.Any(), gomock.Any(), mockUserID, true, true).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertAddressTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectDefault: true,
+			expectedErr:   nil,
+		},
+		{
+			name:  "success second address keeps defaults",
+			input: address.AddressInput{Label: "Office", Address: mockAddress()},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {
+				withTx(mockTx)
+				mockRepo.EXPECT().CountAddressesTx(gomock.Any(), gomock.Any(), mockUserID).Return(1, nil).Times(1)
+				mockRepo.EXPECT().ClearDefaultsTx(gomock.Any(), gomock.Any(), mockUserID, false, false).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertAddressTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectDefault: false,
+			expectedErr:   nil,
+		},
+		{
+			name: "fail unsupported country",
+			input: func() address.AddressInput {
+				a := mockAddress()
+				a.Country = "XX"
+				return address.AddressInput{Address: a}
+			}(),
+			mockFn:      func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {},
+			expectedErr: errs.ErrUnsupportedCountry,
+		},
+		{
+			name: "fail province required",
+			input: func() address.AddressInput {
+				a := mockAddress()
+				a.Province = ""
+				return address.AddressInput{Address: a}
+			}(),
+			mockFn:      func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {},
+			expectedErr: errs.ErrProvinceRequired,
+		},
+		{
+			name: "fail invalid postal code",
+			input: func() address.AddressInput {
+				a := mockAddress()
+				a.PostalCode = "1011"
+				return address.AddressInput{Address: a}
+			}(),
+			mockFn:      func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {},
+			expectedErr: errs.ErrInvalidPostalCode,
+		},
+		{
+			name: "success postal code without province",
+			input: func() address.AddressInput {
+				a := mockAddress()
+				a.Province = ""
+				a.City = "London"
+				a.PostalCode = "sw1a 1aa"
+				a.Country = "GB"
+				return address.AddressInput{Address: a}
+			}(),
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {
+				withTx(mockTx)
+				mockRepo.EXPECT().CountAddressesTx(gomock.Any(), gomock.Any(), mockUserID).Return(2, nil).Times(1)
+				mockRepo.EXPECT().ClearDefaultsTx(gomock.Any(), gomock.Any(), mockUserID, false, false).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertAddressTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail invalid phone",
+			input: func() address.AddressInput {
+				a := mockAddress()
+				a.Phone = "call me"
+				return address.AddressInput{Address: a}
+			}(),
+			mockFn:      func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {},
+			expectedErr: errs.ErrInvalidPhone,
+		},
+		{
+			name:  "fail insert",
+			input: address.AddressInput{Address: mockAddress()},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *addressrepository.MockAddressRepository) {
+				withTx(mockTx)
+				mockRepo.EXPECT().CountAddressesTx(gomock.Any(), gomock.Any(), mockUserID).Return(1, nil).Times(1)
+				mockRepo.EXPECT().ClearDefaultsTx(gomock.Any(), gomock.Any(), mockUserID, false, false).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertAddressTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockRepo := setup(t)
+
+			tc.mockFn(mockTx, mockRepo)
+
+			resp, err := service.CreateAddress(context.Background(), mockUserID, tc.input)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectDefault, resp.IsDefaultShipping)
+				assert.Equal(t, tc.expectDefault, resp.IsDefaultBilling)
+			}
+		})
+	}
+}
+
+func TestUpdateAddress(t *testing.T) {
+	service, mockTx, mockRepo := setup(t)
+
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).Times(1)
+	mockRepo.EXPECT().ClearDefaultsTx(gomock.Any(), gomock.Any(), mockUserID, true, false).Return(nil).Times(1)
+	mockRepo.EXPECT().UpdateAddressTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(errs.ErrAddressNotFound).Times(1)
+
+	input := address.AddressInput{IsDefaultShipping: true, Address: mockAddress()}
+	resp, err := service.UpdateAddress(context.Background(), mockUserID, 99, input)
+
+	assert.ErrorIs(t, err, errs.ErrAddressNotFound)
+	assert.Nil(t, resp)
+}
+
+func TestResolveShippingAddress(t *testing.T) {
+	saved := &address.UserAddress{ID: 7, UserID: mockUserID, Address: mockAddress()}
+
+	type testCase struct {
+		name        string
+		addressID   int64
+		inline      *address.Address
+		mockFn      func(mockRepo *addressrepository.MockAddressRepository)
+		expected    string
+		expectedErr error
+	}
+
+	inline := mockAddress()
+	invalid := mockAddress()
+	invalid.PostalCode = "ABC"
+
+	testCases := []testCase{
+		{
+			name:        "fail both id and inline",
+			addressID:   7,
+			inline:      &inline,
+			mockFn:      func(mockRepo *addressrepository.MockAddressRepository) {},
+			expectedErr: errs.ErrAddressAmbiguous,
+		},
+		{
+			name:     "success inline",
+			inline:   &inline,
+			mockFn:   func(mockRepo *addressrepository.MockAddressRepository) {},
+			expected: "TH",
+		},
+		{
+			name:        "fail inline invalid",
+			inline:      &invalid,
+			mockFn:      func(mockRepo *addressrepository.MockAddressRepository) {},
+			expectedErr: errs.ErrInvalidPostalCode,
+		},
+		{
+			name:      "success saved address",
+			addressID: 7,
+			mockFn: func(mockRepo *addressrepository.MockAddressRepository) {
+				mockRepo.EXPECT().FindAddress(gomock.Any(), mockUserID, int64(7)).Return(saved, nil).Times(1)
+			},
+			expected: saved.Country,
+		},
+		{
+			name:      "fail saved address not found",
+			addressID: 8,
+			mockFn: func(mockRepo *addressrepository.MockAddressRepository) {
+				mockRepo.EXPECT().FindAddress(gomock.Any(), mockUserID, int64(8)).Return(nil, errs.ErrAddressNotFound).Times(1)
+			},
+			expectedErr: errs.ErrAddressNotFound,
+		},
+		{
+			name: "success default shipping address",
+			mockFn: func(mockRepo *addressrepository.MockAddressRepository) {
+				mockRepo.EXPECT().FindDefaultShippingAddress(gomock.Any(), mockUserID).Return(saved, nil).Times(1)
+			},
+			expected: saved.Country,
+		},
+		{
+			name: "fail no default address",
+			mockFn: func(mockRepo *addressrepository.MockAddressRepository) {
+				mockRepo.EXPECT().FindDefaultShippingAddress(gomock.Any(), mockUserID).Return(nil, errs.ErrAddressNotFound).Times(1)
+			},
+			expectedErr: errs.ErrAddressRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _, mockRepo := setup(t)
+
+			tc.mockFn(mockRepo)
+
+			addr, err := service.ResolveShippingAddress(context.Background(), mockUserID, tc.addressID, tc.inline)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, addr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, addr.Country)
+			}
+		})
+	}
+}
+
+func setup(t *testing.T) (addressservice.AddressService, *database.MockTxManager, *addressrepository.MockAddressRepository) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := database.NewMockTxManager(ctrl)
+	mockRepo := addressrepository.NewMockAddressRepository(ctrl)
+
+	service := addressservice.NewAddressService(mockTx, mockRepo)
+
+	return service, mockTx, mockRepo
+}
@@ -1,7 +1,73 @@
 package orderhandler
 
-const ParamOrderID = "order_id"
+import addresshandler "github.com/codepnw/go-starter-kit/internal/features/address/handler"
 
+const (
+	ParamOrderID  = "order_id"  // back-office
+	ParamOrderNo  = "order_no"  // customer, ORD-YYYYDDMM-000001
+	ParamReturnID = "return_id" // back-office
+
+	ParamShipmentID = "shipment_id" // back-office
+)
+
+// CreateOrderReq : saved address_id or inline address, none = default shipping address
 type CreateOrderReq struct {
-	Address string `json:"address" binding:"required"`
+	AddressID int64                      `json:"address_id" binding:"omitempty,min=1,excluded_with=Address"`
+	Address   *addresshandler.AddressReq `json:"address"`
+}
+
+type UpdateOrderStatusReq struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type CancelOrderReq struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type RefundOrderReq struct {
+	Items   []RefundItemReq `json:"items" binding:"omitempty,dive"` // empty = full refund
+	Reason  string          `json:"reason" binding:"max=255"`
+	Restock bool            `json:"restock"`
+}
+
+type RefundItemReq struct {
+	OrderItemID int64 `json:"order_item_id" binding:"required"`
+	Quantity    int   `json:"quantity" binding:"required,min=1"`
+}
+
+type CreateShipmentReq struct {
+	Carrier        string            `json:"carrier" binding:"required,max=50"`
+	TrackingNumber string            `json:"tracking_number" binding:"required,max=100"`
+	Items          []ShipmentItemReq `json:"items" binding:"omitempty,dive"` // empty = ship everything left
+}
+
+type ShipmentItemReq struct {
+	OrderItemID int64 `json:"order_item_id" binding:"required"`
+	Quantity    int   `json:"quantity" binding:"required,min=1"`
+}
+
+type RequestReturnReq struct {
+	Items []ReturnItemReq `json:"items" binding:"required,min=1,dive"`
+	Note  string          `json:"note" binding:"max=500"`
+}
+
+type ReturnItemReq struct {
+	OrderItemID int64  `json:"order_item_id" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"required,min=1"`
+	ReasonCode  string `json:"reason_code" binding:"required,oneof=DAMAGED DEFECTIVE WRONG_ITEM NOT_AS_DESCRIBED NO_LONGER_NEEDED OTHER"`
+}
+
+type ReviewReturnReq struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+type ReceiveReturnReq struct {
+	Items []ReturnResolutionReq `json:"items" binding:"required,min=1,dive"`
+	Note  string                `json:"note" binding:"max=500"`
+}
+
+type ReturnResolutionReq struct {
+	ReturnItemID int64  `json:"return_item_id" binding:"required"`
+	Resolution   string `json:"resolution" binding:"required,oneof=RESTOCK WRITE_OFF"`
 }
@@ -1,11 +1,14 @@
 package orderhandler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
 	orderservice "github.com/codepnw/go-starter-kit/internal/features/order/service"
 	"github.com/codepnw/go-starter-kit/pkg/utils/response"
 	"github.com/gin-gonic/gin"
@@ -26,17 +29,27 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	// Body is optional, empty = default shipping address
 	req := new(CreateOrderReq)
-	if err := c.ShouldBindJSON(req); err != nil {
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
 		response.ResponseError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	orderNo, err := h.service.CreateOrder(c.Request.Context(), userID, req.Address)
+	input := order.CheckoutInput{AddressID: req.AddressID}
+	if req.Address != nil {
+		addr := req.Address.ToAddress()
+		input.Address = &addr
+	}
+
+	orderNo, err := h.service.CreateOrder(c.Request.Context(), userID, input)
 	if err != nil {
 		switch err {
-		case errs.ErrCartEmpty:
+		case errs.ErrCartEmpty, errs.ErrAddressRequired, errs.ErrAddressAmbiguous, errs.ErrInvalidAddress,
+			errs.ErrUnsupportedCountry, errs.ErrProvinceRequired, errs.ErrInvalidPostalCode, errs.ErrInvalidPhone:
 			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrAddressNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
 		}
@@ -50,9 +63,13 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 }
 
 func (h *OrderHandler) GetOrderDetails(c *gin.Context) {
-	orderID, _ := strconv.ParseInt(c.Param(ParamOrderID), 10, 64)
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
 
-	resp, err := h.service.GetOrderDetails(c.Request.Context(), orderID)
+	resp, err := h.service.GetOrderDetails(c.Request.Context(), userID, c.Param(ParamOrderNo))
 	if err != nil {
 		switch err {
 		case errs.ErrOrderNotFound:
@@ -89,3 +106,169 @@ func (h *OrderHandler) MyOrders(c *gin.Context) {
 
 	response.ResponseSuccess(c, http.StatusOK, resp)
 }
+
+func (h *OrderHandler) AdminGetOrderDetails(c *gin.Context) {
+	orderID, err := h.getOrderID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.AdminGetOrderDetails(c.Request.Context(), orderID)
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *OrderHandler) AdminListOrders(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := order.OrderStatus(c.Query("status"))
+
+	resp, err := h.service.AdminListOrders(c.Request.Context(), status, page, limit)
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidOrderStatus:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	orderID, err := h.getOrderID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	req := new(UpdateOrderStatusReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.service.UpdateOrderStatus(c.Request.Context(), orderID, order.OrderStatus(req.Status), req.Reason)
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidOrderStatus:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrInvalidStatusTransition:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, gin.H{
+		"message": "order status updated",
+		"status":  req.Status,
+	})
+}
+
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	// Body is optional
+	req := new(CancelOrderReq)
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.CancelOrder(c.Request.Context(), c.Param(ParamOrderNo), req.Reason); err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidStatusTransition, errs.ErrRefundConflict:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, gin.H{
+		"message": "order cancelled",
+		"status":  order.StatusCancelled,
+	})
+}
+
+func (h *OrderHandler) RefundOrder(c *gin.Context) {
+	orderID, err := h.getOrderID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Body is optional, empty = full refund
+	req := new(RefundOrderReq)
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	input := order.RefundInput{
+		Reason:  req.Reason,
+		Restock: req.Restock,
+		Items:   make([]order.RefundItemReq, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		input.Items = append(input.Items, order.RefundItemReq{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	resp, err := h.service.RefundOrder(c.Request.Context(), orderID, input)
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidRefundItem, errs.ErrRefundExceedsPaid, errs.ErrNothingToRefund:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrOrderNotRefundable, errs.ErrInvalidStatusTransition, errs.ErrRefundConflict:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *OrderHandler) getOrderID(c *gin.Context) (int64, error) {
+	return strconv.ParseInt(c.Param(ParamOrderID), 10, 64)
+}
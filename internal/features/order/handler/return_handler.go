@@ -0,0 +1,188 @@
+package orderhandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *OrderHandler) RequestReturn(c *gin.Context) {
+	req := new(RequestReturnReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	input := order.ReturnInput{
+		Note:  req.Note,
+		Items: make([]order.ReturnItemReq, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		input.Items = append(input.Items, order.ReturnItemReq{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			ReasonCode:  order.ReturnReason(item.ReasonCode),
+		})
+	}
+
+	resp, err := h.service.RequestReturn(c.Request.Context(), userID, c.Param(ParamOrderNo), input)
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidReturnItem:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrOrderNotReturnable:
+			response.ResponseError(c, http.StatusConflict, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *OrderHandler) MyOrderReturns(c *gin.Context) {
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	resp, err := h.service.MyOrderReturns(c.Request.Context(), userID, c.Param(ParamOrderNo))
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *OrderHandler) AdminListReturns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := order.ReturnStatus(c.Query("status"))
+
+	resp, err := h.service.AdminListReturns(c.Request.Context(), status, page, limit)
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidReturnStatus:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *OrderHandler) ApproveReturn(c *gin.Context) {
+	h.reviewReturn(c, h.service.ApproveReturn)
+}
+
+func (h *OrderHandler) RejectReturn(c *gin.Context) {
+	h.reviewReturn(c, h.service.RejectReturn)
+}
+
+func (h *OrderHandler) ReceiveReturn(c *gin.Context) {
+	returnID, err := h.getReturnID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	req := new(ReceiveReturnReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	input := order.ReceiveReturnInput{
+		Note:  req.Note,
+		Items: make([]order.ReturnResolutionReq, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		input.Items = append(input.Items, order.ReturnResolutionReq{
+			ReturnItemID: item.ReturnItemID,
+			Resolution:   order.ReturnResolution(item.Resolution),
+		})
+	}
+
+	resp, err := h.service.ReceiveReturn(c.Request.Context(), returnID, input)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *OrderHandler) reviewReturn(c *gin.Context, review func(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error)) {
+	returnID, err := h.getReturnID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Body is optional
+	req := new(ReviewReturnReq)
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := review(c.Request.Context(), returnID, req.Note)
+	if err != nil {
+		h.returnError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+// returnError : shared by back-office return actions
+func (h *OrderHandler) returnError(c *gin.Context, err error) {
+	switch err {
+	case errs.ErrReturnNotFound, errs.ErrOrderNotFound:
+		response.ResponseError(c, http.StatusNotFound, err)
+	case errs.ErrReturnResolutionRequired, errs.ErrInvalidRefundItem, errs.ErrRefundExceedsPaid, errs.ErrNothingToRefund:
+		response.ResponseError(c, http.StatusBadRequest, err)
+	case errs.ErrInvalidReturnTransition, errs.ErrOrderNotRefundable, errs.ErrInvalidStatusTransition, errs.ErrRefundConflict:
+		response.ResponseError(c, http.StatusConflict, err)
+	case errs.ErrUnauthorized:
+		response.ResponseError(c, http.StatusUnauthorized, err)
+	case errs.ErrForbidden:
+		response.ResponseError(c, http.StatusForbidden, err)
+	default:
+		response.ResponseError(c, http.StatusInternalServerError, err)
+	}
+}
+
+func (h *OrderHandler) getReturnID(c *gin.Context) (int64, error) {
+	return strconv.ParseInt(c.Param(ParamReturnID), 10, 64)
+}
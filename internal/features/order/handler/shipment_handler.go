@@ -0,0 +1,92 @@
+package orderhandler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *OrderHandler) CreateShipment(c *gin.Context) {
+	orderID, err := h.getOrderID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	req := new(CreateShipmentReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	input := order.ShipmentInput{
+		Carrier:        strings.TrimSpace(req.Carrier),
+		TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+		Items:          make([]order.ShipmentItemReq, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		input.Items = append(input.Items, order.ShipmentItemReq{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	resp, err := h.service.CreateShipment(c.Request.Context(), orderID, input)
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidShipmentItem, errs.ErrNothingToShip:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrOrderNotShippable, errs.ErrTrackingNumberExists, errs.ErrInvalidStatusTransition:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *OrderHandler) DeliverShipment(c *gin.Context) {
+	orderID, err := h.getOrderID(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	shipmentID, err := strconv.ParseInt(c.Param(ParamShipmentID), 10, 64)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.DeliverShipment(c.Request.Context(), orderID, shipmentID)
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound, errs.ErrShipmentNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrShipmentAlreadyDelivered:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
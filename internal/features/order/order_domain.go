@@ -1,6 +1,14 @@
 package order
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/features/address"
+)
 
 type OrderStatus string
 
@@ -10,21 +18,101 @@ const (
 	StatusShipped   OrderStatus = "SHIPPED"
 	StatusCompleted OrderStatus = "COMPLETED"
 	StatusCancelled OrderStatus = "CANCELLED"
+
+	// Set by shipments only, some items still waiting to ship
+	StatusPartiallyShipped OrderStatus = "PARTIALLY_SHIPPED"
+
+	// Set by refunds only, the amount is kept in Order.RefundedAmount.
+	// Shipping and completion go on from PARTIALLY_REFUNDED, shipments tell what is left.
+	StatusPartiallyRefunded OrderStatus = "PARTIALLY_REFUNDED"
+	StatusRefunded          OrderStatus = "REFUNDED"
 )
 
+// statusTransitions : allowed next statuses, missing key = final status
+var statusTransitions = map[OrderStatus][]OrderStatus{
+	StatusPending:           {StatusPaid, StatusCancelled},
+	StatusPaid:              {StatusShipped, StatusPartiallyShipped, StatusCancelled, StatusPartiallyRefunded, StatusRefunded},
+	StatusPartiallyShipped:  {StatusShipped, StatusPartiallyRefunded, StatusRefunded},
+	StatusShipped:           {StatusCompleted, StatusPartiallyRefunded, StatusRefunded},
+	StatusCompleted:         {StatusPartiallyRefunded, StatusRefunded},
+	StatusPartiallyRefunded: {StatusShipped, StatusPartiallyShipped, StatusCompleted, StatusRefunded},
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusPaid, StatusShipped, StatusCompleted, StatusCancelled,
+		StatusPartiallyShipped, StatusPartiallyRefunded, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// IsRefundStatus : status must come from a refund, not a manual update
+func (s OrderStatus) IsRefundStatus() bool {
+	return s == StatusPartiallyRefunded || s == StatusRefunded
+}
+
+// IsShippable : order can get a new shipment
+func (s OrderStatus) IsShippable() bool {
+	return s == StatusPartiallyShipped || s.CanTransitionTo(StatusPartiallyShipped)
+}
+
+// IsReturnable : delivered items can be returned, delivered quantity is checked per line
+func (s OrderStatus) IsReturnable() bool {
+	return s == StatusPartiallyShipped || s == StatusShipped || s == StatusCompleted || s == StatusPartiallyRefunded
+}
+
+// IsRefundable : paid order, refund can still be issued
+func (s OrderStatus) IsRefundable() bool {
+	return s.CanTransitionTo(StatusRefunded)
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, st := range statusTransitions[s] {
+		if st == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Order struct {
-	ID          int64       `json:"id" db:"id"`
-	UserID      string      `json:"user_id" db:"user_id"`
-	TotalAmount int         `json:"total_amount" db:"total_amount"`
-	Status      OrderStatus `json:"status" db:"status"`
-	Address     string      `json:"address" db:"address"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+	ID             int64       `json:"id" db:"id"`
+	UserID         string      `json:"user_id" db:"user_id"`
+	TotalAmount    int         `json:"total_amount" db:"total_amount"`
+	RefundedAmount int64       `json:"refunded_amount" db:"refunded_amount"` // sum of refunds
+	Status         OrderStatus `json:"status" db:"status"`
+	Address        string      `json:"address" db:"address"` // one line, kept for older orders
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
+
+	// Snapshot at checkout, nil for older orders
+	ShippingAddress *address.Address `json:"shipping_address" db:"shipping_address"`
 
 	// Field not in orders table
 	Items []OrderItem `db:"-"`
 }
 
+// GenerateOrderNo : public order number, format ORD-YYYYDDMM-000001
+func GenerateOrderNo(orderID int64, createdAt time.Time) string {
+	now := createdAt.Format("20060201")
+	return fmt.Sprintf("ORD-%s-%06d", now, orderID)
+}
+
+// ParseOrderNo : return order id, caller must compare GenerateOrderNo with stored created_at
+func ParseOrderNo(orderNo string) (int64, error) {
+	parts := strings.Split(orderNo, "-")
+	if len(parts) != 3 || parts[0] != "ORD" || len(parts[1]) != 8 {
+		return 0, errors.New("invalid order number")
+	}
+
+	orderID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || orderID <= 0 {
+		return 0, errors.New("invalid order number")
+	}
+	return orderID, nil
+}
+
 type OrderItem struct {
 	ID        int64 `json:"id" db:"id"`
 	OrderID   int64 `json:"order_id" db:"order_id"`
@@ -36,24 +124,185 @@ type OrderItem struct {
 	ProductName string `db:"-"`
 }
 
+type Refund struct {
+	ID          int64     `json:"id" db:"id"`
+	OrderID     int64     `json:"order_id" db:"order_id"`
+	PaymentID   int64     `json:"payment_id" db:"payment_id"`
+	ReturnID    int64     `json:"return_id" db:"return_id"` // 0 when not from a return
+	Amount      int64     `json:"amount" db:"amount"`
+	Reason      string    `json:"reason" db:"reason"`
+	Restock     bool      `json:"restock" db:"restock"`
+	ProviderRef string    `json:"provider_ref" db:"provider_ref"` // refund id at provider
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+
+	// Field not in refunds table
+	Items []RefundItem `db:"-"`
+}
+
+type RefundItem struct {
+	ID          int64 `json:"id" db:"id"`
+	RefundID    int64 `json:"refund_id" db:"refund_id"`
+	OrderItemID int64 `json:"order_item_id" db:"order_item_id"`
+	Quantity    int   `json:"quantity" db:"quantity"`
+	Amount      int64 `json:"amount" db:"amount"`
+}
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "REQUESTED"
+	ReturnApproved  ReturnStatus = "APPROVED"
+	ReturnRejected  ReturnStatus = "REJECTED"
+	ReturnRefunded  ReturnStatus = "REFUNDED" // received, inspected and refunded
+)
+
+func (s ReturnStatus) IsValid() bool {
+	switch s {
+	case ReturnRequested, ReturnApproved, ReturnRejected, ReturnRefunded:
+		return true
+	}
+	return false
+}
+
+// IsOpen : items still waiting, not refunded yet
+func (s ReturnStatus) IsOpen() bool {
+	return s == ReturnRequested || s == ReturnApproved
+}
+
+type ReturnReason string
+
+const (
+	ReasonDamaged        ReturnReason = "DAMAGED"
+	ReasonDefective      ReturnReason = "DEFECTIVE"
+	ReasonWrongItem      ReturnReason = "WRONG_ITEM"
+	ReasonNotAsDescribed ReturnReason = "NOT_AS_DESCRIBED"
+	ReasonNoLongerNeeded ReturnReason = "NO_LONGER_NEEDED"
+	ReasonOther          ReturnReason = "OTHER"
+)
+
+func (r ReturnReason) IsValid() bool {
+	switch r {
+	case ReasonDamaged, ReasonDefective, ReasonWrongItem, ReasonNotAsDescribed, ReasonNoLongerNeeded, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// ReturnResolution : decided when returned item is received
+type ReturnResolution string
+
+const (
+	ResolutionRestock  ReturnResolution = "RESTOCK"
+	ResolutionWriteOff ReturnResolution = "WRITE_OFF"
+)
+
+func (r ReturnResolution) IsValid() bool {
+	return r == ResolutionRestock || r == ResolutionWriteOff
+}
+
+type Return struct {
+	ID         int64        `json:"id" db:"id"`
+	OrderID    int64        `json:"order_id" db:"order_id"`
+	UserID     string       `json:"user_id" db:"user_id"`
+	Status     ReturnStatus `json:"status" db:"status"`
+	Note       string       `json:"note" db:"note"`             // customer note
+	AdminNote  string       `json:"admin_note" db:"admin_note"` // approval / inspection note
+	ReviewedBy string       `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt *time.Time   `json:"reviewed_at" db:"reviewed_at"`
+	ReceivedAt *time.Time   `json:"received_at" db:"received_at"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+
+	// Field not in returns table
+	OrderCreatedAt time.Time    `db:"-"`
+	Items          []ReturnItem `db:"-"`
+}
+
+type ReturnItem struct {
+	ID          int64            `json:"id" db:"id"`
+	ReturnID    int64            `json:"return_id" db:"return_id"`
+	OrderItemID int64            `json:"order_item_id" db:"order_item_id"`
+	Quantity    int              `json:"quantity" db:"quantity"`
+	ReasonCode  ReturnReason     `json:"reason_code" db:"reason_code"`
+	Resolution  ReturnResolution `json:"resolution" db:"resolution"` // empty until received
+
+	// Field not in return_items table
+	ProductID   int64  `db:"-"`
+	ProductName string `db:"-"`
+}
+
+type Shipment struct {
+	ID             int64      `json:"id" db:"id"`
+	OrderID        int64      `json:"order_id" db:"order_id"`
+	Carrier        string     `json:"carrier" db:"carrier"`
+	TrackingNumber string     `json:"tracking_number" db:"tracking_number"`
+	ShippedAt      time.Time  `json:"shipped_at" db:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"` // nil until delivered
+	CreatedBy      string     `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+
+	// Field not in shipments table
+	Items []ShipmentItem `db:"-"`
+}
+
+type ShipmentItem struct {
+	ID          int64 `json:"id" db:"id"`
+	ShipmentID  int64 `json:"shipment_id" db:"shipment_id"`
+	OrderItemID int64 `json:"order_item_id" db:"order_item_id"`
+	Quantity    int   `json:"quantity" db:"quantity"`
+
+	// Field not in shipment_items table
+	ProductName string `db:"-"`
+}
+
+type OrderStatusHistory struct {
+	ID         int64       `json:"id" db:"id"`
+	OrderID    int64       `json:"order_id" db:"order_id"`
+	FromStatus OrderStatus `json:"from_status" db:"from_status"` // empty when order created
+	ToStatus   OrderStatus `json:"to_status" db:"to_status"`
+	ChangedBy  string      `json:"changed_by" db:"changed_by"` // empty when changed by system
+	Reason     string      `json:"reason" db:"reason"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+}
+
 // ============ Order DTO =================
 
 type OrderDetailResponse struct {
-	OrderNo   string              `json:"order_no"`
-	OrderDate string              `json:"order_date"`
-	Status    OrderStatus         `json:"status"`
-	Address   string              `json:"address"`
-	Amount    int64               `json:"amount"`
-	Items     []OrderItemResponse `json:"items"`
+	OrderNo         string              `json:"order_no"`
+	OrderDate       string              `json:"order_date"`
+	Status          OrderStatus         `json:"status"`
+	Address         string              `json:"address"`
+	ShippingAddress *address.Address    `json:"shipping_address,omitempty"`
+	Amount          int64               `json:"amount"`
+	RefundedAmount  int64               `json:"refunded_amount"`
+	Items           []OrderItemResponse `json:"items"`
+	Shipments       []ShipmentResponse  `json:"shipments"`
+	Timeline        []OrderTimeline     `json:"timeline"`
+}
+
+type OrderTimeline struct {
+	FromStatus OrderStatus `json:"from_status,omitempty"`
+	ToStatus   OrderStatus `json:"to_status"`
+	ChangedBy  string      `json:"changed_by,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	ChangedAt  string      `json:"changed_at"`
 }
 
 type OrderItemResponse struct {
+	OrderItemID int64  `json:"order_item_id"`
 	ProductName string `json:"product_name"`
 	Quantity    int    `json:"quantity"`
 	Price       int64  `json:"price"`
 	Total       int64  `json:"total"`
 }
 
+// CheckoutInput : AddressID or Address, both empty = default shipping address
+type CheckoutInput struct {
+	AddressID int64
+	Address   *address.Address
+}
+
 type OrderItemReq struct {
 	OrderID   int64 `json:"order_id"`
 	ProductID int64 `json:"product_id"`
@@ -61,6 +310,114 @@ type OrderItemReq struct {
 	Price     int   `json:"price"`
 }
 
+// RefundInput : empty Items = refund everything not yet refunded
+type RefundInput struct {
+	Items   []RefundItemReq
+	Reason  string
+	Restock bool
+}
+
+type RefundItemReq struct {
+	OrderItemID int64
+	Quantity    int
+}
+
+type RefundResponse struct {
+	RefundID      int64                `json:"refund_id"`
+	OrderNo       string               `json:"order_no"`
+	Amount        int64                `json:"amount"`
+	TotalRefunded int64                `json:"total_refunded"`
+	Status        OrderStatus          `json:"status"`
+	Restock       bool                 `json:"restock"`
+	Items         []RefundItemResponse `json:"items"`
+}
+
+type RefundItemResponse struct {
+	OrderItemID int64 `json:"order_item_id"`
+	Quantity    int   `json:"quantity"`
+	Amount      int64 `json:"amount"`
+}
+
+// ShipmentInput : empty Items = ship everything not yet shipped
+type ShipmentInput struct {
+	Carrier        string
+	TrackingNumber string
+	Items          []ShipmentItemReq
+}
+
+type ShipmentItemReq struct {
+	OrderItemID int64
+	Quantity    int
+}
+
+type ShipmentResponse struct {
+	ShipmentID     int64                  `json:"shipment_id"`
+	Carrier        string                 `json:"carrier"`
+	TrackingNumber string                 `json:"tracking_number"`
+	ShippedAt      string                 `json:"shipped_at"`
+	DeliveredAt    string                 `json:"delivered_at,omitempty"`
+	Items          []ShipmentItemResponse `json:"items"`
+
+	// Set when shipment changed the order
+	OrderStatus OrderStatus `json:"order_status,omitempty"`
+}
+
+type ShipmentItemResponse struct {
+	OrderItemID int64  `json:"order_item_id"`
+	ProductName string `json:"product_name,omitempty"`
+	Quantity    int    `json:"quantity"`
+}
+
+type ReturnInput struct {
+	Items []ReturnItemReq
+	Note  string
+}
+
+type ReturnItemReq struct {
+	OrderItemID int64
+	Quantity    int
+	ReasonCode  ReturnReason
+}
+
+// ReceiveReturnInput : every return item needs a resolution
+type ReceiveReturnInput struct {
+	Items []ReturnResolutionReq
+	Note  string
+}
+
+type ReturnResolutionReq struct {
+	ReturnItemID int64
+	Resolution   ReturnResolution
+}
+
+type ReturnResponse struct {
+	ReturnID  int64                `json:"return_id"`
+	OrderNo   string               `json:"order_no"`
+	Status    ReturnStatus         `json:"status"`
+	Note      string               `json:"note,omitempty"`
+	AdminNote string               `json:"admin_note,omitempty"`
+	Items     []ReturnItemResponse `json:"items"`
+	Refund    *RefundResponse      `json:"refund,omitempty"` // set when received
+	CreatedAt string               `json:"created_at"`
+}
+
+type ReturnItemResponse struct {
+	ReturnItemID int64            `json:"return_item_id"`
+	OrderItemID  int64            `json:"order_item_id"`
+	ProductName  string           `json:"product_name,omitempty"`
+	Quantity     int              `json:"quantity"`
+	ReasonCode   ReturnReason     `json:"reason_code"`
+	Resolution   ReturnResolution `json:"resolution,omitempty"`
+}
+
+type ReturnListResponse struct {
+	Returns      []*ReturnResponse `json:"returns"`
+	TotalReturns int64             `json:"total_returns"`
+	Page         int               `json:"page"`
+	Limit        int               `json:"limit"`
+	TotalPage    int               `json:"total_page"`
+}
+
 type OrderListResponse struct {
 	Orders      []*OrderResponse `json:"orders"`
 	TotalOrders int64            `json:"total_orders"`
@@ -72,8 +429,10 @@ type OrderListResponse struct {
 }
 
 type OrderResponse struct {
-	OrderNo     string      `json:"order_no"`
-	Status      OrderStatus `json:"status"`
-	TotalAmount int64       `json:"total_amount"`
-	CreatedAt   string      `json:"created_at"`
+	OrderNo        string      `json:"order_no"`
+	UserID         string      `json:"user_id,omitempty"` // admin list only
+	Status         OrderStatus `json:"status"`
+	TotalAmount    int64       `json:"total_amount"`
+	RefundedAmount int64       `json:"refunded_amount"`
+	CreatedAt      string      `json:"created_at"`
 }
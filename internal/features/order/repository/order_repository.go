@@ -3,22 +3,50 @@ package orderrepository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/address"
 	"github.com/codepnw/go-starter-kit/internal/features/order"
+	"github.com/lib/pq"
 )
 
 //go:generate mockgen -source=order_repository.go -destination=order_repository_mock.go -package=orderrepository
 type OrderRepository interface {
 	FindOrderDetails(ctx context.Context, orderID int64) (*order.Order, error)
+	FindUserOrderDetails(ctx context.Context, userID string, orderID int64) (*order.Order, error)
+	FindOrders(ctx context.Context, status order.OrderStatus, limit, offset int) ([]*order.Order, int64, error)
 	FindMyOrders(ctx context.Context, userID string, limit, offset int) ([]*order.Order, int64, error)
+	FindStatusHistory(ctx context.Context, orderID int64) ([]order.OrderStatusHistory, error)
 
 	// Transaction
-	InsertOrderTx(ctx context.Context, tx *sql.Tx, userID string, totalAmount int64, address string) (int64, time.Time, error)
+	InsertOrderTx(ctx context.Context, tx *sql.Tx, userID string, totalAmount int64, addr address.Address) (int64, time.Time, error)
 	InsertOrderItemTx(ctx context.Context, tx *sql.Tx, item order.OrderItemReq) error
+	FindOrderForUpdateTx(ctx context.Context, tx *sql.Tx, orderID int64) (*order.Order, error)
+	FindOrderItemsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]order.OrderItem, error)
+	UpdateOrderStatusTx(ctx context.Context, tx *sql.Tx, orderID int64, status order.OrderStatus) error
+	InsertStatusHistoryTx(ctx context.Context, tx *sql.Tx, h *order.OrderStatusHistory) error
+	FindRefundsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]order.Refund, error)
+	InsertRefundTx(ctx context.Context, tx *sql.Tx, refund *order.Refund) error
+
+	// Returns
+	FindReturn(ctx context.Context, returnID int64) (*order.Return, error)
+	FindReturns(ctx context.Context, status order.ReturnStatus, limit, offset int) ([]*order.Return, int64, error)
+	FindOrderReturns(ctx context.Context, orderID int64) ([]*order.Return, error)
+	FindOrderReturnsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]*order.Return, error)
+	FindReturnForUpdateTx(ctx context.Context, tx *sql.Tx, returnID int64) (*order.Return, error)
+	InsertReturnTx(ctx context.Context, tx *sql.Tx, ret *order.Return) error
+	UpdateReturnTx(ctx context.Context, tx *sql.Tx, ret *order.Return) error
+
+	// Shipments
+	FindShipments(ctx context.Context, orderID int64) ([]*order.Shipment, error)
+	FindShipmentsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]*order.Shipment, error)
+	InsertShipmentTx(ctx context.Context, tx *sql.Tx, sh *order.Shipment) error
+	MarkShipmentDeliveredTx(ctx context.Context, tx *sql.Tx, orderID, shipmentID int64) error
 }
 
 type orderRepository struct {
@@ -32,16 +60,33 @@ func NewOrderRepository(db *sql.DB) OrderRepository {
 func (r *orderRepository) FindOrderDetails(ctx context.Context, orderID int64) (*order.Order, error) {
 	// Find orders table
 	queryOrder := `
-		SELECT id, user_id, address, total_amount, status, created_at, updated_at
+		SELECT id, user_id, address, shipping_address, total_amount, refunded_amount, status, created_at, updated_at
 		FROM orders WHERE id = $1
 	`
+	return r.findOrderDetails(ctx, queryOrder, orderID)
+}
+
+// FindUserOrderDetails : scoped by owner, other user's order = not found
+func (r *orderRepository) FindUserOrderDetails(ctx context.Context, userID string, orderID int64) (*order.Order, error) {
+	// Find orders table
+	queryOrder := `
+		SELECT id, user_id, address, shipping_address, total_amount, refunded_amount, status, created_at, updated_at
+		FROM orders WHERE id = $1 AND user_id = $2
+	`
+	return r.findOrderDetails(ctx, queryOrder, orderID, userID)
+}
+
+func (r *orderRepository) findOrderDetails(ctx context.Context, queryOrder string, args ...any) (*order.Order, error) {
 	ord := new(order.Order)
+	var shippingAddress []byte
 
-	err := r.db.QueryRowContext(ctx, queryOrder, orderID).Scan(
+	err := r.db.QueryRowContext(ctx, queryOrder, args...).Scan(
 		&ord.ID,
 		&ord.UserID,
 		&ord.Address,
+		&shippingAddress,
 		&ord.TotalAmount,
+		&ord.RefundedAmount,
 		&ord.Status,
 		&ord.CreatedAt,
 		&ord.UpdatedAt,
@@ -53,6 +98,14 @@ func (r *orderRepository) FindOrderDetails(ctx context.Context, orderID int64) (
 		return nil, fmt.Errorf("get order failed: %w", err)
 	}
 
+	// NULL for orders placed before structured addresses
+	if shippingAddress != nil {
+		ord.ShippingAddress = new(address.Address)
+		if err := json.Unmarshal(shippingAddress, ord.ShippingAddress); err != nil {
+			return nil, fmt.Errorf("decode shipping address failed: %w", err)
+		}
+	}
+
 	// Find order_items table
 	queryItems := `
 		SELECT oi.id, oi.product_id, p.name, oi.quantity, oi.price
@@ -60,7 +113,7 @@ func (r *orderRepository) FindOrderDetails(ctx context.Context, orderID int64) (
 		JOIN products p ON oi.product_id = p.id
 		WHERE oi.order_id = $1
 	`
-	rows, err := r.db.QueryContext(ctx, queryItems, orderID)
+	rows, err := r.db.QueryContext(ctx, queryItems, ord.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -90,15 +143,21 @@ func (r *orderRepository) FindOrderDetails(ctx context.Context, orderID int64) (
 	return ord, nil
 }
 
-func (r *orderRepository) InsertOrderTx(ctx context.Context, tx *sql.Tx, userID string, totalAmount int64, address string) (int64, time.Time, error) {
+// InsertOrderTx : address is copied onto the order, later address book edits do not change it
+func (r *orderRepository) InsertOrderTx(ctx context.Context, tx *sql.Tx, userID string, totalAmount int64, addr address.Address) (int64, time.Time, error) {
 	var orderID int64
 	var createdAt time.Time
 
+	shippingAddress, err := json.Marshal(addr)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
 	query := `
-		INSERT INTO orders (user_id, total_amount, status, address)
-		VALUES ($1, $2, 'PENDING', $3) RETURNING id, created_at
+		INSERT INTO orders (user_id, total_amount, status, address, shipping_address)
+		VALUES ($1, $2, 'PENDING', $3, $4) RETURNING id, created_at
 	`
-	err := tx.QueryRowContext(ctx, query, userID, totalAmount, address).Scan(&orderID, &createdAt)
+	err = tx.QueryRowContext(ctx, query, userID, totalAmount, addr.String(), shippingAddress).Scan(&orderID, &createdAt)
 	if err != nil {
 		return 0, time.Time{}, err
 	}
@@ -119,7 +178,7 @@ func (r *orderRepository) InsertOrderItemTx(ctx context.Context, tx *sql.Tx, ite
 
 func (r *orderRepository) FindMyOrders(ctx context.Context, userID string, limit, offset int) ([]*order.Order, int64, error) {
 	query := `
-		SELECT id, created_at, status, total_amount
+		SELECT id, created_at, status, total_amount, refunded_amount
 		FROM orders
 		WHERE user_id = $1 ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
@@ -142,6 +201,7 @@ func (r *orderRepository) FindMyOrders(ctx context.Context, userID string, limit
 			&o.CreatedAt,
 			&o.Status,
 			&o.TotalAmount,
+			&o.RefundedAmount,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -161,3 +221,717 @@ func (r *orderRepository) FindMyOrders(ctx context.Context, userID string, limit
 	}
 	return orders, total, nil
 }
+
+// FindOrders : all users, empty status = no filter
+func (r *orderRepository) FindOrders(ctx context.Context, status order.OrderStatus, limit, offset int) ([]*order.Order, int64, error) {
+	query := `
+		SELECT id, user_id, created_at, status, total_amount, refunded_amount
+		FROM orders
+		WHERE ($1::text = '' OR status = $1) ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var orders []*order.Order
+
+	for rows.Next() {
+		o := new(order.Order)
+		if err := rows.Scan(
+			&o.ID,
+			&o.UserID,
+			&o.CreatedAt,
+			&o.Status,
+			&o.TotalAmount,
+			&o.RefundedAmount,
+		); err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// --- Count Orders
+	var total int64
+	queryCount := `SELECT COUNT(*) FROM orders WHERE ($1::text = '' OR status = $1)`
+
+	err = r.db.QueryRowContext(ctx, queryCount, status).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// FindOrderForUpdateTx : lock order row until transaction end
+func (r *orderRepository) FindOrderForUpdateTx(ctx context.Context, tx *sql.Tx, orderID int64) (*order.Order, error) {
+	query := `
+		SELECT id, user_id, address, total_amount, refunded_amount, status, created_at, updated_at
+		FROM orders WHERE id = $1
+		FOR UPDATE
+	`
+	ord := new(order.Order)
+
+	err := tx.QueryRowContext(ctx, query, orderID).Scan(
+		&ord.ID,
+		&ord.UserID,
+		&ord.Address,
+		&ord.TotalAmount,
+		&ord.RefundedAmount,
+		&ord.Status,
+		&ord.CreatedAt,
+		&ord.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return ord, nil
+}
+
+func (r *orderRepository) FindOrderItemsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]order.OrderItem, error) {
+	query := `
+		SELECT id, order_id, product_id, quantity, price
+		FROM order_items WHERE order_id = $1
+		ORDER BY id ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []order.OrderItem
+
+	for rows.Next() {
+		var item order.OrderItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.OrderID,
+			&item.ProductID,
+			&item.Quantity,
+			&item.Price,
+		); err != nil {
+			return nil, fmt.Errorf("scan item failed: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return items, nil
+}
+
+func (r *orderRepository) UpdateOrderStatusTx(ctx context.Context, tx *sql.Tx, orderID int64, status order.OrderStatus) error {
+	query := `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2`
+	res, err := tx.ExecContext(ctx, query, status, orderID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrOrderNotFound
+	}
+	return nil
+}
+
+func (r *orderRepository) InsertStatusHistoryTx(ctx context.Context, tx *sql.Tx, h *order.OrderStatusHistory) error {
+	query := `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, '')::uuid, NULLIF($5, ''))
+		RETURNING id, created_at
+	`
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		h.OrderID,
+		h.FromStatus,
+		h.ToStatus,
+		h.ChangedBy,
+		h.Reason,
+	).Scan(&h.ID, &h.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *orderRepository) FindStatusHistory(ctx context.Context, orderID int64) ([]order.OrderStatusHistory, error) {
+	query := `
+		SELECT
+			id,
+			order_id,
+			COALESCE(from_status, ''),
+			to_status,
+			COALESCE(changed_by::text, ''),
+			COALESCE(reason, ''),
+			created_at
+		FROM order_status_history
+		WHERE order_id = $1 ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []order.OrderStatusHistory
+
+	for rows.Next() {
+		var h order.OrderStatusHistory
+		if err := rows.Scan(
+			&h.ID,
+			&h.OrderID,
+			&h.FromStatus,
+			&h.ToStatus,
+			&h.ChangedBy,
+			&h.Reason,
+			&h.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan history failed: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return history, nil
+}
+
+// FindRefundsTx : refunds of the order with items, call after order row is locked
+func (r *orderRepository) FindRefundsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]order.Refund, error) {
+	query := `
+		SELECT
+			rf.id,
+			rf.order_id,
+			rf.payment_id,
+			COALESCE(rf.return_id, 0),
+			rf.amount,
+			COALESCE(rf.reason, ''),
+			rf.restock,
+			rf.provider_ref,
+			COALESCE(rf.created_by::text, ''),
+			rf.created_at,
+			ri.id,
+			ri.order_item_id,
+			ri.quantity,
+			ri.amount
+		FROM refunds rf
+		JOIN refund_items ri ON ri.refund_id = rf.id
+		WHERE rf.order_id = $1
+		ORDER BY rf.id ASC, ri.id ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []order.Refund
+
+	for rows.Next() {
+		var rf order.Refund
+		var item order.RefundItem
+		if err := rows.Scan(
+			&rf.ID,
+			&rf.OrderID,
+			&rf.PaymentID,
+			&rf.ReturnID,
+			&rf.Amount,
+			&rf.Reason,
+			&rf.Restock,
+			&rf.ProviderRef,
+			&rf.CreatedBy,
+			&rf.CreatedAt,
+			&item.ID,
+			&item.OrderItemID,
+			&item.Quantity,
+			&item.Amount,
+		); err != nil {
+			return nil, fmt.Errorf("scan refund failed: %w", err)
+		}
+		item.RefundID = rf.ID
+
+		// Group items by refund
+		if n := len(refunds); n > 0 && refunds[n-1].ID == rf.ID {
+			refunds[n-1].Items = append(refunds[n-1].Items, item)
+			continue
+		}
+		rf.Items = []order.RefundItem{item}
+		refunds = append(refunds, rf)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return refunds, nil
+}
+
+func (r *orderRepository) InsertRefundTx(ctx context.Context, tx *sql.Tx, refund *order.Refund) error {
+	query := `
+		INSERT INTO refunds (order_id, payment_id, amount, reason, restock, provider_ref, created_by, return_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, '')::uuid, NULLIF($8, 0))
+		RETURNING id, created_at
+	`
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		refund.OrderID,
+		refund.PaymentID,
+		refund.Amount,
+		refund.Reason,
+		refund.Restock,
+		refund.ProviderRef,
+		refund.CreatedBy,
+		refund.ReturnID,
+	).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	queryItem := `
+		INSERT INTO refund_items (refund_id, order_item_id, quantity, amount)
+		VALUES ($1, $2, $3, $4) RETURNING id
+	`
+	for i := range refund.Items {
+		item := &refund.Items[i]
+		item.RefundID = refund.ID
+
+		err := tx.QueryRowContext(ctx, queryItem, item.RefundID, item.OrderItemID, item.Quantity, item.Amount).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("insert refund item failed: %w", err)
+		}
+	}
+
+	queryOrder := `UPDATE orders SET refunded_amount = refunded_amount + $1, updated_at = NOW() WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, queryOrder, refund.Amount, refund.OrderID); err != nil {
+		return fmt.Errorf("update refunded amount failed: %w", err)
+	}
+	return nil
+}
+
+// queryer : *sql.DB or *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const returnColumns = `
+	r.id,
+	r.order_id,
+	r.user_id,
+	r.status,
+	COALESCE(r.note, ''),
+	COALESCE(r.admin_note, ''),
+	COALESCE(r.reviewed_by::text, ''),
+	r.reviewed_at,
+	r.received_at,
+	r.created_at,
+	r.updated_at,
+	o.created_at
+`
+
+func (r *orderRepository) FindReturn(ctx context.Context, returnID int64) (*order.Return, error) {
+	query := `SELECT ` + returnColumns + ` FROM returns r JOIN orders o ON o.id = r.order_id WHERE r.id = $1`
+	return r.findReturn(ctx, r.db, query, returnID)
+}
+
+// FindReturnForUpdateTx : lock return row until transaction end
+func (r *orderRepository) FindReturnForUpdateTx(ctx context.Context, tx *sql.Tx, returnID int64) (*order.Return, error) {
+	query := `
+		SELECT ` + returnColumns + ` FROM returns r JOIN orders o ON o.id = r.order_id
+		WHERE r.id = $1
+		FOR UPDATE OF r
+	`
+	return r.findReturn(ctx, tx, query, returnID)
+}
+
+func (r *orderRepository) findReturn(ctx context.Context, q queryer, query string, args ...any) (*order.Return, error) {
+	ret, err := scanReturn(q.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrReturnNotFound
+		}
+		return nil, err
+	}
+
+	if err := r.loadReturnItems(ctx, q, []*order.Return{ret}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FindReturns : all orders, empty status = no filter
+func (r *orderRepository) FindReturns(ctx context.Context, status order.ReturnStatus, limit, offset int) ([]*order.Return, int64, error) {
+	query := `
+		SELECT ` + returnColumns + ` FROM returns r JOIN orders o ON o.id = r.order_id
+		WHERE ($1::text = '' OR r.status = $1) ORDER BY r.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	returns, err := r.findReturns(ctx, r.db, query, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// --- Count Returns
+	var total int64
+	queryCount := `SELECT COUNT(*) FROM returns WHERE ($1::text = '' OR status = $1)`
+
+	err = r.db.QueryRowContext(ctx, queryCount, status).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	return returns, total, nil
+}
+
+func (r *orderRepository) FindOrderReturns(ctx context.Context, orderID int64) ([]*order.Return, error) {
+	query := `
+		SELECT ` + returnColumns + ` FROM returns r JOIN orders o ON o.id = r.order_id
+		WHERE r.order_id = $1 ORDER BY r.id ASC
+	`
+	return r.findReturns(ctx, r.db, query, orderID)
+}
+
+// FindOrderReturnsTx : call after order row is locked
+func (r *orderRepository) FindOrderReturnsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]*order.Return, error) {
+	query := `
+		SELECT ` + returnColumns + ` FROM returns r JOIN orders o ON o.id = r.order_id
+		WHERE r.order_id = $1 ORDER BY r.id ASC
+	`
+	return r.findReturns(ctx, tx, query, orderID)
+}
+
+func (r *orderRepository) findReturns(ctx context.Context, q queryer, query string, args ...any) ([]*order.Return, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []*order.Return
+
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan return failed: %w", err)
+		}
+		returns = append(returns, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if err := r.loadReturnItems(ctx, q, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// loadReturnItems : one query for all returns
+func (r *orderRepository) loadReturnItems(ctx context.Context, q queryer, returns []*order.Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(returns))
+	byID := make(map[int64]*order.Return, len(returns))
+	for _, ret := range returns {
+		ids = append(ids, ret.ID)
+		byID[ret.ID] = ret
+	}
+
+	query := `
+		SELECT ri.id, ri.return_id, ri.order_item_id, ri.quantity, ri.reason_code, COALESCE(ri.resolution, ''), oi.product_id, p.name
+		FROM return_items ri
+		JOIN order_items oi ON oi.id = ri.order_item_id
+		JOIN products p ON p.id = oi.product_id
+		WHERE ri.return_id = ANY($1)
+		ORDER BY ri.id ASC
+	`
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item order.ReturnItem
+		if err := rows.Scan(
+			&item.ID,
+			&item.ReturnID,
+			&item.OrderItemID,
+			&item.Quantity,
+			&item.ReasonCode,
+			&item.Resolution,
+			&item.ProductID,
+			&item.ProductName,
+		); err != nil {
+			return fmt.Errorf("scan return item failed: %w", err)
+		}
+		ret := byID[item.ReturnID]
+		ret.Items = append(ret.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) InsertReturnTx(ctx context.Context, tx *sql.Tx, ret *order.Return) error {
+	query := `
+		INSERT INTO returns (order_id, user_id, status, note)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING id, created_at, updated_at
+	`
+	err := tx.QueryRowContext(ctx, query, ret.OrderID, ret.UserID, ret.Status, ret.Note).Scan(
+		&ret.ID,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	queryItem := `
+		INSERT INTO return_items (return_id, order_item_id, quantity, reason_code)
+		VALUES ($1, $2, $3, $4) RETURNING id
+	`
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		item.ReturnID = ret.ID
+
+		err := tx.QueryRowContext(ctx, queryItem, item.ReturnID, item.OrderItemID, item.Quantity, item.ReasonCode).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("insert return item failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpdateReturnTx : save status, review fields and item resolutions
+func (r *orderRepository) UpdateReturnTx(ctx context.Context, tx *sql.Tx, ret *order.Return) error {
+	query := `
+		UPDATE returns SET
+			status = $1,
+			admin_note = NULLIF($2, ''),
+			reviewed_by = NULLIF($3, '')::uuid,
+			reviewed_at = $4,
+			received_at = $5,
+			updated_at = NOW()
+		WHERE id = $6
+	`
+	res, err := tx.ExecContext(
+		ctx,
+		query,
+		ret.Status,
+		ret.AdminNote,
+		ret.ReviewedBy,
+		ret.ReviewedAt,
+		ret.ReceivedAt,
+		ret.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errs.ErrReturnNotFound
+	}
+
+	queryItem := `UPDATE return_items SET resolution = NULLIF($1, '') WHERE id = $2`
+	for _, item := range ret.Items {
+		if _, err := tx.ExecContext(ctx, queryItem, item.Resolution, item.ID); err != nil {
+			return fmt.Errorf("update return item failed: %w", err)
+		}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReturn(row rowScanner) (*order.Return, error) {
+	ret := new(order.Return)
+
+	err := row.Scan(
+		&ret.ID,
+		&ret.OrderID,
+		&ret.UserID,
+		&ret.Status,
+		&ret.Note,
+		&ret.AdminNote,
+		&ret.ReviewedBy,
+		&ret.ReviewedAt,
+		&ret.ReceivedAt,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+		&ret.OrderCreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (r *orderRepository) FindShipments(ctx context.Context, orderID int64) ([]*order.Shipment, error) {
+	return r.findShipments(ctx, r.db, orderID)
+}
+
+// FindShipmentsTx : call after order row is locked
+func (r *orderRepository) FindShipmentsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]*order.Shipment, error) {
+	return r.findShipments(ctx, tx, orderID)
+}
+
+func (r *orderRepository) findShipments(ctx context.Context, q queryer, orderID int64) ([]*order.Shipment, error) {
+	query := `
+		SELECT
+			s.id,
+			s.order_id,
+			s.carrier,
+			s.tracking_number,
+			s.shipped_at,
+			s.delivered_at,
+			COALESCE(s.created_by::text, ''),
+			s.created_at,
+			si.id,
+			si.order_item_id,
+			si.quantity,
+			p.name
+		FROM shipments s
+		JOIN shipment_items si ON si.shipment_id = s.id
+		JOIN order_items oi ON oi.id = si.order_item_id
+		JOIN products p ON p.id = oi.product_id
+		WHERE s.order_id = $1
+		ORDER BY s.id ASC, si.id ASC
+	`
+	rows, err := q.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []*order.Shipment
+	var current *order.Shipment
+
+	for rows.Next() {
+		var sh order.Shipment
+		var item order.ShipmentItem
+
+		err := rows.Scan(
+			&sh.ID,
+			&sh.OrderID,
+			&sh.Carrier,
+			&sh.TrackingNumber,
+			&sh.ShippedAt,
+			&sh.DeliveredAt,
+			&sh.CreatedBy,
+			&sh.CreatedAt,
+			&item.ID,
+			&item.OrderItemID,
+			&item.Quantity,
+			&item.ProductName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan shipment failed: %w", err)
+		}
+
+		// Rows are ordered by shipment, start new one when id changes
+		if current == nil || current.ID != sh.ID {
+			current = &sh
+			shipments = append(shipments, current)
+		}
+		item.ShipmentID = current.ID
+		current.Items = append(current.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return shipments, nil
+}
+
+func (r *orderRepository) InsertShipmentTx(ctx context.Context, tx *sql.Tx, sh *order.Shipment) error {
+	query := `
+		INSERT INTO shipments (order_id, carrier, tracking_number, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid)
+		RETURNING id, shipped_at, created_at
+	`
+	err := tx.QueryRowContext(ctx, query, sh.OrderID, sh.Carrier, sh.TrackingNumber, sh.CreatedBy).Scan(
+		&sh.ID,
+		&sh.ShippedAt,
+		&sh.CreatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "shipments_tracking_unique") {
+			return errs.ErrTrackingNumberExists
+		}
+		return err
+	}
+
+	queryItem := `
+		INSERT INTO shipment_items (shipment_id, order_item_id, quantity)
+		VALUES ($1, $2, $3) RETURNING id
+	`
+	for i := range sh.Items {
+		item := &sh.Items[i]
+		item.ShipmentID = sh.ID
+
+		err := tx.QueryRowContext(ctx, queryItem, item.ShipmentID, item.OrderItemID, item.Quantity).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("insert shipment item failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// MarkShipmentDeliveredTx : set delivered_at once, ErrShipmentAlreadyDelivered on second call
+func (r *orderRepository) MarkShipmentDeliveredTx(ctx context.Context, tx *sql.Tx, orderID, shipmentID int64) error {
+	query := `
+		UPDATE shipments SET delivered_at = NOW()
+		WHERE id = $1 AND order_id = $2 AND delivered_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, shipmentID, orderID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	// Not updated, find out why
+	var delivered bool
+	queryCheck := `SELECT delivered_at IS NOT NULL FROM shipments WHERE id = $1 AND order_id = $2`
+
+	err = tx.QueryRowContext(ctx, queryCheck, shipmentID, orderID).Scan(&delivered)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrShipmentNotFound
+		}
+		return err
+	}
+	return errs.ErrShipmentAlreadyDelivered
+}
@@ -10,6 +10,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	address "github.com/codepnw/go-starter-kit/internal/features/address"
 	order "github.com/codepnw/go-starter-kit/internal/features/order"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -68,6 +69,203 @@ func (mr *MockOrderRepositoryMockRecorder) FindOrderDetails(ctx, orderID interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrderDetails", reflect.TypeOf((*MockOrderRepository)(nil).FindOrderDetails), ctx, orderID)
 }
 
+// FindOrderForUpdateTx mocks base method.
+func (m *MockOrderRepository) FindOrderForUpdateTx(ctx context.Context, tx *sql.Tx, orderID int64) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrderForUpdateTx", ctx, tx, orderID)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrderForUpdateTx indicates an expected call of FindOrderForUpdateTx.
+func (mr *MockOrderRepositoryMockRecorder) FindOrderForUpdateTx(ctx, tx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrderForUpdateTx", reflect.TypeOf((*MockOrderRepository)(nil).FindOrderForUpdateTx), ctx, tx, orderID)
+}
+
+// FindOrderItemsTx mocks base method.
+func (m *MockOrderRepository) FindOrderItemsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]order.OrderItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrderItemsTx", ctx, tx, orderID)
+	ret0, _ := ret[0].([]order.OrderItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrderItemsTx indicates an expected call of FindOrderItemsTx.
+func (mr *MockOrderRepositoryMockRecorder) FindOrderItemsTx(ctx, tx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrderItemsTx", reflect.TypeOf((*MockOrderRepository)(nil).FindOrderItemsTx), ctx, tx, orderID)
+}
+
+// FindOrderReturns mocks base method.
+func (m *MockOrderRepository) FindOrderReturns(ctx context.Context, orderID int64) ([]*order.Return, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrderReturns", ctx, orderID)
+	ret0, _ := ret[0].([]*order.Return)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrderReturns indicates an expected call of FindOrderReturns.
+func (mr *MockOrderRepositoryMockRecorder) FindOrderReturns(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrderReturns", reflect.TypeOf((*MockOrderRepository)(nil).FindOrderReturns), ctx, orderID)
+}
+
+// FindOrderReturnsTx mocks base method.
+func (m *MockOrderRepository) FindOrderReturnsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]*order.Return, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrderReturnsTx", ctx, tx, orderID)
+	ret0, _ := ret[0].([]*order.Return)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrderReturnsTx indicates an expected call of FindOrderReturnsTx.
+func (mr *MockOrderRepositoryMockRecorder) FindOrderReturnsTx(ctx, tx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrderReturnsTx", reflect.TypeOf((*MockOrderRepository)(nil).FindOrderReturnsTx), ctx, tx, orderID)
+}
+
+// FindOrders mocks base method.
+func (m *MockOrderRepository) FindOrders(ctx context.Context, status order.OrderStatus, limit, offset int) ([]*order.Order, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrders", ctx, status, limit, offset)
+	ret0, _ := ret[0].([]*order.Order)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindOrders indicates an expected call of FindOrders.
+func (mr *MockOrderRepositoryMockRecorder) FindOrders(ctx, status, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrders", reflect.TypeOf((*MockOrderRepository)(nil).FindOrders), ctx, status, limit, offset)
+}
+
+// FindRefundsTx mocks base method.
+func (m *MockOrderRepository) FindRefundsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]order.Refund, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRefundsTx", ctx, tx, orderID)
+	ret0, _ := ret[0].([]order.Refund)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRefundsTx indicates an expected call of FindRefundsTx.
+func (mr *MockOrderRepositoryMockRecorder) FindRefundsTx(ctx, tx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRefundsTx", reflect.TypeOf((*MockOrderRepository)(nil).FindRefundsTx), ctx, tx, orderID)
+}
+
+// FindReturn mocks base method.
+func (m *MockOrderRepository) FindReturn(ctx context.Context, returnID int64) (*order.Return, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindReturn", ctx, returnID)
+	ret0, _ := ret[0].(*order.Return)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindReturn indicates an expected call of FindReturn.
+func (mr *MockOrderRepositoryMockRecorder) FindReturn(ctx, returnID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReturn", reflect.TypeOf((*MockOrderRepository)(nil).FindReturn), ctx, returnID)
+}
+
+// FindReturnForUpdateTx mocks base method.
+func (m *MockOrderRepository) FindReturnForUpdateTx(ctx context.Context, tx *sql.Tx, returnID int64) (*order.Return, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindReturnForUpdateTx", ctx, tx, returnID)
+	ret0, _ := ret[0].(*order.Return)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindReturnForUpdateTx indicates an expected call of FindReturnForUpdateTx.
+func (mr *MockOrderRepositoryMockRecorder) FindReturnForUpdateTx(ctx, tx, returnID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReturnForUpdateTx", reflect.TypeOf((*MockOrderRepository)(nil).FindReturnForUpdateTx), ctx, tx, returnID)
+}
+
+// FindReturns mocks base method.
+func (m *MockOrderRepository) FindReturns(ctx context.Context, status order.ReturnStatus, limit, offset int) ([]*order.Return, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindReturns", ctx, status, limit, offset)
+	ret0, _ := ret[0].([]*order.Return)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindReturns indicates an expected call of FindReturns.
+func (mr *MockOrderRepositoryMockRecorder) FindReturns(ctx, status, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindReturns", reflect.TypeOf((*MockOrderRepository)(nil).FindReturns), ctx, status, limit, offset)
+}
+
+// FindShipments mocks base method.
+func (m *MockOrderRepository) FindShipments(ctx context.Context, orderID int64) ([]*order.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindShipments", ctx, orderID)
+	ret0, _ := ret[0].([]*order.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindShipments indicates an expected call of FindShipments.
+func (mr *MockOrderRepositoryMockRecorder) FindShipments(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindShipments", reflect.TypeOf((*MockOrderRepository)(nil).FindShipments), ctx, orderID)
+}
+
+// FindShipmentsTx mocks base method.
+func (m *MockOrderRepository) FindShipmentsTx(ctx context.Context, tx *sql.Tx, orderID int64) ([]*order.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindShipmentsTx", ctx, tx, orderID)
+	ret0, _ := ret[0].([]*order.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindShipmentsTx indicates an expected call of FindShipmentsTx.
+func (mr *MockOrderRepositoryMockRecorder) FindShipmentsTx(ctx, tx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindShipmentsTx", reflect.TypeOf((*MockOrderRepository)(nil).FindShipmentsTx), ctx, tx, orderID)
+}
+
+// FindStatusHistory mocks base method.
+func (m *MockOrderRepository) FindStatusHistory(ctx context.Context, orderID int64) ([]order.OrderStatusHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindStatusHistory", ctx, orderID)
+	ret0, _ := ret[0].([]order.OrderStatusHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindStatusHistory indicates an expected call of FindStatusHistory.
+func (mr *MockOrderRepositoryMockRecorder) FindStatusHistory(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindStatusHistory", reflect.TypeOf((*MockOrderRepository)(nil).FindStatusHistory), ctx, orderID)
+}
+
+// FindUserOrderDetails mocks base method.
+func (m *MockOrderRepository) FindUserOrderDetails(ctx context.Context, userID string, orderID int64) (*order.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserOrderDetails", ctx, userID, orderID)
+	ret0, _ := ret[0].(*order.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserOrderDetails indicates an expected call of FindUserOrderDetails.
+func (mr *MockOrderRepositoryMockRecorder) FindUserOrderDetails(ctx, userID, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserOrderDetails", reflect.TypeOf((*MockOrderRepository)(nil).FindUserOrderDetails), ctx, userID, orderID)
+}
+
 // InsertOrderItemTx mocks base method.
 func (m *MockOrderRepository) InsertOrderItemTx(ctx context.Context, tx *sql.Tx, item order.OrderItemReq) error {
 	m.ctrl.T.Helper()
@@ -83,9 +281,9 @@ func (mr *MockOrderRepositoryMockRecorder) InsertOrderItemTx(ctx, tx, item inter
 }
 
 // InsertOrderTx mocks base method.
-func (m *MockOrderRepository) InsertOrderTx(ctx context.Context, tx *sql.Tx, userID string, totalAmount int64, address string) (int64, time.Time, error) {
+func (m *MockOrderRepository) InsertOrderTx(ctx context.Context, tx *sql.Tx, userID string, totalAmount int64, addr address.Address) (int64, time.Time, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertOrderTx", ctx, tx, userID, totalAmount, address)
+	ret := m.ctrl.Call(m, "InsertOrderTx", ctx, tx, userID, totalAmount, addr)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(time.Time)
 	ret2, _ := ret[2].(error)
@@ -93,7 +291,208 @@ func (m *MockOrderRepository) InsertOrderTx(ctx context.Context, tx *sql.Tx, use
 }
 
 // InsertOrderTx indicates an expected call of InsertOrderTx.
-func (mr *MockOrderRepositoryMockRecorder) InsertOrderTx(ctx, tx, userID, totalAmount, address interface{}) *gomock.Call {
+func (mr *MockOrderRepositoryMockRecorder) InsertOrderTx(ctx, tx, userID, totalAmount, addr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOrderTx", reflect.TypeOf((*MockOrderRepository)(nil).InsertOrderTx), ctx, tx, userID, totalAmount, addr)
+}
+
+// InsertRefundTx mocks base method.
+func (m *MockOrderRepository) InsertRefundTx(ctx context.Context, tx *sql.Tx, refund *order.Refund) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertRefundTx", ctx, tx, refund)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertRefundTx indicates an expected call of InsertRefundTx.
+func (mr *MockOrderRepositoryMockRecorder) InsertRefundTx(ctx, tx, refund interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRefundTx", reflect.TypeOf((*MockOrderRepository)(nil).InsertRefundTx), ctx, tx, refund)
+}
+
+// InsertReturnTx mocks base method.
+func (m *MockOrderRepository) InsertReturnTx(ctx context.Context, tx *sql.Tx, ret *order.Return) error {
+	m.ctrl.T.Helper()
+	ret_2 := m.ctrl.Call(m, "InsertReturnTx", ctx, tx, ret)
+	ret0, _ := ret_2[0].(error)
+	return ret0
+}
+
+// InsertReturnTx indicates an expected call of InsertReturnTx.
+func (mr *MockOrderRepositoryMockRecorder) InsertReturnTx(ctx, tx, ret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertReturnTx", reflect.TypeOf((*MockOrderRepository)(nil).InsertReturnTx), ctx, tx, ret)
+}
+
+// InsertShipmentTx mocks base method.
+func (m *MockOrderRepository) InsertShipmentTx(ctx context.Context, tx *sql.Tx, sh *order.Shipment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertShipmentTx", ctx, tx, sh)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertShipmentTx indicates an expected call of InsertShipmentTx.
+func (mr *MockOrderRepositoryMockRecorder) InsertShipmentTx(ctx, tx, sh interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertShipmentTx", reflect.TypeOf((*MockOrderRepository)(nil).InsertShipmentTx), ctx, tx, sh)
+}
+
+// InsertStatusHistoryTx mocks base method.
+func (m *MockOrderRepository) InsertStatusHistoryTx(ctx context.Context, tx *sql.Tx, h *order.OrderStatusHistory) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertStatusHistoryTx", ctx, tx, h)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertStatusHistoryTx indicates an expected call of InsertStatusHistoryTx.
+func (mr *MockOrderRepositoryMockRecorder) InsertStatusHistoryTx(ctx, tx, h interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertStatusHistoryTx", reflect.TypeOf((*MockOrderRepository)(nil).InsertStatusHistoryTx), ctx, tx, h)
+}
+
+// MarkShipmentDeliveredTx mocks base method.
+func (m *MockOrderRepository) MarkShipmentDeliveredTx(ctx context.Context, tx *sql.Tx, orderID, shipmentID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkShipmentDeliveredTx", ctx, tx, orderID, shipmentID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkShipmentDeliveredTx indicates an expected call of MarkShipmentDeliveredTx.
+func (mr *MockOrderRepositoryMockRecorder) MarkShipmentDeliveredTx(ctx, tx, orderID, shipmentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkShipmentDeliveredTx", reflect.TypeOf((*MockOrderRepository)(nil).MarkShipmentDeliveredTx), ctx, tx, orderID, shipmentID)
+}
+
+// UpdateOrderStatusTx mocks base method.
+func (m *MockOrderRepository) UpdateOrderStatusTx(ctx context.Context, tx *sql.Tx, orderID int64, status order.OrderStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOrderStatusTx", ctx, tx, orderID, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOrderStatusTx indicates an expected call of UpdateOrderStatusTx.
+func (mr *MockOrderRepositoryMockRecorder) UpdateOrderStatusTx(ctx, tx, orderID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderStatusTx", reflect.TypeOf((*MockOrderRepository)(nil).UpdateOrderStatusTx), ctx, tx, orderID, status)
+}
+
+// UpdateReturnTx mocks base method.
+func (m *MockOrderRepository) UpdateReturnTx(ctx context.Context, tx *sql.Tx, ret *order.Return) error {
+	m.ctrl.T.Helper()
+	ret_2 := m.ctrl.Call(m, "UpdateReturnTx", ctx, tx, ret)
+	ret0, _ := ret_2[0].(error)
+	return ret0
+}
+
+// UpdateReturnTx indicates an expected call of UpdateReturnTx.
+func (mr *MockOrderRepositoryMockRecorder) UpdateReturnTx(ctx, tx, ret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateReturnTx", reflect.TypeOf((*MockOrderRepository)(nil).UpdateReturnTx), ctx, tx, ret)
+}
+
+// Mockqueryer is a mock of queryer interface.
+type Mockqueryer struct {
+	ctrl     *gomock.Controller
+	recorder *MockqueryerMockRecorder
+}
+
+// MockqueryerMockRecorder is the mock recorder for Mockqueryer.
+type MockqueryerMockRecorder struct {
+	mock *Mockqueryer
+}
+
+// NewMockqueryer creates a new mock instance.
+func NewMockqueryer(ctrl *gomock.Controller) *Mockqueryer {
+	mock := &Mockqueryer{ctrl: ctrl}
+	mock.recorder = &MockqueryerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockqueryer) EXPECT() *MockqueryerMockRecorder {
+	return m.recorder
+}
+
+// QueryContext mocks base method.
+func (m *Mockqueryer) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryContext", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryContext indicates an expected call of QueryContext.
+func (mr *MockqueryerMockRecorder) QueryContext(ctx, query interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*Mockqueryer)(nil).QueryContext), varargs...)
+}
+
+// QueryRowContext mocks base method.
+func (m *Mockqueryer) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockqueryerMockRecorder) QueryRowContext(ctx, query interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*Mockqueryer)(nil).QueryRowContext), varargs...)
+}
+
+// MockrowScanner is a mock of rowScanner interface.
+type MockrowScanner struct {
+	ctrl     *gomock.Controller
+	recorder *MockrowScannerMockRecorder
+}
+
+// MockrowScannerMockRecorder is the mock recorder for MockrowScanner.
+type MockrowScannerMockRecorder struct {
+	mock *MockrowScanner
+}
+
+// NewMockrowScanner creates a new mock instance.
+func NewMockrowScanner(ctrl *gomock.Controller) *MockrowScanner {
+	mock := &MockrowScanner{ctrl: ctrl}
+	mock.recorder = &MockrowScannerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrowScanner) EXPECT() *MockrowScannerMockRecorder {
+	return m.recorder
+}
+
+// Scan mocks base method.
+func (m *MockrowScanner) Scan(dest ...any) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range dest {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Scan", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockrowScannerMockRecorder) Scan(dest ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOrderTx", reflect.TypeOf((*MockOrderRepository)(nil).InsertOrderTx), ctx, tx, userID, totalAmount, address)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockrowScanner)(nil).Scan), dest...)
 }
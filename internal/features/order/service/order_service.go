@@ -7,26 +7,61 @@ import (
 	"math"
 	"time"
 
+	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/config"
 	"github.com/codepnw/go-starter-kit/internal/errs"
+	addressservice "github.com/codepnw/go-starter-kit/internal/features/address/service"
 	cartrepository "github.com/codepnw/go-starter-kit/internal/features/cart/repository"
 	"github.com/codepnw/go-starter-kit/internal/features/order"
 	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/payment"
+	"github.com/codepnw/go-starter-kit/internal/features/payment/provider"
+	paymentrepository "github.com/codepnw/go-starter-kit/internal/features/payment/repository"
 	productrepository "github.com/codepnw/go-starter-kit/internal/features/product/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
 	"github.com/codepnw/go-starter-kit/pkg/database"
 )
 
+const (
+	defaultOrdersLimit = 10
+	maxOrdersLimit     = 100
+)
+
+//go:generate mockgen -source=order_service.go -destination=order_service_mock.go -package=orderservice
 type OrderService interface {
-	CreateOrder(ctx context.Context, userID, address string) (string, error)
-	GetOrderDetails(ctx context.Context, orderID int64) (*order.OrderDetailResponse, error)
+	CreateOrder(ctx context.Context, userID string, input order.CheckoutInput) (string, error)
+	GetOrderDetails(ctx context.Context, userID, orderNo string) (*order.OrderDetailResponse, error)
 	MyOrders(ctx context.Context, userID string, page, limit int) (*order.OrderListResponse, error)
+	CancelOrder(ctx context.Context, orderNo, reason string) error
+
+	// Back-Office
+	AdminGetOrderDetails(ctx context.Context, orderID int64) (*order.OrderDetailResponse, error)
+	AdminListOrders(ctx context.Context, status order.OrderStatus, page, limit int) (*order.OrderListResponse, error)
+	UpdateOrderStatus(ctx context.Context, orderID int64, status order.OrderStatus, reason string) error
+	RefundOrder(ctx context.Context, orderID int64, input order.RefundInput) (*order.RefundResponse, error)
+	CreateShipment(ctx context.Context, orderID int64, input order.ShipmentInput) (*order.ShipmentResponse, error)
+	DeliverShipment(ctx context.Context, orderID, shipmentID int64) (*order.ShipmentResponse, error)
+
+	// Returns
+	RequestReturn(ctx context.Context, userID, orderNo string, input order.ReturnInput) (*order.ReturnResponse, error)
+	MyOrderReturns(ctx context.Context, userID, orderNo string) ([]*order.ReturnResponse, error)
+	AdminListReturns(ctx context.Context, status order.ReturnStatus, page, limit int) (*order.ReturnListResponse, error)
+	ApproveReturn(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error)
+	RejectReturn(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error)
+	ReceiveReturn(ctx context.Context, returnID int64, input order.ReceiveReturnInput) (*order.ReturnResponse, error)
+
+	// Used by other features inside their own transaction
+	TransitionStatusTx(ctx context.Context, tx *sql.Tx, ord *order.Order, next order.OrderStatus, changedBy, reason string) error
 }
 
 type orderService struct {
-	tx        database.TxManager
-	orderRepo orderrepository.OrderRepository
-	prodRepo  productrepository.ProductRepository
-	cartRepo  cartrepository.CartRepository
+	tx          database.TxManager
+	orderRepo   orderrepository.OrderRepository
+	prodRepo    productrepository.ProductRepository
+	cartRepo    cartrepository.CartRepository
+	payRepo     paymentrepository.PaymentRepository
+	payProvider provider.PaymentProvider
+	addrSrv     addressservice.AddressService
 }
 
 func NewOrderService(
@@ -34,53 +69,72 @@ func NewOrderService(
 	orderRepo orderrepository.OrderRepository,
 	prodRepo productrepository.ProductRepository,
 	cartRepo cartrepository.CartRepository,
+	payRepo paymentrepository.PaymentRepository,
+	payProvider provider.PaymentProvider,
+	addrSrv addressservice.AddressService,
 ) OrderService {
 	return &orderService{
-		tx:        tx,
-		orderRepo: orderRepo,
-		prodRepo:  prodRepo,
-		cartRepo:  cartRepo,
+		tx:          tx,
+		orderRepo:   orderRepo,
+		prodRepo:    prodRepo,
+		cartRepo:    cartRepo,
+		payRepo:     payRepo,
+		payProvider: payProvider,
+		addrSrv:     addrSrv,
 	}
 }
 
 // GetOrderDetails implements OrderService.
-func (s *orderService) GetOrderDetails(ctx context.Context, orderID int64) (*order.OrderDetailResponse, error) {
+func (s *orderService) GetOrderDetails(ctx context.Context, userID, orderNo string) (*order.OrderDetailResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
-	ordData, err := s.orderRepo.FindOrderDetails(ctx, orderID)
+	orderID, err := order.ParseOrderNo(orderNo)
+	if err != nil {
+		return nil, errs.ErrOrderNotFound
+	}
+
+	ordData, err := s.orderRepo.FindUserOrderDetails(ctx, userID, orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Details Response
-	resp := &order.OrderDetailResponse{
-		OrderNo:   generateOrderNo(ordData.ID, ordData.CreatedAt),
-		OrderDate: ordData.CreatedAt.Format(time.DateTime),
-		Status:    ordData.Status,
-		Address:   ordData.Address,
-		Amount:    int64(ordData.TotalAmount),
-		Items:     make([]order.OrderItemResponse, 0),
+	// Order No must match created date, not only sequence id
+	if order.GenerateOrderNo(ordData.ID, ordData.CreatedAt) != orderNo {
+		return nil, errs.ErrOrderNotFound
 	}
 
-	// Add Items Response
-	for _, item := range ordData.Items {
-		ordItem := order.OrderItemResponse{
-			ProductName: item.ProductName,
-			Quantity:    item.Quantity,
-			Price:       int64(item.Price),
-			Total:       int64(item.Price) * int64(item.Quantity),
-		}
-		resp.Items = append(resp.Items, ordItem)
+	return s.orderDetailResponse(ctx, ordData)
+}
+
+// AdminGetOrderDetails implements OrderService.
+func (s *orderService) AdminGetOrderDetails(ctx context.Context, orderID int64) (*order.OrderDetailResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersRead); err != nil {
+		return nil, err
 	}
-	return resp, nil
+
+	ordData, err := s.orderRepo.FindOrderDetails(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orderDetailResponse(ctx, ordData)
 }
 
 // CreateOrder implements OrderService.
-func (s *orderService) CreateOrder(ctx context.Context, userID, address string) (string, error) {
+func (s *orderService) CreateOrder(ctx context.Context, userID string, input order.CheckoutInput) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
+	// 0. Shipping Address
+	addr, err := s.addrSrv.ResolveShippingAddress(ctx, userID, input.AddressID, input.Address)
+	if err != nil {
+		return "", err
+	}
+
 	// 1. Find Cart Items
 	cartItems, err := s.cartRepo.GetCartItems(ctx, userID)
 	if err != nil {
@@ -101,13 +155,24 @@ func (s *orderService) CreateOrder(ctx context.Context, userID, address string)
 	// Transaction
 	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
 		// 2. Create Order
-		id, createdAt, err := s.orderRepo.InsertOrderTx(ctx, tx, userID, totalAmount, address)
+		id, createdAt, err := s.orderRepo.InsertOrderTx(ctx, tx, userID, totalAmount, *addr)
 		if err != nil {
 			return fmt.Errorf("insert order failed: %w", err)
 		}
 		orderID = id
 		orderCreatedAt = createdAt
 
+		// 2.1 Status History
+		err = s.orderRepo.InsertStatusHistoryTx(ctx, tx, &order.OrderStatusHistory{
+			OrderID:   orderID,
+			ToStatus:  order.StatusPending,
+			ChangedBy: userID,
+			Reason:    "order placed",
+		})
+		if err != nil {
+			return fmt.Errorf("insert status history failed: %w", err)
+		}
+
 		// 3. Loop Items
 		for _, item := range cartItems {
 			// 3.1 Product Decrease Stock
@@ -138,7 +203,7 @@ func (s *orderService) CreateOrder(ctx context.Context, userID, address string)
 		return "", err
 	}
 
-	return generateOrderNo(orderID, orderCreatedAt), nil
+	return order.GenerateOrderNo(orderID, orderCreatedAt), nil
 }
 
 // MyOrders implements OrderService.
@@ -146,13 +211,7 @@ func (s *orderService) MyOrders(ctx context.Context, userID string, page, limit
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
-	if page <= 0 {
-		page = 1
-	}
-	if limit <= 0 {
-		limit = 10
-	}
-	offset := (page - 1) * limit
+	page, limit, offset := paginate(page, limit)
 
 	// Find My Orders
 	orders, total, err := s.orderRepo.FindMyOrders(ctx, userID, limit, offset)
@@ -160,6 +219,570 @@ func (s *orderService) MyOrders(ctx context.Context, userID string, page, limit
 		return nil, err
 	}
 
+	return orderListResponse(orders, total, page, limit), nil
+}
+
+// AdminListOrders implements OrderService.
+func (s *orderService) AdminListOrders(ctx context.Context, status order.OrderStatus, page, limit int) (*order.OrderListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersRead); err != nil {
+		return nil, err
+	}
+
+	if status != "" && !status.IsValid() {
+		return nil, errs.ErrInvalidOrderStatus
+	}
+
+	page, limit, offset := paginate(page, limit)
+
+	orders, total, err := s.orderRepo.FindOrders(ctx, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := orderListResponse(orders, total, page, limit)
+	for i, o := range orders {
+		resp.Orders[i].UserID = o.UserID
+	}
+	return resp, nil
+}
+
+// UpdateOrderStatus implements OrderService.
+func (s *orderService) UpdateOrderStatus(ctx context.Context, orderID int64, status order.OrderStatus, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersWrite); err != nil {
+		return err
+	}
+
+	if !status.IsValid() {
+		return errs.ErrInvalidOrderStatus
+	}
+	// Refund statuses must move money, use RefundOrder.
+	// Shipping needs shipment records, use CreateShipment.
+	// Cancelling must restore stock, use CancelOrder
+	if status.IsRefundStatus() || status == order.StatusShipped || status == order.StatusPartiallyShipped || status == order.StatusCancelled {
+		return errs.ErrInvalidStatusTransition
+	}
+
+	changedBy, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		return s.TransitionStatusTx(ctx, tx, ord, status, changedBy, reason)
+	})
+}
+
+// CancelOrder implements OrderService.
+// Owner or staff can cancel, stock is restored once even on repeated calls.
+// A captured payment is refunded in full first, the provider is called outside the order lock.
+func (s *orderService) CancelOrder(ctx context.Context, orderNo, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	orderID, err := order.ParseOrderNo(orderNo)
+	if err != nil {
+		return errs.ErrOrderNotFound
+	}
+
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+	isStaff := claims.Role.HasPermission(user.PermOrdersWrite)
+
+	if reason == "" {
+		reason = "cancelled by customer"
+		if isStaff {
+			reason = "cancelled by staff"
+		}
+	}
+	refund := order.RefundInput{Reason: reason, Restock: true}
+
+	// 1. Cancel, or plan the refund of a captured payment
+	var plan *refundPlan
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, err := s.lockCancellableTx(ctx, tx, orderID, orderNo, claims.UserID, isStaff)
+		if err != nil || ord == nil {
+			return err
+		}
+
+		if ord.Status == order.StatusPaid {
+			// Marked paid by hand = no captured payment, nothing to refund
+			plan, err = s.planRefundTx(ctx, tx, ord, refund)
+			if err != errs.ErrOrderNotRefundable {
+				return err
+			}
+			plan = nil
+		}
+
+		return s.cancelTx(ctx, tx, ord, claims.UserID, reason)
+	})
+	if err != nil || plan == nil {
+		return err
+	}
+
+	// 2. Refund at Provider
+	providerRef, err := s.refundAtProvider(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	// 3. Save Refund, refunded items are restocked, then Cancel
+	return s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, err := s.lockCancellableTx(ctx, tx, orderID, orderNo, claims.UserID, isStaff)
+		if err == errs.ErrInvalidStatusTransition {
+			// Moved on while the provider refunded, retry re-plans with the same reference
+			return errs.ErrRefundConflict
+		}
+		if err != nil || ord == nil {
+			return err
+		}
+
+		if _, _, err := s.recordRefundTx(ctx, tx, ord, refund, plan, providerRef, claims.UserID, 0); err != nil {
+			return err
+		}
+		return s.TransitionStatusTx(ctx, tx, ord, order.StatusCancelled, claims.UserID, reason)
+	})
+}
+
+// lockCancellableTx : lock the order the caller may cancel, nil when it is already cancelled
+func (s *orderService) lockCancellableTx(ctx context.Context, tx *sql.Tx, orderID int64, orderNo, userID string, isStaff bool) (*order.Order, error) {
+	ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff && ord.UserID != userID {
+		return nil, errs.ErrOrderNotFound
+	}
+	if order.GenerateOrderNo(ord.ID, ord.CreatedAt) != orderNo {
+		return nil, errs.ErrOrderNotFound
+	}
+
+	// Already Cancelled (Idempotent)
+	if ord.Status == order.StatusCancelled {
+		return nil, nil
+	}
+	if !ord.Status.CanTransitionTo(order.StatusCancelled) {
+		return nil, errs.ErrInvalidStatusTransition
+	}
+	return ord, nil
+}
+
+// cancelTx : cancel an order with nothing to refund and restore its stock
+func (s *orderService) cancelTx(ctx context.Context, tx *sql.Tx, ord *order.Order, changedBy, reason string) error {
+	if err := s.TransitionStatusTx(ctx, tx, ord, order.StatusCancelled, changedBy, reason); err != nil {
+		return err
+	}
+
+	items, err := s.orderRepo.FindOrderItemsTx(ctx, tx, ord.ID)
+	if err != nil {
+		return fmt.Errorf("get order items failed: %w", err)
+	}
+	for _, item := range items {
+		if err := s.prodRepo.IncreaseStockTx(ctx, tx, item.ProductID, item.Quantity); err != nil {
+			return fmt.Errorf("restore stock product %d failed: %w", item.ProductID, err)
+		}
+	}
+	return nil
+}
+
+// RefundOrder implements OrderService.
+// Refund full order or selected items against the captured payment.
+func (s *orderService) RefundOrder(ctx context.Context, orderID int64, input order.RefundInput) (*order.RefundResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersWrite); err != nil {
+		return nil, err
+	}
+
+	changedBy, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Validate, order lock is released before calling the provider
+	var plan *refundPlan
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		plan, err = s.planRefundTx(ctx, tx, ord, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Refund at Provider
+	providerRef, err := s.refundAtProvider(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Save Refund
+	var resp *order.RefundResponse
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		resp, err = s.saveRefundTx(ctx, tx, ord, input, plan, providerRef, changedBy, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// refundPlan : validated refund, the provider is called with it outside the order lock
+type refundPlan struct {
+	payment   *payment.Payment
+	items     []order.OrderItem
+	lines     []order.RefundItem
+	amount    int64
+	refunded  int64 // refunded before this refund
+	orderNo   string
+	reference string
+}
+
+// planRefundTx : validate refund lines and amount against the captured payment.
+// ord must be locked with FindOrderForUpdateTx in the same transaction.
+func (s *orderService) planRefundTx(ctx context.Context, tx *sql.Tx, ord *order.Order, input order.RefundInput) (*refundPlan, error) {
+	if !ord.Status.IsRefundable() {
+		return nil, errs.ErrOrderNotRefundable
+	}
+
+	// 1. Captured Payment
+	pay, err := s.payRepo.FindOpenPaymentByOrderTx(ctx, tx, ord.ID)
+	if err != nil {
+		if err == errs.ErrPaymentNotFound {
+			return nil, errs.ErrOrderNotRefundable
+		}
+		return nil, err
+	}
+	if pay.Status != payment.StatusCaptured {
+		return nil, errs.ErrOrderNotRefundable
+	}
+
+	// 2. Already Refunded
+	items, err := s.orderRepo.FindOrderItemsTx(ctx, tx, ord.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get order items failed: %w", err)
+	}
+	refunds, err := s.orderRepo.FindRefundsTx(ctx, tx, ord.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get refunds failed: %w", err)
+	}
+
+	var refundedAmount int64
+	refundedQty := refundedQuantities(refunds)
+	for _, rf := range refunds {
+		refundedAmount += rf.Amount
+	}
+
+	// 3. Refund Lines
+	lines, err := refundLines(items, refundedQty, input.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	var amount int64
+	for _, l := range lines {
+		amount += l.Amount
+	}
+	if amount == 0 {
+		return nil, errs.ErrNothingToRefund
+	}
+	if refundedAmount+amount > pay.Amount {
+		return nil, errs.ErrRefundExceedsPaid
+	}
+
+	// Reference is stable, a retry after a failed save is not refunded twice
+	orderNo := order.GenerateOrderNo(ord.ID, ord.CreatedAt)
+	return &refundPlan{
+		payment:   pay,
+		items:     items,
+		lines:     lines,
+		amount:    amount,
+		refunded:  refundedAmount,
+		orderNo:   orderNo,
+		reference: fmt.Sprintf("%s-R%d", orderNo, len(refunds)+1),
+	}, nil
+}
+
+// refundAtProvider : return refund id at provider, must not run inside a transaction
+func (s *orderService) refundAtProvider(ctx context.Context, plan *refundPlan) (string, error) {
+	providerRefund, err := s.payProvider.Refund(ctx, provider.RefundRequest{
+		IntentID:  plan.payment.ProviderRef,
+		Amount:    plan.amount,
+		Reference: plan.reference,
+	})
+	if err != nil {
+		return "", err
+	}
+	return providerRefund.ID, nil
+}
+
+// saveRefundTx : check the order did not change since plan, save refund and update order status.
+// ord must be locked with FindOrderForUpdateTx in the same transaction.
+func (s *orderService) saveRefundTx(ctx context.Context, tx *sql.Tx, ord *order.Order, input order.RefundInput, plan *refundPlan, providerRef, changedBy string, returnID int64) (*order.RefundResponse, error) {
+	refund, current, err := s.recordRefundTx(ctx, tx, ord, input, plan, providerRef, changedBy, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Order Status
+	next := order.StatusPartiallyRefunded
+	if ord.RefundedAmount == current.payment.Amount {
+		next = order.StatusRefunded
+	}
+	if ord.Status != next {
+		reason := input.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("refund %d", current.amount)
+		}
+		if err := s.TransitionStatusTx(ctx, tx, ord, next, changedBy, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	return refundResponse(refund, current.orderNo, ord.RefundedAmount, ord.Status), nil
+}
+
+// recordRefundTx : check the order did not change since plan, save refund and restock.
+// The order status is left to the caller.
+func (s *orderService) recordRefundTx(ctx context.Context, tx *sql.Tx, ord *order.Order, input order.RefundInput, plan *refundPlan, providerRef, changedBy string, returnID int64) (*order.Refund, *refundPlan, error) {
+	// 1. Same Refund as Planned, another refund saved in between = refund number changed
+	current, err := s.planRefundTx(ctx, tx, ord, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current.reference != plan.reference || current.amount != plan.amount {
+		return nil, nil, errs.ErrRefundConflict
+	}
+
+	// 2. Save Refund
+	refund := &order.Refund{
+		OrderID:     ord.ID,
+		PaymentID:   current.payment.ID,
+		ReturnID:    returnID,
+		Amount:      current.amount,
+		Reason:      input.Reason,
+		Restock:     input.Restock,
+		ProviderRef: providerRef,
+		CreatedBy:   changedBy,
+		Items:       current.lines,
+	}
+	if err := s.orderRepo.InsertRefundTx(ctx, tx, refund); err != nil {
+		return nil, nil, fmt.Errorf("insert refund failed: %w", err)
+	}
+	ord.RefundedAmount = current.refunded + current.amount
+
+	// 3. Restock
+	if input.Restock {
+		productIDs := make(map[int64]int64, len(current.items))
+		for _, item := range current.items {
+			productIDs[item.ID] = item.ProductID
+		}
+		for _, l := range current.lines {
+			if err := s.prodRepo.IncreaseStockTx(ctx, tx, productIDs[l.OrderItemID], l.Quantity); err != nil {
+				return nil, nil, fmt.Errorf("restock product %d failed: %w", productIDs[l.OrderItemID], err)
+			}
+		}
+	}
+
+	return refund, current, nil
+}
+
+// TransitionStatusTx implements OrderService.
+// Validate state machine, update status and write history.
+// ord must be locked with FindOrderForUpdateTx in the same transaction.
+func (s *orderService) TransitionStatusTx(ctx context.Context, tx *sql.Tx, ord *order.Order, next order.OrderStatus, changedBy, reason string) error {
+	if !ord.Status.CanTransitionTo(next) {
+		return errs.ErrInvalidStatusTransition
+	}
+
+	if err := s.orderRepo.UpdateOrderStatusTx(ctx, tx, ord.ID, next); err != nil {
+		return fmt.Errorf("update order status failed: %w", err)
+	}
+
+	err := s.orderRepo.InsertStatusHistoryTx(ctx, tx, &order.OrderStatusHistory{
+		OrderID:    ord.ID,
+		FromStatus: ord.Status,
+		ToStatus:   next,
+		ChangedBy:  changedBy,
+		Reason:     reason,
+	})
+	if err != nil {
+		return fmt.Errorf("insert status history failed: %w", err)
+	}
+
+	ord.Status = next
+	return nil
+}
+
+// -------- HELPER ------------
+
+func (s *orderService) orderDetailResponse(ctx context.Context, ordData *order.Order) (*order.OrderDetailResponse, error) {
+	history, err := s.orderRepo.FindStatusHistory(ctx, ordData.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipments, err := s.orderRepo.FindShipments(ctx, ordData.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Details Response
+	resp := &order.OrderDetailResponse{
+		OrderNo:         order.GenerateOrderNo(ordData.ID, ordData.CreatedAt),
+		OrderDate:       ordData.CreatedAt.Format(time.DateTime),
+		Status:          ordData.Status,
+		Address:         ordData.Address,
+		ShippingAddress: ordData.ShippingAddress,
+		Amount:          int64(ordData.TotalAmount),
+		RefundedAmount:  ordData.RefundedAmount,
+		Items:           make([]order.OrderItemResponse, 0),
+		Shipments:       make([]order.ShipmentResponse, 0, len(shipments)),
+		Timeline:        make([]order.OrderTimeline, 0, len(history)),
+	}
+
+	// Add Items Response
+	for _, item := range ordData.Items {
+		ordItem := order.OrderItemResponse{
+			OrderItemID: item.ID,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			Price:       int64(item.Price),
+			Total:       int64(item.Price) * int64(item.Quantity),
+		}
+		resp.Items = append(resp.Items, ordItem)
+	}
+
+	// Add Shipments Response
+	for _, sh := range shipments {
+		resp.Shipments = append(resp.Shipments, *shipmentResponse(sh))
+	}
+
+	// Add Timeline Response
+	for _, h := range history {
+		resp.Timeline = append(resp.Timeline, order.OrderTimeline{
+			FromStatus: h.FromStatus,
+			ToStatus:   h.ToStatus,
+			ChangedBy:  h.ChangedBy,
+			Reason:     h.Reason,
+			ChangedAt:  h.CreatedAt.Format(time.DateTime),
+		})
+	}
+	return resp, nil
+}
+
+// refundedQuantities : order item id -> refunded quantity
+func refundedQuantities(refunds []order.Refund) map[int64]int {
+	qty := make(map[int64]int)
+	for _, rf := range refunds {
+		for _, ri := range rf.Items {
+			qty[ri.OrderItemID] += ri.Quantity
+		}
+	}
+	return qty
+}
+
+// refundLines : empty req = every remaining quantity
+func refundLines(items []order.OrderItem, refundedQty map[int64]int, req []order.RefundItemReq) ([]order.RefundItem, error) {
+	byID := make(map[int64]order.OrderItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	var lines []order.RefundItem
+
+	if len(req) == 0 {
+		for _, item := range items {
+			if qty := item.Quantity - refundedQty[item.ID]; qty > 0 {
+				lines = append(lines, order.RefundItem{
+					OrderItemID: item.ID,
+					Quantity:    qty,
+					Amount:      int64(item.Price) * int64(qty),
+				})
+			}
+		}
+		return lines, nil
+	}
+
+	seen := make(map[int64]bool, len(req))
+	for _, r := range req {
+		item, ok := byID[r.OrderItemID]
+		if !ok || seen[r.OrderItemID] || r.Quantity <= 0 {
+			return nil, errs.ErrInvalidRefundItem
+		}
+		seen[r.OrderItemID] = true
+
+		if r.Quantity > item.Quantity-refundedQty[item.ID] {
+			return nil, errs.ErrRefundExceedsPaid
+		}
+		lines = append(lines, order.RefundItem{
+			OrderItemID: item.ID,
+			Quantity:    r.Quantity,
+			Amount:      int64(item.Price) * int64(r.Quantity),
+		})
+	}
+	return lines, nil
+}
+
+func refundResponse(refund *order.Refund, orderNo string, totalRefunded int64, status order.OrderStatus) *order.RefundResponse {
+	resp := &order.RefundResponse{
+		RefundID:      refund.ID,
+		OrderNo:       orderNo,
+		Amount:        refund.Amount,
+		TotalRefunded: totalRefunded,
+		Status:        status,
+		Restock:       refund.Restock,
+		Items:         make([]order.RefundItemResponse, 0, len(refund.Items)),
+	}
+	for _, item := range refund.Items {
+		resp.Items = append(resp.Items, order.RefundItemResponse{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Amount:      item.Amount,
+		})
+	}
+	return resp
+}
+
+func paginate(page, limit int) (int, int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = defaultOrdersLimit
+	}
+	if limit > maxOrdersLimit {
+		limit = maxOrdersLimit
+	}
+	return page, limit, (page - 1) * limit
+}
+
+func orderListResponse(orders []*order.Order, total int64, page, limit int) *order.OrderListResponse {
 	totalPage := int(math.Ceil(float64(total) / float64(limit)))
 
 	// Response
@@ -175,19 +798,13 @@ func (s *orderService) MyOrders(ctx context.Context, userID string, page, limit
 
 	for _, item := range orders {
 		o := &order.OrderResponse{
-			OrderNo:     generateOrderNo(item.ID, item.CreatedAt),
-			TotalAmount: int64(item.TotalAmount),
-			Status:      item.Status,
-			CreatedAt:   item.CreatedAt.Format(time.DateTime),
+			OrderNo:        order.GenerateOrderNo(item.ID, item.CreatedAt),
+			TotalAmount:    int64(item.TotalAmount),
+			RefundedAmount: item.RefundedAmount,
+			Status:         item.Status,
+			CreatedAt:      item.CreatedAt.Format(time.DateTime),
 		}
 		resp.Orders = append(resp.Orders, o)
 	}
-	return resp, nil
-}
-
-// -------- HELPER ------------
-
-func generateOrderNo(orderID int64, createdAt time.Time) string {
-	now := createdAt.Format("20060201")
-	return fmt.Sprintf("ORD-%s-%06d", now, orderID)
+	return resp
 }
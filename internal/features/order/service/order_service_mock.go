@@ -0,0 +1,289 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: order_service.go
+
+// Package orderservice is a generated GoMock package.
+package orderservice
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+
+	order "github.com/codepnw/go-starter-kit/internal/features/order"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockOrderService is a mock of OrderService interface.
+type MockOrderService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderServiceMockRecorder
+}
+
+// MockOrderServiceMockRecorder is the mock recorder for MockOrderService.
+type MockOrderServiceMockRecorder struct {
+	mock *MockOrderService
+}
+
+// NewMockOrderService creates a new mock instance.
+func NewMockOrderService(ctrl *gomock.Controller) *MockOrderService {
+	mock := &MockOrderService{ctrl: ctrl}
+	mock.recorder = &MockOrderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderService) EXPECT() *MockOrderServiceMockRecorder {
+	return m.recorder
+}
+
+// AdminGetOrderDetails mocks base method.
+func (m *MockOrderService) AdminGetOrderDetails(ctx context.Context, orderID int64) (*order.OrderDetailResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdminGetOrderDetails", ctx, orderID)
+	ret0, _ := ret[0].(*order.OrderDetailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdminGetOrderDetails indicates an expected call of AdminGetOrderDetails.
+func (mr *MockOrderServiceMockRecorder) AdminGetOrderDetails(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdminGetOrderDetails", reflect.TypeOf((*MockOrderService)(nil).AdminGetOrderDetails), ctx, orderID)
+}
+
+// AdminListOrders mocks base method.
+func (m *MockOrderService) AdminListOrders(ctx context.Context, status order.OrderStatus, page, limit int) (*order.OrderListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdminListOrders", ctx, status, page, limit)
+	ret0, _ := ret[0].(*order.OrderListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdminListOrders indicates an expected call of AdminListOrders.
+func (mr *MockOrderServiceMockRecorder) AdminListOrders(ctx, status, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdminListOrders", reflect.TypeOf((*MockOrderService)(nil).AdminListOrders), ctx, status, page, limit)
+}
+
+// AdminListReturns mocks base method.
+func (m *MockOrderService) AdminListReturns(ctx context.Context, status order.ReturnStatus, page, limit int) (*order.ReturnListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdminListReturns", ctx, status, page, limit)
+	ret0, _ := ret[0].(*order.ReturnListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdminListReturns indicates an expected call of AdminListReturns.
+func (mr *MockOrderServiceMockRecorder) AdminListReturns(ctx, status, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdminListReturns", reflect.TypeOf((*MockOrderService)(nil).AdminListReturns), ctx, status, page, limit)
+}
+
+// ApproveReturn mocks base method.
+func (m *MockOrderService) ApproveReturn(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveReturn", ctx, returnID, note)
+	ret0, _ := ret[0].(*order.ReturnResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveReturn indicates an expected call of ApproveReturn.
+func (mr *MockOrderServiceMockRecorder) ApproveReturn(ctx, returnID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveReturn", reflect.TypeOf((*MockOrderService)(nil).ApproveReturn), ctx, returnID, note)
+}
+
+// CancelOrder mocks base method.
+func (m *MockOrderService) CancelOrder(ctx context.Context, orderNo, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOrder", ctx, orderNo, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelOrder indicates an expected call of CancelOrder.
+func (mr *MockOrderServiceMockRecorder) CancelOrder(ctx, orderNo, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOrder", reflect.TypeOf((*MockOrderService)(nil).CancelOrder), ctx, orderNo, reason)
+}
+
+// CreateOrder mocks base method.
+func (m *MockOrderService) CreateOrder(ctx context.Context, userID string, input order.CheckoutInput) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrder", ctx, userID, input)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrder indicates an expected call of CreateOrder.
+func (mr *MockOrderServiceMockRecorder) CreateOrder(ctx, userID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrderService)(nil).CreateOrder), ctx, userID, input)
+}
+
+// CreateShipment mocks base method.
+func (m *MockOrderService) CreateShipment(ctx context.Context, orderID int64, input order.ShipmentInput) (*order.ShipmentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateShipment", ctx, orderID, input)
+	ret0, _ := ret[0].(*order.ShipmentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateShipment indicates an expected call of CreateShipment.
+func (mr *MockOrderServiceMockRecorder) CreateShipment(ctx, orderID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShipment", reflect.TypeOf((*MockOrderService)(nil).CreateShipment), ctx, orderID, input)
+}
+
+// DeliverShipment mocks base method.
+func (m *MockOrderService) DeliverShipment(ctx context.Context, orderID, shipmentID int64) (*order.ShipmentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliverShipment", ctx, orderID, shipmentID)
+	ret0, _ := ret[0].(*order.ShipmentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeliverShipment indicates an expected call of DeliverShipment.
+func (mr *MockOrderServiceMockRecorder) DeliverShipment(ctx, orderID, shipmentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliverShipment", reflect.TypeOf((*MockOrderService)(nil).DeliverShipment), ctx, orderID, shipmentID)
+}
+
+// GetOrderDetails mocks base method.
+func (m *MockOrderService) GetOrderDetails(ctx context.Context, userID, orderNo string) (*order.OrderDetailResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderDetails", ctx, userID, orderNo)
+	ret0, _ := ret[0].(*order.OrderDetailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderDetails indicates an expected call of GetOrderDetails.
+func (mr *MockOrderServiceMockRecorder) GetOrderDetails(ctx, userID, orderNo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderDetails", reflect.TypeOf((*MockOrderService)(nil).GetOrderDetails), ctx, userID, orderNo)
+}
+
+// MyOrderReturns mocks base method.
+func (m *MockOrderService) MyOrderReturns(ctx context.Context, userID, orderNo string) ([]*order.ReturnResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MyOrderReturns", ctx, userID, orderNo)
+	ret0, _ := ret[0].([]*order.ReturnResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MyOrderReturns indicates an expected call of MyOrderReturns.
+func (mr *MockOrderServiceMockRecorder) MyOrderReturns(ctx, userID, orderNo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MyOrderReturns", reflect.TypeOf((*MockOrderService)(nil).MyOrderReturns), ctx, userID, orderNo)
+}
+
+// MyOrders mocks base method.
+func (m *MockOrderService) MyOrders(ctx context.Context, userID string, page, limit int) (*order.OrderListResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MyOrders", ctx, userID, page, limit)
+	ret0, _ := ret[0].(*order.OrderListResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MyOrders indicates an expected call of MyOrders.
+func (mr *MockOrderServiceMockRecorder) MyOrders(ctx, userID, page, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MyOrders", reflect.TypeOf((*MockOrderService)(nil).MyOrders), ctx, userID, page, limit)
+}
+
+// ReceiveReturn mocks base method.
+func (m *MockOrderService) ReceiveReturn(ctx context.Context, returnID int64, input order.ReceiveReturnInput) (*order.ReturnResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveReturn", ctx, returnID, input)
+	ret0, _ := ret[0].(*order.ReturnResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReceiveReturn indicates an expected call of ReceiveReturn.
+func (mr *MockOrderServiceMockRecorder) ReceiveReturn(ctx, returnID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveReturn", reflect.TypeOf((*MockOrderService)(nil).ReceiveReturn), ctx, returnID, input)
+}
+
+// RefundOrder mocks base method.
+func (m *MockOrderService) RefundOrder(ctx context.Context, orderID int64, input order.RefundInput) (*order.RefundResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundOrder", ctx, orderID, input)
+	ret0, _ := ret[0].(*order.RefundResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundOrder indicates an expected call of RefundOrder.
+func (mr *MockOrderServiceMockRecorder) RefundOrder(ctx, orderID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundOrder", reflect.TypeOf((*MockOrderService)(nil).RefundOrder), ctx, orderID, input)
+}
+
+// RejectReturn mocks base method.
+func (m *MockOrderService) RejectReturn(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectReturn", ctx, returnID, note)
+	ret0, _ := ret[0].(*order.ReturnResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectReturn indicates an expected call of RejectReturn.
+func (mr *MockOrderServiceMockRecorder) RejectReturn(ctx, returnID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectReturn", reflect.TypeOf((*MockOrderService)(nil).RejectReturn), ctx, returnID, note)
+}
+
+// RequestReturn mocks base method.
+func (m *MockOrderService) RequestReturn(ctx context.Context, userID, orderNo string, input order.ReturnInput) (*order.ReturnResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestReturn", ctx, userID, orderNo, input)
+	ret0, _ := ret[0].(*order.ReturnResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestReturn indicates an expected call of RequestReturn.
+func (mr *MockOrderServiceMockRecorder) RequestReturn(ctx, userID, orderNo, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestReturn", reflect.TypeOf((*MockOrderService)(nil).RequestReturn), ctx, userID, orderNo, input)
+}
+
+// TransitionStatusTx mocks base method.
+func (m *MockOrderService) TransitionStatusTx(ctx context.Context, tx *sql.Tx, ord *order.Order, next order.OrderStatus, changedBy, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionStatusTx", ctx, tx, ord, next, changedBy, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransitionStatusTx indicates an expected call of TransitionStatusTx.
+func (mr *MockOrderServiceMockRecorder) TransitionStatusTx(ctx, tx, ord, next, changedBy, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionStatusTx", reflect.TypeOf((*MockOrderService)(nil).TransitionStatusTx), ctx, tx, ord, next, changedBy, reason)
+}
+
+// UpdateOrderStatus mocks base method.
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID int64, status order.OrderStatus, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOrderStatus", ctx, orderID, status, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOrderStatus indicates an expected call of UpdateOrderStatus.
+func (mr *MockOrderServiceMockRecorder) UpdateOrderStatus(ctx, orderID, status, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrderStatus", reflect.TypeOf((*MockOrderService)(nil).UpdateOrderStatus), ctx, orderID, status, reason)
+}
@@ -7,38 +7,63 @@ import (
 	"testing"
 	"time"
 
+	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/address"
+	addressservice "github.com/codepnw/go-starter-kit/internal/features/address/service"
 	"github.com/codepnw/go-starter-kit/internal/features/cart"
 	cartrepository "github.com/codepnw/go-starter-kit/internal/features/cart/repository"
 	"github.com/codepnw/go-starter-kit/internal/features/order"
 	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
 	orderservice "github.com/codepnw/go-starter-kit/internal/features/order/service"
+	"github.com/codepnw/go-starter-kit/internal/features/payment"
+	"github.com/codepnw/go-starter-kit/internal/features/payment/provider"
+	paymentrepository "github.com/codepnw/go-starter-kit/internal/features/payment/repository"
 	productrepository "github.com/codepnw/go-starter-kit/internal/features/product/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
 	"github.com/codepnw/go-starter-kit/pkg/database"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
-var ErrDB = errors.New("database error")
+const mockUserID = "mock-uuid-1"
+
+var (
+	ErrDB         = errors.New("database error")
+	mockCreatedAt = time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+)
 
 func TestCreateOrder(t *testing.T) {
+	mockAddress := &address.Address{
+		RecipientName: "John Doe",
+		Phone:         "+66812345678",
+		Line1:         "99 Sukhumvit Rd",
+		City:          "Bangkok",
+		Province:      "Bangkok",
+		PostalCode:    "10110",
+		Country:       "TH",
+	}
+
 	type createOrderInput struct {
-		userID  string
-		address string
+		userID string
+		input  order.CheckoutInput
 	}
 
 	type testCase struct {
 		name        string
 		input       createOrderInput
-		mockFn      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, input createOrderInput)
+		mockFn      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, mockAddr *addressservice.MockAddressService, input createOrderInput)
 		expectedErr error
 	}
 
 	testCases := []testCase{
 		{
 			name:  "success",
-			input: createOrderInput{userID: "mock-uuid-1", address: "Bangkok, Thailand"},
-			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, input createOrderInput) {
+			input: createOrderInput{userID: "mock-uuid-1", input: order.CheckoutInput{AddressID: 7}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, mockAddr *addressservice.MockAddressService, input createOrderInput) {
+				mockAddr.EXPECT().ResolveShippingAddress(gomock.Any(), input.userID, int64(7), nil).Return(mockAddress, nil).Times(1)
+
 				mockItems := []*cart.CartItemResult{
 					{ID: 1, ProductID: 101, Quantity: 2, ProductName: "IPhone-17", Price: 44900, Stock: 10},
 					{ID: 2, ProductID: 102, Quantity: 1, ProductName: "Macbook-air-M4", Price: 34900, Stock: 5},
@@ -51,7 +76,9 @@ func TestCreateOrder(t *testing.T) {
 					},
 				).Times(1)
 
-				mockOrder.EXPECT().InsertOrderTx(gomock.Any(), gomock.Any(), input.userID, gomock.Any(), input.address).Return(int64(101), time.Time{}, nil).Times(1)
+				mockOrder.EXPECT().InsertOrderTx(gomock.Any(), gomock.Any(), input.userID, gomock.Any(), *mockAddress).Return(int64(101), time.Time{}, nil).Times(1)
+
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
 				for _, i := range mockItems {
 					mockProd.EXPECT().DecreaseStockTx(gomock.Any(), gomock.Any(), i.ProductID, i.Quantity).Return(nil).Times(1)
@@ -63,10 +90,20 @@ func TestCreateOrder(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name:  "fail address required",
+			input: createOrderInput{userID: "mock-uuid-1"},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, mockAddr *addressservice.MockAddressService, input createOrderInput) {
+				mockAddr.EXPECT().ResolveShippingAddress(gomock.Any(), input.userID, int64(0), nil).Return(nil, errs.ErrAddressRequired).Times(1)
+			},
+			expectedErr: errs.ErrAddressRequired,
+		},
 		{
 			name:  "fail cart empty",
-			input: createOrderInput{userID: "mock-uuid-1", address: "Bangkok, Thailand"},
-			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, input createOrderInput) {
+			input: createOrderInput{userID: "mock-uuid-1", input: order.CheckoutInput{Address: mockAddress}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, mockAddr *addressservice.MockAddressService, input createOrderInput) {
+				mockAddr.EXPECT().ResolveShippingAddress(gomock.Any(), input.userID, int64(0), mockAddress).Return(mockAddress, nil).Times(1)
+
 				mockItems := []*cart.CartItemResult{}
 				mockCart.EXPECT().GetCartItems(gomock.Any(), input.userID).Return(mockItems, nil).Times(1)
 			},
@@ -75,14 +112,14 @@ func TestCreateOrder(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		service, mockTx, mockOrd, mockProd, mockCart := setup(t)
+		service, mockTx, mockOrd, mockProd, mockCart, _, _, mockAddr := setupWithAddress(t)
 
-		tc.mockFn(mockTx, mockOrd, mockProd, mockCart, tc.input)
+		tc.mockFn(mockTx, mockOrd, mockProd, mockCart, mockAddr, tc.input)
 
-		orderNo, err := service.CreateOrder(context.Background(), tc.input.userID, tc.input.address)
+		orderNo, err := service.CreateOrder(context.Background(), tc.input.userID, tc.input.input)
 
 		if tc.expectedErr != nil {
-			assert.Error(t, err)
+			assert.ErrorIs(t, err, tc.expectedErr)
 		} else {
 			assert.NoError(t, err)
 			assert.NotEmpty(t, orderNo)
@@ -93,45 +130,132 @@ func TestCreateOrder(t *testing.T) {
 func TestGetOrderDetails(t *testing.T) {
 	type testCase struct {
 		name        string
-		orderID     int64
-		mockFn      func(mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, orderID int64)
+		orderNo     string
+		mockFn      func(mockOrder *orderrepository.MockOrderRepository, orderID int64)
 		expectedErr error
 	}
 
+	const orderID int64 = 101
+	orderNo := order.GenerateOrderNo(orderID, mockCreatedAt)
+
 	testCases := []testCase{
 		{
 			name:    "success",
-			orderID: 101,
-			mockFn: func(mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, orderID int64) {
+			orderNo: orderNo,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {
 				mockOrderData := &order.Order{
+					ID:        orderID,
+					UserID:    mockUserID,
+					CreatedAt: mockCreatedAt,
 					Items: []order.OrderItem{
 						{OrderID: orderID, ProductID: 101, ProductName: "IPhone-17", Quantity: 1, Price: 35000},
 						{OrderID: orderID, ProductID: 102, ProductName: "IPhone-17-Pro", Quantity: 2, Price: 45000},
 					},
 				}
-				mockOrder.EXPECT().FindOrderDetails(gomock.Any(), orderID).Return(mockOrderData, nil).Times(1)
+				mockOrder.EXPECT().FindUserOrderDetails(gomock.Any(), mockUserID, orderID).Return(mockOrderData, nil).Times(1)
+
+				mockHistory := []order.OrderStatusHistory{
+					{OrderID: orderID, ToStatus: order.StatusPending, CreatedAt: time.Now()},
+					{OrderID: orderID, FromStatus: order.StatusPending, ToStatus: order.StatusPaid, CreatedAt: time.Now()},
+				}
+				mockOrder.EXPECT().FindStatusHistory(gomock.Any(), orderID).Return(mockHistory, nil).Times(1)
+				mockOrder.EXPECT().FindShipments(gomock.Any(), orderID).Return([]*order.Shipment{
+					{ID: 1, OrderID: orderID, Carrier: "Kerry", TrackingNumber: "KEX0001", ShippedAt: time.Now(), Items: []order.ShipmentItem{{OrderItemID: 1, Quantity: 1}}},
+				}, nil).Times(1)
 			},
 			expectedErr: nil,
 		},
+		{
+			name:    "fail other user order",
+			orderNo: orderNo,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {
+				mockOrder.EXPECT().FindUserOrderDetails(gomock.Any(), mockUserID, orderID).Return(nil, errs.ErrOrderNotFound).Times(1)
+			},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+		{
+			name:    "fail order no date mismatch",
+			orderNo: order.GenerateOrderNo(orderID, mockCreatedAt.AddDate(0, 0, -1)),
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {
+				mockOrderData := &order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt}
+				mockOrder.EXPECT().FindUserOrderDetails(gomock.Any(), mockUserID, orderID).Return(mockOrderData, nil).Times(1)
+			},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+		{
+			name:        "fail invalid order no",
+			orderNo:     "101",
+			mockFn:      func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrOrderNotFound,
+		},
 		{
 			name:    "fail get order",
-			orderID: 101,
-			mockFn: func(mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockCart *cartrepository.MockCartRepository, orderID int64) {
-				mockOrder.EXPECT().FindOrderDetails(gomock.Any(), orderID).Return(nil, ErrDB).Times(1)
+			orderNo: orderNo,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {
+				mockOrder.EXPECT().FindUserOrderDetails(gomock.Any(), mockUserID, orderID).Return(nil, ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
 	}
 
 	for _, tc := range testCases {
-		service, _, mockOrd, mockProd, mockCart := setup(t)
+		service, _, mockOrd, _, _ := setup(t)
 
-		tc.mockFn(mockOrd, mockProd, mockCart, tc.orderID)
+		tc.mockFn(mockOrd, orderID)
 
-		resp, err := service.GetOrderDetails(context.Background(), tc.orderID)
+		resp, err := service.GetOrderDetails(context.Background(), mockUserID, tc.orderNo)
 
 		if tc.expectedErr != nil {
-			assert.Error(t, err)
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+			assert.NotNil(t, resp)
+			assert.Equal(t, tc.orderNo, resp.OrderNo)
+			assert.Len(t, resp.Timeline, 2)
+			assert.Len(t, resp.Shipments, 1)
+		}
+	}
+}
+
+func TestAdminGetOrderDetails(t *testing.T) {
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		mockFn      func(mockOrder *orderrepository.MockOrderRepository, orderID int64)
+		expectedErr error
+	}
+
+	const orderID int64 = 101
+
+	testCases := []testCase{
+		{
+			name: "success staff any order",
+			ctx:  withRole(user.RoleStaff),
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {
+				mockOrderData := &order.Order{ID: orderID, UserID: "other-uuid", CreatedAt: mockCreatedAt}
+				mockOrder.EXPECT().FindOrderDetails(gomock.Any(), orderID).Return(mockOrderData, nil).Times(1)
+				mockOrder.EXPECT().FindStatusHistory(gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindShipments(gomock.Any(), orderID).Return(nil, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			mockFn:      func(mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		service, _, mockOrd, _, _ := setup(t)
+
+		tc.mockFn(mockOrd, orderID)
+
+		resp, err := service.AdminGetOrderDetails(tc.ctx, orderID)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
 		} else {
 			assert.NoError(t, err)
 			assert.NotNil(t, resp)
@@ -143,6 +267,7 @@ func TestMyOrders(t *testing.T) {
 	type testCase struct {
 		name        string
 		userID      string
+		limit       int
 		mockFn      func(mockOrder *orderrepository.MockOrderRepository, userID string)
 		expectedErr error
 	}
@@ -160,6 +285,15 @@ func TestMyOrders(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name:   "limit capped",
+			userID: "mock-uuid-01",
+			limit:  500,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository, userID string) {
+				mockOrder.EXPECT().FindMyOrders(gomock.Any(), userID, 100, 0).Return([]*order.Order{}, int64(0), nil).Times(1)
+			},
+			expectedErr: nil,
+		},
 		{
 			name:   "fail",
 			userID: "mock-uuid-01",
@@ -175,7 +309,7 @@ func TestMyOrders(t *testing.T) {
 
 		tc.mockFn(mockOrd, tc.userID)
 
-		resp, err := service.MyOrders(context.Background(), "mock-uuid-01", 0, 0)
+		resp, err := service.MyOrders(context.Background(), "mock-uuid-01", 0, tc.limit)
 
 		if tc.expectedErr != nil {
 			assert.Error(t, err)
@@ -186,7 +320,586 @@ func TestMyOrders(t *testing.T) {
 	}
 }
 
+func TestUpdateOrderStatus(t *testing.T) {
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		status      order.OrderStatus
+		mockFn      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64)
+		expectedErr error
+	}
+
+	const orderID int64 = 101
+
+	testCases := []testCase{
+		{
+			name:   "success pending to paid",
+			ctx:    withRole(user.RoleStaff),
+			status: order.StatusPaid,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, Status: order.StatusPending}, nil).Times(1)
+
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusPaid).Return(nil).Times(1)
+
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, h *order.OrderStatusHistory) error {
+						assert.Equal(t, order.StatusPending, h.FromStatus)
+						assert.Equal(t, order.StatusPaid, h.ToStatus)
+						assert.Equal(t, mockUserID, h.ChangedBy)
+						return nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "fail invalid transition",
+			ctx:    withRole(user.RoleAdmin),
+			status: order.StatusCompleted,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, Status: order.StatusPending}, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidStatusTransition,
+		},
+		{
+			name:        "fail invalid status",
+			ctx:         withRole(user.RoleAdmin),
+			status:      order.OrderStatus("LOST"),
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrInvalidOrderStatus,
+		},
+		{
+			name:        "fail refund status without refund",
+			ctx:         withRole(user.RoleAdmin),
+			status:      order.StatusRefunded,
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrInvalidStatusTransition,
+		},
+		{
+			name:        "fail shipped without shipment",
+			ctx:         withRole(user.RoleAdmin),
+			status:      order.StatusShipped,
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrInvalidStatusTransition,
+		},
+		{
+			name:        "fail cancel without restoring stock",
+			ctx:         withRole(user.RoleAdmin),
+			status:      order.StatusCancelled,
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrInvalidStatusTransition,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			status:      order.StatusPaid,
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, orderID int64) {},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		service, mockTx, mockOrd, _, _ := setup(t)
+
+		tc.mockFn(mockTx, mockOrd, orderID)
+
+		err := service.UpdateOrderStatus(tc.ctx, orderID, tc.status, "")
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		mockFn      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64)
+		payFn       func(mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider)
+		expectedErr error
+	}
+
+	const orderID int64 = 101
+
+	// 2 x 500 + 1 x 1000 = 2000
+	mockItems := []order.OrderItem{
+		{ID: 1, OrderID: orderID, ProductID: 11, Quantity: 2, Price: 500},
+		{ID: 2, OrderID: orderID, ProductID: 12, Quantity: 1, Price: 1000},
+	}
+	capturedPayment := &payment.Payment{ID: 7, OrderID: orderID, ProviderRef: "pi_1", Amount: 2000, Status: payment.StatusCaptured}
+
+	withTx := func(mockTx *database.MockTxManager) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+	}
+
+	testCases := []testCase{
+		{
+			name: "success owner cancel pending",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusPending}, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusCancelled).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+
+				for _, i := range mockItems {
+					mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), i.ProductID, i.Quantity).Return(nil).Times(1)
+				}
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "success staff cancel other user order",
+			ctx:  withRole(user.RoleStaff),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: "other-uuid", CreatedAt: mockCreatedAt, Status: order.StatusPaid}, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusCancelled).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+
+				mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(len(mockItems))
+			},
+			payFn: func(mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				// Marked paid by hand, no payment to refund
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(nil, errs.ErrPaymentNotFound).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "success paid with captured payment is refunded and restocked",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				// Plan, then save after the provider refund
+				withTx(mockTx)
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, id int64) (*order.Order, error) {
+						return &order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusPaid}, nil
+					},
+				).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(2)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, r *order.Refund) error {
+						assert.Equal(t, int64(2000), r.Amount)
+						assert.True(t, r.Restock)
+						assert.Equal(t, "re_1", r.ProviderRef)
+						return nil
+					},
+				).Times(1)
+				for _, i := range mockItems {
+					mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), i.ProductID, i.Quantity).Return(nil).Times(1)
+				}
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusCancelled).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			payFn: func(mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockProvider.EXPECT().Refund(gomock.Any(), provider.RefundRequest{IntentID: "pi_1", Amount: 2000, Reference: order.GenerateOrderNo(orderID, mockCreatedAt) + "-R1"}).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail provider refund keeps order paid",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusPaid}, nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			payFn: func(mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name: "fail order shipped during provider refund",
+			ctx:  withRole(user.RoleStaff),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusPaid}, nil).Times(1)
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusShipped}, nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			payFn: func(mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+			},
+			expectedErr: errs.ErrRefundConflict,
+		},
+		{
+			name: "success already cancelled no restock",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusCancelled}, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail not owner",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: "other-uuid", CreatedAt: mockCreatedAt, Status: order.StatusPending}, nil).Times(1)
+			},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+		{
+			name: "fail already shipped",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusShipped}, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidStatusTransition,
+		},
+		{
+			name: "fail restore stock",
+			ctx:  withRole(user.RoleCustomer),
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {
+				withTx(mockTx)
+
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, UserID: mockUserID, CreatedAt: mockCreatedAt, Status: order.StatusPending}, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusCancelled).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+
+				mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), mockItems[0].ProductID, mockItems[0].Quantity).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:        "fail no claims",
+			ctx:         context.Background(),
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, orderID int64) {},
+			expectedErr: errs.ErrUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		service, mockTx, mockOrd, mockProd, _, mockPay, mockProvider := setupWithPayment(t)
+
+		tc.mockFn(mockTx, mockOrd, mockProd, orderID)
+		if tc.payFn != nil {
+			tc.payFn(mockPay, mockProvider)
+		}
+
+		err := service.CancelOrder(tc.ctx, order.GenerateOrderNo(orderID, mockCreatedAt), "")
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	testCases := []struct {
+		from     order.OrderStatus
+		to       order.OrderStatus
+		expected bool
+	}{
+		{order.StatusPending, order.StatusPaid, true},
+		{order.StatusPaid, order.StatusShipped, true},
+		{order.StatusShipped, order.StatusCompleted, true},
+		{order.StatusPending, order.StatusCancelled, true},
+		{order.StatusPaid, order.StatusCancelled, true},
+		{order.StatusPending, order.StatusShipped, false},
+		{order.StatusShipped, order.StatusCancelled, false},
+		{order.StatusCompleted, order.StatusPending, false},
+		{order.StatusCancelled, order.StatusPaid, false},
+		{order.StatusPaid, order.StatusRefunded, true},
+		{order.StatusPartiallyShipped, order.StatusRefunded, true},
+		{order.StatusCompleted, order.StatusRefunded, true},
+		{order.StatusShipped, order.StatusPartiallyRefunded, true},
+		{order.StatusPartiallyRefunded, order.StatusShipped, true},
+		{order.StatusPartiallyRefunded, order.StatusCompleted, true},
+		{order.StatusPartiallyRefunded, order.StatusCancelled, false},
+		{order.StatusPending, order.StatusRefunded, false},
+		{order.StatusRefunded, order.StatusShipped, false},
+		{order.StatusPaid, order.StatusPartiallyShipped, true},
+		{order.StatusPartiallyShipped, order.StatusShipped, true},
+		{order.StatusPartiallyShipped, order.StatusCompleted, false},
+		{order.StatusPartiallyShipped, order.StatusCancelled, false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, tc.from.CanTransitionTo(tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
+
+func TestRefundOrder(t *testing.T) {
+	const orderID = int64(1)
+
+	type testCase struct {
+		name           string
+		ctx            context.Context
+		input          order.RefundInput
+		mockFn         func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider)
+		expectedStatus order.OrderStatus
+		expectedAmount int64
+		expectedErr    error
+	}
+
+	// 2 x 500 + 1 x 1000 = 2000
+	mockItems := []order.OrderItem{
+		{ID: 11, OrderID: orderID, ProductID: 101, Quantity: 2, Price: 500},
+		{ID: 12, OrderID: orderID, ProductID: 102, Quantity: 1, Price: 1000},
+	}
+	capturedPayment := &payment.Payment{ID: 5, OrderID: orderID, ProviderRef: "pi_1", Amount: 2000, Status: payment.StatusCaptured}
+	// times = 2 when the refund is validated again before save
+	lockOrder := func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, status order.OrderStatus, times int) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(times)
+		mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).DoAndReturn(
+			func(ctx context.Context, tx *sql.Tx, id int64) (*order.Order, error) {
+				return &order.Order{ID: orderID, Status: status, CreatedAt: mockCreatedAt}, nil
+			},
+		).Times(times)
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success full refund",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid, 2)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(2)
+				mockProvider.EXPECT().Refund(gomock.Any(), provider.RefundRequest{IntentID: "pi_1", Amount: 2000, Reference: order.GenerateOrderNo(orderID, mockCreatedAt) + "-R1"}).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusRefunded).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: order.StatusRefunded,
+			expectedAmount: 2000,
+			expectedErr:    nil,
+		},
+		{
+			name:  "success partial refund with restock",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{Items: []order.RefundItemReq{{OrderItemID: 11, Quantity: 1}}, Restock: true},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusShipped, 2)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(2)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), int64(101), 1).Return(nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusPartiallyRefunded).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: order.StatusPartiallyRefunded,
+			expectedAmount: 500,
+			expectedErr:    nil,
+		},
+		{
+			name:  "success second partial refund keeps status",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{Items: []order.RefundItemReq{{OrderItemID: 12, Quantity: 1}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPartiallyRefunded, 2)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return([]order.Refund{
+					{ID: 1, Amount: 500, Items: []order.RefundItem{{OrderItemID: 11, Quantity: 1, Amount: 500}}},
+				}, nil).Times(2)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(&provider.Refund{ID: "re_2"}, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedStatus: order.StatusPartiallyRefunded,
+			expectedAmount: 1000,
+			expectedErr:    nil,
+		},
+		{
+			name:  "success refund remaining after partial refund",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPartiallyRefunded, 2)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return([]order.Refund{
+					{ID: 1, Amount: 500, Items: []order.RefundItem{{OrderItemID: 11, Quantity: 1, Amount: 500}}},
+				}, nil).Times(2)
+				mockProvider.EXPECT().Refund(gomock.Any(), provider.RefundRequest{IntentID: "pi_1", Amount: 1500, Reference: order.GenerateOrderNo(orderID, mockCreatedAt) + "-R2"}).Return(&provider.Refund{ID: "re_2"}, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusRefunded).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: order.StatusRefunded,
+			expectedAmount: 1500,
+			expectedErr:    nil,
+		},
+		{
+			name:  "fail quantity exceeds remaining",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{Items: []order.RefundItemReq{{OrderItemID: 11, Quantity: 2}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusShipped, 1)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return([]order.Refund{
+					{ID: 1, Amount: 500, Items: []order.RefundItem{{OrderItemID: 11, Quantity: 1, Amount: 500}}},
+				}, nil).Times(1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrRefundExceedsPaid,
+		},
+		{
+			name:  "fail item not in order",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{Items: []order.RefundItemReq{{OrderItemID: 99, Quantity: 1}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid, 1)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidRefundItem,
+		},
+		{
+			name:  "fail order not paid",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPending, 1)
+			},
+			expectedErr: errs.ErrOrderNotRefundable,
+		},
+		{
+			name:  "fail no captured payment",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid, 1)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(nil, errs.ErrPaymentNotFound).Times(1)
+			},
+			expectedErr: errs.ErrOrderNotRefundable,
+		},
+		{
+			name:  "fail refund saved during provider call",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid, 2)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+				// Another refund saved first with the same reference, provider refunded once
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return([]order.Refund{
+					{ID: 1, Amount: 500, Items: []order.RefundItem{{OrderItemID: 11, Quantity: 1, Amount: 500}}},
+				}, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrRefundConflict,
+		},
+		{
+			name:  "fail provider error",
+			ctx:   withRole(user.RoleStaff),
+			input: order.RefundInput{},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid, 1)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(nil, ErrDB).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			input:       order.RefundInput{},
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockOrd, mockProd, _, mockPay, mockProvider := setupWithPayment(t)
+
+			tc.mockFn(mockTx, mockOrd, mockProd, mockPay, mockProvider)
+
+			resp, err := service.RefundOrder(tc.ctx, orderID, tc.input)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedStatus, resp.Status)
+				assert.Equal(t, tc.expectedAmount, resp.Amount)
+			}
+		})
+	}
+}
+
+func withRole(role user.Role) context.Context {
+	claims := &jwttoken.UserClaims{UserID: mockUserID, Role: role}
+	return auth.SetContextUserClaims(context.Background(), claims)
+}
+
 func setup(t *testing.T) (orderservice.OrderService, *database.MockTxManager, *orderrepository.MockOrderRepository, *productrepository.MockProductRepository, *cartrepository.MockCartRepository) {
+	service, mockTx, mockOrd, mockProd, mockCart, _, _ := setupWithPayment(t)
+	return service, mockTx, mockOrd, mockProd, mockCart
+}
+
+func setupWithPayment(t *testing.T) (orderservice.OrderService, *database.MockTxManager, *orderrepository.MockOrderRepository, *productrepository.MockProductRepository, *cartrepository.MockCartRepository, *paymentrepository.MockPaymentRepository, *provider.MockPaymentProvider) {
+	service, mockTx, mockOrd, mockProd, mockCart, mockPay, mockProvider, _ := setupWithAddress(t)
+	return service, mockTx, mockOrd, mockProd, mockCart, mockPay, mockProvider
+}
+
+func setupWithAddress(t *testing.T) (orderservice.OrderService, *database.MockTxManager, *orderrepository.MockOrderRepository, *productrepository.MockProductRepository, *cartrepository.MockCartRepository, *paymentrepository.MockPaymentRepository, *provider.MockPaymentProvider, *addressservice.MockAddressService) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -194,8 +907,11 @@ func setup(t *testing.T) (orderservice.OrderService, *database.MockTxManager, *o
 	mockOrd := orderrepository.NewMockOrderRepository(ctrl)
 	mockProd := productrepository.NewMockProductRepository(ctrl)
 	mockCart := cartrepository.NewMockCartRepository(ctrl)
+	mockPay := paymentrepository.NewMockPaymentRepository(ctrl)
+	mockProvider := provider.NewMockPaymentProvider(ctrl)
+	mockAddr := addressservice.NewMockAddressService(ctrl)
 
-	service := orderservice.NewOrderService(mockTx, mockOrd, mockProd, mockCart)
+	service := orderservice.NewOrderService(mockTx, mockOrd, mockProd, mockCart, mockPay, mockProvider, mockAddr)
 
-	return service, mockTx, mockOrd, mockProd, mockCart
+	return service, mockTx, mockOrd, mockProd, mockCart, mockPay, mockProvider, mockAddr
 }
@@ -0,0 +1,403 @@
+package orderservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+)
+
+// RequestReturn implements OrderService.
+// Customer can return delivered items not yet refunded or already in an open return.
+// Eligibility is per line from delivered parcels, not from the order status.
+func (s *orderService) RequestReturn(ctx context.Context, userID, orderNo string, input order.ReturnInput) (*order.ReturnResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	orderID, err := order.ParseOrderNo(orderNo)
+	if err != nil {
+		return nil, errs.ErrOrderNotFound
+	}
+	if len(input.Items) == 0 {
+		return nil, errs.ErrInvalidReturnItem
+	}
+
+	var ret *order.Return
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		// 1. Lock Order Row
+		ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if ord.UserID != userID || order.GenerateOrderNo(ord.ID, ord.CreatedAt) != orderNo {
+			return errs.ErrOrderNotFound
+		}
+		if !ord.Status.IsReturnable() {
+			return errs.ErrOrderNotReturnable
+		}
+
+		// 2. Returnable Quantity
+		items, err := s.orderRepo.FindOrderItemsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get order items failed: %w", err)
+		}
+		refunds, err := s.orderRepo.FindRefundsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get refunds failed: %w", err)
+		}
+		returns, err := s.orderRepo.FindOrderReturnsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get returns failed: %w", err)
+		}
+		shipments, err := s.orderRepo.FindShipmentsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get shipments failed: %w", err)
+		}
+
+		openQty := make(map[int64]int)
+		for _, r := range returns {
+			if !r.Status.IsOpen() {
+				continue
+			}
+			for _, ri := range r.Items {
+				openQty[ri.OrderItemID] += ri.Quantity
+			}
+		}
+
+		// Delivered units still held: refunds may have covered unshipped units first
+		refundedQty := refundedQuantities(refunds)
+		deliveredQty := deliveredQuantities(shipments)
+		returnable := make(map[int64]int, len(items))
+		byID := make(map[int64]order.OrderItem, len(items))
+		for _, item := range items {
+			byID[item.ID] = item
+			returnable[item.ID] = min(deliveredQty[item.ID], item.Quantity-refundedQty[item.ID]) - openQty[item.ID]
+		}
+
+		// 3. Validate Lines
+		ret = &order.Return{
+			OrderID:        ord.ID,
+			UserID:         userID,
+			Status:         order.ReturnRequested,
+			Note:           input.Note,
+			OrderCreatedAt: ord.CreatedAt,
+		}
+		seen := make(map[int64]bool, len(input.Items))
+		for _, r := range input.Items {
+			item, ok := byID[r.OrderItemID]
+			if !ok || seen[r.OrderItemID] || r.Quantity <= 0 || !r.ReasonCode.IsValid() {
+				return errs.ErrInvalidReturnItem
+			}
+			seen[r.OrderItemID] = true
+
+			if r.Quantity > returnable[item.ID] {
+				return errs.ErrInvalidReturnItem
+			}
+			ret.Items = append(ret.Items, order.ReturnItem{
+				OrderItemID: item.ID,
+				Quantity:    r.Quantity,
+				ReasonCode:  r.ReasonCode,
+				ProductID:   item.ProductID,
+			})
+		}
+
+		// 4. Save Return
+		if err := s.orderRepo.InsertReturnTx(ctx, tx, ret); err != nil {
+			return fmt.Errorf("insert return failed: %w", err)
+		}
+
+		return nil // Commit Transaction
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return returnResponse(ret), nil
+}
+
+// MyOrderReturns implements OrderService.
+func (s *orderService) MyOrderReturns(ctx context.Context, userID, orderNo string) ([]*order.ReturnResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	orderID, err := order.ParseOrderNo(orderNo)
+	if err != nil {
+		return nil, errs.ErrOrderNotFound
+	}
+
+	ordData, err := s.orderRepo.FindUserOrderDetails(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.GenerateOrderNo(ordData.ID, ordData.CreatedAt) != orderNo {
+		return nil, errs.ErrOrderNotFound
+	}
+
+	returns, err := s.orderRepo.FindOrderReturns(ctx, ordData.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*order.ReturnResponse, 0, len(returns))
+	for _, r := range returns {
+		resp = append(resp, returnResponse(r))
+	}
+	return resp, nil
+}
+
+// AdminListReturns implements OrderService.
+func (s *orderService) AdminListReturns(ctx context.Context, status order.ReturnStatus, page, limit int) (*order.ReturnListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersRead); err != nil {
+		return nil, err
+	}
+
+	if status != "" && !status.IsValid() {
+		return nil, errs.ErrInvalidReturnStatus
+	}
+
+	page, limit, offset := paginate(page, limit)
+
+	returns, total, err := s.orderRepo.FindReturns(ctx, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &order.ReturnListResponse{
+		Returns:      make([]*order.ReturnResponse, 0, len(returns)),
+		TotalReturns: total,
+		Page:         page,
+		Limit:        limit,
+		TotalPage:    int(math.Ceil(float64(total) / float64(limit))),
+	}
+	for _, r := range returns {
+		resp.Returns = append(resp.Returns, returnResponse(r))
+	}
+	return resp, nil
+}
+
+// ApproveReturn implements OrderService.
+func (s *orderService) ApproveReturn(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error) {
+	return s.reviewReturn(ctx, returnID, order.ReturnApproved, note)
+}
+
+// RejectReturn implements OrderService.
+func (s *orderService) RejectReturn(ctx context.Context, returnID int64, note string) (*order.ReturnResponse, error) {
+	return s.reviewReturn(ctx, returnID, order.ReturnRejected, note)
+}
+
+func (s *orderService) reviewReturn(ctx context.Context, returnID int64, next order.ReturnStatus, note string) (*order.ReturnResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersWrite); err != nil {
+		return nil, err
+	}
+
+	reviewer, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret *order.Return
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ret, err = s.orderRepo.FindReturnForUpdateTx(ctx, tx, returnID)
+		if err != nil {
+			return err
+		}
+		if ret.Status != order.ReturnRequested {
+			return errs.ErrInvalidReturnTransition
+		}
+
+		now := time.Now()
+		ret.Status = next
+		ret.AdminNote = note
+		ret.ReviewedBy = reviewer
+		ret.ReviewedAt = &now
+
+		return s.orderRepo.UpdateReturnTx(ctx, tx, ret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return returnResponse(ret), nil
+}
+
+// ReceiveReturn implements OrderService.
+// Inspect returned items, restock or write off each line, then refund the return.
+func (s *orderService) ReceiveReturn(ctx context.Context, returnID int64, input order.ReceiveReturnInput) (*order.ReturnResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersWrite); err != nil {
+		return nil, err
+	}
+
+	changedBy, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.orderRepo.FindReturn(ctx, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	refundInput := order.RefundInput{Reason: fmt.Sprintf("return #%d", returnID)}
+
+	// 1. Validate, order lock is released before calling the provider
+	var plan *refundPlan
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, ret, err := s.lockApprovedReturnTx(ctx, tx, found.OrderID, returnID)
+		if err != nil {
+			return err
+		}
+
+		refundInput.Items, err = resolveReturnItems(ret, input.Items)
+		if err != nil {
+			return err
+		}
+
+		plan, err = s.planRefundTx(ctx, tx, ord, refundInput)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Refund at Provider
+	providerRef, err := s.refundAtProvider(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Save Refund, return must still be approved
+	var ret *order.Return
+	var refund *order.RefundResponse
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		var ord *order.Order
+		ord, ret, err = s.lockApprovedReturnTx(ctx, tx, found.OrderID, returnID)
+		if err != nil {
+			return err
+		}
+		if _, err := resolveReturnItems(ret, input.Items); err != nil {
+			return err
+		}
+
+		refund, err = s.saveRefundTx(ctx, tx, ord, refundInput, plan, providerRef, changedBy, ret.ID)
+		if err != nil {
+			return err
+		}
+
+		// 4. Restock, write-off items stay out of stock
+		for _, item := range ret.Items {
+			if item.Resolution != order.ResolutionRestock {
+				continue
+			}
+			if err := s.prodRepo.IncreaseStockTx(ctx, tx, item.ProductID, item.Quantity); err != nil {
+				return fmt.Errorf("restock product %d failed: %w", item.ProductID, err)
+			}
+		}
+
+		// 5. Close Return
+		now := time.Now()
+		ret.Status = order.ReturnRefunded
+		ret.ReceivedAt = &now
+		if input.Note != "" {
+			ret.AdminNote = input.Note
+		}
+		return s.orderRepo.UpdateReturnTx(ctx, tx, ret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := returnResponse(ret)
+	resp.Refund = refund
+	return resp, nil
+}
+
+// -------- HELPER ------------
+
+// lockApprovedReturnTx : lock order then return, same order as RequestReturn
+func (s *orderService) lockApprovedReturnTx(ctx context.Context, tx *sql.Tx, orderID, returnID int64) (*order.Order, *order.Return, error) {
+	ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret, err := s.orderRepo.FindReturnForUpdateTx(ctx, tx, returnID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ret.Status != order.ReturnApproved {
+		return nil, nil, errs.ErrInvalidReturnTransition
+	}
+	return ord, ret, nil
+}
+
+// resolveReturnItems : set resolution on every return line, return lines to refund
+func resolveReturnItems(ret *order.Return, req []order.ReturnResolutionReq) ([]order.RefundItemReq, error) {
+	resolutions := make(map[int64]order.ReturnResolution, len(req))
+	for _, r := range req {
+		if !r.Resolution.IsValid() {
+			return nil, errs.ErrReturnResolutionRequired
+		}
+		resolutions[r.ReturnItemID] = r.Resolution
+	}
+	if len(resolutions) != len(ret.Items) {
+		return nil, errs.ErrReturnResolutionRequired
+	}
+
+	refundItems := make([]order.RefundItemReq, 0, len(ret.Items))
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		resolution, ok := resolutions[item.ID]
+		if !ok {
+			return nil, errs.ErrReturnResolutionRequired
+		}
+		item.Resolution = resolution
+
+		refundItems = append(refundItems, order.RefundItemReq{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+	return refundItems, nil
+}
+
+func returnResponse(r *order.Return) *order.ReturnResponse {
+	resp := &order.ReturnResponse{
+		ReturnID:  r.ID,
+		OrderNo:   order.GenerateOrderNo(r.OrderID, r.OrderCreatedAt),
+		Status:    r.Status,
+		Note:      r.Note,
+		AdminNote: r.AdminNote,
+		Items:     make([]order.ReturnItemResponse, 0, len(r.Items)),
+		CreatedAt: r.CreatedAt.Format(time.DateTime),
+	}
+	for _, item := range r.Items {
+		resp.Items = append(resp.Items, order.ReturnItemResponse{
+			ReturnItemID: item.ID,
+			OrderItemID:  item.OrderItemID,
+			ProductName:  item.ProductName,
+			Quantity:     item.Quantity,
+			ReasonCode:   item.ReasonCode,
+			Resolution:   item.Resolution,
+		})
+	}
+	return resp
+}
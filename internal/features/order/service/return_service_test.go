@@ -0,0 +1,438 @@
+package orderservice_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/payment"
+	"github.com/codepnw/go-starter-kit/internal/features/payment/provider"
+	paymentrepository "github.com/codepnw/go-starter-kit/internal/features/payment/repository"
+	productrepository "github.com/codepnw/go-starter-kit/internal/features/product/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const mockReturnID = int64(7)
+
+// 2 x 500 + 1 x 1000 = 2000
+var mockReturnOrderItems = []order.OrderItem{
+	{ID: 11, OrderID: 1, ProductID: 101, Quantity: 2, Price: 500},
+	{ID: 12, OrderID: 1, ProductID: 102, Quantity: 1, Price: 1000},
+}
+
+func TestRequestReturn(t *testing.T) {
+	const orderID = int64(1)
+	orderNo := order.GenerateOrderNo(orderID, mockCreatedAt)
+
+	type testCase struct {
+		name        string
+		orderNo     string
+		input       order.ReturnInput
+		mockFn      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository)
+		expectedErr error
+	}
+
+	lockOrder := func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, ord *order.Order) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(ord, nil).Times(1)
+	}
+	completedOrder := &order.Order{ID: orderID, UserID: mockUserID, Status: order.StatusCompleted, CreatedAt: mockCreatedAt}
+	deliveredAll := []*order.Shipment{
+		{ID: 1, DeliveredAt: &mockCreatedAt, Items: []order.ShipmentItem{{OrderItemID: 11, Quantity: 2}, {OrderItemID: 12, Quantity: 1}}},
+	}
+	validInput := order.ReturnInput{Items: []order.ReturnItemReq{{OrderItemID: 11, Quantity: 1, ReasonCode: order.ReasonDamaged}}}
+
+	testCases := []testCase{
+		{
+			name:    "success",
+			orderNo: orderNo,
+			input:   validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, completedOrder)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindOrderReturnsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return(deliveredAll, nil).Times(1)
+				mockOrder.EXPECT().InsertReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "fail quantity already in open return",
+			orderNo: orderNo,
+			input:   order.ReturnInput{Items: []order.ReturnItemReq{{OrderItemID: 11, Quantity: 2, ReasonCode: order.ReasonDamaged}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, completedOrder)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindOrderReturnsTx(gomock.Any(), gomock.Any(), orderID).Return([]*order.Return{
+					{ID: 1, Status: order.ReturnApproved, Items: []order.ReturnItem{{OrderItemID: 11, Quantity: 1}}},
+					{ID: 2, Status: order.ReturnRejected, Items: []order.ReturnItem{{OrderItemID: 11, Quantity: 2}}},
+				}, nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return(deliveredAll, nil).Times(1)
+				mockOrder.EXPECT().InsertReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrInvalidReturnItem,
+		},
+		{
+			name:    "fail quantity already refunded",
+			orderNo: orderNo,
+			input:   order.ReturnInput{Items: []order.ReturnItemReq{{OrderItemID: 12, Quantity: 1, ReasonCode: order.ReasonOther}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, &order.Order{ID: orderID, UserID: mockUserID, Status: order.StatusCompleted, RefundedAmount: 1000, CreatedAt: mockCreatedAt})
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return([]order.Refund{
+					{ID: 1, Amount: 1000, Items: []order.RefundItem{{OrderItemID: 12, Quantity: 1, Amount: 1000}}},
+				}, nil).Times(1)
+				mockOrder.EXPECT().FindOrderReturnsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return(deliveredAll, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidReturnItem,
+		},
+		{
+			name:    "fail invalid reason code",
+			orderNo: orderNo,
+			input:   order.ReturnInput{Items: []order.ReturnItemReq{{OrderItemID: 11, Quantity: 1, ReasonCode: "CHANGED_MIND"}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, completedOrder)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindOrderReturnsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return(deliveredAll, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidReturnItem,
+		},
+		{
+			name:    "success delivered parcel of shipped order",
+			orderNo: orderNo,
+			input:   validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, &order.Order{ID: orderID, UserID: mockUserID, Status: order.StatusShipped, CreatedAt: mockCreatedAt})
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindOrderReturnsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return([]*order.Shipment{
+					{ID: 1, DeliveredAt: &mockCreatedAt, Items: []order.ShipmentItem{{OrderItemID: 11, Quantity: 1}}},
+					{ID: 2, Items: []order.ShipmentItem{{OrderItemID: 11, Quantity: 1}, {OrderItemID: 12, Quantity: 1}}},
+				}, nil).Times(1)
+				mockOrder.EXPECT().InsertReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "fail item not delivered",
+			orderNo: orderNo,
+			input:   order.ReturnInput{Items: []order.ReturnItemReq{{OrderItemID: 12, Quantity: 1, ReasonCode: order.ReasonDamaged}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, &order.Order{ID: orderID, UserID: mockUserID, Status: order.StatusShipped, CreatedAt: mockCreatedAt})
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindOrderReturnsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return([]*order.Shipment{
+					{ID: 1, DeliveredAt: &mockCreatedAt, Items: []order.ShipmentItem{{OrderItemID: 11, Quantity: 2}}},
+					{ID: 2, Items: []order.ShipmentItem{{OrderItemID: 12, Quantity: 1}}},
+				}, nil).Times(1)
+				mockOrder.EXPECT().InsertReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrInvalidReturnItem,
+		},
+		{
+			name:    "fail order not shipped",
+			orderNo: orderNo,
+			input:   validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, &order.Order{ID: orderID, UserID: mockUserID, Status: order.StatusPaid, CreatedAt: mockCreatedAt})
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrOrderNotReturnable,
+		},
+		{
+			name:    "fail other user order",
+			orderNo: orderNo,
+			input:   validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, &order.Order{ID: orderID, UserID: "other-user", Status: order.StatusCompleted, CreatedAt: mockCreatedAt})
+			},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+		{
+			name:        "fail invalid order no",
+			orderNo:     "ORD-bad",
+			input:       validInput,
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockOrd, _, _ := setup(t)
+
+			tc.mockFn(mockTx, mockOrd)
+
+			resp, err := service.RequestReturn(context.Background(), mockUserID, tc.orderNo, tc.input)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, order.ReturnRequested, resp.Status)
+				assert.Equal(t, orderNo, resp.OrderNo)
+				assert.Len(t, resp.Items, 1)
+			}
+		})
+	}
+}
+
+func TestReviewReturn(t *testing.T) {
+	type testCase struct {
+		name           string
+		ctx            context.Context
+		approve        bool
+		mockFn         func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository)
+		expectedStatus order.ReturnStatus
+		expectedErr    error
+	}
+
+	lockReturn := func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, status order.ReturnStatus) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockOrder.EXPECT().FindReturnForUpdateTx(gomock.Any(), gomock.Any(), mockReturnID).Return(&order.Return{ID: mockReturnID, OrderID: 1, Status: status}, nil).Times(1)
+	}
+
+	testCases := []testCase{
+		{
+			name:    "success approve",
+			ctx:     withRole(user.RoleStaff),
+			approve: true,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockReturn(mockTx, mockOrder, order.ReturnRequested)
+				mockOrder.EXPECT().UpdateReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: order.ReturnApproved,
+			expectedErr:    nil,
+		},
+		{
+			name:    "success reject",
+			ctx:     withRole(user.RoleAdmin),
+			approve: false,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockReturn(mockTx, mockOrder, order.ReturnRequested)
+				mockOrder.EXPECT().UpdateReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: order.ReturnRejected,
+			expectedErr:    nil,
+		},
+		{
+			name:    "fail already reviewed",
+			ctx:     withRole(user.RoleStaff),
+			approve: true,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockReturn(mockTx, mockOrder, order.ReturnRejected)
+				mockOrder.EXPECT().UpdateReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrInvalidReturnTransition,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			approve:     true,
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockOrd, _, _ := setup(t)
+
+			tc.mockFn(mockTx, mockOrd)
+
+			review := service.RejectReturn
+			if tc.approve {
+				review = service.ApproveReturn
+			}
+			resp, err := review(tc.ctx, mockReturnID, "checked photos")
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedStatus, resp.Status)
+				assert.Equal(t, "checked photos", resp.AdminNote)
+			}
+		})
+	}
+}
+
+func TestReceiveReturn(t *testing.T) {
+	const orderID = int64(1)
+
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		input       order.ReceiveReturnInput
+		mockFn      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider)
+		expectedErr error
+	}
+
+	mockReturn := func(status order.ReturnStatus) *order.Return {
+		return &order.Return{
+			ID:             mockReturnID,
+			OrderID:        orderID,
+			Status:         status,
+			OrderCreatedAt: mockCreatedAt,
+			Items: []order.ReturnItem{
+				{ID: 71, OrderItemID: 11, ProductID: 101, Quantity: 2, ReasonCode: order.ReasonDamaged},
+				{ID: 72, OrderItemID: 12, ProductID: 102, Quantity: 1, ReasonCode: order.ReasonNoLongerNeeded},
+			},
+		}
+	}
+	// times = 2 when the refund is validated again before save
+	lockReturn := func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, status order.ReturnStatus, times int) {
+		mockOrder.EXPECT().FindReturn(gomock.Any(), mockReturnID).Return(mockReturn(status), nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(times)
+		mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).DoAndReturn(
+			func(ctx context.Context, tx *sql.Tx, id int64) (*order.Order, error) {
+				return &order.Order{ID: orderID, Status: order.StatusCompleted, CreatedAt: mockCreatedAt}, nil
+			},
+		).Times(times)
+		mockOrder.EXPECT().FindReturnForUpdateTx(gomock.Any(), gomock.Any(), mockReturnID).DoAndReturn(
+			func(ctx context.Context, tx *sql.Tx, id int64) (*order.Return, error) {
+				return mockReturn(status), nil
+			},
+		).Times(times)
+	}
+	capturedPayment := &payment.Payment{ID: 5, OrderID: orderID, ProviderRef: "pi_1", Amount: 2000, Status: payment.StatusCaptured}
+	validInput := order.ReceiveReturnInput{Items: []order.ReturnResolutionReq{
+		{ReturnItemID: 71, Resolution: order.ResolutionWriteOff},
+		{ReturnItemID: 72, Resolution: order.ResolutionRestock},
+	}}
+
+	testCases := []testCase{
+		{
+			name:  "success refund and restock",
+			ctx:   withRole(user.RoleStaff),
+			input: validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockReturn(mockTx, mockOrder, order.ReturnApproved, 2)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(2)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(2)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(2)
+				mockProvider.EXPECT().Refund(gomock.Any(), provider.RefundRequest{IntentID: "pi_1", Amount: 2000, Reference: order.GenerateOrderNo(orderID, mockCreatedAt) + "-R1"}).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, refund *order.Refund) error {
+						assert.Equal(t, mockReturnID, refund.ReturnID)
+						return nil
+					},
+				).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusRefunded).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				// Only RESTOCK lines go back to stock
+				mockProd.EXPECT().IncreaseStockTx(gomock.Any(), gomock.Any(), int64(102), 1).Return(nil).Times(1)
+				mockOrder.EXPECT().UpdateReturnTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:  "fail missing resolution",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ReceiveReturnInput{Items: []order.ReturnResolutionReq{{ReturnItemID: 71, Resolution: order.ResolutionRestock}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockReturn(mockTx, mockOrder, order.ReturnApproved, 1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrReturnResolutionRequired,
+		},
+		{
+			name:  "fail return not approved",
+			ctx:   withRole(user.RoleStaff),
+			input: validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				lockReturn(mockTx, mockOrder, order.ReturnRequested, 1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrInvalidReturnTransition,
+		},
+		{
+			name:  "fail return received during provider call",
+			ctx:   withRole(user.RoleStaff),
+			input: validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				mockOrder.EXPECT().FindReturn(gomock.Any(), mockReturnID).Return(mockReturn(order.ReturnApproved), nil).Times(1)
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(2)
+				mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, Status: order.StatusCompleted, CreatedAt: mockCreatedAt}, nil).Times(2)
+				mockOrder.EXPECT().FindReturnForUpdateTx(gomock.Any(), gomock.Any(), mockReturnID).Return(mockReturn(order.ReturnApproved), nil).Times(1)
+				mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), orderID).Return(capturedPayment, nil).Times(1)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockReturnOrderItems, nil).Times(1)
+				mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(nil, nil).Times(1)
+				mockProvider.EXPECT().Refund(gomock.Any(), gomock.Any()).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+				// Received by another request, the same reference was refunded once at provider
+				mockOrder.EXPECT().FindReturnForUpdateTx(gomock.Any(), gomock.Any(), mockReturnID).Return(mockReturn(order.ReturnRefunded), nil).Times(1)
+				mockOrder.EXPECT().InsertRefundTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrInvalidReturnTransition,
+		},
+		{
+			name:  "fail return not found",
+			ctx:   withRole(user.RoleStaff),
+			input: validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+				mockOrder.EXPECT().FindReturn(gomock.Any(), mockReturnID).Return(nil, errs.ErrReturnNotFound).Times(1)
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrReturnNotFound,
+		},
+		{
+			name:  "fail customer forbidden",
+			ctx:   withRole(user.RoleCustomer),
+			input: validInput,
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, mockProd *productrepository.MockProductRepository, mockPay *paymentrepository.MockPaymentRepository, mockProvider *provider.MockPaymentProvider) {
+			},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockOrd, mockProd, _, mockPay, mockProvider := setupWithPayment(t)
+
+			tc.mockFn(mockTx, mockOrd, mockProd, mockPay, mockProvider)
+
+			resp, err := service.ReceiveReturn(tc.ctx, mockReturnID, tc.input)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, order.ReturnRefunded, resp.Status)
+				assert.Equal(t, int64(2000), resp.Refund.Amount)
+				assert.Equal(t, order.ResolutionWriteOff, resp.Items[0].Resolution)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package orderservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+)
+
+// CreateShipment implements OrderService.
+// Order moves to SHIPPED when every item is shipped, otherwise PARTIALLY_SHIPPED.
+func (s *orderService) CreateShipment(ctx context.Context, orderID int64, input order.ShipmentInput) (*order.ShipmentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersWrite); err != nil {
+		return nil, err
+	}
+
+	changedBy, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *order.ShipmentResponse
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		// 1. Lock Order Row
+		ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if !ord.Status.IsShippable() {
+			return errs.ErrOrderNotShippable
+		}
+
+		// 2. Remaining Quantity, units refunded outside a return are not shipped
+		items, err := s.orderRepo.FindOrderItemsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get order items failed: %w", err)
+		}
+		refunds, err := s.orderRepo.FindRefundsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get refunds failed: %w", err)
+		}
+		shipments, err := s.orderRepo.FindShipmentsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get shipments failed: %w", err)
+		}
+
+		remaining := make(map[int64]int, len(items))
+		refundedQty := unshippedRefundQuantities(refunds)
+		shippedQty := shippedQuantities(shipments)
+		for _, item := range items {
+			remaining[item.ID] = max(item.Quantity-shippedQty[item.ID]-refundedQty[item.ID], 0)
+		}
+
+		// 3. Shipment Lines
+		lines, err := shipmentLines(items, remaining, input.Items)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return errs.ErrNothingToShip
+		}
+
+		sh := &order.Shipment{
+			OrderID:        ord.ID,
+			Carrier:        input.Carrier,
+			TrackingNumber: input.TrackingNumber,
+			CreatedBy:      changedBy,
+			Items:          lines,
+		}
+		if err := s.orderRepo.InsertShipmentTx(ctx, tx, sh); err != nil {
+			return err
+		}
+
+		// 4. Order Status
+		next := order.StatusShipped
+		for _, l := range lines {
+			remaining[l.OrderItemID] -= l.Quantity
+		}
+		for _, qty := range remaining {
+			if qty > 0 {
+				next = order.StatusPartiallyShipped
+				break
+			}
+		}
+		if ord.Status != next {
+			reason := fmt.Sprintf("shipped with %s %s", sh.Carrier, sh.TrackingNumber)
+			if err := s.TransitionStatusTx(ctx, tx, ord, next, changedBy, reason); err != nil {
+				return err
+			}
+		}
+
+		resp = shipmentResponse(sh)
+		resp.OrderStatus = ord.Status
+		return nil // Commit Transaction
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeliverShipment implements OrderService.
+// Order moves to COMPLETED when it is fully shipped and every parcel is delivered.
+func (s *orderService) DeliverShipment(ctx context.Context, orderID, shipmentID int64) (*order.ShipmentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermOrdersWrite); err != nil {
+		return nil, err
+	}
+
+	changedBy, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *order.ShipmentResponse
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.orderRepo.MarkShipmentDeliveredTx(ctx, tx, ord.ID, shipmentID); err != nil {
+			return err
+		}
+
+		shipments, err := s.orderRepo.FindShipmentsTx(ctx, tx, ord.ID)
+		if err != nil {
+			return fmt.Errorf("get shipments failed: %w", err)
+		}
+
+		allDelivered := true
+		for _, sh := range shipments {
+			if sh.ID == shipmentID {
+				resp = shipmentResponse(sh)
+			}
+			if sh.DeliveredAt == nil {
+				allDelivered = false
+			}
+		}
+		if resp == nil {
+			return errs.ErrShipmentNotFound
+		}
+
+		if allDelivered && ord.Status == order.StatusShipped {
+			if err := s.TransitionStatusTx(ctx, tx, ord, order.StatusCompleted, changedBy, "all shipments delivered"); err != nil {
+				return err
+			}
+		}
+
+		resp.OrderStatus = ord.Status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// -------- HELPER ------------
+
+// shippedQuantities : order item id -> shipped quantity
+func shippedQuantities(shipments []*order.Shipment) map[int64]int {
+	qty := make(map[int64]int)
+	for _, sh := range shipments {
+		for _, si := range sh.Items {
+			qty[si.OrderItemID] += si.Quantity
+		}
+	}
+	return qty
+}
+
+// deliveredQuantities : order item id -> quantity in delivered parcels
+func deliveredQuantities(shipments []*order.Shipment) map[int64]int {
+	qty := make(map[int64]int)
+	for _, sh := range shipments {
+		if sh.DeliveredAt == nil {
+			continue
+		}
+		for _, si := range sh.Items {
+			qty[si.OrderItemID] += si.Quantity
+		}
+	}
+	return qty
+}
+
+// unshippedRefundQuantities : order item id -> refunded quantity not from a return, never shipped
+func unshippedRefundQuantities(refunds []order.Refund) map[int64]int {
+	qty := make(map[int64]int)
+	for _, rf := range refunds {
+		if rf.ReturnID != 0 {
+			continue
+		}
+		for _, ri := range rf.Items {
+			qty[ri.OrderItemID] += ri.Quantity
+		}
+	}
+	return qty
+}
+
+// shipmentLines : empty req = every remaining quantity
+func shipmentLines(items []order.OrderItem, remaining map[int64]int, req []order.ShipmentItemReq) ([]order.ShipmentItem, error) {
+	byID := make(map[int64]order.OrderItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	var lines []order.ShipmentItem
+
+	if len(req) == 0 {
+		for _, item := range items {
+			if qty := remaining[item.ID]; qty > 0 {
+				lines = append(lines, order.ShipmentItem{
+					OrderItemID: item.ID,
+					Quantity:    qty,
+					ProductName: item.ProductName,
+				})
+			}
+		}
+		return lines, nil
+	}
+
+	seen := make(map[int64]bool, len(req))
+	for _, r := range req {
+		item, ok := byID[r.OrderItemID]
+		if !ok || seen[r.OrderItemID] || r.Quantity <= 0 || r.Quantity > remaining[item.ID] {
+			return nil, errs.ErrInvalidShipmentItem
+		}
+		seen[r.OrderItemID] = true
+
+		lines = append(lines, order.ShipmentItem{
+			OrderItemID: item.ID,
+			Quantity:    r.Quantity,
+			ProductName: item.ProductName,
+		})
+	}
+	return lines, nil
+}
+
+func shipmentResponse(sh *order.Shipment) *order.ShipmentResponse {
+	resp := &order.ShipmentResponse{
+		ShipmentID:     sh.ID,
+		Carrier:        sh.Carrier,
+		TrackingNumber: sh.TrackingNumber,
+		ShippedAt:      sh.ShippedAt.Format(time.DateTime),
+		Items:          make([]order.ShipmentItemResponse, 0, len(sh.Items)),
+	}
+	if sh.DeliveredAt != nil {
+		resp.DeliveredAt = sh.DeliveredAt.Format(time.DateTime)
+	}
+	for _, item := range sh.Items {
+		resp.Items = append(resp.Items, order.ShipmentItemResponse{
+			OrderItemID: item.OrderItemID,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,281 @@
+package orderservice_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateShipment(t *testing.T) {
+	const orderID = int64(1)
+
+	type testCase struct {
+		name           string
+		ctx            context.Context
+		input          order.ShipmentInput
+		mockFn         func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository)
+		expectedStatus order.OrderStatus
+		expectedErr    error
+	}
+
+	mockItems := []order.OrderItem{
+		{ID: 11, OrderID: orderID, ProductID: 101, Quantity: 2, Price: 500},
+		{ID: 12, OrderID: orderID, ProductID: 102, Quantity: 1, Price: 1000},
+	}
+	lockOrder := func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository, status order.OrderStatus) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockOrder.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, Status: status, CreatedAt: mockCreatedAt}, nil).Times(1)
+	}
+	loadShipped := func(mockOrder *orderrepository.MockOrderRepository, refunds []order.Refund, shipments []*order.Shipment) {
+		mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), orderID).Return(mockItems, nil).Times(1)
+		mockOrder.EXPECT().FindRefundsTx(gomock.Any(), gomock.Any(), orderID).Return(refunds, nil).Times(1)
+		mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return(shipments, nil).Times(1)
+	}
+	transition := func(mockOrder *orderrepository.MockOrderRepository, next order.OrderStatus) {
+		mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, next).Return(nil).Times(1)
+		mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	}
+	firstParcel := &order.Shipment{ID: 1, OrderID: orderID, Items: []order.ShipmentItem{{OrderItemID: 11, Quantity: 2}}}
+
+	testCases := []testCase{
+		{
+			name:  "success ship everything",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0001"},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid)
+				loadShipped(mockOrder, nil, nil)
+				mockOrder.EXPECT().InsertShipmentTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, sh *order.Shipment) error {
+						assert.Len(t, sh.Items, 2)
+						assert.Equal(t, mockUserID, sh.CreatedBy)
+						return nil
+					},
+				).Times(1)
+				transition(mockOrder, order.StatusShipped)
+			},
+			expectedStatus: order.StatusShipped,
+			expectedErr:    nil,
+		},
+		{
+			name:  "success first parcel",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0001", Items: []order.ShipmentItemReq{{OrderItemID: 11, Quantity: 2}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid)
+				loadShipped(mockOrder, nil, nil)
+				mockOrder.EXPECT().InsertShipmentTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				transition(mockOrder, order.StatusPartiallyShipped)
+			},
+			expectedStatus: order.StatusPartiallyShipped,
+			expectedErr:    nil,
+		},
+		{
+			name:  "success last parcel",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Flash", TrackingNumber: "TH0002"},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPartiallyShipped)
+				loadShipped(mockOrder, nil, []*order.Shipment{firstParcel})
+				mockOrder.EXPECT().InsertShipmentTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, sh *order.Shipment) error {
+						assert.Equal(t, []order.ShipmentItem{{OrderItemID: 12, Quantity: 1}}, sh.Items)
+						return nil
+					},
+				).Times(1)
+				transition(mockOrder, order.StatusShipped)
+			},
+			expectedStatus: order.StatusShipped,
+			expectedErr:    nil,
+		},
+		{
+			name:  "success refunded item is not shipped",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0003"},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPartiallyRefunded)
+				loadShipped(mockOrder, []order.Refund{
+					{ID: 1, Items: []order.RefundItem{{OrderItemID: 12, Quantity: 1, Amount: 1000}}},
+				}, nil)
+				mockOrder.EXPECT().InsertShipmentTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, sh *order.Shipment) error {
+						assert.Equal(t, []order.ShipmentItem{{OrderItemID: 11, Quantity: 2}}, sh.Items)
+						return nil
+					},
+				).Times(1)
+				transition(mockOrder, order.StatusShipped)
+			},
+			expectedStatus: order.StatusShipped,
+			expectedErr:    nil,
+		},
+		{
+			name:  "fail quantity already shipped",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0004", Items: []order.ShipmentItemReq{{OrderItemID: 11, Quantity: 1}}},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPartiallyShipped)
+				loadShipped(mockOrder, nil, []*order.Shipment{firstParcel})
+				mockOrder.EXPECT().InsertShipmentTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrInvalidShipmentItem,
+		},
+		{
+			name:  "fail tracking number exists",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0001"},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPaid)
+				loadShipped(mockOrder, nil, nil)
+				mockOrder.EXPECT().InsertShipmentTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(errs.ErrTrackingNumberExists).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrTrackingNumberExists,
+		},
+		{
+			name:  "fail order not paid",
+			ctx:   withRole(user.RoleStaff),
+			input: order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0005"},
+			mockFn: func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {
+				lockOrder(mockTx, mockOrder, order.StatusPending)
+				mockOrder.EXPECT().FindOrderItemsTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrOrderNotShippable,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			input:       order.ShipmentInput{Carrier: "Kerry", TrackingNumber: "KEX0006"},
+			mockFn:      func(mockTx *database.MockTxManager, mockOrder *orderrepository.MockOrderRepository) {},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockOrd, _, _ := setup(t)
+
+			tc.mockFn(mockTx, mockOrd)
+
+			resp, err := service.CreateShipment(tc.ctx, orderID, tc.input)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedStatus, resp.OrderStatus)
+				assert.Equal(t, tc.input.TrackingNumber, resp.TrackingNumber)
+			}
+		})
+	}
+}
+
+func TestDeliverShipment(t *testing.T) {
+	const (
+		orderID    = int64(1)
+		shipmentID = int64(2)
+	)
+
+	type testCase struct {
+		name           string
+		status         order.OrderStatus
+		mockFn         func(mockOrder *orderrepository.MockOrderRepository)
+		expectedStatus order.OrderStatus
+		expectedErr    error
+	}
+
+	deliveredAt := time.Now()
+	parcel := func(id int64, delivered *time.Time) *order.Shipment {
+		return &order.Shipment{ID: id, OrderID: orderID, DeliveredAt: delivered, Items: []order.ShipmentItem{{OrderItemID: 11, Quantity: 1}}}
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success last parcel completes order",
+			status: order.StatusShipped,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository) {
+				mockOrder.EXPECT().MarkShipmentDeliveredTx(gomock.Any(), gomock.Any(), orderID, shipmentID).Return(nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return([]*order.Shipment{
+					parcel(1, &deliveredAt), parcel(shipmentID, &deliveredAt),
+				}, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), orderID, order.StatusCompleted).Return(nil).Times(1)
+				mockOrder.EXPECT().InsertStatusHistoryTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: order.StatusCompleted,
+			expectedErr:    nil,
+		},
+		{
+			name:   "success other parcel still in transit",
+			status: order.StatusShipped,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository) {
+				mockOrder.EXPECT().MarkShipmentDeliveredTx(gomock.Any(), gomock.Any(), orderID, shipmentID).Return(nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return([]*order.Shipment{
+					parcel(1, nil), parcel(shipmentID, &deliveredAt),
+				}, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedStatus: order.StatusShipped,
+			expectedErr:    nil,
+		},
+		{
+			name:   "success order not fully shipped",
+			status: order.StatusPartiallyShipped,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository) {
+				mockOrder.EXPECT().MarkShipmentDeliveredTx(gomock.Any(), gomock.Any(), orderID, shipmentID).Return(nil).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), orderID).Return([]*order.Shipment{
+					parcel(shipmentID, &deliveredAt),
+				}, nil).Times(1)
+				mockOrder.EXPECT().UpdateOrderStatusTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedStatus: order.StatusPartiallyShipped,
+			expectedErr:    nil,
+		},
+		{
+			name:   "fail already delivered",
+			status: order.StatusShipped,
+			mockFn: func(mockOrder *orderrepository.MockOrderRepository) {
+				mockOrder.EXPECT().MarkShipmentDeliveredTx(gomock.Any(), gomock.Any(), orderID, shipmentID).Return(errs.ErrShipmentAlreadyDelivered).Times(1)
+				mockOrder.EXPECT().FindShipmentsTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrShipmentAlreadyDelivered,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockTx, mockOrd, _, _ := setup(t)
+
+			mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+					return fn(nil)
+				},
+			).Times(1)
+			mockOrd.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), orderID).Return(&order.Order{ID: orderID, Status: tc.status, CreatedAt: mockCreatedAt}, nil).Times(1)
+			tc.mockFn(mockOrd)
+
+			resp, err := service.DeliverShipment(withRole(user.RoleStaff), orderID, shipmentID)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedStatus, resp.OrderStatus)
+				assert.NotEmpty(t, resp.DeliveredAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package paymenthandler
+
+const (
+	ParamProvider = "provider" // webhook source, e.g. fake
+)
@@ -0,0 +1,71 @@
+package paymenthandler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	orderhandler "github.com/codepnw/go-starter-kit/internal/features/order/handler"
+	paymentservice "github.com/codepnw/go-starter-kit/internal/features/payment/service"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody : webhook payloads are small JSON events
+const maxWebhookBody = 1 << 20
+
+type PaymentHandler struct {
+	service paymentservice.PaymentService
+}
+
+func NewPaymentHandler(service paymentservice.PaymentService) *PaymentHandler {
+	return &PaymentHandler{service: service}
+}
+
+func (h *PaymentHandler) CreatePayment(c *gin.Context) {
+	userID, err := auth.GetUserIDFromContext(c.Request.Context())
+	if err != nil {
+		response.ResponseError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	resp, err := h.service.CreatePayment(c.Request.Context(), userID, c.Param(orderhandler.ParamOrderNo))
+	if err != nil {
+		switch err {
+		case errs.ErrOrderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrOrderNotPayable:
+			response.ResponseError(c, http.StatusConflict, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	// Raw body is required for signature check
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.service.HandleWebhook(c.Request.Context(), c.Param(ParamProvider), payload, c.Request.Header)
+	if err != nil {
+		switch err {
+		case errs.ErrPaymentProviderNotFound, errs.ErrPaymentNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidWebhookSignature:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, gin.H{"received": true})
+}
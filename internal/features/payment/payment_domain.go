@@ -0,0 +1,37 @@
+package payment
+
+import "time"
+
+type PaymentStatus string
+
+const (
+	StatusPending  PaymentStatus = "PENDING" // intent created, waiting for customer
+	StatusCaptured PaymentStatus = "CAPTURED"
+	StatusFailed   PaymentStatus = "FAILED"
+)
+
+type Payment struct {
+	ID          int64         `json:"id" db:"id"`
+	OrderID     int64         `json:"order_id" db:"order_id"`
+	Provider    string        `json:"provider" db:"provider"`
+	ProviderRef string        `json:"provider_ref" db:"provider_ref"` // intent id at provider
+	Amount      int64         `json:"amount" db:"amount"`
+	Currency    string        `json:"currency" db:"currency"`
+	Status      PaymentStatus `json:"status" db:"status"`
+	CheckoutURL string        `json:"checkout_url" db:"checkout_url"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// ============ Payment DTO =================
+
+type PaymentResponse struct {
+	PaymentID   int64         `json:"payment_id"`
+	OrderNo     string        `json:"order_no"`
+	Provider    string        `json:"provider"`
+	Amount      int64         `json:"amount"`
+	Currency    string        `json:"currency"`
+	Status      PaymentStatus `json:"status"`
+	CheckoutURL string        `json:"checkout_url,omitempty"`
+	CreatedAt   string        `json:"created_at"`
+}
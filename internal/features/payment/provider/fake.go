@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+)
+
+const (
+	FakeProviderName = "fake"
+
+	// webhookTolerance : reject replayed webhooks older than this
+	webhookTolerance = 5 * time.Minute
+)
+
+type fakeProvider struct {
+	baseURL string
+	secret  string
+	client  *http.Client
+	now     func() time.Time
+}
+
+// NewFakeProvider : HTTP client for FakeGateway, same shape as a real gateway integration
+func NewFakeProvider(baseURL, webhookSecret string, client *http.Client) PaymentProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &fakeProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  webhookSecret,
+		client:  client,
+		now:     time.Now,
+	}
+}
+
+func (p *fakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *fakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	intent := new(Intent)
+	if err := p.do(ctx, http.MethodPost, "/v1/intents", req, intent); err != nil {
+		return nil, fmt.Errorf("create intent failed: %w", err)
+	}
+	return intent, nil
+}
+
+func (p *fakeProvider) Capture(ctx context.Context, intentID string, amount int64) error {
+	body := map[string]int64{"amount": amount}
+	if err := p.do(ctx, http.MethodPost, "/v1/intents/"+intentID+"/capture", body, nil); err != nil {
+		return fmt.Errorf("capture intent failed: %w", err)
+	}
+	return nil
+}
+
+func (p *fakeProvider) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	refund := new(Refund)
+	if err := p.do(ctx, http.MethodPost, "/v1/intents/"+req.IntentID+"/refunds", req, refund); err != nil {
+		return nil, fmt.Errorf("refund intent failed: %w", err)
+	}
+	return refund, nil
+}
+
+func (p *fakeProvider) VerifyWebhookSignature(payload []byte, header http.Header) (*WebhookEvent, error) {
+	var t, sig string
+	for _, part := range strings.Split(header.Get(FakeSignatureHeader), ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			sig = v
+		}
+	}
+	if t == "" || sig == "" {
+		return nil, errs.ErrInvalidWebhookSignature
+	}
+
+	ts, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return nil, errs.ErrInvalidWebhookSignature
+	}
+	if age := p.now().Sub(time.Unix(ts, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return nil, errs.ErrInvalidWebhookSignature
+	}
+
+	expected := fakeSignature(p.secret, t, payload)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return nil, errs.ErrInvalidWebhookSignature
+	}
+
+	event := new(WebhookEvent)
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("decode webhook failed: %w", err)
+	}
+	return event, nil
+}
+
+func (p *fakeProvider) do(ctx context.Context, method, path string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var gwErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&gwErr)
+		return fmt.Errorf("gateway status %d: %s", resp.StatusCode, gwErr.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
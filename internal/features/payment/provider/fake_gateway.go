@@ -0,0 +1,316 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeGateway : in-process stand-in for a hosted payment gateway.
+// Customer "pays" with POST /checkout/{id}, gateway then sends a signed webhook.
+type FakeGateway struct {
+	secret     string
+	webhookURL string
+	client     *http.Client
+	mux        *http.ServeMux
+
+	mu      sync.Mutex
+	seq     int
+	baseURL string
+	intents map[string]*fakeIntent
+	refunds map[string]*Refund // key: intent id + reference
+}
+
+type fakeIntent struct {
+	Intent
+	Captured int64
+	Refunded int64
+}
+
+func NewFakeGateway(secret, webhookURL string) *FakeGateway {
+	g := &FakeGateway{
+		secret:     secret,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		mux:        http.NewServeMux(),
+		intents:    make(map[string]*fakeIntent),
+		refunds:    make(map[string]*Refund),
+	}
+
+	g.mux.HandleFunc("POST /v1/intents", g.createIntent)
+	g.mux.HandleFunc("GET /v1/intents/{id}", g.getIntent)
+	g.mux.HandleFunc("POST /v1/intents/{id}/capture", g.capture)
+	g.mux.HandleFunc("POST /v1/intents/{id}/refunds", g.refund)
+	g.mux.HandleFunc("POST /checkout/{id}", g.checkout)
+	return g
+}
+
+// StartFakeGateway : listen on addr in background, return base url
+func StartFakeGateway(addr, secret, webhookURL string) (*http.Server, string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("fake gateway listen failed: %w", err)
+	}
+
+	g := NewFakeGateway(secret, webhookURL)
+	baseURL := "http://" + ln.Addr().String()
+	g.SetBaseURL(baseURL)
+
+	srv := &http.Server{Handler: g, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+
+	return srv, baseURL, nil
+}
+
+// SetBaseURL : used to build checkout_url, required when served by httptest
+func (g *FakeGateway) SetBaseURL(baseURL string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.baseURL = baseURL
+}
+
+// SetWebhookURL : target for signed events
+func (g *FakeGateway) SetWebhookURL(webhookURL string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.webhookURL = webhookURL
+}
+
+func (g *FakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *FakeGateway) createIntent(w http.ResponseWriter, r *http.Request) {
+	req := new(IntentRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeGatewayError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Amount <= 0 || req.Currency == "" {
+		writeGatewayError(w, http.StatusBadRequest, "amount and currency are required")
+		return
+	}
+
+	g.mu.Lock()
+	g.seq++
+	id := fmt.Sprintf("pi_fake_%06d", g.seq)
+	in := &fakeIntent{Intent: Intent{
+		ID:          id,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Reference:   req.Reference,
+		Status:      IntentRequiresPayment,
+		CheckoutURL: g.baseURL + "/checkout/" + id,
+	}}
+	g.intents[id] = in
+	resp := in.Intent
+	g.mu.Unlock()
+
+	writeGatewayJSON(w, http.StatusCreated, resp)
+}
+
+func (g *FakeGateway) getIntent(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	in, ok := g.intents[r.PathValue("id")]
+	var resp Intent
+	if ok {
+		resp = in.Intent
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		writeGatewayError(w, http.StatusNotFound, "intent not found")
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, resp)
+}
+
+func (g *FakeGateway) capture(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount int64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGatewayError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	in, ok := g.intents[r.PathValue("id")]
+	if !ok {
+		writeGatewayError(w, http.StatusNotFound, "intent not found")
+		return
+	}
+
+	switch in.Status {
+	case IntentCaptured:
+		// Idempotent
+	case IntentAuthorized:
+		if req.Amount <= 0 || req.Amount > in.Amount {
+			writeGatewayError(w, http.StatusBadRequest, "invalid capture amount")
+			return
+		}
+		in.Status = IntentCaptured
+		in.Captured = req.Amount
+	default:
+		writeGatewayError(w, http.StatusConflict, "intent is not authorized")
+		return
+	}
+	writeGatewayJSON(w, http.StatusOK, in.Intent)
+}
+
+func (g *FakeGateway) refund(w http.ResponseWriter, r *http.Request) {
+	req := new(RefundRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeGatewayError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	in, ok := g.intents[r.PathValue("id")]
+	if !ok {
+		writeGatewayError(w, http.StatusNotFound, "intent not found")
+		return
+	}
+
+	// Same reference = same refund
+	refundKey := in.ID + "/" + req.Reference
+	if req.Reference != "" {
+		if existing, ok := g.refunds[refundKey]; ok {
+			writeGatewayJSON(w, http.StatusOK, existing)
+			return
+		}
+	}
+
+	if in.Status != IntentCaptured {
+		writeGatewayError(w, http.StatusConflict, "intent is not captured")
+		return
+	}
+	if req.Amount <= 0 || req.Amount > in.Captured-in.Refunded {
+		writeGatewayError(w, http.StatusBadRequest, "invalid refund amount")
+		return
+	}
+
+	g.seq++
+	ref := &Refund{
+		ID:        fmt.Sprintf("re_fake_%06d", g.seq),
+		IntentID:  in.ID,
+		Amount:    req.Amount,
+		Reference: req.Reference,
+	}
+	in.Refunded += req.Amount
+	if req.Reference != "" {
+		g.refunds[refundKey] = ref
+	}
+	writeGatewayJSON(w, http.StatusCreated, ref)
+}
+
+// checkout : simulate customer on hosted page, ?outcome=fail to decline
+func (g *FakeGateway) checkout(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	in, ok := g.intents[r.PathValue("id")]
+	if !ok {
+		g.mu.Unlock()
+		writeGatewayError(w, http.StatusNotFound, "intent not found")
+		return
+	}
+	if in.Status != IntentRequiresPayment {
+		g.mu.Unlock()
+		writeGatewayError(w, http.StatusConflict, "intent already processed")
+		return
+	}
+
+	eventType := EventPaymentAuthorized
+	in.Status = IntentAuthorized
+	if r.URL.Query().Get("outcome") == "fail" {
+		eventType = EventPaymentFailed
+		in.Status = IntentFailed
+	}
+
+	g.seq++
+	event := WebhookEvent{
+		ID:        fmt.Sprintf("evt_fake_%06d", g.seq),
+		Type:      eventType,
+		IntentID:  in.ID,
+		Amount:    in.Amount,
+		CreatedAt: time.Now().Unix(),
+	}
+	resp := in.Intent
+	webhookURL := g.webhookURL
+	g.mu.Unlock()
+
+	// Deliver webhook synchronously, result is returned to the caller
+	webhookStatus, err := g.sendWebhook(r, webhookURL, event)
+	if err != nil {
+		writeGatewayError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeGatewayJSON(w, http.StatusOK, map[string]any{
+		"intent":         resp,
+		"webhook_status": webhookStatus,
+	})
+}
+
+func (g *FakeGateway) sendWebhook(r *http.Request, webhookURL string, event WebhookEvent) (int, error) {
+	if webhookURL == "" {
+		return 0, nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(FakeSignatureHeader, signFakePayload(g.secret, time.Now(), payload))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("deliver webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// signFakePayload : header format t=<unix>,v1=<hex hmac-sha256 of "t.payload">
+func signFakePayload(secret string, ts time.Time, payload []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + fakeSignature(secret, t, payload)
+}
+
+func fakeSignature(secret, t string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeGatewayJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeGatewayError(w http.ResponseWriter, code int, msg string) {
+	writeGatewayJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "whsec_test"
+
+func startGateway(t *testing.T, webhook http.HandlerFunc) PaymentProvider {
+	t.Helper()
+
+	hook := httptest.NewServer(webhook)
+	t.Cleanup(hook.Close)
+
+	gateway := NewFakeGateway(testSecret, hook.URL)
+	srv := httptest.NewServer(gateway)
+	t.Cleanup(srv.Close)
+	gateway.SetBaseURL(srv.URL)
+
+	return NewFakeProvider(srv.URL, testSecret, srv.Client())
+}
+
+func TestFakeGatewayFlow(t *testing.T) {
+	ctx := context.Background()
+
+	var p PaymentProvider
+	events := make(chan *WebhookEvent, 1)
+
+	p = startGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		event, err := p.VerifyWebhookSignature(payload, r.Header)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events <- event
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// 1. Create Intent
+	intent, err := p.CreateIntent(ctx, IntentRequest{Amount: 1000, Currency: "THB", Reference: "ORD-20251403-000001"})
+	assert.NoError(t, err)
+	assert.Equal(t, IntentRequiresPayment, intent.Status)
+	assert.NotEmpty(t, intent.CheckoutURL)
+
+	// Capture before customer paid
+	assert.Error(t, p.Capture(ctx, intent.ID, 1000))
+
+	// 2. Customer Pays
+	resp, err := http.Post(intent.CheckoutURL, "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var checkout struct {
+		WebhookStatus int `json:"webhook_status"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&checkout))
+	assert.Equal(t, http.StatusOK, checkout.WebhookStatus)
+
+	event := <-events
+	assert.Equal(t, EventPaymentAuthorized, event.Type)
+	assert.Equal(t, intent.ID, event.IntentID)
+
+	// 3. Capture, retry is idempotent
+	assert.NoError(t, p.Capture(ctx, intent.ID, 1000))
+	assert.NoError(t, p.Capture(ctx, intent.ID, 1000))
+
+	// 4. Refund, same reference returns same refund
+	r1, err := p.Refund(ctx, RefundRequest{IntentID: intent.ID, Amount: 400, Reference: "refund-1"})
+	assert.NoError(t, err)
+	r2, err := p.Refund(ctx, RefundRequest{IntentID: intent.ID, Amount: 400, Reference: "refund-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, r1.ID, r2.ID)
+
+	// Over refund
+	_, err = p.Refund(ctx, RefundRequest{IntentID: intent.ID, Amount: 700, Reference: "refund-2"})
+	assert.Error(t, err)
+}
+
+func TestFakeGatewayDeclined(t *testing.T) {
+	var p PaymentProvider
+	events := make(chan *WebhookEvent, 1)
+
+	p = startGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		event, err := p.VerifyWebhookSignature(payload, r.Header)
+		assert.NoError(t, err)
+		events <- event
+	})
+
+	intent, err := p.CreateIntent(context.Background(), IntentRequest{Amount: 1000, Currency: "THB"})
+	assert.NoError(t, err)
+
+	resp, err := http.Post(intent.CheckoutURL+"?outcome=fail", "application/json", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	event := <-events
+	assert.Equal(t, EventPaymentFailed, event.Type)
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	payload := []byte(`{"id":"evt_1","type":"payment.authorized","intent_id":"pi_1"}`)
+
+	p := NewFakeProvider("http://localhost", testSecret, nil).(*fakeProvider)
+	p.now = func() time.Time { return now }
+
+	type testCase struct {
+		name        string
+		header      string
+		payload     []byte
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "success",
+			header:      signFakePayload(testSecret, now, payload),
+			payload:     payload,
+			expectedErr: nil,
+		},
+		{
+			name:        "fail missing header",
+			header:      "",
+			payload:     payload,
+			expectedErr: errs.ErrInvalidWebhookSignature,
+		},
+		{
+			name:        "fail wrong secret",
+			header:      signFakePayload("other-secret", now, payload),
+			payload:     payload,
+			expectedErr: errs.ErrInvalidWebhookSignature,
+		},
+		{
+			name:        "fail payload changed",
+			header:      signFakePayload(testSecret, now, payload),
+			payload:     []byte(`{"id":"evt_1","type":"payment.authorized","intent_id":"pi_2"}`),
+			expectedErr: errs.ErrInvalidWebhookSignature,
+		},
+		{
+			name:        "fail replayed old event",
+			header:      signFakePayload(testSecret, now.Add(-time.Hour), payload),
+			payload:     payload,
+			expectedErr: errs.ErrInvalidWebhookSignature,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.header != "" {
+				header.Set(FakeSignatureHeader, tc.header)
+			}
+
+			event, err := p.VerifyWebhookSignature(tc.payload, header)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, event)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "pi_1", event.IntentID)
+			}
+		})
+	}
+}
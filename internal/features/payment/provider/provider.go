@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+)
+
+type IntentStatus string
+
+const (
+	IntentRequiresPayment IntentStatus = "requires_payment"
+	IntentAuthorized      IntentStatus = "authorized"
+	IntentCaptured        IntentStatus = "captured"
+	IntentFailed          IntentStatus = "failed"
+)
+
+type EventType string
+
+const (
+	EventPaymentAuthorized EventType = "payment.authorized"
+	EventPaymentFailed     EventType = "payment.failed"
+)
+
+//go:generate mockgen -source=provider.go -destination=provider_mock.go -package=provider
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	Capture(ctx context.Context, intentID string, amount int64) error
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+	// VerifyWebhookSignature : return decoded event only when signature is valid
+	VerifyWebhookSignature(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+type IntentRequest struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Reference string `json:"reference"` // order no
+}
+
+type Intent struct {
+	ID          string       `json:"id"`
+	Amount      int64        `json:"amount"`
+	Currency    string       `json:"currency"`
+	Reference   string       `json:"reference"`
+	Status      IntentStatus `json:"status"`
+	CheckoutURL string       `json:"checkout_url"`
+}
+
+type RefundRequest struct {
+	IntentID  string `json:"-"`
+	Amount    int64  `json:"amount"`
+	Reference string `json:"reference"` // same reference = same refund
+}
+
+type Refund struct {
+	ID        string `json:"id"`
+	IntentID  string `json:"intent_id"`
+	Amount    int64  `json:"amount"`
+	Reference string `json:"reference"`
+}
+
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	IntentID  string    `json:"intent_id"`
+	Amount    int64     `json:"amount"`
+	CreatedAt int64     `json:"created_at"`
+}
@@ -0,0 +1,115 @@
+package paymentrepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/payment"
+)
+
+//go:generate mockgen -source=payment_repository.go -destination=payment_repository_mock.go -package=paymentrepository
+type PaymentRepository interface {
+	FindPaymentByProviderRef(ctx context.Context, provider, providerRef string) (*payment.Payment, error)
+
+	// Transaction
+	InsertPaymentTx(ctx context.Context, tx *sql.Tx, p *payment.Payment) error
+	FindOpenPaymentByOrderTx(ctx context.Context, tx *sql.Tx, orderID int64) (*payment.Payment, error)
+	FindPaymentForUpdateTx(ctx context.Context, tx *sql.Tx, paymentID int64) (*payment.Payment, error)
+	UpdatePaymentStatusTx(ctx context.Context, tx *sql.Tx, paymentID int64, status payment.PaymentStatus) error
+}
+
+type paymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) PaymentRepository {
+	return &paymentRepository{db: db}
+}
+
+const paymentColumns = `
+	id, order_id, provider, provider_ref, amount, currency, status,
+	COALESCE(checkout_url, ''), created_at, updated_at
+`
+
+func (r *paymentRepository) FindPaymentByProviderRef(ctx context.Context, provider, providerRef string) (*payment.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND provider_ref = $2`
+	return scanPayment(r.db.QueryRowContext(ctx, query, provider, providerRef))
+}
+
+func (r *paymentRepository) InsertPaymentTx(ctx context.Context, tx *sql.Tx, p *payment.Payment) error {
+	query := `
+		INSERT INTO payments (order_id, provider, provider_ref, amount, currency, status, checkout_url)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		RETURNING id, created_at, updated_at
+	`
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		p.OrderID,
+		p.Provider,
+		p.ProviderRef,
+		p.Amount,
+		p.Currency,
+		p.Status,
+		p.CheckoutURL,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// FindOpenPaymentByOrderTx : PENDING or CAPTURED payment of the order
+func (r *paymentRepository) FindOpenPaymentByOrderTx(ctx context.Context, tx *sql.Tx, orderID int64) (*payment.Payment, error) {
+	query := `
+		SELECT ` + paymentColumns + ` FROM payments
+		WHERE order_id = $1 AND status IN ('PENDING', 'CAPTURED')
+	`
+	return scanPayment(tx.QueryRowContext(ctx, query, orderID))
+}
+
+// FindPaymentForUpdateTx : lock payment row until transaction end
+func (r *paymentRepository) FindPaymentForUpdateTx(ctx context.Context, tx *sql.Tx, paymentID int64) (*payment.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1 FOR UPDATE`
+	return scanPayment(tx.QueryRowContext(ctx, query, paymentID))
+}
+
+func (r *paymentRepository) UpdatePaymentStatusTx(ctx context.Context, tx *sql.Tx, paymentID int64, status payment.PaymentStatus) error {
+	query := `UPDATE payments SET status = $1, updated_at = NOW() WHERE id = $2`
+	res, err := tx.ExecContext(ctx, query, status, paymentID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrPaymentNotFound
+	}
+	return nil
+}
+
+func scanPayment(row *sql.Row) (*payment.Payment, error) {
+	p := new(payment.Payment)
+
+	err := row.Scan(
+		&p.ID,
+		&p.OrderID,
+		&p.Provider,
+		&p.ProviderRef,
+		&p.Amount,
+		&p.Currency,
+		&p.Status,
+		&p.CheckoutURL,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: payment_repository.go
+
+// Package paymentrepository is a generated GoMock package.
+package paymentrepository
+
+import (
+	context "context"
+	sql "database/sql"
+	reflect "reflect"
+
+	payment "github.com/codepnw/go-starter-kit/internal/features/payment"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPaymentRepository is a mock of PaymentRepository interface.
+type MockPaymentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPaymentRepositoryMockRecorder
+}
+
+// MockPaymentRepositoryMockRecorder is the mock recorder for MockPaymentRepository.
+type MockPaymentRepositoryMockRecorder struct {
+	mock *MockPaymentRepository
+}
+
+// NewMockPaymentRepository creates a new mock instance.
+func NewMockPaymentRepository(ctrl *gomock.Controller) *MockPaymentRepository {
+	mock := &MockPaymentRepository{ctrl: ctrl}
+	mock.recorder = &MockPaymentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPaymentRepository) EXPECT() *MockPaymentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindOpenPaymentByOrderTx mocks base method.
+func (m *MockPaymentRepository) FindOpenPaymentByOrderTx(ctx context.Context, tx *sql.Tx, orderID int64) (*payment.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOpenPaymentByOrderTx", ctx, tx, orderID)
+	ret0, _ := ret[0].(*payment.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOpenPaymentByOrderTx indicates an expected call of FindOpenPaymentByOrderTx.
+func (mr *MockPaymentRepositoryMockRecorder) FindOpenPaymentByOrderTx(ctx, tx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOpenPaymentByOrderTx", reflect.TypeOf((*MockPaymentRepository)(nil).FindOpenPaymentByOrderTx), ctx, tx, orderID)
+}
+
+// FindPaymentByProviderRef mocks base method.
+func (m *MockPaymentRepository) FindPaymentByProviderRef(ctx context.Context, provider, providerRef string) (*payment.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPaymentByProviderRef", ctx, provider, providerRef)
+	ret0, _ := ret[0].(*payment.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPaymentByProviderRef indicates an expected call of FindPaymentByProviderRef.
+func (mr *MockPaymentRepositoryMockRecorder) FindPaymentByProviderRef(ctx, provider, providerRef interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPaymentByProviderRef", reflect.TypeOf((*MockPaymentRepository)(nil).FindPaymentByProviderRef), ctx, provider, providerRef)
+}
+
+// FindPaymentForUpdateTx mocks base method.
+func (m *MockPaymentRepository) FindPaymentForUpdateTx(ctx context.Context, tx *sql.Tx, paymentID int64) (*payment.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPaymentForUpdateTx", ctx, tx, paymentID)
+	ret0, _ := ret[0].(*payment.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPaymentForUpdateTx indicates an expected call of FindPaymentForUpdateTx.
+func (mr *MockPaymentRepositoryMockRecorder) FindPaymentForUpdateTx(ctx, tx, paymentID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPaymentForUpdateTx", reflect.TypeOf((*MockPaymentRepository)(nil).FindPaymentForUpdateTx), ctx, tx, paymentID)
+}
+
+// InsertPaymentTx mocks base method.
+func (m *MockPaymentRepository) InsertPaymentTx(ctx context.Context, tx *sql.Tx, p *payment.Payment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertPaymentTx", ctx, tx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertPaymentTx indicates an expected call of InsertPaymentTx.
+func (mr *MockPaymentRepositoryMockRecorder) InsertPaymentTx(ctx, tx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertPaymentTx", reflect.TypeOf((*MockPaymentRepository)(nil).InsertPaymentTx), ctx, tx, p)
+}
+
+// UpdatePaymentStatusTx mocks base method.
+func (m *MockPaymentRepository) UpdatePaymentStatusTx(ctx context.Context, tx *sql.Tx, paymentID int64, status payment.PaymentStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePaymentStatusTx", ctx, tx, paymentID, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePaymentStatusTx indicates an expected call of UpdatePaymentStatusTx.
+func (mr *MockPaymentRepositoryMockRecorder) UpdatePaymentStatusTx(ctx, tx, paymentID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePaymentStatusTx", reflect.TypeOf((*MockPaymentRepository)(nil).UpdatePaymentStatusTx), ctx, tx, paymentID, status)
+}
@@ -0,0 +1,317 @@
+package paymentservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
+	orderservice "github.com/codepnw/go-starter-kit/internal/features/order/service"
+	"github.com/codepnw/go-starter-kit/internal/features/payment"
+	"github.com/codepnw/go-starter-kit/internal/features/payment/provider"
+	paymentrepository "github.com/codepnw/go-starter-kit/internal/features/payment/repository"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+)
+
+type PaymentService interface {
+	CreatePayment(ctx context.Context, userID, orderNo string) (*payment.PaymentResponse, error)
+	HandleWebhook(ctx context.Context, providerName string, payload []byte, header http.Header) error
+}
+
+type paymentService struct {
+	tx        database.TxManager
+	payRepo   paymentrepository.PaymentRepository
+	orderRepo orderrepository.OrderRepository
+	orderSrv  orderservice.OrderService
+	provider  provider.PaymentProvider
+	currency  string
+}
+
+func NewPaymentService(
+	tx database.TxManager,
+	payRepo paymentrepository.PaymentRepository,
+	orderRepo orderrepository.OrderRepository,
+	orderSrv orderservice.OrderService,
+	provider provider.PaymentProvider,
+	currency string,
+) PaymentService {
+	return &paymentService{
+		tx:        tx,
+		payRepo:   payRepo,
+		orderRepo: orderRepo,
+		orderSrv:  orderSrv,
+		provider:  provider,
+		currency:  currency,
+	}
+}
+
+// CreatePayment implements PaymentService.
+// Open payment of the order is returned again instead of creating a second intent.
+// The provider is called with no transaction open, the order is checked again before saving.
+func (s *paymentService) CreatePayment(ctx context.Context, userID, orderNo string) (*payment.PaymentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	orderID, err := order.ParseOrderNo(orderNo)
+	if err != nil {
+		return nil, errs.ErrOrderNotFound
+	}
+
+	// 1. Validate Order, Reuse Open Payment
+	var (
+		ord *order.Order
+		pay *payment.Payment
+	)
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, pay, err = s.payableOrderTx(ctx, tx, userID, orderID, orderNo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pay != nil {
+		return paymentResponse(pay, orderNo), nil
+	}
+
+	// 2. Create Intent at Provider, no lock held
+	intent, err := s.provider.CreateIntent(ctx, provider.IntentRequest{
+		Amount:    int64(ord.TotalAmount),
+		Currency:  s.currency,
+		Reference: orderNo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Save Payment, unless the order changed or another request saved one meanwhile
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		current, existing, err := s.payableOrderTx(ctx, tx, userID, orderID, orderNo)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			// Our intent is never paid and expires at the provider
+			pay = existing
+			return nil
+		}
+		if current.TotalAmount != ord.TotalAmount {
+			return errs.ErrOrderNotPayable
+		}
+
+		pay = &payment.Payment{
+			OrderID:     current.ID,
+			Provider:    s.provider.Name(),
+			ProviderRef: intent.ID,
+			Amount:      int64(current.TotalAmount),
+			Currency:    s.currency,
+			Status:      payment.StatusPending,
+			CheckoutURL: intent.CheckoutURL,
+		}
+		if err := s.payRepo.InsertPaymentTx(ctx, tx, pay); err != nil {
+			return fmt.Errorf("insert payment failed: %w", err)
+		}
+
+		return nil // Commit Transaction
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paymentResponse(pay, orderNo), nil
+}
+
+// payableOrderTx : lock the customer's PENDING order, with its open payment when there is one
+func (s *paymentService) payableOrderTx(ctx context.Context, tx *sql.Tx, userID string, orderID int64, orderNo string) (*order.Order, *payment.Payment, error) {
+	ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ord.UserID != userID || order.GenerateOrderNo(ord.ID, ord.CreatedAt) != orderNo {
+		return nil, nil, errs.ErrOrderNotFound
+	}
+	if ord.Status != order.StatusPending {
+		return nil, nil, errs.ErrOrderNotPayable
+	}
+
+	existing, err := s.payRepo.FindOpenPaymentByOrderTx(ctx, tx, ord.ID)
+	if err == nil {
+		return ord, existing, nil
+	}
+	if err != errs.ErrPaymentNotFound {
+		return nil, nil, err
+	}
+	return ord, nil, nil
+}
+
+// HandleWebhook implements PaymentService.
+// Provider may deliver the same event more than once, every branch is idempotent.
+func (s *paymentService) HandleWebhook(ctx context.Context, providerName string, payload []byte, header http.Header) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if providerName != s.provider.Name() {
+		return errs.ErrPaymentProviderNotFound
+	}
+
+	event, err := s.provider.VerifyWebhookSignature(payload, header)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case provider.EventPaymentAuthorized:
+		return s.capturePayment(ctx, event.IntentID)
+	case provider.EventPaymentFailed:
+		return s.failPayment(ctx, event.IntentID)
+	default:
+		slog.Info("ignored payment webhook", slog.String("event_id", event.ID), slog.String("type", string(event.Type)))
+		return nil
+	}
+}
+
+// capturePayment : capture authorized intent and move order to PAID.
+// The provider is called between two transactions, no order lock is held during the call.
+func (s *paymentService) capturePayment(ctx context.Context, intentID string) error {
+	found, err := s.payRepo.FindPaymentByProviderRef(ctx, s.provider.Name(), intentID)
+	if err != nil {
+		return err
+	}
+
+	// 1. Check State
+	var pay *payment.Payment
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, locked, err := s.lockPaymentTx(ctx, tx, found)
+		if err != nil {
+			return err
+		}
+
+		// Already Handled
+		if locked.Status != payment.StatusPending {
+			return nil
+		}
+
+		// Order cancelled while customer was paying, authorization is left to expire
+		if !ord.Status.CanTransitionTo(order.StatusPaid) {
+			slog.Warn("payment authorized for unpayable order",
+				slog.Int64("order_id", ord.ID),
+				slog.String("status", string(ord.Status)),
+			)
+			return s.payRepo.UpdatePaymentStatusTx(ctx, tx, locked.ID, payment.StatusFailed)
+		}
+
+		pay = locked
+		return nil
+	})
+	if err != nil || pay == nil {
+		return err
+	}
+
+	// 2. Capture, idempotent at the provider if saving fails and the webhook is retried
+	if err := s.provider.Capture(ctx, pay.ProviderRef, pay.Amount); err != nil {
+		return err
+	}
+
+	// 3. Save Capture, the order may have been cancelled during the call
+	var voidOrderNo string
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		ord, locked, err := s.lockPaymentTx(ctx, tx, found)
+		if err != nil {
+			return err
+		}
+		if locked.Status != payment.StatusPending {
+			return nil
+		}
+
+		if err := s.payRepo.UpdatePaymentStatusTx(ctx, tx, locked.ID, payment.StatusCaptured); err != nil {
+			return fmt.Errorf("update payment status failed: %w", err)
+		}
+		if !ord.Status.CanTransitionTo(order.StatusPaid) {
+			voidOrderNo = order.GenerateOrderNo(ord.ID, ord.CreatedAt)
+			return nil
+		}
+
+		// Order Paid
+		return s.orderSrv.TransitionStatusTx(ctx, tx, ord, order.StatusPaid, "", "payment captured")
+	})
+	if err != nil || voidOrderNo == "" {
+		return err
+	}
+
+	// 4. Money taken for a cancelled order, give it back
+	s.voidCapture(ctx, pay, voidOrderNo)
+	return nil
+}
+
+// lockPaymentTx : lock order then payment, same order as CreatePayment
+func (s *paymentService) lockPaymentTx(ctx context.Context, tx *sql.Tx, found *payment.Payment) (*order.Order, *payment.Payment, error) {
+	ord, err := s.orderRepo.FindOrderForUpdateTx(ctx, tx, found.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pay, err := s.payRepo.FindPaymentForUpdateTx(ctx, tx, found.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ord, pay, nil
+}
+
+// voidCapture : refund the whole capture, the stable reference makes a retry safe.
+// A failure is only logged, the payment stays CAPTURED on a cancelled order for staff to settle.
+func (s *paymentService) voidCapture(ctx context.Context, pay *payment.Payment, orderNo string) {
+	_, err := s.provider.Refund(ctx, provider.RefundRequest{
+		IntentID:  pay.ProviderRef,
+		Amount:    pay.Amount,
+		Reference: orderNo + "-VOID",
+	})
+	if err != nil {
+		slog.Error("void capture of cancelled order failed",
+			slog.Int64("payment_id", pay.ID),
+			slog.String("order_no", orderNo),
+			slog.Any("error", err),
+		)
+		return
+	}
+	slog.Warn("payment captured after order was cancelled, refunded",
+		slog.Int64("payment_id", pay.ID),
+		slog.String("order_no", orderNo),
+	)
+}
+
+func (s *paymentService) failPayment(ctx context.Context, intentID string) error {
+	found, err := s.payRepo.FindPaymentByProviderRef(ctx, s.provider.Name(), intentID)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		pay, err := s.payRepo.FindPaymentForUpdateTx(ctx, tx, found.ID)
+		if err != nil {
+			return err
+		}
+		if pay.Status != payment.StatusPending {
+			return nil
+		}
+		return s.payRepo.UpdatePaymentStatusTx(ctx, tx, pay.ID, payment.StatusFailed)
+	})
+}
+
+// -------- HELPER ------------
+
+func paymentResponse(p *payment.Payment, orderNo string) *payment.PaymentResponse {
+	return &payment.PaymentResponse{
+		PaymentID:   p.ID,
+		OrderNo:     orderNo,
+		Provider:    p.Provider,
+		Amount:      p.Amount,
+		Currency:    p.Currency,
+		Status:      p.Status,
+		CheckoutURL: p.CheckoutURL,
+		CreatedAt:   p.CreatedAt.Format(time.DateTime),
+	}
+}
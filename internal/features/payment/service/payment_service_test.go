@@ -0,0 +1,421 @@
+package paymentservice_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
+	orderservice "github.com/codepnw/go-starter-kit/internal/features/order/service"
+	"github.com/codepnw/go-starter-kit/internal/features/payment"
+	"github.com/codepnw/go-starter-kit/internal/features/payment/provider"
+	paymentrepository "github.com/codepnw/go-starter-kit/internal/features/payment/repository"
+	paymentservice "github.com/codepnw/go-starter-kit/internal/features/payment/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mockUserID   = "mock-uuid-1"
+	mockCurrency = "THB"
+)
+
+var (
+	ErrDB         = errors.New("database error")
+	mockCreatedAt = time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	mockOrderNo   = order.GenerateOrderNo(1, mockCreatedAt)
+)
+
+type mocks struct {
+	tx       *database.MockTxManager
+	pay      *paymentrepository.MockPaymentRepository
+	ord      *orderrepository.MockOrderRepository
+	ordSrv   *orderservice.MockOrderService
+	provider *provider.MockPaymentProvider
+}
+
+func TestCreatePayment(t *testing.T) {
+	type testCase struct {
+		name        string
+		orderNo     string
+		mockFn      func(m *mocks)
+		expectedErr error
+	}
+
+	mockOrder := func(status order.OrderStatus) *order.Order {
+		return &order.Order{ID: 1, UserID: mockUserID, TotalAmount: 1000, Status: status, CreatedAt: mockCreatedAt}
+	}
+
+	testCases := []testCase{
+		{
+			name:    "success",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTxTimes(m.tx, 2)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusPending), nil).Times(2)
+				m.pay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(nil, errs.ErrPaymentNotFound).Times(2)
+				m.provider.EXPECT().CreateIntent(gomock.Any(), provider.IntentRequest{Amount: 1000, Currency: mockCurrency, Reference: mockOrderNo}).
+					Return(&provider.Intent{ID: "pi_1", CheckoutURL: "http://gateway/checkout/pi_1"}, nil).Times(1)
+				m.provider.EXPECT().Name().Return("fake").AnyTimes()
+				m.pay.EXPECT().InsertPaymentTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "success payment saved by another request during provider call",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTxTimes(m.tx, 2)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusPending), nil).Times(2)
+				gomock.InOrder(
+					m.pay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(nil, errs.ErrPaymentNotFound),
+					m.pay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(&payment.Payment{ID: 6, Status: payment.StatusPending}, nil),
+				)
+				m.provider.EXPECT().CreateIntent(gomock.Any(), gomock.Any()).Return(&provider.Intent{ID: "pi_2"}, nil).Times(1)
+				m.pay.EXPECT().InsertPaymentTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:    "fail order cancelled during provider call",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTxTimes(m.tx, 2)
+				gomock.InOrder(
+					m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusPending), nil),
+					m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusCancelled), nil),
+				)
+				m.pay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(nil, errs.ErrPaymentNotFound).Times(1)
+				m.provider.EXPECT().CreateIntent(gomock.Any(), gomock.Any()).Return(&provider.Intent{ID: "pi_2"}, nil).Times(1)
+				m.pay.EXPECT().InsertPaymentTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrOrderNotPayable,
+		},
+		{
+			name:    "success reuse open payment",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTx(m.tx)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusPending), nil).Times(1)
+				m.pay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(&payment.Payment{ID: 5, Status: payment.StatusPending}, nil).Times(1)
+				m.provider.EXPECT().CreateIntent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail invalid order no",
+			orderNo:     "INVALID",
+			mockFn:      func(m *mocks) {},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+		{
+			name:    "fail other user's order",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTx(m.tx)
+				ord := mockOrder(order.StatusPending)
+				ord.UserID = "other-uuid"
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(ord, nil).Times(1)
+			},
+			expectedErr: errs.ErrOrderNotFound,
+		},
+		{
+			name:    "fail order already paid",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTx(m.tx)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusPaid), nil).Times(1)
+			},
+			expectedErr: errs.ErrOrderNotPayable,
+		},
+		{
+			name:    "fail provider error",
+			orderNo: mockOrderNo,
+			mockFn: func(m *mocks) {
+				expectTx(m.tx)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(mockOrder(order.StatusPending), nil).Times(1)
+				m.pay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(nil, errs.ErrPaymentNotFound).Times(1)
+				m.provider.EXPECT().CreateIntent(gomock.Any(), gomock.Any()).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, m := setup(t)
+
+			tc.mockFn(m)
+
+			resp, err := service.CreatePayment(context.Background(), mockUserID, tc.orderNo)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, resp)
+				assert.Equal(t, tc.orderNo, resp.OrderNo)
+			}
+		})
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	type testCase struct {
+		name        string
+		provider    string
+		mockFn      func(m *mocks)
+		expectedErr error
+	}
+
+	pendingPayment := func() *payment.Payment {
+		return &payment.Payment{ID: 5, OrderID: 1, ProviderRef: "pi_1", Amount: 1000, Status: payment.StatusPending}
+	}
+	authorized := &provider.WebhookEvent{ID: "evt_1", Type: provider.EventPaymentAuthorized, IntentID: "pi_1"}
+
+	testCases := []testCase{
+		{
+			name:     "success capture and mark order paid",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(authorized, nil).Times(1)
+				m.pay.EXPECT().FindPaymentByProviderRef(gomock.Any(), "fake", "pi_1").Return(pendingPayment(), nil).Times(1)
+				expectTxTimes(m.tx, 2)
+				ord := &order.Order{ID: 1, Status: order.StatusPending}
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(ord, nil).Times(2)
+				m.pay.EXPECT().FindPaymentForUpdateTx(gomock.Any(), gomock.Any(), int64(5)).Return(pendingPayment(), nil).Times(2)
+				m.provider.EXPECT().Capture(gomock.Any(), "pi_1", int64(1000)).Return(nil).Times(1)
+				m.pay.EXPECT().UpdatePaymentStatusTx(gomock.Any(), gomock.Any(), int64(5), payment.StatusCaptured).Return(nil).Times(1)
+				m.ordSrv.EXPECT().TransitionStatusTx(gomock.Any(), gomock.Any(), ord, order.StatusPaid, "", gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "success order cancelled during capture is refunded",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(authorized, nil).Times(1)
+				m.pay.EXPECT().FindPaymentByProviderRef(gomock.Any(), "fake", "pi_1").Return(pendingPayment(), nil).Times(1)
+				expectTxTimes(m.tx, 2)
+				gomock.InOrder(
+					m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(&order.Order{ID: 1, Status: order.StatusPending}, nil),
+					m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(&order.Order{ID: 1, Status: order.StatusCancelled, CreatedAt: mockCreatedAt}, nil),
+				)
+				m.pay.EXPECT().FindPaymentForUpdateTx(gomock.Any(), gomock.Any(), int64(5)).Return(pendingPayment(), nil).Times(2)
+				m.provider.EXPECT().Capture(gomock.Any(), "pi_1", int64(1000)).Return(nil).Times(1)
+				m.pay.EXPECT().UpdatePaymentStatusTx(gomock.Any(), gomock.Any(), int64(5), payment.StatusCaptured).Return(nil).Times(1)
+				m.ordSrv.EXPECT().TransitionStatusTx(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.provider.EXPECT().Refund(gomock.Any(), provider.RefundRequest{IntentID: "pi_1", Amount: 1000, Reference: mockOrderNo + "-VOID"}).Return(&provider.Refund{ID: "re_1"}, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "success duplicate webhook",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(authorized, nil).Times(1)
+				m.pay.EXPECT().FindPaymentByProviderRef(gomock.Any(), "fake", "pi_1").Return(pendingPayment(), nil).Times(1)
+				expectTx(m.tx)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(&order.Order{ID: 1, Status: order.StatusPaid}, nil).Times(1)
+				captured := pendingPayment()
+				captured.Status = payment.StatusCaptured
+				m.pay.EXPECT().FindPaymentForUpdateTx(gomock.Any(), gomock.Any(), int64(5)).Return(captured, nil).Times(1)
+				m.provider.EXPECT().Capture(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "success order cancelled before payment, not captured",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(authorized, nil).Times(1)
+				m.pay.EXPECT().FindPaymentByProviderRef(gomock.Any(), "fake", "pi_1").Return(pendingPayment(), nil).Times(1)
+				expectTx(m.tx)
+				m.ord.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(&order.Order{ID: 1, Status: order.StatusCancelled}, nil).Times(1)
+				m.pay.EXPECT().FindPaymentForUpdateTx(gomock.Any(), gomock.Any(), int64(5)).Return(pendingPayment(), nil).Times(1)
+				m.provider.EXPECT().Capture(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				m.pay.EXPECT().UpdatePaymentStatusTx(gomock.Any(), gomock.Any(), int64(5), payment.StatusFailed).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "success payment failed",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				event := &provider.WebhookEvent{ID: "evt_2", Type: provider.EventPaymentFailed, IntentID: "pi_1"}
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(event, nil).Times(1)
+				m.pay.EXPECT().FindPaymentByProviderRef(gomock.Any(), "fake", "pi_1").Return(pendingPayment(), nil).Times(1)
+				expectTx(m.tx)
+				m.pay.EXPECT().FindPaymentForUpdateTx(gomock.Any(), gomock.Any(), int64(5)).Return(pendingPayment(), nil).Times(1)
+				m.pay.EXPECT().UpdatePaymentStatusTx(gomock.Any(), gomock.Any(), int64(5), payment.StatusFailed).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail unknown provider",
+			provider:    "stripe",
+			mockFn:      func(m *mocks) {},
+			expectedErr: errs.ErrPaymentProviderNotFound,
+		},
+		{
+			name:     "fail invalid signature",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(nil, errs.ErrInvalidWebhookSignature).Times(1)
+			},
+			expectedErr: errs.ErrInvalidWebhookSignature,
+		},
+		{
+			name:     "fail payment not found",
+			provider: "fake",
+			mockFn: func(m *mocks) {
+				m.provider.EXPECT().VerifyWebhookSignature(gomock.Any(), gomock.Any()).Return(authorized, nil).Times(1)
+				m.pay.EXPECT().FindPaymentByProviderRef(gomock.Any(), "fake", "pi_1").Return(nil, errs.ErrPaymentNotFound).Times(1)
+			},
+			expectedErr: errs.ErrPaymentNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, m := setup(t)
+			m.provider.EXPECT().Name().Return("fake").AnyTimes()
+
+			tc.mockFn(m)
+
+			err := service.HandleWebhook(context.Background(), tc.provider, []byte(`{}`), http.Header{})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestCheckoutPayFlow : create payment -> customer pays on fake gateway -> webhook -> order PAID
+func TestCheckoutPayFlow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := database.NewMockTxManager(ctrl)
+	mockPay := paymentrepository.NewMockPaymentRepository(ctrl)
+	mockOrd := orderrepository.NewMockOrderRepository(ctrl)
+	mockOrdSrv := orderservice.NewMockOrderService(ctrl)
+
+	// Fake Gateway + Webhook Endpoint
+	var service paymentservice.PaymentService
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		if err := service.HandleWebhook(r.Context(), provider.FakeProviderName, payload, r.Header); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	gateway := provider.NewFakeGateway("whsec_test", webhook.URL)
+	gatewaySrv := httptest.NewServer(gateway)
+	defer gatewaySrv.Close()
+	gateway.SetBaseURL(gatewaySrv.URL)
+
+	fake := provider.NewFakeProvider(gatewaySrv.URL, "whsec_test", gatewaySrv.Client())
+	service = paymentservice.NewPaymentService(mockTx, mockPay, mockOrd, mockOrdSrv, fake, mockCurrency)
+
+	// In-memory payment row
+	ord := &order.Order{ID: 1, UserID: mockUserID, TotalAmount: 1000, Status: order.StatusPending, CreatedAt: mockCreatedAt}
+	var saved *payment.Payment
+
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).AnyTimes()
+	mockOrd.EXPECT().FindOrderForUpdateTx(gomock.Any(), gomock.Any(), int64(1)).Return(ord, nil).AnyTimes()
+	mockPay.EXPECT().FindOpenPaymentByOrderTx(gomock.Any(), gomock.Any(), int64(1)).Return(nil, errs.ErrPaymentNotFound).Times(2)
+	mockPay.EXPECT().InsertPaymentTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, tx *sql.Tx, p *payment.Payment) error {
+			p.ID = 5
+			saved = p
+			return nil
+		},
+	).Times(1)
+	mockPay.EXPECT().FindPaymentByProviderRef(gomock.Any(), provider.FakeProviderName, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, name, ref string) (*payment.Payment, error) {
+			return saved, nil
+		},
+	).Times(1)
+	mockPay.EXPECT().FindPaymentForUpdateTx(gomock.Any(), gomock.Any(), int64(5)).DoAndReturn(
+		func(ctx context.Context, tx *sql.Tx, id int64) (*payment.Payment, error) {
+			return saved, nil
+		},
+	).Times(2)
+	mockPay.EXPECT().UpdatePaymentStatusTx(gomock.Any(), gomock.Any(), int64(5), payment.StatusCaptured).DoAndReturn(
+		func(ctx context.Context, tx *sql.Tx, id int64, status payment.PaymentStatus) error {
+			saved.Status = status
+			return nil
+		},
+	).Times(1)
+	mockOrdSrv.EXPECT().TransitionStatusTx(gomock.Any(), gomock.Any(), ord, order.StatusPaid, "", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, tx *sql.Tx, o *order.Order, next order.OrderStatus, changedBy, reason string) error {
+			o.Status = next
+			return nil
+		},
+	).Times(1)
+
+	// 1. Create Payment
+	resp, err := service.CreatePayment(context.Background(), mockUserID, mockOrderNo)
+	assert.NoError(t, err)
+	assert.Equal(t, payment.StatusPending, resp.Status)
+	assert.NotEmpty(t, resp.CheckoutURL)
+
+	// 2. Customer Pays on Gateway, webhook delivered synchronously
+	httpResp, err := http.Post(resp.CheckoutURL, "application/json", nil)
+	assert.NoError(t, err)
+	httpResp.Body.Close()
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	// 3. Payment Captured & Order Paid
+	assert.Equal(t, payment.StatusCaptured, saved.Status)
+	assert.Equal(t, order.StatusPaid, ord.Status)
+}
+
+func expectTx(mockTx *database.MockTxManager) {
+	expectTxTimes(mockTx, 1)
+}
+
+func expectTxTimes(mockTx *database.MockTxManager, times int) {
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).Times(times)
+}
+
+func setup(t *testing.T) (paymentservice.PaymentService, *mocks) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := &mocks{
+		tx:       database.NewMockTxManager(ctrl),
+		pay:      paymentrepository.NewMockPaymentRepository(ctrl),
+		ord:      orderrepository.NewMockOrderRepository(ctrl),
+		ordSrv:   orderservice.NewMockOrderService(ctrl),
+		provider: provider.NewMockPaymentProvider(ctrl),
+	}
+
+	service := paymentservice.NewPaymentService(m.tx, m.pay, m.ord, m.ordSrv, m.provider, mockCurrency)
+
+	return service, m
+}
@@ -39,6 +39,10 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		switch err {
 		case errs.ErrProductSKUExists:
 			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
 		}
@@ -96,6 +100,10 @@ func (h *ProductHandler) IncreaseStock(c *gin.Context) {
 		switch err {
 		case errs.ErrProductNotFound:
 			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
 		}
@@ -128,7 +136,18 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateProduct(c.Request.Context(), input); err != nil {
-		response.ResponseError(c, http.StatusInternalServerError, err)
+		switch err {
+		case errs.ErrProductNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrProductSKUExists:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
 		return
 	}
 
@@ -147,6 +166,10 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		switch err {
 		case errs.ErrProductNotFound:
 			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
 		}
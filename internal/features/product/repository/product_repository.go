@@ -21,6 +21,7 @@ type ProductRepository interface {
 	
 	// Transaction
 	DecreaseStockTx(ctx context.Context, tx *sql.Tx, productID int64, qty int) error
+	IncreaseStockTx(ctx context.Context, tx *sql.Tx, productID int64, qty int) error
 }
 
 type productRepository struct {
@@ -191,3 +192,24 @@ func (r *productRepository) DecreaseStockTx(ctx context.Context, tx *sql.Tx, pro
 	}
 	return nil
 }
+
+func (r *productRepository) IncreaseStockTx(ctx context.Context, tx *sql.Tx, productID int64, qty int) error {
+	query := `
+		UPDATE products SET stock = stock + $1, version = version + 1
+		WHERE id = $2
+	`
+	res, err := tx.ExecContext(ctx, query, qty, productID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrProductNotFound
+	}
+	return nil
+}
@@ -3,9 +3,11 @@ package productservice
 import (
 	"context"
 
+	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/config"
 	"github.com/codepnw/go-starter-kit/internal/features/product"
 	productrepository "github.com/codepnw/go-starter-kit/internal/features/product/repository"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
 )
 
 //go:generate mockgen -source=product_service.go -destination=product_service_mock.go -package=productservice
@@ -30,6 +32,10 @@ func (s *productService) CreateProduct(ctx context.Context, input *product.Produ
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
+	if err := auth.RequirePermission(ctx, user.PermProductsWrite); err != nil {
+		return err
+	}
+
 	if err := s.repo.InsertProduct(ctx, input); err != nil {
 		return err
 	}
@@ -65,6 +71,10 @@ func (s *productService) GetProducts(ctx context.Context, limit int, offset int)
 func (s *productService) IncreaseStock(ctx context.Context, productID int64, qty int) error {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermProductsWrite); err != nil {
+		return err
+	}
 	
 	if err := s.repo.IncreaseStock(ctx, productID, qty); err != nil {
 		return err
@@ -82,6 +92,10 @@ type UpdateProductInput struct {
 func (s *productService) UpdateProduct(ctx context.Context, input UpdateProductInput) error {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermProductsWrite); err != nil {
+		return err
+	}
 	
 	exists, err := s.repo.FindProduct(ctx, input.ID)
 	if err != nil {
@@ -108,6 +122,10 @@ func (s *productService) DeleteProduct(ctx context.Context, productID int64) err
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
+	if err := auth.RequirePermission(ctx, user.PermProductsWrite); err != nil {
+		return err
+	}
+
 	if err := s.repo.DeleteProduct(ctx, productID); err != nil {
 		return err
 	}
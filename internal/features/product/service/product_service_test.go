@@ -0,0 +1,147 @@
+package productservice_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/product"
+	productrepository "github.com/codepnw/go-starter-kit/internal/features/product/repository"
+	productservice "github.com/codepnw/go-starter-kit/internal/features/product/service"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrDB = errors.New("DB Error")
+
+func TestCreateProduct(t *testing.T) {
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		input       *product.Product
+		mockFn      func(mockRepo *productrepository.MockProductRepository, input *product.Product)
+		expectedErr error
+	}
+
+	mockInput := &product.Product{Name: "IPhone-17", Price: 43900, Stock: 10, SKU: "IP-17"}
+
+	testCases := []testCase{
+		{
+			name:  "success staff",
+			ctx:   withRole(user.RoleStaff),
+			input: mockInput,
+			mockFn: func(mockRepo *productrepository.MockProductRepository, input *product.Product) {
+				mockRepo.EXPECT().InsertProduct(gomock.Any(), input).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:  "success admin",
+			ctx:   withRole(user.RoleAdmin),
+			input: mockInput,
+			mockFn: func(mockRepo *productrepository.MockProductRepository, input *product.Product) {
+				mockRepo.EXPECT().InsertProduct(gomock.Any(), input).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			input:       mockInput,
+			mockFn:      func(mockRepo *productrepository.MockProductRepository, input *product.Product) {},
+			expectedErr: errs.ErrForbidden,
+		},
+		{
+			name:        "fail no claims",
+			ctx:         context.Background(),
+			input:       mockInput,
+			mockFn:      func(mockRepo *productrepository.MockProductRepository, input *product.Product) {},
+			expectedErr: errs.ErrUnauthorized,
+		},
+		{
+			name:  "fail insert product",
+			ctx:   withRole(user.RoleAdmin),
+			input: mockInput,
+			mockFn: func(mockRepo *productrepository.MockProductRepository, input *product.Product) {
+				mockRepo.EXPECT().InsertProduct(gomock.Any(), input).Return(ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockRepo, service := setup(t)
+
+		tc.mockFn(mockRepo, tc.input)
+
+		err := service.CreateProduct(tc.ctx, tc.input)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		productID   int64
+		mockFn      func(mockRepo *productrepository.MockProductRepository, productID int64)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:      "success",
+			ctx:       withRole(user.RoleAdmin),
+			productID: 1,
+			mockFn: func(mockRepo *productrepository.MockProductRepository, productID int64) {
+				mockRepo.EXPECT().DeleteProduct(gomock.Any(), productID).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail customer forbidden",
+			ctx:         withRole(user.RoleCustomer),
+			productID:   1,
+			mockFn:      func(mockRepo *productrepository.MockProductRepository, productID int64) {},
+			expectedErr: errs.ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockRepo, service := setup(t)
+
+		tc.mockFn(mockRepo, tc.productID)
+
+		err := service.DeleteProduct(tc.ctx, tc.productID)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func withRole(role user.Role) context.Context {
+	claims := &jwttoken.UserClaims{UserID: "mock-uuid-1", Role: role}
+	return auth.SetContextUserClaims(context.Background(), claims)
+}
+
+func setup(t *testing.T) (*productrepository.MockProductRepository, productservice.ProductService) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := productrepository.NewMockProductRepository(ctrl)
+	service := productservice.NewProductService(mockRepo)
+
+	return mockRepo, service
+}
@@ -0,0 +1,168 @@
+package userhandler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ListUsers : ?email=&role=&verified=&locked=&disabled=&created_from=&created_to=&page=&limit=
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	filter, err := userFilter(c)
+	if err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	resp, err := h.service.ListUsers(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidRole:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) GetUser(c *gin.Context) {
+	resp, err := h.service.GetUser(c.Request.Context(), c.Param(ParamUserID))
+	if err != nil {
+		adminUserError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) DisableUser(c *gin.Context) {
+	if err := h.service.DisableUser(c.Request.Context(), c.Param(ParamUserID)); err != nil {
+		adminUserError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "user disabled")
+}
+
+func (h *UserHandler) EnableUser(c *gin.Context) {
+	if err := h.service.EnableUser(c.Request.Context(), c.Param(ParamUserID)); err != nil {
+		adminUserError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "user enabled")
+}
+
+func (h *UserHandler) ForceLogout(c *gin.Context) {
+	if err := h.service.ForceLogout(c.Request.Context(), c.Param(ParamUserID)); err != nil {
+		adminUserError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+// Impersonate : admin gets a customer access token, no refresh token
+func (h *UserHandler) Impersonate(c *gin.Context) {
+	req := new(ImpersonateReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.Impersonate(c.Request.Context(), c.Param(ParamUserID), req.Reason, clientInfo(c, ""))
+	if err != nil {
+		adminUserError(c, err)
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func adminUserError(c *gin.Context, err error) {
+	switch err {
+	case errs.ErrUserNotFound:
+		response.ResponseError(c, http.StatusNotFound, err)
+	case errs.ErrCannotDisableSelf, errs.ErrCannotImpersonate, errs.ErrImpersonationReason:
+		response.ResponseError(c, http.StatusBadRequest, err)
+	case errs.ErrUnauthorized:
+		response.ResponseError(c, http.StatusUnauthorized, err)
+	case errs.ErrForbidden, errs.ErrAccountDisabled:
+		response.ResponseError(c, http.StatusForbidden, err)
+	default:
+		response.ResponseError(c, http.StatusInternalServerError, err)
+	}
+}
+
+// userFilter : dates are RFC 3339 or YYYY-MM-DD, a created_to date includes that whole day
+func userFilter(c *gin.Context) (user.UserFilter, error) {
+	filter := user.UserFilter{
+		Email: strings.TrimSpace(c.Query("email")),
+		Role:  user.Role(c.Query("role")),
+	}
+
+	var err error
+	if filter.Verified, err = boolQuery(c, "verified"); err != nil {
+		return filter, err
+	}
+	if filter.Locked, err = boolQuery(c, "locked"); err != nil {
+		return filter, err
+	}
+	if filter.Disabled, err = boolQuery(c, "disabled"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = timeQuery(c, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = timeQuery(c, "created_to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func boolQuery(c *gin.Context, key string) (*bool, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: want true or false", key)
+	}
+	return &b, nil
+}
+
+func timeQuery(c *gin.Context, key string, endOfDay bool) (*time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: want RFC 3339 or YYYY-MM-DD", key)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,82 @@
+package userhandler
+
+import (
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey : the key is in the response only once
+func (h *UserHandler) CreateAPIKey(c *gin.Context) {
+	req := new(CreateAPIKeyReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	scopes := make([]user.Permission, len(req.Scopes))
+	for i, s := range req.Scopes {
+		scopes[i] = user.Permission(s)
+	}
+
+	resp, err := h.service.CreateAPIKey(c.Request.Context(), userservice.CreateAPIKeyInput{
+		Name:      req.Name,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidAPIKeyScope, errs.ErrInvalidAPIKeyExpiry:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *UserHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		switch err {
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, keys)
+}
+
+func (h *UserHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.service.RevokeAPIKey(c.Request.Context(), c.Param(ParamAPIKeyID)); err != nil {
+		switch err {
+		case errs.ErrAPIKeyNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
@@ -0,0 +1,148 @@
+package userhandler
+
+import (
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyMFA : second login step after Login answered mfa_required
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	req := new(VerifyMFAReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c, req.DeviceName))
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidUserToken, errs.ErrInvalidMFACode, errs.ErrMFANotEnabled:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrAccountDisabled:
+			response.ResponseError(c, http.StatusForbidden, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrMFAUnavailable:
+			response.ResponseError(c, http.StatusServiceUnavailable, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) EnrollTOTP(c *gin.Context) {
+	resp, err := h.service.EnrollTOTP(c.Request.Context())
+	if err != nil {
+		switch err {
+		case errs.ErrMFAAlreadyEnabled:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrMFAUnavailable:
+			response.ResponseError(c, http.StatusServiceUnavailable, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusCreated, resp)
+}
+
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	req := new(MFACodeReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.ConfirmTOTP(c.Request.Context(), req.Code)
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidMFACode, errs.ErrMFANotEnrolled:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrMFAAlreadyEnabled:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrMFAUnavailable:
+			response.ResponseError(c, http.StatusServiceUnavailable, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	req := new(ReauthMFAReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.DisableTOTP(c.Request.Context(), req.Password, req.Code, clientInfo(c, "")); err != nil {
+		switch err {
+		case errs.ErrInvalidPassword, errs.ErrInvalidMFACode, errs.ErrMFANotEnabled:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrMFAUnavailable:
+			response.ResponseError(c, http.StatusServiceUnavailable, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	req := new(ReauthMFAReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), req.Password, req.Code, clientInfo(c, ""))
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidPassword, errs.ErrInvalidMFACode, errs.ErrMFANotEnabled:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrMFAUnavailable:
+			response.ResponseError(c, http.StatusServiceUnavailable, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
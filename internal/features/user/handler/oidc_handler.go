@@ -0,0 +1,71 @@
+package userhandler
+
+import (
+	"net/http"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// StartOIDC : returns the provider login url, the browser comes back to the frontend
+func (h *UserHandler) StartOIDC(c *gin.Context) {
+	resp, err := h.service.StartOIDC(c.Request.Context(), c.Param(ParamProvider))
+	if err != nil {
+		switch err {
+		case errs.ErrOIDCProviderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrOIDCLoginFailed:
+			response.ResponseError(c, http.StatusBadGateway, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+// OIDCCallback : finish social login, answers like Login
+func (h *UserHandler) OIDCCallback(c *gin.Context) {
+	req := new(OIDCCallbackReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.OIDCCallback(c.Request.Context(), c.Param(ParamProvider), req.Code, req.State, clientInfo(c, req.DeviceName))
+	if err != nil {
+		switch err {
+		case errs.ErrOIDCProviderNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidOIDCState, errs.ErrOIDCLoginFailed:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrEmailNotVerified, errs.ErrOIDCEmailNotVerified, errs.ErrAccountDisabled:
+			response.ResponseError(c, http.StatusForbidden, err)
+		case errs.ErrOIDCLinkConflict:
+			response.ResponseError(c, http.StatusConflict, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) ListIdentities(c *gin.Context) {
+	identities, err := h.service.ListIdentities(c.Request.Context())
+	if err != nil {
+		switch err {
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, identities)
+}
@@ -1,15 +1,105 @@
 package userhandler
 
+import "time"
+
+const (
+	ParamUserID    = "user_id"
+	ParamSessionID = "session_id"
+	ParamProvider  = "provider"
+	ParamAPIKeyID  = "api_key_id"
+)
+
+// RegisterReq : password rules come from the password policy, max only bounds the request
 type RegisterReq struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required,max=1024"`
 }
 
 type LoginReq struct {
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=100"`
+}
+
+// VerifyMFAReq : code is a TOTP code or a recovery code
+type VerifyMFAReq struct {
+	MFAToken   string `json:"mfa_token" binding:"required"`
+	Code       string `json:"code" binding:"required,max=20"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=100"`
+}
+
+// OIDCCallbackReq : code and state from the provider redirect to the frontend
+type OIDCCallbackReq struct {
+	Code       string `json:"code" binding:"required,max=2048"`
+	State      string `json:"state" binding:"required,max=100"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=100"`
 }
 
 type RefreshTokenReq struct {
 	RefreshToken string `json:"token" binding:"required"`
 }
+
+type VerifyEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordReq : password rules come from the password policy
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,max=1024"`
+}
+
+// UpdateProfileReq : current_password is required to change the email
+type UpdateProfileReq struct {
+	Name            *string `json:"name" binding:"omitempty,max=100"`
+	Email           *string `json:"email" binding:"omitempty,email,max=255"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type ConfirmEmailChangeReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ChangePasswordReq struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,max=1024"`
+	DeviceName      string `json:"device_name" binding:"omitempty,max=100"`
+}
+
+type DeleteAccountReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type MFACodeReq struct {
+	Code string `json:"code" binding:"required,max=20"`
+}
+
+// ReauthMFAReq : password and a code, for changes a stolen access token alone must not make
+type ReauthMFAReq struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,max=20"`
+}
+
+type UpdateRoleReq struct {
+	Role string `json:"role" binding:"required,oneof=customer staff admin"`
+}
+
+// CreateAPIKeyReq : scopes are permissions like products:write, expires_at is RFC 3339
+type CreateAPIKeyReq struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,max=10"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ImpersonateReq : reason is kept in the audit log
+type ImpersonateReq struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
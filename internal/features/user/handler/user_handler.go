@@ -30,10 +30,10 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Email:    req.Email,
 		Password: req.Password,
 	}
-	resp, err := h.service.Register(c.Request.Context(), input)
+	resp, err := h.service.Register(c.Request.Context(), input, clientInfo(c, ""))
 	if err != nil {
 		switch err {
-		case errs.ErrEmailAlreadyExists:
+		case errs.ErrEmailAlreadyExists, errs.ErrPasswordTooShort, errs.ErrPasswordTooLong, errs.ErrPasswordBreached:
 			response.ResponseError(c, http.StatusBadRequest, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
@@ -52,11 +52,15 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.Login(c.Request.Context(), req.Email, req.Password)
+	resp, err := h.service.Login(c.Request.Context(), req.Email, req.Password, clientInfo(c, req.DeviceName))
 	if err != nil {
 		switch err {
 		case errs.ErrInvalidEmailOrPassword:
 			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrEmailNotVerified, errs.ErrAccountDisabled:
+			response.ResponseError(c, http.StatusForbidden, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
 		}
@@ -74,7 +78,7 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.RefreshToken(c.Request.Context(), req.RefreshToken)
+	resp, err := h.service.RefreshToken(c.Request.Context(), req.RefreshToken, clientInfo(c, ""))
 	if err != nil {
 		switch err {
 		case errs.ErrTokenNotFound:
@@ -83,6 +87,10 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 			response.ResponseError(c, http.StatusBadRequest, err)
 		case errs.ErrTokenExpires:
 			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrTokenReused, errs.ErrInvalidToken:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrAccountDisabled:
+			response.ResponseError(c, http.StatusForbidden, err)
 		default:
 			response.ResponseError(c, http.StatusInternalServerError, err)
 		}
@@ -113,6 +121,82 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	response.ResponseSuccess(c, http.StatusNoContent, nil)
 }
 
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	req := new(VerifyEmailReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		switch err {
+		case errs.ErrInvalidUserToken:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "email verified")
+}
+
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	req := new(ResendVerificationReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		response.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Same answer for unknown emails
+	response.ResponseSuccess(c, http.StatusAccepted, "if the account exists and is not verified, a new email has been sent")
+}
+
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	req := new(ForgotPasswordReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		response.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Same answer for unknown emails
+	response.ResponseSuccess(c, http.StatusAccepted, "if the account exists, a password reset email has been sent")
+}
+
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	req := new(ResetPasswordReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		switch err {
+		case errs.ErrInvalidUserToken, errs.ErrPasswordTooShort, errs.ErrPasswordTooLong, errs.ErrPasswordBreached:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "password updated, please login again")
+}
+
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	resp, err := h.service.GetProfile(c.Request.Context())
 	if err != nil {
@@ -126,3 +210,258 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	}
 	response.ResponseSuccess(c, http.StatusOK, resp)
 }
+
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	req := new(UpdateProfileReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	input := user.UpdateProfileInput{
+		Name:            req.Name,
+		Email:           req.Email,
+		CurrentPassword: req.CurrentPassword,
+	}
+	resp, err := h.service.UpdateProfile(c.Request.Context(), input, clientInfo(c, ""))
+	if err != nil {
+		switch err {
+		case errs.ErrEmailAlreadyExists:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrInvalidPassword:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	req := new(ConfirmEmailChangeReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.ConfirmEmailChange(c.Request.Context(), req.Token); err != nil {
+		switch err {
+		case errs.ErrInvalidUserToken, errs.ErrEmailAlreadyExists:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "email changed")
+}
+
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	req := new(ChangePasswordReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.service.ChangePassword(c.Request.Context(), req.CurrentPassword, req.NewPassword, clientInfo(c, req.DeviceName))
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidPassword, errs.ErrSamePassword, errs.ErrPasswordTooShort, errs.ErrPasswordTooLong, errs.ErrPasswordBreached:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	req := new(DeleteAccountReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.service.DeleteAccount(c.Request.Context(), req.Password, clientInfo(c, "")); err != nil {
+		switch err {
+		case errs.ErrInvalidPassword:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+// RequestReauth : accounts without a password get a code by email, sent as the current password
+func (h *UserHandler) RequestReauth(c *gin.Context) {
+	if err := h.service.RequestReauth(c.Request.Context()); err != nil {
+		switch err {
+		case errs.ErrPasswordSet:
+			response.ResponseError(c, http.StatusConflict, err)
+		case errs.ErrTooManyLoginAttempts:
+			response.ResponseError(c, http.StatusTooManyRequests, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusAccepted, "a confirmation code has been sent to your email")
+}
+
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	req := new(UpdateRoleReq)
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := c.Param(ParamUserID)
+
+	if err := h.service.UpdateUserRole(c.Request.Context(), userID, user.Role(req.Role)); err != nil {
+		switch err {
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrInvalidRole, errs.ErrCannotChangeOwnRole:
+			response.ResponseError(c, http.StatusBadRequest, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "user role updated")
+}
+
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	userID := c.Param(ParamUserID)
+
+	if err := h.service.UnlockUser(c.Request.Context(), userID); err != nil {
+		switch err {
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, "user unlocked")
+}
+
+func (h *UserHandler) ListLoginFailures(c *gin.Context) {
+	userID := c.Param(ParamUserID)
+
+	resp, err := h.service.ListLoginFailures(c.Request.Context(), userID)
+	if err != nil {
+		switch err {
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		case errs.ErrForbidden:
+			response.ResponseError(c, http.StatusForbidden, err)
+		case errs.ErrUserNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	resp, err := h.service.ListSessions(c.Request.Context())
+	if err != nil {
+		switch err {
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	response.ResponseSuccess(c, http.StatusOK, resp)
+}
+
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	sessionID := c.Param(ParamSessionID)
+
+	if err := h.service.RevokeSession(c.Request.Context(), sessionID); err != nil {
+		switch err {
+		case errs.ErrSessionNotFound:
+			response.ResponseError(c, http.StatusNotFound, err)
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+func (h *UserHandler) RevokeAllSessions(c *gin.Context) {
+	if err := h.service.RevokeAllSessions(c.Request.Context()); err != nil {
+		switch err {
+		case errs.ErrUnauthorized:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	response.ResponseSuccess(c, http.StatusNoContent, nil)
+}
+
+// clientInfo : device of the request, saved on the session
+func clientInfo(c *gin.Context, deviceName string) user.ClientInfo {
+	return user.ClientInfo{
+		UserAgent:   c.Request.UserAgent(),
+		IPAddress:   c.ClientIP(),
+		DeviceLabel: deviceName,
+	}
+}
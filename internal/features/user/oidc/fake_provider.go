@@ -0,0 +1,317 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	FakeProviderName = "fake"
+
+	fakeKeyID   = "fake-1"
+	fakeCodeTTL = time.Minute
+	fakeIDTTL   = 5 * time.Minute
+)
+
+// FakeProvider : in-process stand-in for an OIDC identity provider.
+// GET /authorize shows a form asking for an email, or signs in at once with ?login_hint=
+// (&email_verified=false and &name= are optional), then redirects with a code.
+type FakeProvider struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	key          *rsa.PrivateKey
+	mux          *http.ServeMux
+	now          func() time.Time
+
+	mu     sync.Mutex
+	issuer string
+	codes  map[string]*fakeCode
+}
+
+type fakeCode struct {
+	redirectURI   string
+	challenge     string
+	nonce         string
+	email         string
+	name          string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+func NewFakeProvider(clientID, clientSecret, redirectURL string) (*FakeProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate fake oidc key failed: %w", err)
+	}
+
+	f := &FakeProvider{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		key:          key,
+		mux:          http.NewServeMux(),
+		now:          time.Now,
+		codes:        make(map[string]*fakeCode),
+	}
+
+	f.mux.HandleFunc("GET /.well-known/openid-configuration", f.configuration)
+	f.mux.HandleFunc("GET /jwks", f.jwks)
+	f.mux.HandleFunc("GET /authorize", f.authorize)
+	f.mux.HandleFunc("POST /authorize", f.approve)
+	f.mux.HandleFunc("POST /token", f.token)
+	return f, nil
+}
+
+// StartFakeProvider : listen on addr in background, return issuer url
+func StartFakeProvider(addr, clientID, clientSecret, redirectURL string) (*http.Server, string, error) {
+	f, err := NewFakeProvider(clientID, clientSecret, redirectURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("fake oidc provider listen failed: %w", err)
+	}
+
+	issuer := "http://" + ln.Addr().String()
+	f.SetIssuer(issuer)
+
+	srv := &http.Server{Handler: f, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+
+	return srv, issuer, nil
+}
+
+// SetIssuer : base url of the provider, required when served by httptest
+func (f *FakeProvider) SetIssuer(issuer string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.issuer = strings.TrimRight(issuer, "/")
+}
+
+func (f *FakeProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.ServeHTTP(w, r)
+}
+
+func (f *FakeProvider) getIssuer() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.issuer
+}
+
+func (f *FakeProvider) configuration(w http.ResponseWriter, r *http.Request) {
+	issuer := f.getIssuer()
+	writeProviderJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (f *FakeProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := f.key.PublicKey
+	writeProviderJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": fakeKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var fakeLoginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><title>Fake OIDC Provider</title></head>
+<body style="font-family:sans-serif;max-width:24rem;margin:4rem auto">
+<h2>Fake OIDC Provider</h2>
+<p>Local stand-in, any email signs in.</p>
+<form method="post" action="/authorize">
+{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<p><input name="email" type="email" placeholder="email" required></p>
+<p><input name="name" placeholder="name"></p>
+<p><label><input name="email_verified" type="checkbox" value="true" checked> email verified</label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body></html>`))
+
+func (f *FakeProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if err := f.checkAuthorizeRequest(q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if hint := q.Get("login_hint"); hint != "" {
+		f.issueCode(w, r, q, hint, q.Get("name"), q.Get("email_verified") != "false")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fakeLoginPage.Execute(w, q)
+}
+
+func (f *FakeProvider) approve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	if err := f.checkAuthorizeRequest(form); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(form.Get("email"))
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+	f.issueCode(w, r, form, email, form.Get("name"), form.Get("email_verified") == "true")
+}
+
+func (f *FakeProvider) checkAuthorizeRequest(q url.Values) error {
+	switch {
+	case q.Get("response_type") != "code":
+		return fmt.Errorf("unsupported response_type")
+	case q.Get("client_id") != f.clientID:
+		return fmt.Errorf("unknown client_id")
+	case q.Get("redirect_uri") != f.redirectURL:
+		return fmt.Errorf("redirect_uri not registered")
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		return fmt.Errorf("PKCE with S256 is required")
+	}
+	return nil
+}
+
+func (f *FakeProvider) issueCode(w http.ResponseWriter, r *http.Request, q url.Values, email, name string, verified bool) {
+	code, err := securetoken.Generate(24)
+	if err != nil {
+		http.Error(w, "generate code failed", http.StatusInternalServerError)
+		return
+	}
+
+	f.mu.Lock()
+	f.codes[code] = &fakeCode{
+		redirectURI:   q.Get("redirect_uri"),
+		challenge:     q.Get("code_challenge"),
+		nonce:         q.Get("nonce"),
+		email:         email,
+		name:          name,
+		emailVerified: verified,
+		expiresAt:     f.now().Add(fakeCodeTTL),
+	}
+	f.mu.Unlock()
+
+	target := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (f *FakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != f.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(f.clientSecret)) != 1 {
+		writeProviderJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// Codes work once, even when the rest of the request is wrong
+	f.mu.Lock()
+	c, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	f.mu.Unlock()
+
+	if !ok || f.now().After(c.expiresAt) || c.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+	if S256Challenge(r.PostForm.Get("code_verifier")) != c.challenge {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	now := f.now()
+	claims := jwt.MapClaims{
+		"iss":            f.getIssuer(),
+		"sub":            fakeSubject(c.email),
+		"aud":            f.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(fakeIDTTL).Unix(),
+		"nonce":          c.nonce,
+		"email":          c.email,
+		"email_verified": c.emailVerified,
+		"name":           c.name,
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = fakeKeyID
+
+	idToken, err := t.SignedString(f.key)
+	if err != nil {
+		writeTokenError(w, "server_error")
+		return
+	}
+
+	accessToken, _ := securetoken.Generate(24)
+	writeProviderJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(fakeIDTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// fakeSubject : same email, same subject, like a real account id
+func fakeSubject(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "fake-" + hex.EncodeToString(sum[:8])
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	writeProviderJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeProviderJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,194 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID     = "starter-kit"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://localhost:3000/oidc/callback"
+)
+
+func startProvider(t *testing.T) (*FakeProvider, *httptest.Server, Provider) {
+	t.Helper()
+
+	fake, err := NewFakeProvider(testClientID, testClientSecret, testRedirectURL)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	fake.SetIssuer(srv.URL)
+
+	p := NewProvider(Config{
+		Name:         FakeProviderName,
+		Issuer:       srv.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	}, srv.Client())
+	return fake, srv, p
+}
+
+// authorize : follow the login_hint shortcut, return code and state from the redirect
+func authorize(t *testing.T, srv *httptest.Server, authURL string, extra url.Values) (string, string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	for k, v := range extra {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	c := srv.Client()
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	resp, err := c.Get(u.String())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	_, srv, p := startProvider(t)
+
+	verifier, challenge, err := NewPKCE()
+	require.NoError(t, err)
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	require.NoError(t, err)
+
+	code, state := authorize(t, srv, authURL, url.Values{"login_hint": {"a@mail.com"}, "name": {"Alice"}})
+	assert.Equal(t, "state-1", state)
+
+	identity, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, FakeProviderName, identity.Provider)
+	assert.Equal(t, fakeSubject("a@mail.com"), identity.Subject)
+	assert.Equal(t, "a@mail.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Alice", identity.Name)
+
+	// Codes work once
+	_, err = p.Exchange(ctx, code, verifier, "nonce-1")
+	assert.ErrorIs(t, err, ErrExchangeFailed)
+}
+
+func TestCodeFlowRejects(t *testing.T) {
+	ctx := context.Background()
+
+	login := func(t *testing.T, srv *httptest.Server, p Provider, challenge string) string {
+		authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+		require.NoError(t, err)
+		code, _ := authorize(t, srv, authURL, url.Values{"login_hint": {"a@mail.com"}})
+		return code
+	}
+
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		_, srv, p := startProvider(t)
+		_, challenge, _ := NewPKCE()
+		other, _, _ := NewPKCE()
+
+		_, err := p.Exchange(ctx, login(t, srv, p, challenge), other, "nonce-1")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		_, srv, p := startProvider(t)
+		verifier, challenge, _ := NewPKCE()
+
+		_, err := p.Exchange(ctx, login(t, srv, p, challenge), verifier, "nonce-2")
+		assert.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+
+	t.Run("wrong client secret", func(t *testing.T) {
+		_, srv, _ := startProvider(t)
+		p := NewProvider(Config{Name: FakeProviderName, Issuer: srv.URL, ClientID: testClientID, ClientSecret: "wrong", RedirectURL: testRedirectURL}, srv.Client())
+		verifier, challenge, _ := NewPKCE()
+
+		_, err := p.Exchange(ctx, login(t, srv, p, challenge), verifier, "nonce-1")
+		assert.ErrorIs(t, err, ErrExchangeFailed)
+	})
+
+	t.Run("issuer mismatch", func(t *testing.T) {
+		fake, _, p := startProvider(t)
+		fake.SetIssuer("https://evil.example")
+
+		_, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", "challenge")
+		assert.ErrorIs(t, err, ErrDiscoveryFailed)
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	ctx := context.Background()
+	fake, srv, p := startProvider(t)
+	c := p.(*client)
+
+	meta, err := c.discover(ctx)
+	require.NoError(t, err)
+
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		tk := jwt.NewWithClaims(method, claims)
+		tk.Header["kid"] = fakeKeyID
+		ss, err := tk.SignedString(key)
+		require.NoError(t, err)
+		return ss
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   srv.URL,
+			"sub":   "sub-1",
+			"aud":   testClientID,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": "nonce-1",
+			// Some providers send the flag as a string
+			"email_verified": "true",
+		}
+	}
+
+	identity, err := c.verifyIDToken(ctx, meta, sign(jwt.SigningMethodRS256, fake.key, valid()), "nonce-1")
+	require.NoError(t, err)
+	assert.True(t, identity.EmailVerified)
+
+	testCases := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+	}{
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"several audiences without azp", func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "other-client"} }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := valid()
+			tc.mutate(claims)
+
+			_, err := c.verifyIDToken(ctx, meta, sign(jwt.SigningMethodRS256, fake.key, claims), "nonce-1")
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+
+	t.Run("HMAC with the client secret", func(t *testing.T) {
+		_, err := c.verifyIDToken(ctx, meta, sign(jwt.SigningMethodHS256, []byte(testClientSecret), valid()), "nonce-1")
+		assert.ErrorIs(t, err, ErrInvalidIDToken)
+	})
+}
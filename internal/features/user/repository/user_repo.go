@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/errs"
 	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/lib/pq"
 )
 
 //go:generate mockgen -source=user_repo.go -destination=user_repo_mock.go -package=userrepository
@@ -15,12 +17,72 @@ type UserRepository interface {
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
 	FindUserByEmail(ctx context.Context, email string) (*user.User, error)
 	FindUserByID(ctx context.Context, userID string) (*user.User, error)
-	ValidateRefreshToken(ctx context.Context, token string) error
+	CheckRoleExists(ctx context.Context, role user.Role) (bool, error)
+	UpdateUserRole(ctx context.Context, userID string, role user.Role) error
+	ValidateRefreshToken(ctx context.Context, tokenHash string) (*user.RefreshToken, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) (int64, error)
+
+	// Sessions
+	FindSessions(ctx context.Context, userID string) ([]*user.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID string) (int64, error)
+	RehashPassword(ctx context.Context, userID, oldHash, newHash string) error
 
 	// Transaction
 	InsertUserTx(ctx context.Context, tx *sql.Tx, u *user.User) error
 	InsertRefreshTokenTx(ctx context.Context, tx *sql.Tx, token *user.RefreshToken) error
-	RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error
+	RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, tokenHash string) error
+	MarkEmailVerifiedTx(ctx context.Context, tx *sql.Tx, userID string) error
+	UpdatePasswordTx(ctx context.Context, tx *sql.Tx, userID, hashedPassword string) error
+	UpdateProfileTx(ctx context.Context, tx *sql.Tx, u *user.User) error
+	ApplyPendingEmailTx(ctx context.Context, tx *sql.Tx, userID string) error
+	AnonymizeUserTx(ctx context.Context, tx *sql.Tx, userID string) error
+	RevokeAllSessionsTx(ctx context.Context, tx *sql.Tx, userID string) (int64, error)
+	UpdateUserRoleTx(ctx context.Context, tx *sql.Tx, userID string, role user.Role) error
+
+	// Email Tokens
+	InsertUserTokenTx(ctx context.Context, tx *sql.Tx, t *user.UserToken) error
+	InvalidateUserTokensTx(ctx context.Context, tx *sql.Tx, userID string, purpose user.TokenPurpose) error
+	UseUserTokenTx(ctx context.Context, tx *sql.Tx, tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error)
+	CountUserTokensSince(ctx context.Context, userID string, purpose user.TokenPurpose, since time.Time) (int, error)
+	FindUserToken(ctx context.Context, tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error)
+	AddUserTokenAttempt(ctx context.Context, tokenID string, maxAttempts int) (int, error)
+
+	// Two-Factor (TOTP)
+	FindTOTP(ctx context.Context, userID string) (*user.TOTP, error)
+	SetTOTPSecret(ctx context.Context, userID, encryptedSecret string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	EnableTOTPTx(ctx context.Context, tx *sql.Tx, userID string, step int64) error
+	DisableTOTPTx(ctx context.Context, tx *sql.Tx, userID string) error
+	ReplaceRecoveryCodesTx(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+
+	// Social Login (OIDC)
+	InsertOIDCState(ctx context.Context, st *user.OIDCState) error
+	UseOIDCState(ctx context.Context, stateHash, provider string) (*user.OIDCState, error)
+	FindUserByIdentity(ctx context.Context, provider, subject string) (*user.User, error)
+	InsertIdentityTx(ctx context.Context, tx *sql.Tx, identity *user.Identity) error
+	ListIdentities(ctx context.Context, userID string) ([]*user.Identity, error)
+
+	// API Keys
+	InsertAPIKey(ctx context.Context, key *user.APIKey) error
+	ListAPIKeys(ctx context.Context) ([]*user.APIKey, error)
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (*user.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID string) error
+	TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error
+
+	// Admin Users
+	FindUsers(ctx context.Context, filter user.UserFilter, limit, offset int) ([]*user.AdminUser, int64, error)
+	FindAdminUser(ctx context.Context, userID string) (*user.AdminUser, error)
+	FindUserOrderStats(ctx context.Context, userID string, excluded []string) (int64, int64, error)
+	DisableUserTx(ctx context.Context, tx *sql.Tx, userID string, at time.Time) error
+	EnableUser(ctx context.Context, userID string) error
+
+	// Impersonation
+	InsertImpersonation(ctx context.Context, imp *user.Impersonation) error
+	FindActiveImpersonationsByActor(ctx context.Context, actorID string) ([]*user.Impersonation, error)
+	InsertImpersonationRequest(ctx context.Context, req *user.ImpersonationRequest) error
+	UpdateImpersonationRequestStatus(ctx context.Context, id int64, status int) error
 }
 
 type userRepository struct {
@@ -33,10 +95,10 @@ func NewUserRepository(db *sql.DB) UserRepository {
 
 func (r *userRepository) InsertUserTx(ctx context.Context, tx *sql.Tx, u *user.User) error {
 	query := `
-		INSERT INTO users (email, password)
-		VALUES ($1, $2) RETURNING id, created_at, updated_at
+		INSERT INTO users (email, name, password, role, email_verified_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5) RETURNING id, created_at, updated_at
 	`
-	if err := tx.QueryRowContext(ctx, query, u.Email, u.Password).Scan(
+	if err := tx.QueryRowContext(ctx, query, u.Email, u.Name, u.Password, u.Role, u.EmailVerifiedAt).Scan(
 		&u.ID,
 		&u.CreatedAt,
 		&u.UpdatedAt,
@@ -62,14 +124,21 @@ func (r *userRepository) CheckEmailExists(ctx context.Context, email string) (bo
 func (r *userRepository) FindUserByEmail(ctx context.Context, email string) (*user.User, error) {
 	var u user.User
 	query := `
-		SELECT id, email, password
-		FROM users WHERE email = $1 LIMIT 1
+		SELECT id, email, password, role, email_verified_at, totp_enabled_at, disabled_at
+		FROM users WHERE email = $1 AND deleted_at IS NULL LIMIT 1
 	`
 	if err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&u.ID,
 		&u.Email,
 		&u.Password,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.TOTPEnabledAt,
+		&u.DisabledAt,
 	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
 		return nil, err
 	}
 	return &u, nil
@@ -78,12 +147,19 @@ func (r *userRepository) FindUserByEmail(ctx context.Context, email string) (*us
 func (r *userRepository) FindUserByID(ctx context.Context, userID string) (*user.User, error) {
 	var u user.User
 	query := `
-		SELECT id, email, created_at, updated_at
-		FROM users WHERE id = $1 LIMIT 1
+		SELECT id, email, COALESCE(name, ''), pending_email, password, role, email_verified_at, totp_enabled_at, disabled_at, created_at, updated_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 	`
 	if err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&u.ID,
 		&u.Email,
+		&u.Name,
+		&u.PendingEmail,
+		&u.Password,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.TOTPEnabledAt,
+		&u.DisabledAt,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	); err != nil {
@@ -95,56 +171,816 @@ func (r *userRepository) FindUserByID(ctx context.Context, userID string) (*user
 	return &u, nil
 }
 
+func (r *userRepository) CheckRoleExists(ctx context.Context, role user.Role) (bool, error) {
+	var dummy bool
+	query := `SELECT 1 FROM users WHERE role = $1 LIMIT 1`
+
+	if err := r.db.QueryRowContext(ctx, query, role).Scan(&dummy); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *userRepository) UpdateUserRole(ctx context.Context, userID string, role user.Role) error {
+	return updateUserRole(ctx, r.db, userID, role)
+}
+
+// UpdateUserRoleTx : same as UpdateUserRole, commits with revoking the user's sessions
+func (r *userRepository) UpdateUserRoleTx(ctx context.Context, tx *sql.Tx, userID string, role user.Role) error {
+	return updateUserRole(ctx, tx, userID, role)
+}
+
+func updateUserRole(ctx context.Context, db execer, userID string, role user.Role) error {
+	query := `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`
+	res, err := db.ExecContext(ctx, query, role, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+	return nil
+}
+
+// InsertRefreshTokenTx : token.Token must be the hash, empty FamilyID starts a new family
 func (r *userRepository) InsertRefreshTokenTx(ctx context.Context, tx *sql.Tx, token *user.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (user_id, token, expires_at, revoked)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (
+			user_id, token, expires_at, revoked, family_id, parent_id,
+			user_agent, ip_address, device_label, last_used_at
+		)
+		VALUES (
+			$1, $2, $3, $4, COALESCE(NULLIF($5, '')::UUID, gen_random_uuid()), $6,
+			NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NOW()
+		)
+		RETURNING id, family_id, last_used_at, created_at
 	`
-	_, err := tx.ExecContext(
+	if err := tx.QueryRowContext(
 		ctx,
 		query,
 		token.UserID,
 		token.Token,
 		token.ExpiresAt,
 		token.Revoked,
-	)
-	if err != nil {
+		token.FamilyID,
+		token.ParentID,
+		token.UserAgent,
+		token.IPAddress,
+		token.DeviceLabel,
+	).Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *userRepository) ValidateRefreshToken(ctx context.Context, token string) error {
-	var revoked bool
-	var expiresAt time.Time
+// ValidateRefreshToken : the token is also returned with ErrTokenRevoked, so its family can be revoked
+func (r *userRepository) ValidateRefreshToken(ctx context.Context, tokenHash string) (*user.RefreshToken, error) {
+	var rt user.RefreshToken
 
 	query := `
-		SELECT revoked, expires_at
+		SELECT
+			id, user_id, token, family_id, parent_id,
+			COALESCE(user_agent, ''), COALESCE(ip_address, ''), COALESCE(device_label, ''),
+			expires_at, revoked, revoked_at, COALESCE(last_used_at, created_at), created_at
 		FROM refresh_tokens WHERE token = $1 LIMIT 1
 	`
-	if err := r.db.QueryRowContext(ctx, query, token).Scan(
-		&revoked,
-		&expiresAt,
+	if err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&rt.ID,
+		&rt.UserID,
+		&rt.Token,
+		&rt.FamilyID,
+		&rt.ParentID,
+		&rt.UserAgent,
+		&rt.IPAddress,
+		&rt.DeviceLabel,
+		&rt.ExpiresAt,
+		&rt.Revoked,
+		&rt.RevokedAt,
+		&rt.LastUsedAt,
+		&rt.CreatedAt,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return errs.ErrTokenNotFound
+			return nil, errs.ErrTokenNotFound
 		}
+		return nil, err
+	}
+
+	if rt.Revoked {
+		return &rt, errs.ErrTokenRevoked
+	}
+
+	if time.Now().After(rt.ExpiresAt) {
+		return nil, errs.ErrTokenExpires
+	}
+	return &rt, nil
+}
+
+// RevokedRefreshTokenTx : only one caller can revoke a token, the loser gets ErrTokenRevoked
+func (r *userRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, tokenHash string) error {
+	query := `UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW() WHERE token = $1 AND NOT revoked`
+	res, err := tx.ExecContext(ctx, query, tokenHash)
+	if err != nil {
 		return err
 	}
 
-	if revoked {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		var dummy bool
+		query := `SELECT 1 FROM refresh_tokens WHERE token = $1 LIMIT 1`
+
+		if err := tx.QueryRowContext(ctx, query, tokenHash).Scan(&dummy); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errs.ErrTokenNotFound
+			}
+			return err
+		}
 		return errs.ErrTokenRevoked
 	}
+	return nil
+}
+
+// RevokeTokenFamily : revoke every live token of a family, runs outside the refresh transaction
+func (r *userRepository) RevokeTokenFamily(ctx context.Context, familyID string) (int64, error) {
+	query := `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE family_id = $1 AND NOT revoked
+	`
+	res, err := r.db.ExecContext(ctx, query, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FindSessions : live token of each family, a family has at most one after rotation
+func (r *userRepository) FindSessions(ctx context.Context, userID string) ([]*user.Session, error) {
+	query := `
+		SELECT
+			rt.family_id,
+			COALESCE(rt.device_label, ''),
+			COALESCE(rt.user_agent, ''),
+			COALESCE(rt.ip_address, ''),
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = rt.family_id),
+			COALESCE(rt.last_used_at, rt.created_at),
+			rt.expires_at
+		FROM refresh_tokens rt
+		WHERE rt.user_id = $1 AND NOT rt.revoked AND rt.expires_at > NOW()
+		ORDER BY COALESCE(rt.last_used_at, rt.created_at) DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*user.Session, 0)
+	for rows.Next() {
+		var ss user.Session
+		if err := rows.Scan(
+			&ss.ID,
+			&ss.DeviceLabel,
+			&ss.UserAgent,
+			&ss.IPAddress,
+			&ss.CreatedAt,
+			&ss.LastUsedAt,
+			&ss.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &ss)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *userRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	// Compare as text: a malformed id is just not found
+	query := `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND family_id::TEXT = $2 AND NOT revoked
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) RevokeAllSessions(ctx context.Context, userID string) (int64, error) {
+	return revokeAllSessions(ctx, r.db, userID)
+}
+
+// RevokeAllSessionsTx : same as RevokeAllSessions, commits with the password change
+func (r *userRepository) RevokeAllSessionsTx(ctx context.Context, tx *sql.Tx, userID string) (int64, error) {
+	return revokeAllSessions(ctx, tx, userID)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func revokeAllSessions(ctx context.Context, db execer, userID string) (int64, error) {
+	query := `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND NOT revoked
+	`
+	res, err := db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *userRepository) MarkEmailVerifiedTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	query := `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+	`
+	res, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) InsertUserTokenTx(ctx context.Context, tx *sql.Tx, t *user.UserToken) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return tx.QueryRowContext(ctx, query, t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt).Scan(
+		&t.ID,
+		&t.CreatedAt,
+	)
+}
+
+// InvalidateUserTokensTx : only the latest token sent stays usable
+func (r *userRepository) InvalidateUserTokensTx(ctx context.Context, tx *sql.Tx, userID string, purpose user.TokenPurpose) error {
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+	_, err := tx.ExecContext(ctx, query, userID, purpose)
+	return err
+}
+
+// UseUserTokenTx : consume a live token, a second use gets ErrInvalidUserToken
+func (r *userRepository) UseUserTokenTx(ctx context.Context, tx *sql.Tx, tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error) {
+	var t user.UserToken
+
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, tokenHash, purpose).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrInvalidUserToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *userRepository) UpdatePasswordTx(ctx context.Context, tx *sql.Tx, userID, hashedPassword string) error {
+	query := `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`
+	res, err := tx.ExecContext(ctx, query, hashedPassword, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+	return nil
+}
+
+// RehashPassword : same password in a new hash format, updated_at is left alone.
+// No error when the hash changed in between, the newer password wins.
+func (r *userRepository) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	query := `UPDATE users SET password = $3 WHERE id = $1 AND password = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, oldHash, newHash)
+	return err
+}
+
+// CountUserTokensSince : tokens issued after since, used or not
+func (r *userRepository) CountUserTokensSince(ctx context.Context, userID string, purpose user.TokenPurpose, since time.Time) (int, error) {
+	var total int
+	query := `SELECT COUNT(*) FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND created_at > $3`
+
+	if err := r.db.QueryRowContext(ctx, query, userID, purpose, since).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// UpdateProfileTx : name and pending email, email itself changes in ApplyPendingEmailTx
+func (r *userRepository) UpdateProfileTx(ctx context.Context, tx *sql.Tx, u *user.User) error {
+	query := `
+		UPDATE users SET name = NULLIF($1, ''), pending_email = $2, updated_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+	if err := tx.QueryRowContext(ctx, query, u.Name, u.PendingEmail, u.ID).Scan(&u.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ApplyPendingEmailTx : confirmed new email, verified by the confirmation itself
+func (r *userRepository) ApplyPendingEmailTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	query := `
+		UPDATE users SET
+			email = pending_email,
+			pending_email = NULL,
+			email_verified_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND pending_email IS NOT NULL AND deleted_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		// Taken by another account since the change was requested
+		if strings.Contains(err.Error(), "users_email_key") {
+			return errs.ErrEmailAlreadyExists
+		}
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrInvalidUserToken
+	}
+	return nil
+}
+
+// AnonymizeUserTx : delete account but keep the row, orders still reference it.
+// Personal data the user owns (addresses, cart, tokens) is deleted.
+func (r *userRepository) AnonymizeUserTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	query := `
+		UPDATE users SET
+			email = 'deleted-' || id || '@deleted.invalid',
+			name = NULL,
+			pending_email = NULL,
+			password = '',
+			totp_secret = NULL,
+			totp_enabled_at = NULL,
+			deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrUserNotFound
+	}
+
+	cleanup := []string{
+		`DELETE FROM refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM user_tokens WHERE user_id = $1`,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM login_failures WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM user_addresses WHERE user_id = $1`,
+		`DELETE FROM carts WHERE user_id = $1`,
+		`UPDATE api_keys SET revoked_at = NOW() WHERE created_by = $1 AND revoked_at IS NULL`,
+	}
+	for _, q := range cleanup {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindUserToken : unused and unexpired, without consuming it
+func (r *userRepository) FindUserToken(ctx context.Context, tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error) {
+	var t user.UserToken
+
+	query := `
+		SELECT id, user_id, purpose, token_hash, expires_at, used_at, attempts, created_at
+		FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	`
+	if err := r.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.Attempts,
+		&t.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrInvalidUserToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// AddUserTokenAttempt : count a failed attempt, the token is used up at maxAttempts
+func (r *userRepository) AddUserTokenAttempt(ctx context.Context, tokenID string, maxAttempts int) (int, error) {
+	var attempts int
+
+	query := `
+		UPDATE user_tokens SET
+			attempts = attempts + 1,
+			used_at = CASE WHEN attempts + 1 >= $2 THEN NOW() ELSE used_at END
+		WHERE id = $1
+		RETURNING attempts
+	`
+	if err := r.db.QueryRowContext(ctx, query, tokenID, maxAttempts).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errs.ErrInvalidUserToken
+		}
+		return 0, err
+	}
+	return attempts, nil
+}
+
+func (r *userRepository) FindTOTP(ctx context.Context, userID string) (*user.TOTP, error) {
+	var t user.TOTP
+
+	query := `
+		SELECT id, COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+	`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.EnabledAt,
+		&t.LastStep,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SetTOTPSecret : start or restart enrollment, not allowed once enabled
+func (r *userRepository) SetTOTPSecret(ctx context.Context, userID, encryptedSecret string) error {
+	query := `
+		UPDATE users SET totp_secret = $2, totp_last_step = 0, updated_at = NOW()
+		WHERE id = $1 AND totp_enabled_at IS NULL AND deleted_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, encryptedSecret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// UseTOTPStep : accept a code's step once, an older or equal step is a replay
+func (r *userRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	query := `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NOT NULL AND totp_last_step < $2
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (r *userRepository) EnableTOTPTx(ctx context.Context, tx *sql.Tx, userID string, step int64) error {
+	query := `
+		UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 
-	if time.Now().After(expiresAt) {
-		return errs.ErrTokenExpires
+	if rows == 0 {
+		return errs.ErrMFAAlreadyEnabled
 	}
 	return nil
 }
 
-func (r *userRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
-	query := `UPDATE refresh_tokens SET revoked = TRUE WHERE token = $1`
-	res, err := tx.ExecContext(ctx, query, token)
+func (r *userRepository) DisableTOTPTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	query := `
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
+
+// ReplaceRecoveryCodesTx : old codes stop working
+func (r *userRepository) ReplaceRecoveryCodesTx(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx, query, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *userRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	query := `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// InsertOIDCState : expired states of abandoned logins are removed on the way
+func (r *userRepository) InsertOIDCState(ctx context.Context, st *user.OIDCState) error {
+	query := `
+		WITH expired AS (DELETE FROM oidc_login_states WHERE expires_at < NOW())
+		INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.ExecContext(ctx, query, st.StateHash, st.Provider, st.Nonce, st.CodeVerifier, st.ExpiresAt)
+	return err
+}
+
+// UseOIDCState : single use, a replayed callback finds nothing
+func (r *userRepository) UseOIDCState(ctx context.Context, stateHash, provider string) (*user.OIDCState, error) {
+	st := new(user.OIDCState)
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND provider = $2 AND expires_at > NOW()
+		RETURNING state_hash, provider, nonce, code_verifier, expires_at
+	`
+	err := r.db.QueryRowContext(ctx, query, stateHash, provider).Scan(
+		&st.StateHash,
+		&st.Provider,
+		&st.Nonce,
+		&st.CodeVerifier,
+		&st.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+	return st, nil
+}
+
+func (r *userRepository) FindUserByIdentity(ctx context.Context, provider, subject string) (*user.User, error) {
+	var u user.User
+	query := `
+		SELECT u.id, u.email, u.role, u.email_verified_at, u.totp_enabled_at, u.disabled_at
+		FROM user_identities i JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2 AND u.deleted_at IS NULL LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&u.ID,
+		&u.Email,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.TOTPEnabledAt,
+		&u.DisabledAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) InsertIdentityTx(ctx context.Context, tx *sql.Tx, identity *user.Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at
+	`
+	err := tx.QueryRowContext(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		// Another account of the same provider is already linked
+		if strings.Contains(err.Error(), "user_identities_user_provider_key") ||
+			strings.Contains(err.Error(), "user_identities_provider_subject_key") {
+			return errs.ErrOIDCLinkConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) ListIdentities(ctx context.Context, userID string) ([]*user.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities WHERE user_id = $1 ORDER BY created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := make([]*user.Identity, 0)
+	for rows.Next() {
+		i := new(user.Identity)
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+func (r *userRepository) InsertAPIKey(ctx context.Context, key *user.APIKey) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(ctx, query, key.Name, key.Prefix, key.Hash, pq.Array(key.Scopes), key.CreatedBy, key.ExpiresAt).Scan(
+		&key.ID,
+		&key.CreatedAt,
+	)
+}
+
+func (r *userRepository) ListAPIKeys(ctx context.Context) ([]*user.APIKey, error) {
+	query := `
+		SELECT id, name, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*user.APIKey, 0)
+	for rows.Next() {
+		k := new(user.APIKey)
+		var scopes []string
+		if err := rows.Scan(
+			&k.ID,
+			&k.Name,
+			&k.Prefix,
+			pq.Array(&scopes),
+			&k.CreatedBy,
+			&k.ExpiresAt,
+			&k.LastUsedAt,
+			&k.RevokedAt,
+			&k.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		k.Scopes = toPermissions(scopes)
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// FindAPIKeyByHash : with the current role of its creator, keys of deleted or disabled users are not found
+func (r *userRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*user.APIKey, error) {
+	k := new(user.APIKey)
+	var scopes []string
+	query := `
+		SELECT k.id, k.name, k.prefix, k.scopes, k.created_by, k.expires_at, k.last_used_at, k.revoked_at, k.created_at, u.role
+		FROM api_keys k JOIN users u ON u.id = k.created_by
+		WHERE k.key_hash = $1 AND u.deleted_at IS NULL AND u.disabled_at IS NULL
+	`
+	err := r.db.QueryRowContext(ctx, query, keyHash).Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&scopes),
+		&k.CreatedBy,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+		&k.CreatedAt,
+		&k.OwnerRole,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	k.Scopes = toPermissions(scopes)
+	return k, nil
+}
+
+func (r *userRepository) RevokeAPIKey(ctx context.Context, keyID string) error {
+	// Compare as text: a malformed id is just not found
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id::TEXT = $1 AND revoked_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, keyID)
 	if err != nil {
 		return err
 	}
@@ -153,9 +989,232 @@ func (r *userRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx,
 	if err != nil {
 		return err
 	}
+	if rows == 0 {
+		return errs.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, keyID, usedAt)
+	return err
+}
+
+func toPermissions(scopes []string) []user.Permission {
+	perms := make([]user.Permission, len(scopes))
+	for i, s := range scopes {
+		perms[i] = user.Permission(s)
+	}
+	return perms
+}
+
+// adminUserQuery : account lockouts live in login_throttles under account:<lowercase email>
+const adminUserQuery = `
+	SELECT u.id, u.email, COALESCE(u.name, ''), u.pending_email, u.role, u.email_verified_at, u.totp_enabled_at,
+		u.disabled_at, u.created_at, u.updated_at, CASE WHEN t.locked_until > NOW() THEN t.locked_until END
+	FROM users u
+	LEFT JOIN login_throttles t ON t.key = 'account:' || LOWER(TRIM(u.email))
+`
+
+// FindUsers : newest first, deleted accounts are never listed
+func (r *userRepository) FindUsers(ctx context.Context, filter user.UserFilter, limit, offset int) ([]*user.AdminUser, int64, error) {
+	where := `
+		WHERE u.deleted_at IS NULL
+			AND ($1 = '' OR u.email ILIKE '%' || $1 || '%' ESCAPE '\')
+			AND ($2 = '' OR u.role = $2)
+			AND ($3::BOOLEAN IS NULL OR (u.email_verified_at IS NOT NULL) = $3)
+			AND ($4::BOOLEAN IS NULL OR COALESCE(t.locked_until > NOW(), FALSE) = $4)
+			AND ($5::BOOLEAN IS NULL OR (u.disabled_at IS NOT NULL) = $5)
+			AND ($6::TIMESTAMPTZ IS NULL OR u.created_at >= $6)
+			AND ($7::TIMESTAMPTZ IS NULL OR u.created_at < $7)
+	`
+	args := []any{
+		escapeLike(filter.Email),
+		filter.Role,
+		filter.Verified,
+		filter.Locked,
+		filter.Disabled,
+		filter.CreatedFrom,
+		filter.CreatedTo,
+	}
+
+	query := adminUserQuery + where + `ORDER BY u.created_at DESC, u.id LIMIT $8 OFFSET $9`
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*user.AdminUser
+	for rows.Next() {
+		u, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
 
+	// --- Count Users
+	var total int64
+	queryCount := `
+		SELECT COUNT(*) FROM users u
+		LEFT JOIN login_throttles t ON t.key = 'account:' || LOWER(TRIM(u.email))
+	` + where
+	if err := r.db.QueryRowContext(ctx, queryCount, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepository) FindAdminUser(ctx context.Context, userID string) (*user.AdminUser, error) {
+	query := adminUserQuery + `WHERE u.id::TEXT = $1 AND u.deleted_at IS NULL`
+	u, err := scanAdminUser(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// FindUserOrderStats : every order is counted, spend leaves out orders in excluded statuses and subtracts refunds
+func (r *userRepository) FindUserOrderStats(ctx context.Context, userID string, excluded []string) (int64, int64, error) {
+	var count, spend int64
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(o.total_amount - COALESCE(rf.amount, 0)) FILTER (WHERE o.status <> ALL($2)), 0)
+		FROM orders o
+		LEFT JOIN LATERAL (SELECT SUM(amount) AS amount FROM refunds WHERE order_id = o.id) rf ON TRUE
+		WHERE o.user_id = $1
+	`
+	if err := r.db.QueryRowContext(ctx, query, userID, pq.Array(excluded)).Scan(&count, &spend); err != nil {
+		return 0, 0, err
+	}
+	return count, spend, nil
+}
+
+// DisableUserTx : disabling again keeps the first date
+func (r *userRepository) DisableUserTx(ctx context.Context, tx *sql.Tx, userID string, at time.Time) error {
+	query := `
+		UPDATE users SET disabled_at = COALESCE(disabled_at, $2), updated_at = NOW()
+		WHERE id::TEXT = $1 AND deleted_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, userID, at)
+	if err != nil {
+		return err
+	}
+	return userAffected(res)
+}
+
+func (r *userRepository) EnableUser(ctx context.Context, userID string) error {
+	query := `UPDATE users SET disabled_at = NULL, updated_at = NOW() WHERE id::TEXT = $1 AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	return userAffected(res)
+}
+
+func (r *userRepository) InsertImpersonation(ctx context.Context, imp *user.Impersonation) error {
+	query := `
+		INSERT INTO impersonations (id, actor_id, user_id, reason, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6) RETURNING created_at
+	`
+	return r.db.QueryRowContext(ctx, query, imp.ID, imp.ActorID, imp.UserID, imp.Reason, imp.IPAddress, imp.ExpiresAt).Scan(&imp.CreatedAt)
+}
+
+// FindActiveImpersonationsByActor : impersonations the actor started that have not expired yet
+func (r *userRepository) FindActiveImpersonationsByActor(ctx context.Context, actorID string) ([]*user.Impersonation, error) {
+	query := `
+		SELECT id, actor_id, user_id, reason, COALESCE(ip_address, ''), expires_at, created_at
+		FROM impersonations
+		WHERE actor_id = $1 AND expires_at > NOW()
+	`
+	rows, err := r.db.QueryContext(ctx, query, actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imps := make([]*user.Impersonation, 0)
+	for rows.Next() {
+		var imp user.Impersonation
+		if err := rows.Scan(
+			&imp.ID,
+			&imp.ActorID,
+			&imp.UserID,
+			&imp.Reason,
+			&imp.IPAddress,
+			&imp.ExpiresAt,
+			&imp.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		imps = append(imps, &imp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return imps, nil
+}
+
+// InsertImpersonationRequest : status stays NULL until the request completes
+func (r *userRepository) InsertImpersonationRequest(ctx context.Context, req *user.ImpersonationRequest) error {
+	query := `
+		INSERT INTO impersonation_requests (impersonation_id, method, path, ip_address)
+		VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id
+	`
+	return r.db.QueryRowContext(ctx, query, req.ImpersonationID, req.Method, req.Path, req.IPAddress).Scan(&req.ID)
+}
+
+func (r *userRepository) UpdateImpersonationRequestStatus(ctx context.Context, id int64, status int) error {
+	query := `UPDATE impersonation_requests SET status = $1 WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, status, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAdminUser(row rowScanner) (*user.AdminUser, error) {
+	u := &user.AdminUser{User: new(user.User)}
+	if err := row.Scan(
+		&u.ID,
+		&u.Email,
+		&u.Name,
+		&u.PendingEmail,
+		&u.Role,
+		&u.EmailVerifiedAt,
+		&u.TOTPEnabledAt,
+		&u.DisabledAt,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&u.LockedUntil,
+	); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func userAffected(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if rows == 0 {
-		return errs.ErrTokenNotFound
+		return errs.ErrUserNotFound
 	}
 	return nil
 }
+
+// escapeLike : the search text is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -8,6 +8,7 @@ import (
 	context "context"
 	sql "database/sql"
 	reflect "reflect"
+	time "time"
 
 	user "github.com/codepnw/go-starter-kit/internal/features/user"
 	gomock "github.com/golang/mock/gomock"
@@ -36,6 +37,49 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddUserTokenAttempt mocks base method.
+func (m *MockUserRepository) AddUserTokenAttempt(ctx context.Context, tokenID string, maxAttempts int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUserTokenAttempt", ctx, tokenID, maxAttempts)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddUserTokenAttempt indicates an expected call of AddUserTokenAttempt.
+func (mr *MockUserRepositoryMockRecorder) AddUserTokenAttempt(ctx, tokenID, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserTokenAttempt", reflect.TypeOf((*MockUserRepository)(nil).AddUserTokenAttempt), ctx, tokenID, maxAttempts)
+}
+
+// AnonymizeUserTx mocks base method.
+func (m *MockUserRepository) AnonymizeUserTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeUserTx", ctx, tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnonymizeUserTx indicates an expected call of AnonymizeUserTx.
+func (mr *MockUserRepositoryMockRecorder) AnonymizeUserTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeUserTx", reflect.TypeOf((*MockUserRepository)(nil).AnonymizeUserTx), ctx, tx, userID)
+}
+
+// ApplyPendingEmailTx mocks base method.
+func (m *MockUserRepository) ApplyPendingEmailTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyPendingEmailTx", ctx, tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyPendingEmailTx indicates an expected call of ApplyPendingEmailTx.
+func (mr *MockUserRepositoryMockRecorder) ApplyPendingEmailTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyPendingEmailTx", reflect.TypeOf((*MockUserRepository)(nil).ApplyPendingEmailTx), ctx, tx, userID)
+}
+
 // CheckEmailExists mocks base method.
 func (m *MockUserRepository) CheckEmailExists(ctx context.Context, email string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +95,167 @@ func (mr *MockUserRepositoryMockRecorder) CheckEmailExists(ctx, email interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckEmailExists", reflect.TypeOf((*MockUserRepository)(nil).CheckEmailExists), ctx, email)
 }
 
+// CheckRoleExists mocks base method.
+func (m *MockUserRepository) CheckRoleExists(ctx context.Context, role user.Role) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckRoleExists", ctx, role)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckRoleExists indicates an expected call of CheckRoleExists.
+func (mr *MockUserRepositoryMockRecorder) CheckRoleExists(ctx, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRoleExists", reflect.TypeOf((*MockUserRepository)(nil).CheckRoleExists), ctx, role)
+}
+
+// CountUserTokensSince mocks base method.
+func (m *MockUserRepository) CountUserTokensSince(ctx context.Context, userID string, purpose user.TokenPurpose, since time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUserTokensSince", ctx, userID, purpose, since)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUserTokensSince indicates an expected call of CountUserTokensSince.
+func (mr *MockUserRepositoryMockRecorder) CountUserTokensSince(ctx, userID, purpose, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUserTokensSince", reflect.TypeOf((*MockUserRepository)(nil).CountUserTokensSince), ctx, userID, purpose, since)
+}
+
+// DisableTOTPTx mocks base method.
+func (m *MockUserRepository) DisableTOTPTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTPTx", ctx, tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTPTx indicates an expected call of DisableTOTPTx.
+func (mr *MockUserRepositoryMockRecorder) DisableTOTPTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTPTx", reflect.TypeOf((*MockUserRepository)(nil).DisableTOTPTx), ctx, tx, userID)
+}
+
+// DisableUserTx mocks base method.
+func (m *MockUserRepository) DisableUserTx(ctx context.Context, tx *sql.Tx, userID string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUserTx", ctx, tx, userID, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableUserTx indicates an expected call of DisableUserTx.
+func (mr *MockUserRepositoryMockRecorder) DisableUserTx(ctx, tx, userID, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUserTx", reflect.TypeOf((*MockUserRepository)(nil).DisableUserTx), ctx, tx, userID, at)
+}
+
+// EnableTOTPTx mocks base method.
+func (m *MockUserRepository) EnableTOTPTx(ctx context.Context, tx *sql.Tx, userID string, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTPTx", ctx, tx, userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTPTx indicates an expected call of EnableTOTPTx.
+func (mr *MockUserRepositoryMockRecorder) EnableTOTPTx(ctx, tx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTPTx", reflect.TypeOf((*MockUserRepository)(nil).EnableTOTPTx), ctx, tx, userID, step)
+}
+
+// EnableUser mocks base method.
+func (m *MockUserRepository) EnableUser(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUser", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableUser indicates an expected call of EnableUser.
+func (mr *MockUserRepositoryMockRecorder) EnableUser(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUser", reflect.TypeOf((*MockUserRepository)(nil).EnableUser), ctx, userID)
+}
+
+// FindAPIKeyByHash mocks base method.
+func (m *MockUserRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*user.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKeyByHash", ctx, keyHash)
+	ret0, _ := ret[0].(*user.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKeyByHash indicates an expected call of FindAPIKeyByHash.
+func (mr *MockUserRepositoryMockRecorder) FindAPIKeyByHash(ctx, keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKeyByHash", reflect.TypeOf((*MockUserRepository)(nil).FindAPIKeyByHash), ctx, keyHash)
+}
+
+// FindActiveImpersonationsByActor mocks base method.
+func (m *MockUserRepository) FindActiveImpersonationsByActor(ctx context.Context, actorID string) ([]*user.Impersonation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindActiveImpersonationsByActor", ctx, actorID)
+	ret0, _ := ret[0].([]*user.Impersonation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindActiveImpersonationsByActor indicates an expected call of FindActiveImpersonationsByActor.
+func (mr *MockUserRepositoryMockRecorder) FindActiveImpersonationsByActor(ctx, actorID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindActiveImpersonationsByActor", reflect.TypeOf((*MockUserRepository)(nil).FindActiveImpersonationsByActor), ctx, actorID)
+}
+
+// FindAdminUser mocks base method.
+func (m *MockUserRepository) FindAdminUser(ctx context.Context, userID string) (*user.AdminUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAdminUser", ctx, userID)
+	ret0, _ := ret[0].(*user.AdminUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAdminUser indicates an expected call of FindAdminUser.
+func (mr *MockUserRepositoryMockRecorder) FindAdminUser(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAdminUser", reflect.TypeOf((*MockUserRepository)(nil).FindAdminUser), ctx, userID)
+}
+
+// FindSessions mocks base method.
+func (m *MockUserRepository) FindSessions(ctx context.Context, userID string) ([]*user.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSessions", ctx, userID)
+	ret0, _ := ret[0].([]*user.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSessions indicates an expected call of FindSessions.
+func (mr *MockUserRepositoryMockRecorder) FindSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSessions", reflect.TypeOf((*MockUserRepository)(nil).FindSessions), ctx, userID)
+}
+
+// FindTOTP mocks base method.
+func (m *MockUserRepository) FindTOTP(ctx context.Context, userID string) (*user.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTOTP", ctx, userID)
+	ret0, _ := ret[0].(*user.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTOTP indicates an expected call of FindTOTP.
+func (mr *MockUserRepositoryMockRecorder) FindTOTP(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTOTP", reflect.TypeOf((*MockUserRepository)(nil).FindTOTP), ctx, userID)
+}
+
 // FindUserByEmail mocks base method.
 func (m *MockUserRepository) FindUserByEmail(ctx context.Context, email string) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -81,6 +286,138 @@ func (mr *MockUserRepositoryMockRecorder) FindUserByID(ctx, userID interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByID", reflect.TypeOf((*MockUserRepository)(nil).FindUserByID), ctx, userID)
 }
 
+// FindUserByIdentity mocks base method.
+func (m *MockUserRepository) FindUserByIdentity(ctx context.Context, provider, subject string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserByIdentity", ctx, provider, subject)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserByIdentity indicates an expected call of FindUserByIdentity.
+func (mr *MockUserRepositoryMockRecorder) FindUserByIdentity(ctx, provider, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByIdentity", reflect.TypeOf((*MockUserRepository)(nil).FindUserByIdentity), ctx, provider, subject)
+}
+
+// FindUserOrderStats mocks base method.
+func (m *MockUserRepository) FindUserOrderStats(ctx context.Context, userID string, excluded []string) (int64, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserOrderStats", ctx, userID, excluded)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindUserOrderStats indicates an expected call of FindUserOrderStats.
+func (mr *MockUserRepositoryMockRecorder) FindUserOrderStats(ctx, userID, excluded interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserOrderStats", reflect.TypeOf((*MockUserRepository)(nil).FindUserOrderStats), ctx, userID, excluded)
+}
+
+// FindUserToken mocks base method.
+func (m *MockUserRepository) FindUserToken(ctx context.Context, tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserToken", ctx, tokenHash, purpose)
+	ret0, _ := ret[0].(*user.UserToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserToken indicates an expected call of FindUserToken.
+func (mr *MockUserRepositoryMockRecorder) FindUserToken(ctx, tokenHash, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserToken", reflect.TypeOf((*MockUserRepository)(nil).FindUserToken), ctx, tokenHash, purpose)
+}
+
+// FindUsers mocks base method.
+func (m *MockUserRepository) FindUsers(ctx context.Context, filter user.UserFilter, limit, offset int) ([]*user.AdminUser, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUsers", ctx, filter, limit, offset)
+	ret0, _ := ret[0].([]*user.AdminUser)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindUsers indicates an expected call of FindUsers.
+func (mr *MockUserRepositoryMockRecorder) FindUsers(ctx, filter, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockUserRepository)(nil).FindUsers), ctx, filter, limit, offset)
+}
+
+// InsertAPIKey mocks base method.
+func (m *MockUserRepository) InsertAPIKey(ctx context.Context, key *user.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertAPIKey indicates an expected call of InsertAPIKey.
+func (mr *MockUserRepositoryMockRecorder) InsertAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAPIKey", reflect.TypeOf((*MockUserRepository)(nil).InsertAPIKey), ctx, key)
+}
+
+// InsertIdentityTx mocks base method.
+func (m *MockUserRepository) InsertIdentityTx(ctx context.Context, tx *sql.Tx, identity *user.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertIdentityTx", ctx, tx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertIdentityTx indicates an expected call of InsertIdentityTx.
+func (mr *MockUserRepositoryMockRecorder) InsertIdentityTx(ctx, tx, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertIdentityTx", reflect.TypeOf((*MockUserRepository)(nil).InsertIdentityTx), ctx, tx, identity)
+}
+
+// InsertImpersonation mocks base method.
+func (m *MockUserRepository) InsertImpersonation(ctx context.Context, imp *user.Impersonation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertImpersonation", ctx, imp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertImpersonation indicates an expected call of InsertImpersonation.
+func (mr *MockUserRepositoryMockRecorder) InsertImpersonation(ctx, imp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertImpersonation", reflect.TypeOf((*MockUserRepository)(nil).InsertImpersonation), ctx, imp)
+}
+
+// InsertImpersonationRequest mocks base method.
+func (m *MockUserRepository) InsertImpersonationRequest(ctx context.Context, req *user.ImpersonationRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertImpersonationRequest", ctx, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertImpersonationRequest indicates an expected call of InsertImpersonationRequest.
+func (mr *MockUserRepositoryMockRecorder) InsertImpersonationRequest(ctx, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertImpersonationRequest", reflect.TypeOf((*MockUserRepository)(nil).InsertImpersonationRequest), ctx, req)
+}
+
+// InsertOIDCState mocks base method.
+func (m *MockUserRepository) InsertOIDCState(ctx context.Context, st *user.OIDCState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertOIDCState", ctx, st)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertOIDCState indicates an expected call of InsertOIDCState.
+func (mr *MockUserRepositoryMockRecorder) InsertOIDCState(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertOIDCState", reflect.TypeOf((*MockUserRepository)(nil).InsertOIDCState), ctx, st)
+}
+
 // InsertRefreshTokenTx mocks base method.
 func (m *MockUserRepository) InsertRefreshTokenTx(ctx context.Context, tx *sql.Tx, token *user.RefreshToken) error {
 	m.ctrl.T.Helper()
@@ -95,6 +432,20 @@ func (mr *MockUserRepositoryMockRecorder) InsertRefreshTokenTx(ctx, tx, token in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRefreshTokenTx", reflect.TypeOf((*MockUserRepository)(nil).InsertRefreshTokenTx), ctx, tx, token)
 }
 
+// InsertUserTokenTx mocks base method.
+func (m *MockUserRepository) InsertUserTokenTx(ctx context.Context, tx *sql.Tx, t *user.UserToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertUserTokenTx", ctx, tx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertUserTokenTx indicates an expected call of InsertUserTokenTx.
+func (mr *MockUserRepositoryMockRecorder) InsertUserTokenTx(ctx, tx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUserTokenTx", reflect.TypeOf((*MockUserRepository)(nil).InsertUserTokenTx), ctx, tx, t)
+}
+
 // InsertUserTx mocks base method.
 func (m *MockUserRepository) InsertUserTx(ctx context.Context, tx *sql.Tx, u *user.User) error {
 	m.ctrl.T.Helper()
@@ -109,30 +460,430 @@ func (mr *MockUserRepositoryMockRecorder) InsertUserTx(ctx, tx, u interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUserTx", reflect.TypeOf((*MockUserRepository)(nil).InsertUserTx), ctx, tx, u)
 }
 
+// InvalidateUserTokensTx mocks base method.
+func (m *MockUserRepository) InvalidateUserTokensTx(ctx context.Context, tx *sql.Tx, userID string, purpose user.TokenPurpose) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateUserTokensTx", ctx, tx, userID, purpose)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateUserTokensTx indicates an expected call of InvalidateUserTokensTx.
+func (mr *MockUserRepositoryMockRecorder) InvalidateUserTokensTx(ctx, tx, userID, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateUserTokensTx", reflect.TypeOf((*MockUserRepository)(nil).InvalidateUserTokensTx), ctx, tx, userID, purpose)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockUserRepository) ListAPIKeys(ctx context.Context) ([]*user.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx)
+	ret0, _ := ret[0].([]*user.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockUserRepositoryMockRecorder) ListAPIKeys(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockUserRepository)(nil).ListAPIKeys), ctx)
+}
+
+// ListIdentities mocks base method.
+func (m *MockUserRepository) ListIdentities(ctx context.Context, userID string) ([]*user.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIdentities", ctx, userID)
+	ret0, _ := ret[0].([]*user.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIdentities indicates an expected call of ListIdentities.
+func (mr *MockUserRepositoryMockRecorder) ListIdentities(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIdentities", reflect.TypeOf((*MockUserRepository)(nil).ListIdentities), ctx, userID)
+}
+
+// MarkEmailVerifiedTx mocks base method.
+func (m *MockUserRepository) MarkEmailVerifiedTx(ctx context.Context, tx *sql.Tx, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerifiedTx", ctx, tx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerifiedTx indicates an expected call of MarkEmailVerifiedTx.
+func (mr *MockUserRepositoryMockRecorder) MarkEmailVerifiedTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerifiedTx", reflect.TypeOf((*MockUserRepository)(nil).MarkEmailVerifiedTx), ctx, tx, userID)
+}
+
+// RehashPassword mocks base method.
+func (m *MockUserRepository) RehashPassword(ctx context.Context, userID, oldHash, newHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RehashPassword", ctx, userID, oldHash, newHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RehashPassword indicates an expected call of RehashPassword.
+func (mr *MockUserRepositoryMockRecorder) RehashPassword(ctx, userID, oldHash, newHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashPassword", reflect.TypeOf((*MockUserRepository)(nil).RehashPassword), ctx, userID, oldHash, newHash)
+}
+
+// ReplaceRecoveryCodesTx mocks base method.
+func (m *MockUserRepository) ReplaceRecoveryCodesTx(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodesTx", ctx, tx, userID, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodesTx indicates an expected call of ReplaceRecoveryCodesTx.
+func (mr *MockUserRepositoryMockRecorder) ReplaceRecoveryCodesTx(ctx, tx, userID, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodesTx", reflect.TypeOf((*MockUserRepository)(nil).ReplaceRecoveryCodesTx), ctx, tx, userID, codeHashes)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockUserRepository) RevokeAPIKey(ctx context.Context, keyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockUserRepositoryMockRecorder) RevokeAPIKey(ctx, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockUserRepository)(nil).RevokeAPIKey), ctx, keyID)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockUserRepository) RevokeAllSessions(ctx context.Context, userID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", ctx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockUserRepositoryMockRecorder) RevokeAllSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockUserRepository)(nil).RevokeAllSessions), ctx, userID)
+}
+
+// RevokeAllSessionsTx mocks base method.
+func (m *MockUserRepository) RevokeAllSessionsTx(ctx context.Context, tx *sql.Tx, userID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessionsTx", ctx, tx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAllSessionsTx indicates an expected call of RevokeAllSessionsTx.
+func (mr *MockUserRepositoryMockRecorder) RevokeAllSessionsTx(ctx, tx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessionsTx", reflect.TypeOf((*MockUserRepository)(nil).RevokeAllSessionsTx), ctx, tx, userID)
+}
+
+// RevokeSession mocks base method.
+func (m *MockUserRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockUserRepositoryMockRecorder) RevokeSession(ctx, userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockUserRepository)(nil).RevokeSession), ctx, userID, sessionID)
+}
+
+// RevokeTokenFamily mocks base method.
+func (m *MockUserRepository) RevokeTokenFamily(ctx context.Context, familyID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeTokenFamily", ctx, familyID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeTokenFamily indicates an expected call of RevokeTokenFamily.
+func (mr *MockUserRepositoryMockRecorder) RevokeTokenFamily(ctx, familyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeTokenFamily", reflect.TypeOf((*MockUserRepository)(nil).RevokeTokenFamily), ctx, familyID)
+}
+
 // RevokedRefreshTokenTx mocks base method.
-func (m *MockUserRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, token string) error {
+func (m *MockUserRepository) RevokedRefreshTokenTx(ctx context.Context, tx *sql.Tx, tokenHash string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RevokedRefreshTokenTx", ctx, tx, token)
+	ret := m.ctrl.Call(m, "RevokedRefreshTokenTx", ctx, tx, tokenHash)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RevokedRefreshTokenTx indicates an expected call of RevokedRefreshTokenTx.
-func (mr *MockUserRepositoryMockRecorder) RevokedRefreshTokenTx(ctx, tx, token interface{}) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) RevokedRefreshTokenTx(ctx, tx, tokenHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokedRefreshTokenTx", reflect.TypeOf((*MockUserRepository)(nil).RevokedRefreshTokenTx), ctx, tx, token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokedRefreshTokenTx", reflect.TypeOf((*MockUserRepository)(nil).RevokedRefreshTokenTx), ctx, tx, tokenHash)
 }
 
-// ValidateRefreshToken mocks base method.
-func (m *MockUserRepository) ValidateRefreshToken(ctx context.Context, token string) error {
+// SetTOTPSecret mocks base method.
+func (m *MockUserRepository) SetTOTPSecret(ctx context.Context, userID, encryptedSecret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPSecret", ctx, userID, encryptedSecret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPSecret indicates an expected call of SetTOTPSecret.
+func (mr *MockUserRepositoryMockRecorder) SetTOTPSecret(ctx, userID, encryptedSecret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPSecret", reflect.TypeOf((*MockUserRepository)(nil).SetTOTPSecret), ctx, userID, encryptedSecret)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockUserRepository) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, keyID, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockUserRepositoryMockRecorder) TouchAPIKey(ctx, keyID, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockUserRepository)(nil).TouchAPIKey), ctx, keyID, usedAt)
+}
+
+// UpdateImpersonationRequestStatus mocks base method.
+func (m *MockUserRepository) UpdateImpersonationRequestStatus(ctx context.Context, id int64, status int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateImpersonationRequestStatus", ctx, id, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateImpersonationRequestStatus indicates an expected call of UpdateImpersonationRequestStatus.
+func (mr *MockUserRepositoryMockRecorder) UpdateImpersonationRequestStatus(ctx, id, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImpersonationRequestStatus", reflect.TypeOf((*MockUserRepository)(nil).UpdateImpersonationRequestStatus), ctx, id, status)
+}
+
+// UpdatePasswordTx mocks base method.
+func (m *MockUserRepository) UpdatePasswordTx(ctx context.Context, tx *sql.Tx, userID, hashedPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordTx", ctx, tx, userID, hashedPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordTx indicates an expected call of UpdatePasswordTx.
+func (mr *MockUserRepositoryMockRecorder) UpdatePasswordTx(ctx, tx, userID, hashedPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordTx", reflect.TypeOf((*MockUserRepository)(nil).UpdatePasswordTx), ctx, tx, userID, hashedPassword)
+}
+
+// UpdateProfileTx mocks base method.
+func (m *MockUserRepository) UpdateProfileTx(ctx context.Context, tx *sql.Tx, u *user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfileTx", ctx, tx, u)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfileTx indicates an expected call of UpdateProfileTx.
+func (mr *MockUserRepositoryMockRecorder) UpdateProfileTx(ctx, tx, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfileTx", reflect.TypeOf((*MockUserRepository)(nil).UpdateProfileTx), ctx, tx, u)
+}
+
+// UpdateUserRole mocks base method.
+func (m *MockUserRepository) UpdateUserRole(ctx context.Context, userID string, role user.Role) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ValidateRefreshToken", ctx, token)
+	ret := m.ctrl.Call(m, "UpdateUserRole", ctx, userID, role)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockUserRepositoryMockRecorder) UpdateUserRole(ctx, userID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserRole), ctx, userID, role)
+}
+
+// UpdateUserRoleTx mocks base method.
+func (m *MockUserRepository) UpdateUserRoleTx(ctx context.Context, tx *sql.Tx, userID string, role user.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRoleTx", ctx, tx, userID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserRoleTx indicates an expected call of UpdateUserRoleTx.
+func (mr *MockUserRepositoryMockRecorder) UpdateUserRoleTx(ctx, tx, userID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRoleTx", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserRoleTx), ctx, tx, userID, role)
+}
+
+// UseOIDCState mocks base method.
+func (m *MockUserRepository) UseOIDCState(ctx context.Context, stateHash, provider string) (*user.OIDCState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseOIDCState", ctx, stateHash, provider)
+	ret0, _ := ret[0].(*user.OIDCState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseOIDCState indicates an expected call of UseOIDCState.
+func (mr *MockUserRepositoryMockRecorder) UseOIDCState(ctx, stateHash, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseOIDCState", reflect.TypeOf((*MockUserRepository)(nil).UseOIDCState), ctx, stateHash, provider)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockUserRepositoryMockRecorder) UseRecoveryCode(ctx, userID, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserRepository)(nil).UseRecoveryCode), ctx, userID, codeHash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockUserRepositoryMockRecorder) UseTOTPStep(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockUserRepository)(nil).UseTOTPStep), ctx, userID, step)
+}
+
+// UseUserTokenTx mocks base method.
+func (m *MockUserRepository) UseUserTokenTx(ctx context.Context, tx *sql.Tx, tokenHash string, purpose user.TokenPurpose) (*user.UserToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserTokenTx", ctx, tx, tokenHash, purpose)
+	ret0, _ := ret[0].(*user.UserToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseUserTokenTx indicates an expected call of UseUserTokenTx.
+func (mr *MockUserRepositoryMockRecorder) UseUserTokenTx(ctx, tx, tokenHash, purpose interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserTokenTx", reflect.TypeOf((*MockUserRepository)(nil).UseUserTokenTx), ctx, tx, tokenHash, purpose)
+}
+
+// ValidateRefreshToken mocks base method.
+func (m *MockUserRepository) ValidateRefreshToken(ctx context.Context, tokenHash string) (*user.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateRefreshToken", ctx, tokenHash)
+	ret0, _ := ret[0].(*user.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // ValidateRefreshToken indicates an expected call of ValidateRefreshToken.
-func (mr *MockUserRepositoryMockRecorder) ValidateRefreshToken(ctx, token interface{}) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) ValidateRefreshToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateRefreshToken", reflect.TypeOf((*MockUserRepository)(nil).ValidateRefreshToken), ctx, tokenHash)
+}
+
+// Mockexecer is a mock of execer interface.
+type Mockexecer struct {
+	ctrl     *gomock.Controller
+	recorder *MockexecerMockRecorder
+}
+
+// MockexecerMockRecorder is the mock recorder for Mockexecer.
+type MockexecerMockRecorder struct {
+	mock *Mockexecer
+}
+
+// NewMockexecer creates a new mock instance.
+func NewMockexecer(ctrl *gomock.Controller) *Mockexecer {
+	mock := &Mockexecer{ctrl: ctrl}
+	mock.recorder = &MockexecerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockexecer) EXPECT() *MockexecerMockRecorder {
+	return m.recorder
+}
+
+// ExecContext mocks base method.
+func (m *Mockexecer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecContext", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecContext indicates an expected call of ExecContext.
+func (mr *MockexecerMockRecorder) ExecContext(ctx, query interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*Mockexecer)(nil).ExecContext), varargs...)
+}
+
+// MockrowScanner is a mock of rowScanner interface.
+type MockrowScanner struct {
+	ctrl     *gomock.Controller
+	recorder *MockrowScannerMockRecorder
+}
+
+// MockrowScannerMockRecorder is the mock recorder for MockrowScanner.
+type MockrowScannerMockRecorder struct {
+	mock *MockrowScanner
+}
+
+// NewMockrowScanner creates a new mock instance.
+func NewMockrowScanner(ctrl *gomock.Controller) *MockrowScanner {
+	mock := &MockrowScanner{ctrl: ctrl}
+	mock.recorder = &MockrowScannerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockrowScanner) EXPECT() *MockrowScannerMockRecorder {
+	return m.recorder
+}
+
+// Scan mocks base method.
+func (m *MockrowScanner) Scan(dest ...any) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range dest {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Scan", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockrowScannerMockRecorder) Scan(dest ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateRefreshToken", reflect.TypeOf((*MockUserRepository)(nil).ValidateRefreshToken), ctx, token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockrowScanner)(nil).Scan), dest...)
 }
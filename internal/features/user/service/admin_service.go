@@ -0,0 +1,188 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"math"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/order"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+)
+
+// Users per page in the admin search
+const (
+	defaultUsersLimit = 20
+	maxUsersLimit     = 100
+)
+
+// Orders that never took money, left out of the lifetime spend
+var unpaidOrderStatuses = []string{string(order.StatusPending), string(order.StatusCancelled)}
+
+func (s *userService) ListUsers(ctx context.Context, filter user.UserFilter, page, limit int) (*user.UserListResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermCustomersManage); err != nil {
+		return nil, err
+	}
+	if filter.Role != "" && !filter.Role.IsValid() {
+		return nil, errs.ErrInvalidRole
+	}
+
+	page, limit, offset := paginate(page, limit)
+
+	users, total, err := s.repo.FindUsers(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []*user.AdminUser{}
+	}
+
+	totalPage := int(math.Ceil(float64(total) / float64(limit)))
+	return &user.UserListResponse{
+		Users:       users,
+		TotalUsers:  total,
+		Page:        page,
+		Limit:       limit,
+		TotalPage:   totalPage,
+		HasNextPage: page < totalPage,
+		HasPrevPage: page > 1,
+	}, nil
+}
+
+func (s *userService) GetUser(ctx context.Context, userID string) (*user.AdminUserDetails, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequirePermission(ctx, user.PermCustomersManage); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.FindAdminUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, spend, err := s.repo.FindUserOrderStats(ctx, u.ID, unpaidOrderStatuses)
+	if err != nil {
+		return nil, err
+	}
+	return &user.AdminUserDetails{AdminUser: u, OrderCount: count, LifetimeSpend: spend}, nil
+}
+
+// DisableUser : login and refresh are refused and every session is revoked,
+// access tokens already issued are revoked too
+func (s *userService) DisableUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	target, err := s.manageableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	callerID, _ := auth.GetUserIDFromContext(ctx)
+	if callerID == target.ID {
+		return errs.ErrCannotDisableSelf
+	}
+
+	var revoked int64
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.DisableUserTx(ctx, tx, target.ID, s.cfg.Now()); err != nil {
+			return err
+		}
+		revoked, err = s.repo.RevokeAllSessionsTx(ctx, tx, target.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.revokeUserTokens(ctx, target.ID); err != nil {
+		return err
+	}
+	if target.Role == user.RoleAdmin {
+		if err := s.revokeIssuedImpersonations(ctx, target.ID); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("account disabled", slog.String("user_id", target.ID), slog.String("by", callerID), slog.Int64("revoked", revoked))
+	return nil
+}
+
+func (s *userService) EnableUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	target, err := s.manageableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.EnableUser(ctx, target.ID); err != nil {
+		return err
+	}
+
+	callerID, _ := auth.GetUserIDFromContext(ctx)
+	slog.Info("account enabled", slog.String("user_id", target.ID), slog.String("by", callerID))
+	return nil
+}
+
+// ForceLogout : revokes every session and access token of the user
+func (s *userService) ForceLogout(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	target, err := s.manageableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.repo.RevokeAllSessions(ctx, target.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.revokeUserTokens(ctx, target.ID); err != nil {
+		return err
+	}
+
+	callerID, _ := auth.GetUserIDFromContext(ctx)
+	slog.Info("user logged out by staff", slog.String("user_id", target.ID), slog.String("by", callerID), slog.Int64("revoked", count))
+	return nil
+}
+
+// manageableUser : staff manage customers, staff and admin accounts need users:manage
+func (s *userService) manageableUser(ctx context.Context, userID string) (*user.AdminUser, error) {
+	if err := auth.RequirePermission(ctx, user.PermCustomersManage); err != nil {
+		return nil, err
+	}
+
+	target, err := s.repo.FindAdminUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role != user.RoleCustomer {
+		if err := auth.RequirePermission(ctx, user.PermUsersManage); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+func paginate(page, limit int) (int, int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = defaultUsersLimit
+	}
+	if limit > maxUsersLimit {
+		limit = maxUsersLimit
+	}
+	return page, limit, (page - 1) * limit
+}
@@ -0,0 +1,268 @@
+package userservice_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	staffCtx = auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "mock-staff-uuid", Role: user.RoleStaff})
+	adminCtx = auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "mock-admin-uuid", Role: user.RoleAdmin})
+)
+
+func adminUser(id string, role user.Role) *user.AdminUser {
+	return &user.AdminUser{User: &user.User{ID: id, Email: id + "@mail.com", Role: role}}
+}
+
+// setupRevoke : service and denylist share mockNow, revoked user tokens can be checked
+func setupRevoke(t *testing.T) (*database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService, *denylist.MemoryStore) {
+	cfg := defaultConfig
+	cfg.Now = func() time.Time { return mockNow }
+	deny := denylist.NewMemoryStore().WithClock(cfg.Now)
+
+	_, mockTx, mockRepo, service, _ := setupWithDenylist(t, cfg, deny)
+	return mockTx, mockRepo, service, deny
+}
+
+// assertUserTokensRevoked : tokens issued before mockNow are revoked, later ones are not
+func assertUserTokensRevoked(t *testing.T, deny denylist.Store, userID string, expected bool) {
+	revoked, err := deny.UserRevoked(context.Background(), userID, mockNow.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, revoked)
+
+	revoked, _ = deny.UserRevoked(context.Background(), userID, mockNow.Add(time.Second))
+	assert.False(t, revoked)
+}
+
+func TestListUsers(t *testing.T) {
+	verified := true
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		filter := user.UserFilter{Email: "mail", Role: user.RoleCustomer, Verified: &verified, CreatedFrom: &from}
+
+		mockRepo.EXPECT().FindUsers(gomock.Any(), filter, 20, 20).Return([]*user.AdminUser{adminUser("mock-uuid-1", user.RoleCustomer)}, int64(21), nil).Times(1)
+
+		resp, err := service.ListUsers(staffCtx, filter, 2, 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, resp.Users, 1)
+		assert.Equal(t, int64(21), resp.TotalUsers)
+		assert.Equal(t, 2, resp.TotalPage)
+		assert.False(t, resp.HasNextPage)
+		assert.True(t, resp.HasPrevPage)
+	})
+
+	t.Run("success limit capped and empty list", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockRepo.EXPECT().FindUsers(gomock.Any(), user.UserFilter{}, 100, 0).Return(nil, int64(0), nil).Times(1)
+
+		resp, err := service.ListUsers(staffCtx, user.UserFilter{}, 1, 500)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, resp.Users)
+		assert.Empty(t, resp.Users)
+		assert.Equal(t, 100, resp.Limit)
+	})
+
+	t.Run("fail invalid role", func(t *testing.T) {
+		_, _, _, service := setup(t)
+
+		_, err := service.ListUsers(staffCtx, user.UserFilter{Role: "owner"}, 1, 20)
+		assert.ErrorIs(t, err, errs.ErrInvalidRole)
+	})
+
+	t.Run("fail customer", func(t *testing.T) {
+		_, _, _, service := setup(t)
+		ctx := auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "mock-uuid-1", Role: user.RoleCustomer})
+
+		_, err := service.ListUsers(ctx, user.UserFilter{}, 1, 20)
+		assert.ErrorIs(t, err, errs.ErrForbidden)
+	})
+}
+
+func TestGetUser(t *testing.T) {
+	t.Run("success with order stats", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockRepo.EXPECT().FindAdminUser(gomock.Any(), "mock-uuid-1").Return(adminUser("mock-uuid-1", user.RoleCustomer), nil).Times(1)
+		mockRepo.EXPECT().FindUserOrderStats(gomock.Any(), "mock-uuid-1", []string{"PENDING", "CANCELLED"}).Return(int64(3), int64(2500), nil).Times(1)
+
+		resp, err := service.GetUser(staffCtx, "mock-uuid-1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "mock-uuid-1", resp.ID)
+		assert.Equal(t, int64(3), resp.OrderCount)
+		assert.Equal(t, int64(2500), resp.LifetimeSpend)
+	})
+
+	t.Run("fail not found", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockRepo.EXPECT().FindAdminUser(gomock.Any(), "mock-uuid-x").Return(nil, errs.ErrUserNotFound).Times(1)
+
+		_, err := service.GetUser(staffCtx, "mock-uuid-x")
+		assert.ErrorIs(t, err, errs.ErrUserNotFound)
+	})
+}
+
+func TestDisableUser(t *testing.T) {
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		target      *user.AdminUser
+		mockFn      func(mockRepo *userrepository.MockUserRepository)
+		revokedJTI  string // impersonation token the target issued
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success staff disables customer",
+			ctx:    staffCtx,
+			target: adminUser("mock-uuid-1", user.RoleCustomer),
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().DisableUserTx(gomock.Any(), nil, "mock-uuid-1", mockNow).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, "mock-uuid-1").Return(int64(2), nil).Times(1)
+			},
+		},
+		{
+			name:   "success admin disables staff",
+			ctx:    adminCtx,
+			target: adminUser("mock-staff-uuid", user.RoleStaff),
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().DisableUserTx(gomock.Any(), nil, "mock-staff-uuid", mockNow).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, "mock-staff-uuid").Return(int64(0), nil).Times(1)
+			},
+		},
+		{
+			name:   "success admin disables admin revokes impersonations",
+			ctx:    adminCtx,
+			target: adminUser("mock-admin-2", user.RoleAdmin),
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().DisableUserTx(gomock.Any(), nil, "mock-admin-2", mockNow).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, "mock-admin-2").Return(int64(1), nil).Times(1)
+				mockRepo.EXPECT().FindActiveImpersonationsByActor(gomock.Any(), "mock-admin-2").Return([]*user.Impersonation{
+					{ID: "mock-imp-jti", ActorID: "mock-admin-2", UserID: "mock-uuid-1", ExpiresAt: mockNow.Add(time.Hour)},
+				}, nil).Times(1)
+			},
+			revokedJTI: "mock-imp-jti",
+		},
+		{
+			name:        "fail staff disables admin",
+			ctx:         staffCtx,
+			target:      adminUser("mock-admin-uuid", user.RoleAdmin),
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrForbidden,
+		},
+		{
+			name:        "fail disable self",
+			ctx:         adminCtx,
+			target:      adminUser("mock-admin-uuid", user.RoleAdmin),
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrCannotDisableSelf,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTx, mockRepo, service, deny := setupRevoke(t)
+			mockRepo.EXPECT().FindAdminUser(gomock.Any(), tc.target.ID).Return(tc.target, nil).Times(1)
+			if tc.expectedErr == nil {
+				withTx(mockTx)
+			}
+			tc.mockFn(mockRepo)
+
+			err := service.DisableUser(tc.ctx, tc.target.ID)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assertUserTokensRevoked(t, deny, tc.target.ID, false)
+				return
+			}
+			assert.NoError(t, err)
+			assertUserTokensRevoked(t, deny, tc.target.ID, true)
+
+			if tc.revokedJTI != "" {
+				revoked, err := deny.Contains(context.Background(), tc.revokedJTI)
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+			}
+		})
+	}
+}
+
+func TestEnableUser(t *testing.T) {
+	_, _, mockRepo, service := setup(t)
+	mockRepo.EXPECT().FindAdminUser(gomock.Any(), "mock-uuid-1").Return(adminUser("mock-uuid-1", user.RoleCustomer), nil).Times(1)
+	mockRepo.EXPECT().EnableUser(gomock.Any(), "mock-uuid-1").Return(nil).Times(1)
+
+	assert.NoError(t, service.EnableUser(staffCtx, "mock-uuid-1"))
+}
+
+func TestForceLogout(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, mockRepo, service, deny := setupRevoke(t)
+		mockRepo.EXPECT().FindAdminUser(gomock.Any(), "mock-uuid-1").Return(adminUser("mock-uuid-1", user.RoleCustomer), nil).Times(1)
+		mockRepo.EXPECT().RevokeAllSessions(gomock.Any(), "mock-uuid-1").Return(int64(3), nil).Times(1)
+
+		assert.NoError(t, service.ForceLogout(staffCtx, "mock-uuid-1"))
+		assertUserTokensRevoked(t, deny, "mock-uuid-1", true)
+	})
+
+	t.Run("fail staff logs out staff", func(t *testing.T) {
+		_, mockRepo, service, deny := setupRevoke(t)
+		mockRepo.EXPECT().FindAdminUser(gomock.Any(), "mock-staff-2").Return(adminUser("mock-staff-2", user.RoleStaff), nil).Times(1)
+
+		assert.ErrorIs(t, service.ForceLogout(staffCtx, "mock-staff-2"), errs.ErrForbidden)
+		assertUserTokensRevoked(t, deny, "mock-staff-2", false)
+	})
+}
+
+func TestDisabledUserCannotSignIn(t *testing.T) {
+	disabledAt := mockNow.Add(-time.Hour)
+
+	t.Run("login", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2", DisabledAt: &disabledAt}
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+
+		resp, err := service.Login(context.Background(), mockUser.Email, "test_password", user.ClientInfo{})
+
+		assert.ErrorIs(t, err, errs.ErrAccountDisabled)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("login wrong password is not told", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2", DisabledAt: &disabledAt}
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+
+		_, err := service.Login(context.Background(), mockUser.Email, "wrong_password", user.ClientInfo{})
+
+		assert.ErrorIs(t, err, errs.ErrInvalidEmailOrPassword)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		mockToken, _, mockRepo, service := setup(t)
+		mockToken.EXPECT().VerifyRefreshToken("mock-refresh-token").Return(&jwttoken.UserClaims{UserID: "mock-uuid-1"}, nil).Times(1)
+		mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), gomock.Any()).Return(&user.RefreshToken{ID: "mock-token-id", UserID: "mock-uuid-1"}, nil).Times(1)
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", DisabledAt: &disabledAt}, nil).Times(1)
+
+		resp, err := service.RefreshToken(context.Background(), "mock-refresh-token", user.ClientInfo{})
+
+		assert.ErrorIs(t, err, errs.ErrAccountDisabled)
+		assert.Nil(t, resp)
+	})
+}
@@ -0,0 +1,165 @@
+package userservice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+)
+
+const (
+	// Random part of a key, 32 bytes = 43 base64 chars
+	apiKeySecretBytes = 32
+	// Key characters kept in Prefix after "gsk_"
+	apiKeyPrefixChars = 8
+	// last_used_at is written at most this often, not on every request
+	apiKeyTouchInterval = time.Minute
+)
+
+type CreateAPIKeyInput struct {
+	Name      string
+	Scopes    []user.Permission
+	ExpiresAt *time.Time // nil never expires
+}
+
+// APIKeyCreated : Key is shown once, only its hash is stored
+type APIKeyCreated struct {
+	*user.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey : admin only, the key acts as the admin within its scopes
+func (s *userService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*APIKeyCreated, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequireRole(ctx, user.RoleAdmin); err != nil {
+		return nil, err
+	}
+	adminID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+
+	scopes := make([]user.Permission, 0, len(input.Scopes))
+	for _, p := range input.Scopes {
+		if !p.IsAPIKeyScope() {
+			return nil, errs.ErrInvalidAPIKeyScope
+		}
+		if !hasPermission(scopes, p) {
+			scopes = append(scopes, p)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errs.ErrInvalidAPIKeyScope
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.cfg.Now()) {
+		return nil, errs.ErrInvalidAPIKeyExpiry
+	}
+
+	secret, err := securetoken.Generate(apiKeySecretBytes)
+	if err != nil {
+		return nil, fmt.Errorf("generate api key failed: %w", err)
+	}
+	raw := user.APIKeyPrefix + secret
+
+	key := &user.APIKey{
+		Name:      strings.TrimSpace(input.Name),
+		Prefix:    raw[:len(user.APIKeyPrefix)+apiKeyPrefixChars],
+		Hash:      securetoken.Hash(raw),
+		Scopes:    scopes,
+		CreatedBy: adminID,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.repo.InsertAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	slog.Info("api key created", slog.String("key_id", key.ID), slog.String("prefix", key.Prefix), slog.String("by", adminID))
+	return &APIKeyCreated{APIKey: key, Key: raw}, nil
+}
+
+func (s *userService) ListAPIKeys(ctx context.Context) ([]*user.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequireRole(ctx, user.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAPIKeys(ctx)
+}
+
+// RevokeAPIKey : takes effect on the next request, keys are not cached
+func (s *userService) RevokeAPIKey(ctx context.Context, keyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequireRole(ctx, user.RoleAdmin); err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeAPIKey(ctx, keyID); err != nil {
+		return err
+	}
+
+	adminID, _ := auth.GetUserIDFromContext(ctx)
+	slog.Info("api key revoked", slog.String("key_id", keyID), slog.String("by", adminID))
+	return nil
+}
+
+// AuthenticateAPIKey : active key with its scopes cut down to what the creator's role still grants
+func (s *userService) AuthenticateAPIKey(ctx context.Context, rawKey string) (*user.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if !strings.HasPrefix(rawKey, user.APIKeyPrefix) {
+		return nil, errs.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindAPIKeyByHash(ctx, securetoken.Hash(rawKey))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.cfg.Now()
+	if !key.IsActive(now) {
+		return nil, errs.ErrInvalidAPIKey
+	}
+
+	// A demoted admin's keys lose what the new role does not have
+	scopes := make([]user.Permission, 0, len(key.Scopes))
+	for _, p := range key.Scopes {
+		if key.OwnerRole.HasPermission(p) {
+			scopes = append(scopes, p)
+		}
+	}
+	key.Scopes = scopes
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		// The request goes on even when tracking fails
+		if err := s.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			slog.Error("update api key last use failed", slog.String("key_id", key.ID), slog.Any("error", err))
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+
+	return key, nil
+}
+
+func hasPermission(perms []user.Permission, perm user.Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package userservice_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	adminCtx := auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "mock-admin-uuid", Role: user.RoleAdmin})
+	expiresAt := mockNow.Add(24 * time.Hour)
+
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		input       userservice.CreateAPIKeyInput
+		mockFn      func(mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			ctx:  adminCtx,
+			input: userservice.CreateAPIKeyInput{
+				Name:      " ERP ",
+				Scopes:    []user.Permission{user.PermProductsWrite, user.PermOrdersRead, user.PermProductsWrite},
+				ExpiresAt: &expiresAt,
+			},
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().InsertAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, key *user.APIKey) error {
+						assert.Equal(t, "ERP", key.Name)
+						assert.Equal(t, []user.Permission{user.PermProductsWrite, user.PermOrdersRead}, key.Scopes)
+						assert.Equal(t, "mock-admin-uuid", key.CreatedBy)
+						assert.Equal(t, &expiresAt, key.ExpiresAt)
+						key.ID = "mock-key-1"
+						return nil
+					},
+				).Times(1)
+			},
+		},
+		{
+			name:        "fail not admin",
+			ctx:         auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "mock-uuid-1", Role: user.RoleStaff}),
+			input:       userservice.CreateAPIKeyInput{Name: "ERP", Scopes: []user.Permission{user.PermOrdersRead}},
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrForbidden,
+		},
+		{
+			name:        "fail user management scope",
+			ctx:         adminCtx,
+			input:       userservice.CreateAPIKeyInput{Name: "ERP", Scopes: []user.Permission{user.PermUsersManage}},
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrInvalidAPIKeyScope,
+		},
+		{
+			name:        "fail no scope",
+			ctx:         adminCtx,
+			input:       userservice.CreateAPIKeyInput{Name: "ERP"},
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrInvalidAPIKeyScope,
+		},
+		{
+			name:        "fail expiry in the past",
+			ctx:         adminCtx,
+			input:       userservice.CreateAPIKeyInput{Name: "ERP", Scopes: []user.Permission{user.PermOrdersRead}, ExpiresAt: &mockNow},
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrInvalidAPIKeyExpiry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, mockRepo, service := setupMFA(t)
+			tc.mockFn(mockRepo)
+
+			resp, err := service.CreateAPIKey(tc.ctx, tc.input)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(resp.Key, user.APIKeyPrefix))
+			assert.True(t, strings.HasPrefix(resp.Key, resp.Prefix))
+			assert.Equal(t, securetoken.Hash(resp.Key), resp.Hash)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	const rawKey = "gsk_mock-api-key"
+	recently := mockNow.Add(-10 * time.Second)
+	expired := mockNow.Add(-time.Second)
+
+	mockKey := func() *user.APIKey {
+		return &user.APIKey{
+			ID:        "mock-key-1",
+			CreatedBy: "mock-admin-uuid",
+			Scopes:    []user.Permission{user.PermProductsWrite, user.PermOrdersRead},
+			OwnerRole: user.RoleAdmin,
+		}
+	}
+
+	type testCase struct {
+		name           string
+		rawKey         string
+		mockFn         func(mockRepo *userrepository.MockUserRepository)
+		expectedScopes []user.Permission
+		expectedErr    error
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success tracks last use",
+			rawKey: rawKey,
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindAPIKeyByHash(gomock.Any(), securetoken.Hash(rawKey)).Return(mockKey(), nil).Times(1)
+				mockRepo.EXPECT().TouchAPIKey(gomock.Any(), "mock-key-1", mockNow).Return(nil).Times(1)
+			},
+			expectedScopes: []user.Permission{user.PermProductsWrite, user.PermOrdersRead},
+		},
+		{
+			name:   "success used recently",
+			rawKey: rawKey,
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				key := mockKey()
+				key.LastUsedAt = &recently
+				mockRepo.EXPECT().FindAPIKeyByHash(gomock.Any(), securetoken.Hash(rawKey)).Return(key, nil).Times(1)
+			},
+			expectedScopes: []user.Permission{user.PermProductsWrite, user.PermOrdersRead},
+		},
+		{
+			name:   "success scopes limited by owner role",
+			rawKey: rawKey,
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				key := mockKey()
+				key.OwnerRole = user.RoleCustomer
+				key.LastUsedAt = &recently
+				mockRepo.EXPECT().FindAPIKeyByHash(gomock.Any(), securetoken.Hash(rawKey)).Return(key, nil).Times(1)
+			},
+			expectedScopes: []user.Permission{},
+		},
+		{
+			name:        "fail wrong prefix",
+			rawKey:      "Bearer-mock",
+			mockFn:      func(mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrInvalidAPIKey,
+		},
+		{
+			name:   "fail unknown key",
+			rawKey: rawKey,
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindAPIKeyByHash(gomock.Any(), securetoken.Hash(rawKey)).Return(nil, errs.ErrInvalidAPIKey).Times(1)
+			},
+			expectedErr: errs.ErrInvalidAPIKey,
+		},
+		{
+			name:   "fail revoked",
+			rawKey: rawKey,
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				key := mockKey()
+				key.RevokedAt = &recently
+				mockRepo.EXPECT().FindAPIKeyByHash(gomock.Any(), securetoken.Hash(rawKey)).Return(key, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidAPIKey,
+		},
+		{
+			name:   "fail expired",
+			rawKey: rawKey,
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				key := mockKey()
+				key.ExpiresAt = &expired
+				mockRepo.EXPECT().FindAPIKeyByHash(gomock.Any(), securetoken.Hash(rawKey)).Return(key, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, mockRepo, service := setupMFA(t)
+			tc.mockFn(mockRepo)
+
+			key, err := service.AuthenticateAPIKey(context.Background(), tc.rawKey)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, key)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedScopes, key.Scopes)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	adminCtx := auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "mock-admin-uuid", Role: user.RoleAdmin})
+
+	t.Run("success", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockRepo.EXPECT().RevokeAPIKey(gomock.Any(), "mock-key-1").Return(nil).Times(1)
+
+		assert.NoError(t, service.RevokeAPIKey(adminCtx, "mock-key-1"))
+	})
+
+	t.Run("fail not found", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+		mockRepo.EXPECT().RevokeAPIKey(gomock.Any(), "mock-key-1").Return(errs.ErrAPIKeyNotFound).Times(1)
+
+		assert.ErrorIs(t, service.RevokeAPIKey(adminCtx, "mock-key-1"), errs.ErrAPIKeyNotFound)
+	})
+
+	t.Run("fail api key cannot manage keys", func(t *testing.T) {
+		_, _, _, service := setup(t)
+		ctx := auth.SetContextAPIKey(context.Background(), &user.APIKey{ID: "mock-key-2", CreatedBy: "mock-admin-uuid", Scopes: []user.Permission{user.PermOrdersWrite}})
+
+		assert.ErrorIs(t, service.RevokeAPIKey(ctx, "mock-key-1"), errs.ErrForbidden)
+	})
+}
@@ -0,0 +1,143 @@
+package userservice_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonate(t *testing.T) {
+	disabledAt := mockNow.Add(-time.Hour)
+	expiresAt := mockNow.Add(15 * time.Minute)
+	issued := &jwttoken.UserClaims{
+		UserID: "mock-uuid-1",
+		Actor:  &jwttoken.Actor{UserID: "mock-admin-uuid"},
+		RegisteredClaims: &jwt.RegisteredClaims{
+			ID:        "mock-jti",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	type testCase struct {
+		name        string
+		ctx         context.Context
+		target      *user.User
+		reason      string
+		mockFn      func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success",
+			ctx:    adminCtx,
+			target: &user.User{ID: "mock-uuid-1", Role: user.RoleCustomer},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+				mockToken.EXPECT().GenerateImpersonationToken(gomock.Any(), "mock-admin-uuid", 15*time.Minute).Return("mock-access-token", issued, nil).Times(1)
+				mockRepo.EXPECT().InsertImpersonation(gomock.Any(), &user.Impersonation{
+					ID:        "mock-jti",
+					ActorID:   "mock-admin-uuid",
+					UserID:    "mock-uuid-1",
+					Reason:    "order 1001 missing item",
+					IPAddress: "192.0.2.1",
+					ExpiresAt: expiresAt,
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name:        "fail staff",
+			ctx:         staffCtx,
+			mockFn:      func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrForbidden,
+		},
+		{
+			name:        "fail blank reason",
+			ctx:         adminCtx,
+			reason:      "   ",
+			mockFn:      func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrImpersonationReason,
+		},
+		{
+			name:        "fail staff account",
+			ctx:         adminCtx,
+			target:      &user.User{ID: "mock-staff-uuid", Role: user.RoleStaff},
+			mockFn:      func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrCannotImpersonate,
+		},
+		{
+			name:        "fail disabled account",
+			ctx:         adminCtx,
+			target:      &user.User{ID: "mock-uuid-1", Role: user.RoleCustomer, DisabledAt: &disabledAt},
+			mockFn:      func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {},
+			expectedErr: errs.ErrAccountDisabled,
+		},
+		{
+			name:   "fail audit insert, no token",
+			ctx:    adminCtx,
+			target: &user.User{ID: "mock-uuid-1", Role: user.RoleCustomer},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository) {
+				mockToken.EXPECT().GenerateImpersonationToken(gomock.Any(), "mock-admin-uuid", 15*time.Minute).Return("mock-access-token", issued, nil).Times(1)
+				mockRepo.EXPECT().InsertImpersonation(gomock.Any(), gomock.Any()).Return(errors.New("db down")).Times(1)
+			},
+			expectedErr: errors.New("db down"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockToken, _, mockRepo, service := setup(t)
+			userID := "mock-uuid-1"
+			if tc.target != nil {
+				userID = tc.target.ID
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), userID).Return(tc.target, nil).Times(1)
+			}
+			tc.mockFn(mockToken, mockRepo)
+			reason := "  order 1001 missing item "
+			if tc.reason != "" {
+				reason = tc.reason
+			}
+
+			resp, err := service.Impersonate(tc.ctx, userID, reason, user.ClientInfo{IPAddress: "192.0.2.1"})
+
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+				assert.Nil(t, resp)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "mock-access-token", resp.AccessToken)
+			assert.Equal(t, "mock-uuid-1", resp.UserID)
+			assert.Equal(t, expiresAt, resp.ExpiresAt)
+		})
+	}
+}
+
+func TestAuditImpersonation(t *testing.T) {
+	_, _, mockRepo, service := setup(t)
+	long := "/api/v1/orders/" + strings.Repeat("x", 600)
+
+	mockRepo.EXPECT().InsertImpersonationRequest(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *user.ImpersonationRequest) error {
+			assert.Len(t, req.Path, 500)
+			return nil
+		},
+	).Times(1)
+
+	req := &user.ImpersonationRequest{ImpersonationID: "mock-jti", Method: "GET", Path: long}
+	assert.NoError(t, service.AuditImpersonation(context.Background(), req))
+
+	mockRepo.EXPECT().UpdateImpersonationRequestStatus(gomock.Any(), int64(7), 200).Return(nil).Times(1)
+
+	req.ID, req.Status = 7, 200
+	assert.NoError(t, service.CompleteImpersonationAudit(context.Background(), req))
+}
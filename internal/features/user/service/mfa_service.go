@@ -0,0 +1,372 @@
+package userservice
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/loginguard"
+	"github.com/codepnw/go-starter-kit/pkg/totp"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+)
+
+const (
+	// Wrong codes allowed per login challenge
+	maxMFAAttempts = 5
+	// Accept the previous and next 30s window for clock drift
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10 // characters, shown as xxxxx-xxxxx
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment : add to an authenticator app, then confirm with a code
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse : shown once, each code works once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// mfaChallenge : password was right, the caller must finish with VerifyMFA.
+// Challenges from other devices stay valid.
+func (s *userService) mfaChallenge(ctx context.Context, userID string) (*UserTokenResponse, error) {
+	var challenge string
+
+	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		token, err := s.insertUserTokenTx(ctx, tx, userID, user.TokenMFAChallenge, s.cfg.MFAChallengeTTL)
+		if err != nil {
+			return err
+		}
+		challenge = token
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserTokenResponse{MFARequired: true, MFAToken: challenge}, nil
+}
+
+// VerifyMFA : second login step, code is a TOTP code or a recovery code
+func (s *userService) VerifyMFA(ctx context.Context, mfaToken, code string, client user.ClientInfo) (*UserTokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	tokenHash := securetoken.Hash(mfaToken)
+
+	challenge, err := s.repo.FindUserToken(ctx, tokenHash, user.TokenMFAChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.FindUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Disabled after the password step
+	if u.IsDisabled() {
+		return nil, errs.ErrAccountDisabled
+	}
+
+	// Wrong codes count toward the account lockout too
+	if err := s.checkLoginLock(ctx, u.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyMFACode(ctx, u.ID, code); err != nil {
+		if errors.Is(err, errs.ErrInvalidMFACode) {
+			s.loginFailed(ctx, u.Email, u.ID, client, loginguard.ReasonBadMFACode)
+
+			attempts, aerr := s.repo.AddUserTokenAttempt(ctx, challenge.ID, maxMFAAttempts)
+			if aerr != nil {
+				return nil, aerr
+			}
+			if attempts >= maxMFAAttempts {
+				slog.Warn("mfa challenge locked",
+					slog.String("event", "mfa_attempts_exceeded"),
+					slog.String("user_id", challenge.UserID),
+				)
+			}
+		}
+		return nil, err
+	}
+
+	var response *UserTokenResponse
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		// Single use: a second request with the same challenge fails here
+		if _, err := s.repo.UseUserTokenTx(ctx, tx, tokenHash, user.TokenMFAChallenge); err != nil {
+			return err
+		}
+
+		resp, err := s.generateToken(u)
+		if err != nil {
+			return err
+		}
+
+		insertTokenInput := s.insertRefreshTokenInput(u.ID, resp.RefreshToken, client)
+		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
+			return err
+		}
+
+		response = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.loginSucceeded(ctx, u.Email)
+	return response, nil
+}
+
+// EnrollTOTP : new secret, not active until ConfirmTOTP. Enrolling again replaces it.
+func (s *userService) EnrollTOTP(ctx context.Context) (*TOTPEnrollment, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if s.cfg.MFASecrets == nil {
+		return nil, errs.ErrMFAUnavailable
+	}
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsMFAEnabled() {
+		return nil, errs.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("generate totp secret failed: %w", err)
+	}
+
+	sealed, err := s.cfg.MFASecrets.Seal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt totp secret failed: %w", err)
+	}
+
+	if err := s.repo.SetTOTPSecret(ctx, u.ID, sealed); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.cfg.MFAIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP : first valid code turns 2FA on and issues recovery codes
+func (s *userService) ConfirmTOTP(ctx context.Context, code string) (*RecoveryCodesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if s.cfg.MFASecrets == nil {
+		return nil, errs.ErrMFAUnavailable
+	}
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+
+	t, err := s.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, errs.ErrMFAAlreadyEnabled
+	}
+	if t.Secret == "" {
+		return nil, errs.ErrMFANotEnrolled
+	}
+
+	secret, err := s.cfg.MFASecrets.Open(t.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(secret, normalizeMFACode(code), s.cfg.Now(), totpSkew)
+	if !ok {
+		return nil, errs.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.EnableTOTPTx(ctx, tx, userID, step); err != nil {
+			return err
+		}
+		return s.repo.ReplaceRecoveryCodesTx(ctx, tx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("two-factor enabled", slog.String("user_id", userID))
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP : needs the password and a code, a stolen access token alone is not enough
+func (s *userService) DisableTOTP(ctx context.Context, currentPassword, code string, client user.ClientInfo) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkCurrentPassword(ctx, u, currentPassword, client); err != nil {
+		return err
+	}
+	if err := s.checkCurrentMFACode(ctx, u, code, client); err != nil {
+		return err
+	}
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.repo.DisableTOTPTx(ctx, tx, u.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("two-factor disabled", slog.String("user_id", u.ID))
+	return nil
+}
+
+// RegenerateRecoveryCodes : old codes stop working. Needs the password and a code like DisableTOTP.
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, currentPassword, code string, client user.ClientInfo) (*RecoveryCodesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCurrentPassword(ctx, u, currentPassword, client); err != nil {
+		return nil, err
+	}
+	if err := s.checkCurrentMFACode(ctx, u, code, client); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.repo.ReplaceRecoveryCodesTx(ctx, tx, u.ID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("recovery codes regenerated", slog.String("user_id", u.ID))
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// checkCurrentMFACode : wrong codes of a logged in user count toward the login lockout, as in VerifyMFA
+func (s *userService) checkCurrentMFACode(ctx context.Context, u *user.User, code string, client user.ClientInfo) error {
+	if err := s.verifyMFACode(ctx, u.ID, code); err != nil {
+		if errors.Is(err, errs.ErrInvalidMFACode) {
+			s.loginFailed(ctx, u.Email, u.ID, client, loginguard.ReasonBadMFACode)
+		}
+		return err
+	}
+	return nil
+}
+
+// verifyMFACode : 6 digits is a TOTP code, anything else a recovery code. Both work once.
+func (s *userService) verifyMFACode(ctx context.Context, userID, code string) error {
+	t, err := s.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if t.EnabledAt == nil {
+		return errs.ErrMFANotEnabled
+	}
+
+	code = normalizeMFACode(code)
+	if code == "" {
+		return errs.ErrInvalidMFACode
+	}
+
+	if len(code) != totp.Digits {
+		return s.repo.UseRecoveryCode(ctx, userID, securetoken.Hash(code))
+	}
+
+	// Without the key only recovery codes work
+	if s.cfg.MFASecrets == nil {
+		return errs.ErrMFAUnavailable
+	}
+	secret, err := s.cfg.MFASecrets.Open(t.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, s.cfg.Now(), totpSkew)
+	if !ok || step <= t.LastStep {
+		return errs.ErrInvalidMFACode
+	}
+	return s.repo.UseTOTPStep(ctx, userID, step)
+}
+
+// normalizeMFACode : users type codes with spaces and dashes, in any case
+func normalizeMFACode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// newRecoveryCodes : display codes and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeSize*5/8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code failed: %w", err)
+		}
+
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes = append(codes, raw[:recoveryCodeSize/2]+"-"+raw[recoveryCodeSize/2:])
+		hashes = append(hashes, securetoken.Hash(raw))
+	}
+	return codes, hashes, nil
+}
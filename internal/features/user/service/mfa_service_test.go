@@ -0,0 +1,400 @@
+package userservice_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/totp"
+	"github.com/codepnw/go-starter-kit/pkg/utils/secretbox"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fixed clock for TOTP codes
+var mockNow = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+var mfaSecrets = func() *secretbox.Box {
+	box, err := secretbox.New("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		panic(err)
+	}
+	return box
+}()
+
+const mockTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func mockTOTP(t *testing.T, enabled bool, lastStep int64) *user.TOTP {
+	sealed, err := mfaSecrets.Seal(mockTOTPSecret)
+	assert.NoError(t, err)
+
+	m := &user.TOTP{UserID: "mock-uuid-1", Secret: sealed, LastStep: lastStep}
+	if enabled {
+		m.EnabledAt = &mockNow
+	}
+	return m
+}
+
+func mockCode(t *testing.T) string {
+	code, err := totp.Code(mockTOTPSecret, mockNow)
+	assert.NoError(t, err)
+	return code
+}
+
+// setupMFA : default config with the clock fixed at mockNow
+func setupMFA(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService) {
+	cfg := defaultConfig
+	cfg.Now = func() time.Time { return mockNow }
+
+	mockToken, mockTx, mockRepo, service, _ := setupWithConfig(t, cfg)
+	return mockToken, mockTx, mockRepo, service
+}
+
+// setupNoMFAKey : server started without AUTH_MFA_ENCRYPTION_KEY
+func setupNoMFAKey(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService) {
+	cfg := defaultConfig
+	cfg.Now = func() time.Time { return mockNow }
+	cfg.MFASecrets = nil
+
+	mockToken, mockTx, mockRepo, service, _ := setupWithConfig(t, cfg)
+	return mockToken, mockTx, mockRepo, service
+}
+
+func withTx(mockTx *database.MockTxManager) {
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).Times(1)
+}
+
+func TestLoginMFARequired(t *testing.T) {
+	_, mockTx, mockRepo, service := setupMFA(t)
+
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2", TOTPEnabledAt: &mockNow}
+	mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+	withTx(mockTx)
+	// Other pending challenges are not invalidated
+	mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, tx *sql.Tx, token *user.UserToken) error {
+			assert.Equal(t, user.TokenMFAChallenge, token.Purpose)
+			assert.Equal(t, mockNow.Add(defaultConfig.MFAChallengeTTL), token.ExpiresAt)
+			return nil
+		},
+	).Times(1)
+
+	resp, err := service.Login(context.Background(), mockUser.Email, "test_password", user.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.True(t, resp.MFARequired)
+	assert.NotEmpty(t, resp.MFAToken)
+	assert.Empty(t, resp.AccessToken)
+	assert.Empty(t, resp.RefreshToken)
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+	t.Run("success", func(t *testing.T) {
+		_, _, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", Email: "test1@mail.com"}, nil).Times(1)
+
+		var stored string
+		mockRepo.EXPECT().SetTOTPSecret(gomock.Any(), "mock-uuid-1", gomock.Any()).DoAndReturn(
+			func(ctx context.Context, userID, sealed string) error {
+				stored = sealed
+				return nil
+			},
+		).Times(1)
+
+		resp, err := service.EnrollTOTP(ctx)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resp.URI, "otpauth://totp/"))
+
+		// Stored encrypted, not in plain text
+		assert.NotContains(t, stored, resp.Secret)
+		opened, err := mfaSecrets.Open(stored)
+		assert.NoError(t, err)
+		assert.Equal(t, resp.Secret, opened)
+	})
+
+	t.Run("fail already enabled", func(t *testing.T) {
+		_, _, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", TOTPEnabledAt: &mockNow}, nil).Times(1)
+
+		resp, err := service.EnrollTOTP(ctx)
+		assert.ErrorIs(t, err, errs.ErrMFAAlreadyEnabled)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("fail no encryption key", func(t *testing.T) {
+		_, _, _, service := setupNoMFAKey(t)
+
+		resp, err := service.EnrollTOTP(ctx)
+		assert.ErrorIs(t, err, errs.ErrMFAUnavailable)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+	type testCase struct {
+		name        string
+		code        string
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success enables and issues recovery codes",
+			code: mockCode(t),
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), "mock-uuid-1").Return(mockTOTP(t, false, 0), nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().EnableTOTPTx(gomock.Any(), nil, "mock-uuid-1", totp.Step(mockNow)).Return(nil).Times(1)
+				mockRepo.EXPECT().ReplaceRecoveryCodesTx(gomock.Any(), nil, "mock-uuid-1", gomock.Len(10)).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail wrong code",
+			code: "000000",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), "mock-uuid-1").Return(mockTOTP(t, false, 0), nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidMFACode,
+		},
+		{
+			name: "fail enrollment not started",
+			code: mockCode(t),
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), "mock-uuid-1").Return(&user.TOTP{UserID: "mock-uuid-1"}, nil).Times(1)
+			},
+			expectedErr: errs.ErrMFANotEnrolled,
+		},
+		{
+			name: "fail already enabled",
+			code: mockCode(t),
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), "mock-uuid-1").Return(mockTOTP(t, true, 0), nil).Times(1)
+			},
+			expectedErr: errs.ErrMFAAlreadyEnabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockTx, mockRepo, service := setupMFA(t)
+
+			tc.mockFn(mockTx, mockRepo)
+
+			resp, err := service.ConfirmTOTP(ctx, tc.code)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, resp.RecoveryCodes, 10)
+			}
+		})
+	}
+
+	t.Run("fail no encryption key", func(t *testing.T) {
+		_, _, _, service := setupNoMFAKey(t)
+
+		resp, err := service.ConfirmTOTP(ctx, mockCode(t))
+		assert.ErrorIs(t, err, errs.ErrMFAUnavailable)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestVerifyMFA(t *testing.T) {
+	mfaToken := "mock-mfa-token"
+	challenge := &user.UserToken{ID: "challenge-1", UserID: "mock-uuid-1", Purpose: user.TokenMFAChallenge}
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", TOTPEnabledAt: &mockNow}
+
+	issueTokens := func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+		withTx(mockTx)
+		mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash(mfaToken), user.TokenMFAChallenge).Return(challenge, nil).Times(1)
+		mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
+		mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+		mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+	}
+
+	type testCase struct {
+		name        string
+		code        string
+		mockFn      func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success totp code",
+			code: mockCode(t),
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserToken(gomock.Any(), securetoken.Hash(mfaToken), user.TokenMFAChallenge).Return(challenge, nil).Times(1)
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, 0), nil).Times(1)
+				mockRepo.EXPECT().UseTOTPStep(gomock.Any(), mockUser.ID, totp.Step(mockNow)).Return(nil).Times(1)
+				issueTokens(mockToken, mockTx, mockRepo)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "success recovery code",
+			code: "ABCDE-FGHIJ",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserToken(gomock.Any(), securetoken.Hash(mfaToken), user.TokenMFAChallenge).Return(challenge, nil).Times(1)
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, 0), nil).Times(1)
+				mockRepo.EXPECT().UseRecoveryCode(gomock.Any(), mockUser.ID, securetoken.Hash("abcdefghij")).Return(nil).Times(1)
+				issueTokens(mockToken, mockTx, mockRepo)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail replayed code counts attempt",
+			code: mockCode(t),
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserToken(gomock.Any(), securetoken.Hash(mfaToken), user.TokenMFAChallenge).Return(challenge, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, totp.Step(mockNow)), nil).Times(1)
+				mockRepo.EXPECT().AddUserTokenAttempt(gomock.Any(), challenge.ID, 5).Return(1, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidMFACode,
+		},
+		{
+			name: "fail expired challenge",
+			code: mockCode(t),
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserToken(gomock.Any(), securetoken.Hash(mfaToken), user.TokenMFAChallenge).Return(nil, errs.ErrInvalidUserToken).Times(1)
+			},
+			expectedErr: errs.ErrInvalidUserToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockToken, mockTx, mockRepo, service := setupMFA(t)
+
+			tc.mockFn(mockToken, mockTx, mockRepo)
+
+			resp, err := service.VerifyMFA(context.Background(), mfaToken, tc.code, user.ClientInfo{})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "mock-refresh-token", resp.RefreshToken)
+			}
+		})
+	}
+}
+
+func TestDisableTOTP(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2", TOTPEnabledAt: &mockNow}
+	client := user.ClientInfo{IPAddress: "127.0.0.1"}
+
+	t.Run("success", func(t *testing.T) {
+		_, mockTx, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, 0), nil).Times(1)
+		mockRepo.EXPECT().UseTOTPStep(gomock.Any(), mockUser.ID, totp.Step(mockNow)).Return(nil).Times(1)
+		withTx(mockTx)
+		mockRepo.EXPECT().DisableTOTPTx(gomock.Any(), nil, mockUser.ID).Return(nil).Times(1)
+
+		err := service.DisableTOTP(ctx, "test_password", mockCode(t), client)
+		assert.NoError(t, err)
+	})
+
+	t.Run("fail wrong password", func(t *testing.T) {
+		_, _, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+
+		err := service.DisableTOTP(ctx, "wrong_password", mockCode(t), client)
+		assert.ErrorIs(t, err, errs.ErrInvalidPassword)
+	})
+
+	t.Run("fail wrong codes lock the account", func(t *testing.T) {
+		_, _, mockRepo, service := setupMFA(t)
+
+		// Locked on the next call once the account limit is reached
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(loginPolicy.AccountLimit + 1)
+		mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, 0), nil).Times(loginPolicy.AccountLimit)
+		mockRepo.EXPECT().UseRecoveryCode(gomock.Any(), mockUser.ID, gomock.Any()).Return(errs.ErrInvalidMFACode).Times(loginPolicy.AccountLimit)
+
+		for i := 0; i < loginPolicy.AccountLimit; i++ {
+			err := service.DisableTOTP(ctx, "test_password", "wrong-code", client)
+			assert.ErrorIs(t, err, errs.ErrInvalidMFACode)
+		}
+
+		err := service.DisableTOTP(ctx, "test_password", mockCode(t), client)
+		assert.ErrorIs(t, err, errs.ErrTooManyLoginAttempts)
+	})
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2", TOTPEnabledAt: &mockNow}
+	client := user.ClientInfo{IPAddress: "127.0.0.1"}
+
+	t.Run("success", func(t *testing.T) {
+		_, mockTx, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, 0), nil).Times(1)
+		mockRepo.EXPECT().UseTOTPStep(gomock.Any(), mockUser.ID, totp.Step(mockNow)).Return(nil).Times(1)
+		withTx(mockTx)
+		mockRepo.EXPECT().ReplaceRecoveryCodesTx(gomock.Any(), nil, mockUser.ID, gomock.Len(10)).Return(nil).Times(1)
+
+		resp, err := service.RegenerateRecoveryCodes(ctx, "test_password", mockCode(t), client)
+		assert.NoError(t, err)
+		assert.Len(t, resp.RecoveryCodes, 10)
+	})
+
+	t.Run("fail wrong password", func(t *testing.T) {
+		_, _, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().FindTOTP(gomock.Any(), gomock.Any()).Times(0)
+
+		resp, err := service.RegenerateRecoveryCodes(ctx, "wrong_password", mockCode(t), client)
+		assert.ErrorIs(t, err, errs.ErrInvalidPassword)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("fail wrong codes lock the account", func(t *testing.T) {
+		_, _, mockRepo, service := setupMFA(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(loginPolicy.AccountLimit + 1)
+		mockRepo.EXPECT().FindTOTP(gomock.Any(), mockUser.ID).Return(mockTOTP(t, true, 0), nil).Times(loginPolicy.AccountLimit)
+		mockRepo.EXPECT().UseRecoveryCode(gomock.Any(), mockUser.ID, gomock.Any()).Return(errs.ErrInvalidMFACode).Times(loginPolicy.AccountLimit)
+
+		for i := 0; i < loginPolicy.AccountLimit; i++ {
+			_, err := service.RegenerateRecoveryCodes(ctx, "test_password", "wrong-code", client)
+			assert.ErrorIs(t, err, errs.ErrInvalidMFACode)
+		}
+
+		_, err := service.RegenerateRecoveryCodes(ctx, "test_password", mockCode(t), client)
+		assert.ErrorIs(t, err, errs.ErrTooManyLoginAttempts)
+	})
+}
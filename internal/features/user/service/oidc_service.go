@@ -0,0 +1,230 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/internal/features/user/oidc"
+	"github.com/codepnw/go-starter-kit/pkg/loginguard"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+)
+
+// Provider names longer than the users.name column are cut
+const maxNameLength = 100
+
+// OIDCStartResponse : send the browser to AuthURL. Keep State and compare it with
+// the state on the redirect back, so a login started elsewhere cannot be injected.
+type OIDCStartResponse struct {
+	AuthURL string `json:"auth_url"`
+	State   string `json:"state"`
+}
+
+// StartOIDC : authorization code flow with PKCE, verifier and nonce stay on the server
+func (s *userService) StartOIDC(ctx context.Context, provider string) (*OIDCStartResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	p, ok := s.cfg.OIDCProviders[provider]
+	if !ok {
+		return nil, errs.ErrOIDCProviderNotFound
+	}
+
+	state, err := securetoken.Generate(32)
+	if err != nil {
+		return nil, fmt.Errorf("generate oidc state failed: %w", err)
+	}
+	nonce, err := securetoken.Generate(32)
+	if err != nil {
+		return nil, fmt.Errorf("generate oidc nonce failed: %w", err)
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return nil, fmt.Errorf("generate pkce failed: %w", err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		slog.Error("oidc provider unavailable", slog.String("provider", provider), slog.Any("error", err))
+		return nil, errs.ErrOIDCLoginFailed
+	}
+
+	err = s.repo.InsertOIDCState(ctx, &user.OIDCState{
+		StateHash:    securetoken.Hash(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    s.cfg.Now().Add(s.cfg.OIDCStateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCStartResponse{AuthURL: authURL, State: state}, nil
+}
+
+// OIDCCallback : code and state from the provider redirect, same answer as Login
+func (s *userService) OIDCCallback(ctx context.Context, provider, code, state string, client user.ClientInfo) (*UserTokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	p, ok := s.cfg.OIDCProviders[provider]
+	if !ok {
+		return nil, errs.ErrOIDCProviderNotFound
+	}
+
+	st, err := s.repo.UseOIDCState(ctx, securetoken.Hash(state), provider)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := p.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		slog.Warn("oidc sign-in rejected",
+			slog.String("event", "oidc_login_failed"),
+			slog.String("provider", provider),
+			slog.String("ip", client.IPAddress),
+			slog.Any("error", err),
+		)
+		return nil, errs.ErrOIDCLoginFailed
+	}
+
+	u, err := s.oidcUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsDisabled() {
+		return nil, errs.ErrAccountDisabled
+	}
+
+	if !s.cfg.AllowUnverifiedLogin && !u.IsEmailVerified() {
+		return nil, errs.ErrEmailNotVerified
+	}
+
+	// The provider replaces the password, not the second factor
+	if u.IsMFAEnabled() {
+		return s.mfaChallenge(ctx, u.ID)
+	}
+
+	var response *UserTokenResponse
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		resp, err := s.generateToken(u)
+		if err != nil {
+			return err
+		}
+
+		insertTokenInput := s.insertRefreshTokenInput(u.ID, resp.RefreshToken, client)
+		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
+			return err
+		}
+
+		response = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// oidcUser : linked user, else link the account with the same email, else a new account.
+// Linking and sign-up need an email the provider verified, linking also needs the local
+// account verified, so nobody takes over an address they never proved.
+func (s *userService) oidcUser(ctx context.Context, identity *oidc.Identity) (*user.User, error) {
+	u, err := s.repo.FindUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, errs.ErrUserNotFound) {
+		return nil, err
+	}
+
+	// Providers may return any case, matched and stored in lower case
+	email := loginguard.NormalizeEmail(identity.Email)
+	if email == "" || !identity.EmailVerified {
+		return nil, errs.ErrOIDCEmailNotVerified
+	}
+
+	link := &user.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+	}
+
+	existing, err := s.repo.FindUserByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if existing.IsDisabled() {
+			return nil, errs.ErrAccountDisabled
+		}
+		if !existing.IsEmailVerified() {
+			return nil, errs.ErrOIDCLinkConflict
+		}
+
+		link.UserID = existing.ID
+		err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+			return s.repo.InsertIdentityTx(ctx, tx, link)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		slog.Info("oidc identity linked", slog.String("user_id", existing.ID), slog.String("provider", identity.Provider))
+		return existing, nil
+
+	case errors.Is(err, errs.ErrUserNotFound):
+		// No password: sign in with the provider, confirm account changes with /users/reauth
+		verifiedAt := s.cfg.Now()
+		newUser := &user.User{
+			Email:           email,
+			Name:            truncate(identity.Name, maxNameLength),
+			Role:            user.RoleCustomer,
+			EmailVerifiedAt: &verifiedAt,
+		}
+
+		err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+			if err := s.repo.InsertUserTx(ctx, tx, newUser); err != nil {
+				return err
+			}
+			link.UserID = newUser.ID
+			return s.repo.InsertIdentityTx(ctx, tx, link)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		slog.Info("user signed up with oidc", slog.String("user_id", newUser.ID), slog.String("provider", identity.Provider))
+		return newUser, nil
+
+	default:
+		return nil, err
+	}
+}
+
+// ListIdentities : providers linked to the current user
+func (s *userService) ListIdentities(ctx context.Context) ([]*user.Identity, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) > max {
+		return string(r[:max])
+	}
+	return s
+}
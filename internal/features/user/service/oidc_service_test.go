@@ -0,0 +1,266 @@
+package userservice_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/internal/features/user/oidc"
+	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
+	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
+	"github.com/codepnw/go-starter-kit/pkg/database"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	oidcClientID    = "test-client"
+	oidcSecret      = "test-secret"
+	oidcRedirectURL = "http://localhost:3000/oidc/callback"
+)
+
+// setupOIDC : service with the fake provider served by httptest
+func setupOIDC(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService) {
+	fake, err := oidc.NewFakeProvider(oidcClientID, oidcSecret, oidcRedirectURL)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	fake.SetIssuer(srv.URL)
+
+	cfg := defaultConfig
+	cfg.OIDCStateTTL = 10 * time.Minute
+	cfg.OIDCProviders = map[string]oidc.Provider{
+		oidc.FakeProviderName: oidc.NewProvider(oidc.Config{
+			Name:         oidc.FakeProviderName,
+			Issuer:       srv.URL,
+			ClientID:     oidcClientID,
+			ClientSecret: oidcSecret,
+			RedirectURL:  oidcRedirectURL,
+		}, srv.Client()),
+	}
+
+	mockToken, mockTx, mockRepo, service, _ := setupWithConfig(t, cfg)
+	return mockToken, mockTx, mockRepo, service
+}
+
+// startOIDC : StartOIDC, then sign in at the fake provider, returns state and code
+func startOIDC(t *testing.T, mockRepo *userrepository.MockUserRepository, service userservice.UserService, hint url.Values) (*user.OIDCState, string, string) {
+	saved := new(user.OIDCState)
+	mockRepo.EXPECT().InsertOIDCState(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, st *user.OIDCState) error {
+			*saved = *st
+			return nil
+		},
+	).Times(1)
+
+	resp, err := service.StartOIDC(context.Background(), oidc.FakeProviderName)
+	require.NoError(t, err)
+	assert.Equal(t, oidc.FakeProviderName, saved.Provider)
+	assert.NotEqual(t, resp.State, saved.StateHash)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(resp.AuthURL + "&" + hint.Encode())
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.State, location.Query().Get("state"))
+
+	return saved, resp.State, location.Query().Get("code")
+}
+
+func expectTokens(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+	withTx(mockTx)
+	mockToken.EXPECT().GenerateAccessToken(gomock.Any()).Return("mock-access-token", nil).Times(1)
+	mockToken.EXPECT().GenerateRefreshToken(gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+	mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	const email = "social@mail.com"
+	verifiedUser := &user.User{ID: "mock-uuid-1", Email: email, EmailVerifiedAt: &mockNow}
+
+	type testCase struct {
+		name        string
+		hint        url.Values
+		mockFn      func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+		expectedMFA bool
+	}
+
+	testCases := []testCase{
+		{
+			name: "linked user",
+			hint: url.Values{"login_hint": {email}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(verifiedUser, nil).Times(1)
+				expectTokens(mockToken, mockTx, mockRepo)
+			},
+		},
+		{
+			name: "new user signs up",
+			hint: url.Values{"login_hint": {email}, "name": {"Social User"}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(nil, errs.ErrUserNotFound).Times(1)
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, errs.ErrUserNotFound).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().InsertUserTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, u *user.User) error {
+						assert.Equal(t, email, u.Email)
+						assert.Equal(t, "Social User", u.Name)
+						assert.Empty(t, u.Password)
+						assert.Equal(t, user.RoleCustomer, u.Role)
+						assert.True(t, u.IsEmailVerified())
+						u.ID = "mock-uuid-2"
+						return nil
+					},
+				).Times(1)
+				mockRepo.EXPECT().InsertIdentityTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, identity *user.Identity) error {
+						assert.Equal(t, "mock-uuid-2", identity.UserID)
+						assert.Equal(t, oidc.FakeProviderName, identity.Provider)
+						assert.NotEmpty(t, identity.Subject)
+						return nil
+					},
+				).Times(1)
+				expectTokens(mockToken, mockTx, mockRepo)
+			},
+		},
+		{
+			name: "link verified account",
+			hint: url.Values{"login_hint": {email}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(nil, errs.ErrUserNotFound).Times(1)
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(verifiedUser, nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().InsertIdentityTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, identity *user.Identity) error {
+						assert.Equal(t, verifiedUser.ID, identity.UserID)
+						return nil
+					},
+				).Times(1)
+				expectTokens(mockToken, mockTx, mockRepo)
+			},
+		},
+		{
+			name: "mixed case email links lower case account",
+			hint: url.Values{"login_hint": {" Social@Mail.COM "}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(nil, errs.ErrUserNotFound).Times(1)
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(verifiedUser, nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().InsertIdentityTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, identity *user.Identity) error {
+						assert.Equal(t, email, identity.Email)
+						return nil
+					},
+				).Times(1)
+				expectTokens(mockToken, mockTx, mockRepo)
+			},
+		},
+		{
+			name: "unverified local account not linked",
+			hint: url.Values{"login_hint": {email}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(nil, errs.ErrUserNotFound).Times(1)
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(&user.User{ID: "mock-uuid-1", Email: email}, nil).Times(1)
+			},
+			expectedErr: errs.ErrOIDCLinkConflict,
+		},
+		{
+			name: "provider email not verified",
+			hint: url.Values{"login_hint": {email}, "email_verified": {"false"}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(nil, errs.ErrUserNotFound).Times(1)
+			},
+			expectedErr: errs.ErrOIDCEmailNotVerified,
+		},
+		{
+			name: "mfa required",
+			hint: url.Values{"login_hint": {email}},
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mfaUser := &user.User{ID: "mock-uuid-1", Email: email, EmailVerifiedAt: &mockNow, TOTPEnabledAt: &mockNow}
+				mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(mfaUser, nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedMFA: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockToken, mockTx, mockRepo, service := setupOIDC(t)
+
+			saved, state, code := startOIDC(t, mockRepo, service, tc.hint)
+			mockRepo.EXPECT().UseOIDCState(gomock.Any(), saved.StateHash, oidc.FakeProviderName).Return(saved, nil).Times(1)
+			tc.mockFn(mockToken, mockTx, mockRepo)
+
+			resp, err := service.OIDCCallback(context.Background(), oidc.FakeProviderName, code, state, user.ClientInfo{})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+				return
+			}
+			assert.NoError(t, err)
+			if tc.expectedMFA {
+				assert.True(t, resp.MFARequired)
+				assert.Empty(t, resp.AccessToken)
+				return
+			}
+			assert.Equal(t, "mock-access-token", resp.AccessToken)
+			assert.Equal(t, "mock-refresh-token", resp.RefreshToken)
+		})
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	t.Run("unknown provider", func(t *testing.T) {
+		_, _, _, service := setupOIDC(t)
+
+		_, err := service.StartOIDC(context.Background(), "unknown")
+		assert.ErrorIs(t, err, errs.ErrOIDCProviderNotFound)
+
+		_, err = service.OIDCCallback(context.Background(), "unknown", "code", "state", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrOIDCProviderNotFound)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		_, _, mockRepo, service := setupOIDC(t)
+		mockRepo.EXPECT().UseOIDCState(gomock.Any(), gomock.Any(), oidc.FakeProviderName).Return(nil, errs.ErrInvalidOIDCState).Times(1)
+
+		_, err := service.OIDCCallback(context.Background(), oidc.FakeProviderName, "code", "state", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrInvalidOIDCState)
+	})
+
+	t.Run("code used twice", func(t *testing.T) {
+		_, _, mockRepo, service := setupOIDC(t)
+
+		saved, state, code := startOIDC(t, mockRepo, service, url.Values{"login_hint": {"social@mail.com"}})
+		mockRepo.EXPECT().UseOIDCState(gomock.Any(), saved.StateHash, oidc.FakeProviderName).Return(saved, nil).Times(1)
+		mockRepo.EXPECT().FindUserByIdentity(gomock.Any(), oidc.FakeProviderName, gomock.Any()).Return(nil, errs.ErrUserNotFound).Times(1)
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), gomock.Any()).Return(&user.User{ID: "mock-uuid-1"}, nil).Times(1)
+
+		// First use fails after the exchange, the code is spent anyway
+		_, err := service.OIDCCallback(context.Background(), oidc.FakeProviderName, code, state, user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrOIDCLinkConflict)
+
+		mockRepo.EXPECT().UseOIDCState(gomock.Any(), saved.StateHash, oidc.FakeProviderName).Return(saved, nil).Times(1)
+		_, err = service.OIDCCallback(context.Background(), oidc.FakeProviderName, code, state, user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrOIDCLoginFailed)
+	})
+}
@@ -3,50 +3,188 @@ package userservice
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/config"
 	"github.com/codepnw/go-starter-kit/internal/errs"
 	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/internal/features/user/oidc"
 	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
 	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/loginguard"
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
 	"github.com/codepnw/go-starter-kit/pkg/utils/password"
+	"github.com/codepnw/go-starter-kit/pkg/utils/secretbox"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
 )
 
 type UserService interface {
-	Register(ctx context.Context, u *user.User) (*UserTokenResponse, error)
-	Login(ctx context.Context, email, password string) (*UserTokenResponse, error)
-	RefreshToken(ctx context.Context, token string) (*UserTokenResponse, error)
+	Register(ctx context.Context, u *user.User, client user.ClientInfo) (*UserTokenResponse, error)
+	Login(ctx context.Context, email, password string, client user.ClientInfo) (*UserTokenResponse, error)
+	RefreshToken(ctx context.Context, token string, client user.ClientInfo) (*UserTokenResponse, error)
 	Logout(ctx context.Context, token string) error
+
+	// Email Verification
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+
+	// Password Reset
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+
+	// Two-Factor (TOTP)
+	VerifyMFA(ctx context.Context, mfaToken, code string, client user.ClientInfo) (*UserTokenResponse, error)
+	EnrollTOTP(ctx context.Context) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) (*RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, currentPassword, code string, client user.ClientInfo) error
+	RegenerateRecoveryCodes(ctx context.Context, currentPassword, code string, client user.ClientInfo) (*RecoveryCodesResponse, error)
+
+	// Social Login (OIDC)
+	StartOIDC(ctx context.Context, provider string) (*OIDCStartResponse, error)
+	OIDCCallback(ctx context.Context, provider, code, state string, client user.ClientInfo) (*UserTokenResponse, error)
+	ListIdentities(ctx context.Context) ([]*user.Identity, error)
+
+	// Sessions of the current user
+	ListSessions(ctx context.Context) ([]*user.Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	RevokeAllSessions(ctx context.Context) error
 	GetProfile(ctx context.Context) (*user.User, error)
+	UpdateProfile(ctx context.Context, input user.UpdateProfileInput, client user.ClientInfo) (*user.User, error)
+	ConfirmEmailChange(ctx context.Context, token string) error
+	ChangePassword(ctx context.Context, currentPassword, newPassword string, client user.ClientInfo) (*UserTokenResponse, error)
+	DeleteAccount(ctx context.Context, currentPassword string, client user.ClientInfo) error
+	RequestReauth(ctx context.Context) error
+	UpdateUserRole(ctx context.Context, userID string, role user.Role) error
+	UnlockUser(ctx context.Context, userID string) error
+	ListLoginFailures(ctx context.Context, userID string) ([]*loginguard.Failure, error)
+	BootstrapAdmin(ctx context.Context, email, password string) error
+
+	// API Keys: managed by admins, used by back-office integrations
+	CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*APIKeyCreated, error)
+	ListAPIKeys(ctx context.Context) ([]*user.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID string) error
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (*user.APIKey, error)
+
+	// Admin Users: staff search and manage customer accounts
+	ListUsers(ctx context.Context, filter user.UserFilter, page, limit int) (*user.UserListResponse, error)
+	GetUser(ctx context.Context, userID string) (*user.AdminUserDetails, error)
+	DisableUser(ctx context.Context, userID string) error
+	EnableUser(ctx context.Context, userID string) error
+	ForceLogout(ctx context.Context, userID string) error
+
+	// Impersonation: admins see the shop as a customer, every request is audited
+	Impersonate(ctx context.Context, userID, reason string, client user.ClientInfo) (*ImpersonationResponse, error)
+	AuditImpersonation(ctx context.Context, req *user.ImpersonationRequest) error
+	CompleteImpersonationAudit(ctx context.Context, req *user.ImpersonationRequest) error
+}
+
+// Config : email verification and password reset settings, links get ?token= added
+type Config struct {
+	AllowUnverifiedLogin bool
+	VerificationTTL      time.Duration
+	VerifyEmailURL       string
+	ConfirmEmailURL      string // email change, sent to the new address
+
+	VerificationResendLimit  int // emails per address in VerificationResendWindow, 0 = no limit
+	VerificationResendWindow time.Duration
+
+	PasswordResetTTL    time.Duration
+	PasswordResetURL    string
+	PasswordResetLimit  int // emails per address in PasswordResetWindow, 0 = no limit
+	PasswordResetWindow time.Duration
+
+	// Two-factor: TOTP secrets are encrypted with MFASecrets, nil = enrollment and TOTP codes refused
+	MFAIssuer       string
+	MFAChallengeTTL time.Duration
+	MFASecrets      *secretbox.Box
+
+	// Social login: providers by name, state is valid for OIDCStateTTL
+	OIDCProviders map[string]oidc.Provider
+	OIDCStateTTL  time.Duration
+
+	// Passwords hashes new passwords and flags old hashes for rehash on login,
+	// PasswordPolicy is checked on register, reset and change
+	Passwords      password.Hasher
+	PasswordPolicy *password.Policy
+
+	// ImpersonationTTL : lifetime of the access token an admin gets to act as a customer
+	ImpersonationTTL time.Duration
+
+	Now func() time.Time // time.Now when nil, tests control TOTP time
 }
 
 type userService struct {
 	tx    database.TxManager
 	token jwttoken.JWTToken
 	repo  userrepository.UserRepository
+	mail  mailer.Mailer
+	guard *loginguard.Guard
+	deny  denylist.Store
+	cfg   Config
 }
 
-func NewUserService(tx database.TxManager, token jwttoken.JWTToken, repo userrepository.UserRepository) UserService {
+func NewUserService(tx database.TxManager, token jwttoken.JWTToken, repo userrepository.UserRepository, mail mailer.Mailer, guard *loginguard.Guard, deny denylist.Store, cfg Config) UserService {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.Passwords == nil {
+		cfg.Passwords = password.Default()
+	}
+	if cfg.PasswordPolicy == nil {
+		cfg.PasswordPolicy = &password.Policy{MinLength: defaultPasswordMinLength, MaxLength: defaultPasswordMaxLength}
+	}
+	if cfg.ImpersonationTTL <= 0 {
+		cfg.ImpersonationTTL = defaultImpersonationTTL
+	}
 	return &userService{
 		tx:    tx,
 		token: token,
 		repo:  repo,
+		mail:  mail,
+		guard: guard,
+		deny:  deny,
+		cfg:   cfg,
 	}
 }
 
+// Failed logins returned to admins
+const loginFailuresLimit = 50
+
+// Reauth codes of accounts without a password
+const reauthTTL = 10 * time.Minute
+
+// Password policy when Config has none
+const (
+	defaultPasswordMinLength = 8
+	defaultPasswordMaxLength = 128
+)
+
 type UserTokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// VerificationRequired : no tokens until the email is verified
+	VerificationRequired bool `json:"verification_required,omitempty"`
+	// MFARequired : no tokens until MFAToken and a code are sent to /auth/login/mfa
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
-func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenResponse, error) {
+func (s *userService) Register(ctx context.Context, u *user.User, client user.ClientInfo) (*UserTokenResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
+	if err := s.checkPassword(u.Password); err != nil {
+		return nil, err
+	}
+
 	// Check Email Exists
 	exists, err := s.repo.CheckEmailExists(ctx, u.Email)
 	if err != nil {
@@ -57,13 +195,17 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 	}
 
 	// Hash Password
-	hashedPassword, err := password.GenerateHashPassword(u.Password)
+	hashedPassword, err := s.cfg.Passwords.Hash(u.Password)
 	if err != nil {
 		return nil, err
 	}
 	u.Password = hashedPassword
 
+	// Public Register Always Customer
+	u.Role = user.RoleCustomer
+
 	var response *UserTokenResponse
+	var verifyToken string
 	// DB Transaction
 	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
 		// Insert User
@@ -71,6 +213,18 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 			return err
 		}
 
+		// Email Verification Token
+		token, err := s.newUserTokenTx(ctx, tx, u.ID, user.TokenEmailVerification, s.cfg.VerificationTTL)
+		if err != nil {
+			return err
+		}
+		verifyToken = token
+
+		if !s.cfg.AllowUnverifiedLogin {
+			response = &UserTokenResponse{VerificationRequired: true}
+			return nil
+		}
+
 		// Generate Token
 		resp, err := s.generateToken(u)
 		if err != nil {
@@ -78,7 +232,7 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 		}
 
 		// Save Refresh Token
-		insertTokenInput := s.insertRefreshTokenInput(u.ID, resp.RefreshToken)
+		insertTokenInput := s.insertRefreshTokenInput(u.ID, resp.RefreshToken, client)
 		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
 			return err
 		}
@@ -90,23 +244,56 @@ func (s *userService) Register(ctx context.Context, u *user.User) (*UserTokenRes
 		return nil, err
 	}
 
+	// Account exists even when the email fails, user can ask for a new one
+	if err := s.sendVerificationEmail(ctx, u.Email, verifyToken); err != nil {
+		slog.Error("send verification email failed", slog.String("user_id", u.ID), slog.Any("error", err))
+	}
+
 	return response, nil
 }
 
-func (s *userService) Login(ctx context.Context, email string, pwd string) (*UserTokenResponse, error) {
+func (s *userService) Login(ctx context.Context, email string, pwd string, client user.ClientInfo) (*UserTokenResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
+	// Brute-Force Lockout: account or IP
+	if err := s.checkLoginLock(ctx, email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// Find User Email
 	foundUser, err := s.repo.FindUserByEmail(ctx, email)
 	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			s.loginFailed(ctx, email, "", client, loginguard.ReasonUnknownEmail)
+		}
 		return nil, errs.ErrInvalidEmailOrPassword
 	}
 
 	// Verify Password
-	if ok := password.CompareHashedPassword(foundUser.Password, pwd); !ok {
+	ok, rehash := s.cfg.Passwords.Verify(foundUser.Password, pwd)
+	if !ok {
+		s.loginFailed(ctx, email, foundUser.ID, client, loginguard.ReasonBadPassword)
 		return nil, errs.ErrInvalidEmailOrPassword
 	}
+	if rehash {
+		s.rehashPassword(ctx, foundUser, pwd)
+	}
+
+	// Told only after the password, so it does not reveal accounts
+	if foundUser.IsDisabled() {
+		return nil, errs.ErrAccountDisabled
+	}
+
+	if !s.cfg.AllowUnverifiedLogin && !foundUser.IsEmailVerified() {
+		return nil, errs.ErrEmailNotVerified
+	}
+
+	// Second factor before any token
+	if foundUser.IsMFAEnabled() {
+		return s.mfaChallenge(ctx, foundUser.ID)
+	}
+	s.loginSucceeded(ctx, email)
 
 	var response *UserTokenResponse
 	// DB Transaction
@@ -118,7 +305,7 @@ func (s *userService) Login(ctx context.Context, email string, pwd string) (*Use
 		}
 
 		// Save Refresh Token
-		insertTokenInput := s.insertRefreshTokenInput(foundUser.ID, resp.RefreshToken)
+		insertTokenInput := s.insertRefreshTokenInput(foundUser.ID, resp.RefreshToken, client)
 		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
 			return err
 		}
@@ -133,31 +320,44 @@ func (s *userService) Login(ctx context.Context, email string, pwd string) (*Use
 	return response, nil
 }
 
-func (s *userService) RefreshToken(ctx context.Context, token string) (*UserTokenResponse, error) {
+// RefreshToken : works without an access token, the user comes from the refresh token itself
+func (s *userService) RefreshToken(ctx context.Context, token string, client user.ClientInfo) (*UserTokenResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
-	// Validate Token
-	if err := s.repo.ValidateRefreshToken(ctx, token); err != nil {
-		return nil, err
+	// Verify Signature & Expiry
+	claims, err := s.token.VerifyRefreshToken(token)
+	if err != nil {
+		return nil, errs.ErrInvalidToken
 	}
+	tokenHash := securetoken.Hash(token)
 
-	// Get UserID From Context
-	userID, err := auth.GetUserIDFromContext(ctx)
+	// Validate Token
+	stored, err := s.repo.ValidateRefreshToken(ctx, tokenHash)
 	if err != nil {
+		if errors.Is(err, errs.ErrTokenRevoked) {
+			// Rotated token presented again: likely stolen
+			return nil, s.revokeTokenFamily(ctx, stored)
+		}
 		return nil, err
 	}
+	if stored.UserID != claims.UserID {
+		return nil, errs.ErrInvalidToken
+	}
 
-	userData, err := s.repo.FindUserByID(ctx, userID)
+	userData, err := s.repo.FindUserByID(ctx, stored.UserID)
 	if err != nil {
 		return nil, err
 	}
+	if userData.IsDisabled() {
+		return nil, errs.ErrAccountDisabled
+	}
 
 	var response *UserTokenResponse
 	// DB Transaction
 	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
 		// Revoked Old Token
-		if err := s.repo.RevokedRefreshTokenTx(ctx, tx, token); err != nil {
+		if err := s.repo.RevokedRefreshTokenTx(ctx, tx, tokenHash); err != nil {
 			return err
 		}
 
@@ -167,8 +367,13 @@ func (s *userService) RefreshToken(ctx context.Context, token string) (*UserToke
 			return err
 		}
 
-		// Save New Token
-		insertTokenInput := s.insertRefreshTokenInput(userData.ID, resp.RefreshToken)
+		// Save New Token, Same Family
+		if client.DeviceLabel == "" {
+			client.DeviceLabel = stored.DeviceLabel
+		}
+		insertTokenInput := s.insertRefreshTokenInput(userData.ID, resp.RefreshToken, client)
+		insertTokenInput.FamilyID = stored.FamilyID
+		insertTokenInput.ParentID = &stored.ID
 		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
 			return err
 		}
@@ -177,26 +382,254 @@ func (s *userService) RefreshToken(ctx context.Context, token string) (*UserToke
 		return nil
 	})
 	if err != nil {
+		if errors.Is(err, errs.ErrTokenRevoked) {
+			// Concurrent refresh with the same token won the race
+			return nil, s.revokeTokenFamily(ctx, stored)
+		}
 		return nil, err
 	}
 
 	return response, nil
 }
 
+// Logout : revoke the refresh token and the access token that sent the request
 func (s *userService) Logout(ctx context.Context, token string) error {
 	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
 	defer cancel()
 
 	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
-		if err := s.repo.RevokedRefreshTokenTx(ctx, tx, token); err != nil {
+		if err := s.repo.RevokedRefreshTokenTx(ctx, tx, securetoken.Hash(token)); err != nil {
+			return err
+		}
+		return nil
+	})
+	// Already Logged Out
+	if err != nil && !errors.Is(err, errs.ErrTokenRevoked) {
+		return err
+	}
+
+	return s.revokeAccessToken(ctx)
+}
+
+// VerifyEmail : token is single use
+func (s *userService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	return s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		t, err := s.repo.UseUserTokenTx(ctx, tx, securetoken.Hash(token), user.TokenEmailVerification)
+		if err != nil {
 			return err
 		}
+		return s.repo.MarkEmailVerifiedTx(ctx, tx, t.UserID)
+	})
+}
+
+// ResendVerification : unknown, verified or rate limited emails succeed silently, so emails cannot be probed
+func (s *userService) ResendVerification(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	foundUser, err := s.repo.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if foundUser.IsEmailVerified() {
 		return nil
+	}
+
+	// Rate Limit Per Email
+	if s.cfg.VerificationResendLimit > 0 {
+		since := s.cfg.Now().Add(-s.cfg.VerificationResendWindow)
+		count, err := s.repo.CountUserTokensSince(ctx, foundUser.ID, user.TokenEmailVerification, since)
+		if err != nil {
+			return err
+		}
+		if count >= s.cfg.VerificationResendLimit {
+			slog.Warn("verification resend rate limited", slog.String("user_id", foundUser.ID))
+			return nil
+		}
+	}
+
+	var verifyToken string
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		verifyToken, err = s.newUserTokenTx(ctx, tx, foundUser.ID, user.TokenEmailVerification, s.cfg.VerificationTTL)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Send in the background like ForgotPassword, a mail error must not tell the caller the account exists
+	go func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+		defer cancel()
+
+		if err := s.sendVerificationEmail(ctx, foundUser.Email, verifyToken); err != nil {
+			slog.Error("send verification email failed", slog.String("user_id", foundUser.ID), slog.Any("error", err))
+		}
+	}(context.WithoutCancel(ctx))
+
+	return nil
+}
+
+// ForgotPassword : same result for unknown, rate limited or valid emails, so emails cannot be probed
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	foundUser, err := s.repo.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// Rate Limit Per Email
+	if s.cfg.PasswordResetLimit > 0 {
+		since := s.cfg.Now().Add(-s.cfg.PasswordResetWindow)
+		count, err := s.repo.CountUserTokensSince(ctx, foundUser.ID, user.TokenPasswordReset, since)
+		if err != nil {
+			return err
+		}
+		if count >= s.cfg.PasswordResetLimit {
+			slog.Warn("password reset rate limited", slog.String("user_id", foundUser.ID))
+			return nil
+		}
+	}
+
+	var resetToken string
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		resetToken, err = s.newUserTokenTx(ctx, tx, foundUser.ID, user.TokenPasswordReset, s.cfg.PasswordResetTTL)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.PasswordResetURL + "?token=" + url.QueryEscape(resetToken)
+	msg := mailer.Message{
+		To:      foundUser.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your account.\n\nChoose a new password here:\n\n%s\n\nThe link expires in %s and works once. If it was not you, ignore this email, your password stays the same.\n",
+			link,
+			s.cfg.PasswordResetTTL,
+		),
+	}
+
+	// Send in the background, a mail error or a slow SMTP server must not tell the caller the account exists
+	go s.sendEmailInBackground(context.WithoutCancel(ctx), foundUser.ID, msg)
+
+	return nil
+}
+
+// sendEmailInBackground : run with go, failures are only logged, the user can request again
+func (s *userService) sendEmailInBackground(ctx context.Context, userID string, msg mailer.Message) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := s.mail.Send(ctx, msg); err != nil {
+		slog.Error("send email failed", slog.String("user_id", userID), slog.String("subject", msg.Subject), slog.Any("error", err))
+	}
+}
+
+// ResetPassword : consumes the token and logs the user out everywhere
+func (s *userService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := s.checkPassword(newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := s.cfg.Passwords.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	var userID string
+	var revoked int64
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		t, err := s.repo.UseUserTokenTx(ctx, tx, securetoken.Hash(token), user.TokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		userID = t.UserID
+
+		if err := s.repo.UpdatePasswordTx(ctx, tx, userID, hashedPassword); err != nil {
+			return err
+		}
+
+		// Other Reset Links Stop Working
+		if err := s.repo.InvalidateUserTokensTx(ctx, tx, userID, user.TokenPasswordReset); err != nil {
+			return err
+		}
+
+		// The link reached the inbox, so the email is theirs
+		if err := s.repo.MarkEmailVerifiedTx(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		revoked, err = s.repo.RevokeAllSessionsTx(ctx, tx, userID)
+		return err
 	})
 	if err != nil {
 		return err
 	}
 
+	// Access tokens issued with the old password stop working too
+	if err := s.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	slog.Info("password reset, all sessions revoked", slog.String("user_id", userID), slog.Int64("revoked", revoked))
+	return nil
+}
+
+func (s *userService) ListSessions(ctx context.Context) ([]*user.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+	return s.repo.FindSessions(ctx, userID)
+}
+
+// RevokeSession : the device can no longer refresh, its access token lives until expiry
+func (s *userService) RevokeSession(ctx context.Context, sessionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+	return s.repo.RevokeSession(ctx, userID, sessionID)
+}
+
+// RevokeAllSessions : log out everywhere, including this device
+func (s *userService) RevokeAllSessions(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+
+	count, err := s.repo.RevokeAllSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("all sessions revoked", slog.String("user_id", userID), slog.Int64("revoked", count))
 	return nil
 }
 
@@ -216,31 +649,628 @@ func (s *userService) GetProfile(ctx context.Context) (*user.User, error) {
 	return userData, nil
 }
 
-// ------------------ Private Method -------------------
+// UpdateProfile : a new email is only pending until confirmed from the new inbox
+func (s *userService) UpdateProfile(ctx context.Context, input user.UpdateProfileInput, client user.ClientInfo) (*user.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
 
-func (s *userService) generateToken(u *user.User) (*UserTokenResponse, error) {
-	accessToken, err := s.token.GenerateAccessToken(u)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed gen access token: %w", err)
+		return nil, errs.ErrUnauthorized
 	}
 
-	refreshToken, err := s.token.GenerateRefreshToken(u)
+	u, err := s.repo.FindUserByID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed gen refresh token: %w", err)
+		return nil, err
 	}
+	oldEmail := u.Email
 
-	response := &UserTokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	if input.Name != nil {
+		u.Name = strings.TrimSpace(*input.Name)
 	}
-	return response, nil
+
+	var newEmail string
+	var cancelPending bool
+	if input.Email != nil {
+		email := strings.TrimSpace(*input.Email)
+
+		if email == u.Email {
+			// Back to current email: cancel pending change
+			cancelPending = u.PendingEmail != nil
+			u.PendingEmail = nil
+		} else {
+			if err := s.checkCurrentPassword(ctx, u, input.CurrentPassword, client); err != nil {
+				return nil, err
+			}
+
+			exists, err := s.repo.CheckEmailExists(ctx, email)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, errs.ErrEmailAlreadyExists
+			}
+
+			u.PendingEmail = &email
+			newEmail = email
+		}
+	}
+
+	var changeToken string
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.UpdateProfileTx(ctx, tx, u); err != nil {
+			return err
+		}
+
+		if cancelPending {
+			return s.repo.InvalidateUserTokensTx(ctx, tx, u.ID, user.TokenEmailChange)
+		}
+		if newEmail == "" {
+			return nil
+		}
+
+		token, err := s.newUserTokenTx(ctx, tx, u.ID, user.TokenEmailChange, s.cfg.VerificationTTL)
+		if err != nil {
+			return err
+		}
+		changeToken = token
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if newEmail != "" {
+		s.sendEmailChangeEmails(ctx, oldEmail, newEmail, changeToken)
+	}
+
+	return u, nil
 }
 
-func (s *userService) insertRefreshTokenInput(userID, token string) *user.RefreshToken {
-	return &user.RefreshToken{
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(config.RefreshTokenDuration),
-		Revoked:   false,
+// ConfirmEmailChange : token from the new inbox, the new email is verified by it
+func (s *userService) ConfirmEmailChange(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	return s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		t, err := s.repo.UseUserTokenTx(ctx, tx, securetoken.Hash(token), user.TokenEmailChange)
+		if err != nil {
+			return err
+		}
+		return s.repo.ApplyPendingEmailTx(ctx, tx, t.UserID)
+	})
+}
+
+// ChangePassword : every session is revoked, the caller gets a new one in the response
+func (s *userService) ChangePassword(ctx context.Context, currentPassword, newPassword string, client user.ClientInfo) (*UserTokenResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errs.ErrUnauthorized
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCurrentPassword(ctx, u, currentPassword, client); err != nil {
+		return nil, err
+	}
+	if currentPassword == newPassword {
+		return nil, errs.ErrSamePassword
+	}
+	if err := s.checkPassword(newPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.cfg.Passwords.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *UserTokenResponse
+	var revoked int64
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.UpdatePasswordTx(ctx, tx, u.ID, hashedPassword); err != nil {
+			return err
+		}
+
+		// Revoke Other Sessions
+		revoked, err = s.repo.RevokeAllSessionsTx(ctx, tx, u.ID)
+		if err != nil {
+			return err
+		}
+
+		// New Session For This Device
+		resp, err := s.generateToken(u)
+		if err != nil {
+			return err
+		}
+
+		insertTokenInput := s.insertRefreshTokenInput(u.ID, resp.RefreshToken, client)
+		if err := s.repo.InsertRefreshTokenTx(ctx, tx, insertTokenInput); err != nil {
+			return err
+		}
+
+		response = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The new password is saved, a failure here only leaves the old access token alive
+	if err := s.revokeAccessToken(ctx); err != nil {
+		slog.Error("revoke access token failed", slog.String("user_id", u.ID), slog.Any("error", err))
+	}
+
+	slog.Info("password changed, other sessions revoked", slog.String("user_id", u.ID), slog.Int64("revoked", revoked))
+	return response, nil
+}
+
+// DeleteAccount : anonymize the user, orders stay for accounting
+func (s *userService) DeleteAccount(ctx context.Context, currentPassword string, client user.ClientInfo) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkCurrentPassword(ctx, u, currentPassword, client); err != nil {
+		return err
+	}
+
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.repo.AnonymizeUserTx(ctx, tx, u.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Access tokens still valid until expiry
+	if err := s.revokeUserTokens(ctx, u.ID); err != nil {
+		return err
+	}
+
+	slog.Info("account deleted", slog.String("user_id", u.ID))
+	return nil
+}
+
+// RequestReauth : emails a one-time code to an account without a password (social login).
+// The code is sent as the current password to change the email or password, delete the account or turn off 2FA.
+func (s *userService) RequestReauth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return errs.ErrUnauthorized
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.HasPassword() {
+		return errs.ErrPasswordSet
+	}
+
+	// Same limit as password reset emails
+	if s.cfg.PasswordResetLimit > 0 {
+		since := s.cfg.Now().Add(-s.cfg.PasswordResetWindow)
+		count, err := s.repo.CountUserTokensSince(ctx, u.ID, user.TokenReauth, since)
+		if err != nil {
+			return err
+		}
+		if count >= s.cfg.PasswordResetLimit {
+			return errs.ErrTooManyLoginAttempts
+		}
+	}
+
+	var code string
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		code, err = s.newUserTokenTx(ctx, tx, u.ID, user.TokenReauth, reauthTTL)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm it is you",
+		Body: fmt.Sprintf(
+			"Someone signed in to your account asked to change its security settings.\n\nEnter this code to confirm:\n\n%s\n\nThe code expires in %s and works once. If it was not you, sign out of your other sessions.\n",
+			code,
+			reauthTTL,
+		),
+	}
+	go s.sendEmailInBackground(context.WithoutCancel(ctx), u.ID, msg)
+
+	return nil
+}
+
+// UnlockUser : admin clears the login lockout of an account
+func (s *userService) UnlockUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequireRole(ctx, user.RoleAdmin); err != nil {
+		return err
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.guard.Unlock(ctx, u.Email); err != nil {
+		return err
+	}
+
+	adminID, _ := auth.GetUserIDFromContext(ctx)
+	slog.Info("login lockout cleared", slog.String("user_id", u.ID), slog.String("by", adminID))
+	return nil
+}
+
+// ListLoginFailures : latest failed logins of an account, newest first
+func (s *userService) ListLoginFailures(ctx context.Context, userID string) ([]*loginguard.Failure, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if err := auth.RequireRole(ctx, user.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.guard.Failures(ctx, u.Email, loginFailuresLimit)
+}
+
+func (s *userService) UpdateUserRole(ctx context.Context, userID string, role user.Role) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	// Admin Only
+	if err := auth.RequireRole(ctx, user.RoleAdmin); err != nil {
+		return err
+	}
+
+	if !role.IsValid() {
+		return errs.ErrInvalidRole
+	}
+
+	// Prevent Admin Lock Out
+	callerID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if callerID == userID {
+		return errs.ErrCannotChangeOwnRole
+	}
+
+	// Old role must not outlive the change: sessions and access tokens are revoked
+	var revoked int64
+	err = s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := s.repo.UpdateUserRoleTx(ctx, tx, userID, role); err != nil {
+			return err
+		}
+		revoked, err = s.repo.RevokeAllSessionsTx(ctx, tx, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.revokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	// Only admins impersonate, a demoted one loses the tokens already handed out
+	if role != user.RoleAdmin {
+		if err := s.revokeIssuedImpersonations(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("user role changed", slog.String("user_id", userID), slog.String("role", string(role)), slog.String("by", callerID), slog.Int64("revoked", revoked))
+	return nil
+}
+
+// BootstrapAdmin : create the first admin, skip when an admin already exists.
+// An existing account is never promoted, anyone could have registered the address first.
+func (s *userService) BootstrapAdmin(ctx context.Context, email, pwd string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ContextTimeout)
+	defer cancel()
+
+	if email == "" {
+		return nil
+	}
+
+	exists, err := s.repo.CheckRoleExists(ctx, user.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = s.repo.FindUserByEmail(ctx, email)
+	if err == nil {
+		return fmt.Errorf("admin email %s belongs to an existing account, choose an unused address", email)
+	}
+	if !errors.Is(err, errs.ErrUserNotFound) {
+		return err
+	}
+
+	// Create New Admin
+	if pwd == "" {
+		return errors.New("admin password is required")
+	}
+	hashedPassword, err := s.cfg.Passwords.Hash(pwd)
+	if err != nil {
+		return err
+	}
+
+	// Configured by the operator, no verification email
+	now := s.cfg.Now()
+	admin := &user.User{
+		Email:           email,
+		Password:        hashedPassword,
+		Role:            user.RoleAdmin,
+		EmailVerifiedAt: &now,
+	}
+	return s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.repo.InsertUserTx(ctx, tx, admin)
+	})
+}
+
+// ------------------ Private Method -------------------
+
+func (s *userService) generateToken(u *user.User) (*UserTokenResponse, error) {
+	accessToken, err := s.token.GenerateAccessToken(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed gen access token: %w", err)
+	}
+
+	refreshToken, err := s.token.GenerateRefreshToken(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed gen refresh token: %w", err)
+	}
+
+	response := &UserTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+	return response, nil
+}
+
+// newUserTokenTx : older tokens of the same purpose stop working, returns the raw token for the email
+func (s *userService) newUserTokenTx(ctx context.Context, tx *sql.Tx, userID string, purpose user.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := s.repo.InvalidateUserTokensTx(ctx, tx, userID, purpose); err != nil {
+		return "", err
+	}
+	return s.insertUserTokenTx(ctx, tx, userID, purpose, ttl)
+}
+
+// insertUserTokenTx : like newUserTokenTx, but other tokens of the purpose stay valid
+func (s *userService) insertUserTokenTx(ctx context.Context, tx *sql.Tx, userID string, purpose user.TokenPurpose, ttl time.Duration) (string, error) {
+	raw, err := securetoken.Generate(32)
+	if err != nil {
+		return "", fmt.Errorf("generate token failed: %w", err)
+	}
+
+	t := &user.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: securetoken.Hash(raw),
+		ExpiresAt: s.cfg.Now().Add(ttl),
+	}
+	if err := s.repo.InsertUserTokenTx(ctx, tx, t); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *userService) sendVerificationEmail(ctx context.Context, email, token string) error {
+	link := s.cfg.VerifyEmailURL + "?token=" + url.QueryEscape(token)
+
+	return s.mail.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Welcome!\n\nConfirm your email address by opening this link:\n\n%s\n\nThe link expires in %s. If you did not create an account, you can ignore this email.\n",
+			link,
+			s.cfg.VerificationTTL,
+		),
+	})
+}
+
+// checkLoginLock : ErrTooManyLoginAttempts while the account or IP is locked
+func (s *userService) checkLoginLock(ctx context.Context, email, ip string) error {
+	wait, err := s.guard.Check(ctx, email, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return errs.ErrTooManyLoginAttempts
+	}
+	return nil
+}
+
+// checkCurrentPassword : re-authentication of a logged in user, wrong passwords count toward the login lockout.
+// Accounts without a password send the code from RequestReauth instead.
+func (s *userService) checkCurrentPassword(ctx context.Context, u *user.User, pwd string, client user.ClientInfo) error {
+	if err := s.checkLoginLock(ctx, u.Email, client.IPAddress); err != nil {
+		return err
+	}
+
+	ok := false
+	if u.HasPassword() {
+		ok, _ = s.cfg.Passwords.Verify(u.Password, pwd)
+	} else if pwd != "" {
+		ok = s.useReauthCode(ctx, u.ID, pwd)
+	}
+	if !ok {
+		s.loginFailed(ctx, u.Email, u.ID, client, loginguard.ReasonBadPassword)
+		return errs.ErrInvalidPassword
+	}
+	return nil
+}
+
+// useReauthCode : single use, only for the user it was sent to
+func (s *userService) useReauthCode(ctx context.Context, userID, code string) bool {
+	err := s.tx.WithTx(ctx, func(tx *sql.Tx) error {
+		t, err := s.repo.UseUserTokenTx(ctx, tx, securetoken.Hash(strings.TrimSpace(code)), user.TokenReauth)
+		if err != nil {
+			return err
+		}
+		if t.UserID != userID {
+			return errs.ErrInvalidUserToken
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errs.ErrInvalidUserToken) {
+		slog.Error("check reauth code failed", slog.String("user_id", userID), slog.Any("error", err))
+	}
+	return err == nil
+}
+
+// revokeAccessToken : the caller's access token stops working before it expires.
+// Only the current token is known, other devices keep theirs until expiry.
+func (s *userService) revokeAccessToken(ctx context.Context) error {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil || claims.RegisteredClaims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return s.deny.Add(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// revokeUserTokens : every access token of the user issued until now stops working,
+// including impersonation tokens. Called after the change is committed, so a token
+// refreshed meanwhile already carries it.
+func (s *userService) revokeUserTokens(ctx context.Context, userID string) error {
+	now := s.cfg.Now()
+	return s.deny.RevokeUser(ctx, userID, now, now.Add(max(config.AccessTokenDuration, s.cfg.ImpersonationTTL)))
+}
+
+// loginSucceeded : failures of the account are forgiven, not of the IP
+func (s *userService) loginSucceeded(ctx context.Context, email string) {
+	if err := s.guard.Succeed(ctx, email); err != nil {
+		slog.Error("reset login failures failed", slog.Any("error", err))
+	}
+}
+
+// loginFailed : the login answer stays the same when recording fails
+func (s *userService) loginFailed(ctx context.Context, email, userID string, client user.ClientInfo, reason string) {
+	err := s.guard.Fail(ctx, loginguard.Failure{
+		Email:     email,
+		UserID:    userID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Reason:    reason,
+	})
+	if err != nil {
+		slog.Error("record login failure failed", slog.Any("error", err))
+	}
+}
+
+// checkPassword : policy for new passwords, existing ones still log in
+func (s *userService) checkPassword(pwd string) error {
+	switch err := s.cfg.PasswordPolicy.Check(pwd); err {
+	case nil:
+		return nil
+	case password.ErrTooShort:
+		return errs.ErrPasswordTooShort
+	case password.ErrTooLong:
+		return errs.ErrPasswordTooLong
+	case password.ErrBreached:
+		return errs.ErrPasswordBreached
+	default:
+		return err
+	}
+}
+
+// rehashPassword : upgrades a bcrypt or outdated argon2id hash while the plain password is known.
+// Login goes on when it fails, the next login tries again.
+func (s *userService) rehashPassword(ctx context.Context, u *user.User, pwd string) {
+	hashed, err := s.cfg.Passwords.Hash(pwd)
+	if err == nil {
+		// Skipped when the password changed since it was read
+		err = s.repo.RehashPassword(ctx, u.ID, u.Password, hashed)
+	}
+	if err != nil {
+		slog.Error("rehash password failed", slog.String("user_id", u.ID), slog.Any("error", err))
+		return
+	}
+	u.Password = hashed
+}
+
+// sendEmailChangeEmails : confirmation link to the new address, notice to the old one.
+// Profile is already saved, failures are only logged, the user can request again.
+func (s *userService) sendEmailChangeEmails(ctx context.Context, oldEmail, newEmail, token string) {
+	link := s.cfg.ConfirmEmailURL + "?token=" + url.QueryEscape(token)
+
+	confirm := mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Confirm this address for your account by opening this link:\n\n%s\n\nThe link expires in %s. Until then you keep logging in with your current email.\n",
+			link,
+			s.cfg.VerificationTTL,
+		),
+	}
+	if err := s.mail.Send(ctx, confirm); err != nil {
+		slog.Error("send email change confirmation failed", slog.Any("error", err))
+	}
+
+	notice := mailer.Message{
+		To:      oldEmail,
+		Subject: "Your email address is being changed",
+		Body:    "A change of your account email was requested. If it was not you, change your password now.\n",
+	}
+	if err := s.mail.Send(ctx, notice); err != nil {
+		slog.Error("send email change notice failed", slog.Any("error", err))
+	}
+}
+
+// revokeTokenFamily : revoke every token issued from the same login and record a security event
+func (s *userService) revokeTokenFamily(ctx context.Context, rt *user.RefreshToken) error {
+	count, err := s.repo.RevokeTokenFamily(ctx, rt.FamilyID)
+	if err != nil {
+		return fmt.Errorf("revoke token family failed: %w", err)
+	}
+
+	slog.Warn("refresh token reuse detected, token family revoked",
+		slog.String("event", "refresh_token_reuse"),
+		slog.String("user_id", rt.UserID),
+		slog.String("family_id", rt.FamilyID),
+		slog.String("token_id", rt.ID),
+		slog.Int64("revoked", count),
+	)
+	return errs.ErrTokenReused
+}
+
+// insertRefreshTokenInput : only the hash is stored, a DB leak does not leak usable tokens
+func (s *userService) insertRefreshTokenInput(userID, token string, client user.ClientInfo) *user.RefreshToken {
+	return &user.RefreshToken{
+		UserID:      userID,
+		Token:       securetoken.Hash(token),
+		UserAgent:   client.UserAgent,
+		IPAddress:   client.IPAddress,
+		DeviceLabel: client.Label(),
+		ExpiresAt:   s.cfg.Now().Add(config.RefreshTokenDuration),
+		Revoked:     false,
 	}
 }
@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/auth"
 	"github.com/codepnw/go-starter-kit/internal/errs"
@@ -12,9 +16,16 @@ import (
 	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
 	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
 	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/loginguard"
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
+	"github.com/codepnw/go-starter-kit/pkg/utils/password"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var ErrDB = errors.New("DB Error")
@@ -42,6 +53,9 @@ func TestRegister(t *testing.T) {
 
 				repo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
 
+				repo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, input.ID, user.TokenEmailVerification).Return(nil).Times(1)
+				repo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+
 				token.EXPECT().GenerateAccessToken(input).Return("mock-access-token", nil).Times(1)
 				token.EXPECT().GenerateRefreshToken(input).Return("mock-refresh-token", nil).Times(1)
 
@@ -87,6 +101,9 @@ func TestRegister(t *testing.T) {
 
 				repo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
 
+				repo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, input.ID, user.TokenEmailVerification).Return(nil).Times(1)
+				repo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+
 				token.EXPECT().GenerateAccessToken(input).Return("mock-access-token", nil).Times(1)
 				token.EXPECT().GenerateRefreshToken(input).Return("", ErrDB).Times(1)
 			},
@@ -106,6 +123,9 @@ func TestRegister(t *testing.T) {
 
 				repo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
 
+				repo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, input.ID, user.TokenEmailVerification).Return(nil).Times(1)
+				repo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+
 				token.EXPECT().GenerateAccessToken(input).Return("mock-access-token", nil).Times(1)
 				token.EXPECT().GenerateRefreshToken(input).Return("mock-refresh-token", nil).Times(1)
 
@@ -116,21 +136,53 @@ func TestRegister(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		mockToken, mockTx, mockRepo, service := setup(t)
+		mockToken, mockTx, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
 
 		tc.mockFn(mockTx, mockToken, mockRepo, tc.input)
 
-		resp, err := service.Register(context.Background(), tc.input)
+		resp, err := service.Register(context.Background(), tc.input, user.ClientInfo{})
 
 		if tc.expectedErr != nil {
 			assert.Error(t, err)
+			assert.Empty(t, mail.Sent())
 		} else {
 			assert.NoError(t, err)
 			assert.NotNil(t, resp)
+
+			msg, ok := mail.Last(tc.input.Email)
+			assert.True(t, ok)
+			assert.Contains(t, msg.Body, defaultConfig.VerifyEmailURL+"?token=")
 		}
 	}
 }
 
+func TestRegisterVerificationRequired(t *testing.T) {
+	cfg := defaultConfig
+	cfg.AllowUnverifiedLogin = false
+
+	mockToken, mockTx, mockRepo, service, mail := setupWithConfig(t, cfg)
+	input := &user.User{Email: "test1@mail.com", Password: "test_password"}
+
+	mockRepo.EXPECT().CheckEmailExists(gomock.Any(), input.Email).Return(false, nil).Times(1)
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).Times(1)
+	mockRepo.EXPECT().InsertUserTx(gomock.Any(), nil, input).Return(nil).Times(1)
+	mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, input.ID, user.TokenEmailVerification).Return(nil).Times(1)
+	mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+	// No session tokens for unverified users
+	mockToken.EXPECT().GenerateAccessToken(gomock.Any()).Times(0)
+
+	resp, err := service.Register(context.Background(), input, user.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.True(t, resp.VerificationRequired)
+	assert.Empty(t, resp.AccessToken)
+	assert.Len(t, mail.Sent(), 1)
+}
+
 func TestLogin(t *testing.T) {
 	type testCase struct {
 		name        string
@@ -222,7 +274,7 @@ func TestLogin(t *testing.T) {
 
 		tc.mockFn(mockTx, mockToken, mockRepo, tc.input)
 
-		resp, err := service.Login(context.Background(), tc.input.Email, tc.input.Password)
+		resp, err := service.Login(context.Background(), tc.input.Email, tc.input.Password, user.ClientInfo{})
 
 		if tc.expectedErr != nil {
 			assert.Error(t, err)
@@ -233,7 +285,438 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLoginUnverified(t *testing.T) {
+	cfg := defaultConfig
+	cfg.AllowUnverifiedLogin = false
+
+	_, _, mockRepo, service, _ := setupWithConfig(t, cfg)
+
+	mockUser := &user.User{Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+	mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+
+	resp, err := service.Login(context.Background(), mockUser.Email, "test_password", user.ClientInfo{})
+
+	assert.ErrorIs(t, err, errs.ErrEmailNotVerified)
+	assert.Nil(t, resp)
+}
+
+func TestLoginRehashPassword(t *testing.T) {
+	const legacyHash = "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"
+
+	login := func(t *testing.T, rehashErr error) {
+		cfg := defaultConfig
+		cfg.Passwords = password.Default()
+		mockToken, mockTx, mockRepo, service, _ := setupWithConfig(t, cfg)
+
+		mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: legacyHash}
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().RehashPassword(gomock.Any(), mockUser.ID, legacyHash, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, userID, oldHash, newHash string) error {
+				ok, rehash := cfg.Passwords.Verify(newHash, "test_password")
+				assert.True(t, ok)
+				assert.False(t, rehash)
+				return rehashErr
+			},
+		).Times(1)
+
+		withTx(mockTx)
+		mockToken.EXPECT().GenerateAccessToken(gomock.Any()).Return("mock-access-token", nil).Times(1)
+		mockToken.EXPECT().GenerateRefreshToken(gomock.Any()).Return("mock-refresh-token", nil).Times(1)
+		mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+
+		resp, err := service.Login(context.Background(), mockUser.Email, "test_password", user.ClientInfo{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "mock-access-token", resp.AccessToken)
+	}
+
+	t.Run("success bcrypt upgraded to argon2id", func(t *testing.T) {
+		login(t, nil)
+	})
+
+	t.Run("success login when rehash fails", func(t *testing.T) {
+		login(t, ErrDB)
+	})
+}
+
+func TestRegisterPasswordPolicy(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte("# top passwords\npassword123\n\nQwerty123\n"), 0o600))
+
+	policy := &password.Policy{MinLength: 8, MaxLength: 64}
+	require.NoError(t, policy.LoadBreached(list))
+
+	cfg := defaultConfig
+	cfg.PasswordPolicy = policy
+
+	testCases := []struct {
+		name        string
+		password    string
+		expectedErr error
+	}{
+		{name: "fail too short", password: "short", expectedErr: errs.ErrPasswordTooShort},
+		{name: "fail too long", password: strings.Repeat("a", 65), expectedErr: errs.ErrPasswordTooLong},
+		{name: "fail breached", password: "qwerty123", expectedErr: errs.ErrPasswordBreached},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, _, service, _ := setupWithConfig(t, cfg)
+
+			resp, err := service.Register(context.Background(), &user.User{Email: "test1@mail.com", Password: tc.password}, user.ClientInfo{})
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Nil(t, resp)
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+	client := user.ClientInfo{IPAddress: "10.0.0.1"}
+
+	_, _, mockRepo, service := setup(t)
+
+	// loginPolicy.AccountLimit wrong passwords lock the account
+	mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(loginPolicy.AccountLimit)
+	for i := 0; i < loginPolicy.AccountLimit; i++ {
+		resp, err := service.Login(ctx, mockUser.Email, "wrong_password", client)
+		assert.ErrorIs(t, err, errs.ErrInvalidEmailOrPassword)
+		assert.Nil(t, resp)
+	}
+
+	// Even the right password is refused, without a lookup
+	resp, err := service.Login(ctx, mockUser.Email, "test_password", client)
+	assert.ErrorIs(t, err, errs.ErrTooManyLoginAttempts)
+	assert.Nil(t, resp)
+
+	// Staff can neither read the audit trail nor unlock
+	_, err = service.ListLoginFailures(staffCtx, mockUser.ID)
+	assert.ErrorIs(t, err, errs.ErrForbidden)
+	assert.ErrorIs(t, service.UnlockUser(staffCtx, mockUser.ID), errs.ErrForbidden)
+
+	// Admin unlock and the audit trail
+	mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(2)
+
+	failures, err := service.ListLoginFailures(adminCtx, mockUser.ID)
+	assert.NoError(t, err)
+	assert.Len(t, failures, loginPolicy.AccountLimit)
+	assert.Equal(t, "10.0.0.1", failures[0].IPAddress)
+
+	assert.NoError(t, service.UnlockUser(adminCtx, mockUser.ID))
+
+	mockRepo.EXPECT().FindUserByEmail(gomock.Any(), mockUser.Email).Return(mockUser, nil).Times(1)
+	_, err = service.Login(ctx, mockUser.Email, "wrong_password", client)
+	assert.ErrorIs(t, err, errs.ErrInvalidEmailOrPassword)
+}
+
+func TestLoginLockoutUnknownEmail(t *testing.T) {
+	ctx := context.Background()
+	email := "nobody@mail.com"
+
+	_, _, mockRepo, service := setup(t)
+
+	mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, errs.ErrUserNotFound).Times(loginPolicy.AccountLimit)
+	for i := 0; i < loginPolicy.AccountLimit; i++ {
+		_, err := service.Login(ctx, email, "password", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrInvalidEmailOrPassword)
+	}
+
+	// Same answer as an existing account, no enumeration
+	_, err := service.Login(ctx, email, "password", user.ClientInfo{})
+	assert.ErrorIs(t, err, errs.ErrTooManyLoginAttempts)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	withTx := func(mockTx *database.MockTxManager) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		_, mockTx, mockRepo, service := setup(t)
+
+		withTx(mockTx)
+		mockToken := &user.UserToken{UserID: "mock-uuid-1", Purpose: user.TokenEmailVerification}
+		mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash("mock-verify-token"), user.TokenEmailVerification).Return(mockToken, nil).Times(1)
+		mockRepo.EXPECT().MarkEmailVerifiedTx(gomock.Any(), nil, "mock-uuid-1").Return(nil).Times(1)
+
+		assert.NoError(t, service.VerifyEmail(context.Background(), "mock-verify-token"))
+	})
+
+	t.Run("fail used or expired token", func(t *testing.T) {
+		_, mockTx, mockRepo, service := setup(t)
+
+		withTx(mockTx)
+		mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, gomock.Any(), user.TokenEmailVerification).Return(nil, errs.ErrInvalidUserToken).Times(1)
+
+		assert.ErrorIs(t, service.VerifyEmail(context.Background(), "mock-verify-token"), errs.ErrInvalidUserToken)
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	email := "test1@mail.com"
+	mockUser := &user.User{ID: "mock-uuid-1", Email: email}
+
+	issueToken := func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), mockUser.ID, user.TokenEmailVerification, gomock.Any()).Return(1, nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, mockUser.ID, user.TokenEmailVerification).Return(nil).Times(1)
+		mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+	}
+
+	t.Run("success unverified", func(t *testing.T) {
+		_, mockTx, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+
+		issueToken(mockTx, mockRepo)
+
+		assert.NoError(t, service.ResendVerification(context.Background(), email))
+		// Mail goes out in the background
+		assert.Eventually(t, func() bool { return len(mail.Sent()) == 1 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unknown email is silent", func(t *testing.T) {
+		_, _, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, errs.ErrUserNotFound).Times(1)
+
+		assert.NoError(t, service.ResendVerification(context.Background(), email))
+		assert.Empty(t, mail.Sent())
+	})
+
+	t.Run("verified email is silent", func(t *testing.T) {
+		_, _, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+
+		now := time.Now()
+		verifiedUser := &user.User{ID: "mock-uuid-1", Email: email, EmailVerifiedAt: &now}
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(verifiedUser, nil).Times(1)
+
+		assert.NoError(t, service.ResendVerification(context.Background(), email))
+		assert.Empty(t, mail.Sent())
+	})
+
+	t.Run("rate limited is silent", func(t *testing.T) {
+		_, _, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), mockUser.ID, user.TokenEmailVerification, gomock.Any()).Return(defaultConfig.VerificationResendLimit, nil).Times(1)
+		mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		assert.NoError(t, service.ResendVerification(context.Background(), email))
+		assert.Empty(t, mail.Sent())
+	})
+
+	t.Run("mail failure is silent", func(t *testing.T) {
+		_, mockTx, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+		mail.Err = errors.New("smtp down")
+
+		issueToken(mockTx, mockRepo)
+
+		assert.NoError(t, service.ResendVerification(context.Background(), email))
+	})
+}
+
+func TestForgotPassword(t *testing.T) {
+	email := "test1@mail.com"
+	mockUser := &user.User{ID: "mock-uuid-1", Email: email}
+
+	type testCase struct {
+		name        string
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository)
+		mailErr     error
+		expectMail  bool
+		expectedErr error
+	}
+
+	successMock := func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+		mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(mockUser, nil).Times(1)
+		mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), mockUser.ID, user.TokenPasswordReset, gomock.Any()).Return(0, nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, mockUser.ID, user.TokenPasswordReset).Return(nil).Times(1)
+		mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+	}
+
+	testCases := []testCase{
+		{
+			name: "success",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), mockUser.ID, user.TokenPasswordReset, gomock.Any()).Return(0, nil).Times(1)
+
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, mockUser.ID, user.TokenPasswordReset).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, tok *user.UserToken) error {
+						assert.Equal(t, user.TokenPasswordReset, tok.Purpose)
+						assert.WithinDuration(t, time.Now().Add(defaultConfig.PasswordResetTTL), tok.ExpiresAt, time.Minute)
+						return nil
+					},
+				).Times(1)
+			},
+			expectMail:  true,
+			expectedErr: nil,
+		},
+		{
+			name:        "mail failure is silent",
+			mockFn:      successMock,
+			mailErr:     errors.New("smtp down"),
+			expectMail:  false,
+			expectedErr: nil,
+		},
+		{
+			name: "unknown email is silent",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, errs.ErrUserNotFound).Times(1)
+			},
+			expectMail:  false,
+			expectedErr: nil,
+		},
+		{
+			name: "rate limited is silent",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), mockUser.ID, user.TokenPasswordReset, gomock.Any()).Return(defaultConfig.PasswordResetLimit, nil).Times(1)
+			},
+			expectMail:  false,
+			expectedErr: nil,
+		},
+		{
+			name: "fail find user",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockTx, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+			mail.Err = tc.mailErr
+
+			tc.mockFn(mockTx, mockRepo)
+
+			err := service.ForgotPassword(context.Background(), email)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tc.expectMail {
+				// Mail goes out in the background
+				var msg mailer.Message
+				assert.Eventually(t, func() bool {
+					var sent bool
+					msg, sent = mail.Last(email)
+					return sent
+				}, time.Second, 10*time.Millisecond)
+				assert.Contains(t, msg.Body, defaultConfig.PasswordResetURL+"?token=")
+			} else {
+				_, sent := mail.Last(email)
+				assert.False(t, sent)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	token := "mock-reset-token"
+	mockToken := &user.UserToken{UserID: "mock-uuid-1", Purpose: user.TokenPasswordReset}
+
+	type testCase struct {
+		name        string
+		mockFn      func(mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "success revokes sessions and access tokens",
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash(token), user.TokenPasswordReset).Return(mockToken, nil).Times(1)
+				mockRepo.EXPECT().UpdatePasswordTx(gomock.Any(), nil, mockToken.UserID, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, userID, hashed string) error {
+						ok, rehash := bcryptPasswords.Verify(hashed, "new-password")
+						assert.True(t, ok)
+						assert.False(t, rehash)
+						return nil
+					},
+				).Times(1)
+				mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, mockToken.UserID, user.TokenPasswordReset).Return(nil).Times(1)
+				mockRepo.EXPECT().MarkEmailVerifiedTx(gomock.Any(), nil, mockToken.UserID).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, mockToken.UserID).Return(int64(2), nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "fail used or expired token",
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash(token), user.TokenPasswordReset).Return(nil, errs.ErrInvalidUserToken).Times(1)
+			},
+			expectedErr: errs.ErrInvalidUserToken,
+		},
+		{
+			name: "fail revoke sessions",
+			mockFn: func(mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash(token), user.TokenPasswordReset).Return(mockToken, nil).Times(1)
+				mockRepo.EXPECT().UpdatePasswordTx(gomock.Any(), nil, mockToken.UserID, gomock.Any()).Return(nil).Times(1)
+				mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, mockToken.UserID, user.TokenPasswordReset).Return(nil).Times(1)
+				mockRepo.EXPECT().MarkEmailVerifiedTx(gomock.Any(), nil, mockToken.UserID).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, mockToken.UserID).Return(int64(0), ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTx, mockRepo, service, deny := setupRevoke(t)
+
+			mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+					return fn(nil)
+				},
+			).Times(1)
+			tc.mockFn(mockRepo)
+
+			err := service.ResetPassword(context.Background(), token, "new-password")
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assertUserTokensRevoked(t, deny, mockToken.UserID, false)
+			} else {
+				assert.NoError(t, err)
+				assertUserTokensRevoked(t, deny, mockToken.UserID, true)
+			}
+		})
+	}
+}
+
 func TestRefreshToken(t *testing.T) {
+	mockStored := &user.RefreshToken{ID: "mock-token-id", UserID: "mock-uuid-1", FamilyID: "mock-family-id"}
+	mockClaims := &jwttoken.UserClaims{UserID: "mock-uuid-1"}
+	client := user.ClientInfo{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Version/17.0 Mobile Safari/604.1", IPAddress: "203.0.113.7"}
+
 	type testCase struct {
 		name        string
 		token       string
@@ -246,10 +729,11 @@ func TestRefreshToken(t *testing.T) {
 			name:  "success",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, nil).Times(1)
 
 				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockStored.UserID).Return(mockUser, nil).Times(1)
 
 				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
@@ -257,20 +741,91 @@ func TestRefreshToken(t *testing.T) {
 					},
 				).Times(1)
 
-				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(nil).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
-				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
-
-				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-new-refresh-token", nil).Times(1)
+
+				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, rt *user.RefreshToken) error {
+						assert.Equal(t, securetoken.Hash("mock-new-refresh-token"), rt.Token)
+						assert.Equal(t, mockStored.FamilyID, rt.FamilyID)
+						assert.Equal(t, "Safari on iOS", rt.DeviceLabel)
+						assert.Equal(t, client.IPAddress, rt.IPAddress)
+						assert.Equal(t, mockStored.ID, *rt.ParentID)
+						return nil
+					},
+				).Times(1)
 			},
 			expectedErr: nil,
 		},
+		{
+			name:  "fail reused token revokes family",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, errs.ErrTokenRevoked).Times(1)
+
+				mockRepo.EXPECT().RevokeTokenFamily(gomock.Any(), mockStored.FamilyID).Return(int64(2), nil).Times(1)
+			},
+			expectedErr: errs.ErrTokenReused,
+		},
+		{
+			name:  "fail concurrent refresh revokes family",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, nil).Times(1)
+
+				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockStored.UserID).Return(mockUser, nil).Times(1)
+
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(errs.ErrTokenRevoked).Times(1)
+
+				mockRepo.EXPECT().RevokeTokenFamily(gomock.Any(), mockStored.FamilyID).Return(int64(1), nil).Times(1)
+			},
+			expectedErr: errs.ErrTokenReused,
+		},
+		{
+			name:  "fail revoke family",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, errs.ErrTokenRevoked).Times(1)
+
+				mockRepo.EXPECT().RevokeTokenFamily(gomock.Any(), mockStored.FamilyID).Return(int64(0), ErrDB).Times(1)
+			},
+			expectedErr: ErrDB,
+		},
+		{
+			name:  "fail invalid signature",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(nil, errors.New("parse token failed")).Times(1)
+			},
+			expectedErr: errs.ErrInvalidToken,
+		},
+		{
+			name:  "fail token of another user",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(&jwttoken.UserClaims{UserID: "mock-uuid-2"}, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidToken,
+		},
 		{
 			name:  "fail validate token",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(ErrDB).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(nil, ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
@@ -278,9 +833,10 @@ func TestRefreshToken(t *testing.T) {
 			name:  "fail find user",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, nil).Times(1)
 
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, ErrDB).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockStored.UserID).Return(nil, ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
@@ -288,10 +844,11 @@ func TestRefreshToken(t *testing.T) {
 			name:  "fail revoked token",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, nil).Times(1)
 
 				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockStored.UserID).Return(mockUser, nil).Times(1)
 
 				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
@@ -299,7 +856,7 @@ func TestRefreshToken(t *testing.T) {
 					},
 				).Times(1)
 
-				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(ErrDB).Times(1)
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
@@ -307,10 +864,11 @@ func TestRefreshToken(t *testing.T) {
 			name:  "fail insert new token",
 			token: "mock-refresh-token",
 			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
-				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), token).Return(nil).Times(1)
+				mockToken.EXPECT().VerifyRefreshToken(token).Return(mockClaims, nil).Times(1)
+				mockRepo.EXPECT().ValidateRefreshToken(gomock.Any(), securetoken.Hash(token)).Return(mockStored, nil).Times(1)
 
 				mockUser := &user.User{ID: "mock-uuid-1", Email: "mock@mail.com"}
-				mockRepo.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(mockUser, nil).Times(1)
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockStored.UserID).Return(mockUser, nil).Times(1)
 
 				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
 					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
@@ -318,7 +876,7 @@ func TestRefreshToken(t *testing.T) {
 					},
 				).Times(1)
 
-				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(nil).Times(1)
 
 				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
 				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
@@ -334,13 +892,11 @@ func TestRefreshToken(t *testing.T) {
 
 		tc.mockFn(mockTx, mockToken, mockRepo, tc.token)
 
-		ctx := context.Background()
-		ctx = auth.SetContextUserID(ctx, "mock-uuid-1")
-
-		resp, err := service.RefreshToken(ctx, tc.token)
+		// No access token: user comes from the refresh token
+		resp, err := service.RefreshToken(context.Background(), tc.token, client)
 
 		if tc.expectedErr != nil {
-			assert.Error(t, err)
+			assert.ErrorIs(t, err, tc.expectedErr)
 		} else {
 			assert.NoError(t, err)
 			assert.NotEmpty(t, resp)
@@ -367,7 +923,7 @@ func TestLogout(t *testing.T) {
 					},
 				).Times(1)
 
-				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(nil).Times(1)
 			},
 			expectedErr: nil,
 		},
@@ -381,10 +937,24 @@ func TestLogout(t *testing.T) {
 					},
 				).Times(1)
 
-				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, token).Return(ErrDB).Times(1)
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(ErrDB).Times(1)
 			},
 			expectedErr: ErrDB,
 		},
+		{
+			name:  "success already revoked",
+			token: "mock-refresh-token",
+			mockFn: func(mockTx *database.MockTxManager, mockToken *jwttoken.MockJWTToken, mockRepo *userrepository.MockUserRepository, token string) {
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash(token)).Return(errs.ErrTokenRevoked).Times(1)
+			},
+			expectedErr: nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -405,15 +975,738 @@ func TestLogout(t *testing.T) {
 	}
 }
 
+func TestRevokeAccessToken(t *testing.T) {
+	claims := &jwttoken.UserClaims{
+		UserID: "mock-uuid-1",
+		RegisteredClaims: &jwt.RegisteredClaims{
+			ID:        "mock-jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	ctx := auth.SetContextUserClaims(context.Background(), claims)
+
+	t.Run("logout", func(t *testing.T) {
+		deny := denylist.NewMemoryStore()
+		_, mockTx, mockRepo, service, _ := setupWithDenylist(t, defaultConfig, deny)
+
+		withTx(mockTx)
+		mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, securetoken.Hash("mock-refresh-token")).Return(errs.ErrTokenRevoked).Times(1)
+
+		err := service.Logout(ctx, "mock-refresh-token")
+		assert.NoError(t, err)
+
+		revoked, _ := deny.Contains(ctx, "mock-jti")
+		assert.True(t, revoked)
+	})
+
+	t.Run("logout fails, access token still valid", func(t *testing.T) {
+		deny := denylist.NewMemoryStore()
+		_, mockTx, mockRepo, service, _ := setupWithDenylist(t, defaultConfig, deny)
+
+		withTx(mockTx)
+		mockRepo.EXPECT().RevokedRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(ErrDB).Times(1)
+
+		err := service.Logout(ctx, "mock-refresh-token")
+		assert.ErrorIs(t, err, ErrDB)
+
+		revoked, _ := deny.Contains(ctx, "mock-jti")
+		assert.False(t, revoked)
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+
+		deny := denylist.NewMemoryStore()
+		mockToken, mockTx, mockRepo, service, _ := setupWithDenylist(t, defaultConfig, deny)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+		withTx(mockTx)
+		mockRepo.EXPECT().UpdatePasswordTx(gomock.Any(), nil, mockUser.ID, gomock.Any()).Return(nil).Times(1)
+		mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, mockUser.ID).Return(int64(1), nil).Times(1)
+		mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
+		mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+		mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+
+		_, err := service.ChangePassword(ctx, "test_password", "new-password", user.ClientInfo{})
+		assert.NoError(t, err)
+
+		revoked, _ := deny.Contains(ctx, "mock-jti")
+		assert.True(t, revoked)
+	})
+}
+
+func TestSessions(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+
+	t.Run("list sessions", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockSessions := []*user.Session{{ID: "mock-family-1", DeviceLabel: "Chrome on Windows"}}
+		mockRepo.EXPECT().FindSessions(gomock.Any(), "mock-uuid-1").Return(mockSessions, nil).Times(1)
+
+		resp, err := service.ListSessions(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockSessions, resp)
+	})
+
+	t.Run("fail list sessions without user", func(t *testing.T) {
+		_, _, _, service := setup(t)
+
+		resp, err := service.ListSessions(context.Background())
+
+		assert.ErrorIs(t, err, errs.ErrUnauthorized)
+		assert.Nil(t, resp)
+	})
+
+	t.Run("revoke session", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().RevokeSession(gomock.Any(), "mock-uuid-1", "mock-family-1").Return(nil).Times(1)
+
+		assert.NoError(t, service.RevokeSession(ctx, "mock-family-1"))
+	})
+
+	t.Run("fail revoke session of another user", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().RevokeSession(gomock.Any(), "mock-uuid-1", "mock-family-2").Return(errs.ErrSessionNotFound).Times(1)
+
+		assert.ErrorIs(t, service.RevokeSession(ctx, "mock-family-2"), errs.ErrSessionNotFound)
+	})
+
+	t.Run("revoke all sessions", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().RevokeAllSessions(gomock.Any(), "mock-uuid-1").Return(int64(3), nil).Times(1)
+
+		assert.NoError(t, service.RevokeAllSessions(ctx))
+	})
+
+	t.Run("fail revoke all sessions", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().RevokeAllSessions(gomock.Any(), "mock-uuid-1").Return(int64(0), ErrDB).Times(1)
+
+		assert.ErrorIs(t, service.RevokeAllSessions(ctx), ErrDB)
+	})
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+	hashed := "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"
+	name := "John Doe"
+	newEmail := "new@mail.com"
+
+	withTx := func(mockTx *database.MockTxManager) {
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+	}
+
+	type testCase struct {
+		name        string
+		input       user.UpdateProfileInput
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+		expectMails int
+	}
+
+	testCases := []testCase{
+		{
+			name:  "success name only",
+			input: user.UpdateProfileInput{Name: &name},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: hashed}, nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateProfileTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+			expectMails: 0,
+		},
+		{
+			name:  "success cancel pending email",
+			input: user.UpdateProfileInput{Email: func() *string { e := "test1@mail.com"; return &e }()},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", Email: "test1@mail.com", PendingEmail: &newEmail, Password: hashed}, nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateProfileTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, u *user.User) error {
+						assert.Nil(t, u.PendingEmail)
+						return nil
+					},
+				).Times(1)
+				mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, "mock-uuid-1", user.TokenEmailChange).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+			expectMails: 0,
+		},
+		{
+			name:  "success email change pending",
+			input: user.UpdateProfileInput{Email: &newEmail, CurrentPassword: "test_password"},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: hashed}, nil).Times(1)
+				mockRepo.EXPECT().CheckEmailExists(gomock.Any(), newEmail).Return(false, nil).Times(1)
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateProfileTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, u *user.User) error {
+						assert.Equal(t, "test1@mail.com", u.Email)
+						assert.Equal(t, newEmail, *u.PendingEmail)
+						return nil
+					},
+				).Times(1)
+				mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, "mock-uuid-1", user.TokenEmailChange).Return(nil).Times(1)
+				mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+			expectMails: 2,
+		},
+		{
+			name:  "fail email change wrong password",
+			input: user.UpdateProfileInput{Email: &newEmail, CurrentPassword: "wrong_password"},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: hashed}, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidPassword,
+		},
+		{
+			name:  "fail email taken",
+			input: user.UpdateProfileInput{Email: &newEmail, CurrentPassword: "test_password"},
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), "mock-uuid-1").Return(&user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: hashed}, nil).Times(1)
+				mockRepo.EXPECT().CheckEmailExists(gomock.Any(), newEmail).Return(true, nil).Times(1)
+			},
+			expectedErr: errs.ErrEmailAlreadyExists,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockTx, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+
+			tc.mockFn(mockTx, mockRepo)
+
+			resp, err := service.UpdateProfile(ctx, tc.input, user.ClientInfo{})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, resp)
+			}
+			assert.Len(t, mail.Sent(), tc.expectMails)
+
+			if tc.expectMails > 0 {
+				msg, ok := mail.Last(newEmail)
+				assert.True(t, ok)
+				assert.Contains(t, msg.Body, defaultConfig.ConfirmEmailURL+"?token=")
+
+				_, ok = mail.Last("test1@mail.com")
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	token := "mock-change-token"
+	mockToken := &user.UserToken{UserID: "mock-uuid-1", Purpose: user.TokenEmailChange}
+
+	_, mockTx, mockRepo, service := setup(t)
+
+	mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+			return fn(nil)
+		},
+	).Times(1)
+	mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash(token), user.TokenEmailChange).Return(mockToken, nil).Times(1)
+	mockRepo.EXPECT().ApplyPendingEmailTx(gomock.Any(), nil, mockToken.UserID).Return(errs.ErrEmailAlreadyExists).Times(1)
+
+	err := service.ConfirmEmailChange(context.Background(), token)
+	assert.ErrorIs(t, err, errs.ErrEmailAlreadyExists)
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+
+	type testCase struct {
+		name        string
+		current     string
+		newPassword string
+		mockFn      func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "success revokes sessions and issues new token",
+			current:     "test_password",
+			newPassword: "new-password",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+				mockRepo.EXPECT().UpdatePasswordTx(gomock.Any(), nil, mockUser.ID, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, userID, hashed string) error {
+						ok, rehash := bcryptPasswords.Verify(hashed, "new-password")
+						assert.True(t, ok)
+						assert.False(t, rehash)
+						return nil
+					},
+				).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, mockUser.ID).Return(int64(3), nil).Times(1)
+				mockToken.EXPECT().GenerateAccessToken(mockUser).Return("mock-access-token", nil).Times(1)
+				mockToken.EXPECT().GenerateRefreshToken(mockUser).Return("mock-refresh-token", nil).Times(1)
+				mockRepo.EXPECT().InsertRefreshTokenTx(gomock.Any(), nil, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "fail wrong current password",
+			current:     "wrong_password",
+			newPassword: "new-password",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+			},
+			expectedErr: errs.ErrInvalidPassword,
+		},
+		{
+			name:        "fail same password",
+			current:     "test_password",
+			newPassword: "test_password",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+			},
+			expectedErr: errs.ErrSamePassword,
+		},
+		{
+			name:        "fail new password too short",
+			current:     "test_password",
+			newPassword: "short",
+			mockFn: func(mockToken *jwttoken.MockJWTToken, mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository) {
+				mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+			},
+			expectedErr: errs.ErrPasswordTooShort,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockToken, mockTx, mockRepo, service := setup(t)
+
+			tc.mockFn(mockToken, mockTx, mockRepo)
+
+			resp, err := service.ChangePassword(ctx, tc.current, tc.newPassword, user.ClientInfo{})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "mock-refresh-token", resp.RefreshToken)
+			}
+		})
+	}
+
+	t.Run("fail wrong passwords lock the account", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		// Locked on the next call once the account limit is reached
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(loginPolicy.AccountLimit + 1)
+
+		for i := 0; i < loginPolicy.AccountLimit; i++ {
+			_, err := service.ChangePassword(ctx, "wrong_password", "new-password", user.ClientInfo{})
+			assert.ErrorIs(t, err, errs.ErrInvalidPassword)
+		}
+
+		_, err := service.ChangePassword(ctx, "test_password", "new-password", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrTooManyLoginAttempts)
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+	mockUser := &user.User{ID: "mock-uuid-1", Email: "test1@mail.com", Password: "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"}
+
+	t.Run("success anonymize", func(t *testing.T) {
+		mockTx, mockRepo, service, deny := setupRevoke(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(1)
+		mockRepo.EXPECT().AnonymizeUserTx(gomock.Any(), nil, mockUser.ID).Return(nil).Times(1)
+
+		err := service.DeleteAccount(ctx, "test_password", user.ClientInfo{})
+		assert.NoError(t, err)
+		assertUserTokensRevoked(t, deny, mockUser.ID, true)
+	})
+
+	t.Run("fail wrong password", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(1)
+
+		err := service.DeleteAccount(ctx, "wrong_password", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrInvalidPassword)
+	})
+
+	t.Run("fail wrong passwords lock the account", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), mockUser.ID).Return(mockUser, nil).Times(loginPolicy.AccountLimit + 1)
+		mockRepo.EXPECT().AnonymizeUserTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		for i := 0; i < loginPolicy.AccountLimit; i++ {
+			err := service.DeleteAccount(ctx, "wrong_password", user.ClientInfo{})
+			assert.ErrorIs(t, err, errs.ErrInvalidPassword)
+		}
+
+		err := service.DeleteAccount(ctx, "test_password", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrTooManyLoginAttempts)
+	})
+
+	t.Run("success without password using reauth code", func(t *testing.T) {
+		mockTx, mockRepo, service, _ := setupRevoke(t)
+		socialUser := &user.User{ID: "mock-uuid-1", Email: "social@mail.com"}
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), socialUser.ID).Return(socialUser, nil).Times(1)
+		// Reauth code, then anonymize
+		mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+				return fn(nil)
+			},
+		).Times(2)
+		mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash("mock-reauth-code"), user.TokenReauth).Return(&user.UserToken{UserID: socialUser.ID, Purpose: user.TokenReauth}, nil).Times(1)
+		mockRepo.EXPECT().AnonymizeUserTx(gomock.Any(), nil, socialUser.ID).Return(nil).Times(1)
+
+		err := service.DeleteAccount(ctx, "mock-reauth-code", user.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("fail without password and a wrong reauth code", func(t *testing.T) {
+		_, mockTx, mockRepo, service := setup(t)
+		socialUser := &user.User{ID: "mock-uuid-1", Email: "social@mail.com"}
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), socialUser.ID).Return(socialUser, nil).Times(1)
+		withTx(mockTx)
+		mockRepo.EXPECT().UseUserTokenTx(gomock.Any(), nil, securetoken.Hash("wrong-code"), user.TokenReauth).Return(nil, errs.ErrInvalidUserToken).Times(1)
+		mockRepo.EXPECT().AnonymizeUserTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		err := service.DeleteAccount(ctx, "wrong-code", user.ClientInfo{})
+		assert.ErrorIs(t, err, errs.ErrInvalidPassword)
+	})
+}
+
+func TestRequestReauth(t *testing.T) {
+	ctx := auth.SetContextUserID(context.Background(), "mock-uuid-1")
+	socialUser := &user.User{ID: "mock-uuid-1", Email: "social@mail.com"}
+
+	t.Run("success emails a code", func(t *testing.T) {
+		_, mockTx, mockRepo, service, mail := setupWithConfig(t, defaultConfig)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), socialUser.ID).Return(socialUser, nil).Times(1)
+		mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), socialUser.ID, user.TokenReauth, gomock.Any()).Return(0, nil).Times(1)
+		withTx(mockTx)
+		mockRepo.EXPECT().InvalidateUserTokensTx(gomock.Any(), nil, socialUser.ID, user.TokenReauth).Return(nil).Times(1)
+		mockRepo.EXPECT().InsertUserTokenTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, tx *sql.Tx, tok *user.UserToken) error {
+				assert.Equal(t, user.TokenReauth, tok.Purpose)
+				return nil
+			},
+		).Times(1)
+
+		assert.NoError(t, service.RequestReauth(ctx))
+		// Mail goes out in the background
+		assert.Eventually(t, func() bool {
+			sent := mail.Sent()
+			return len(sent) == 1 && sent[0].To == socialUser.Email
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("fail account has a password", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), socialUser.ID).Return(&user.User{ID: socialUser.ID, Password: "$2y$10$hash"}, nil).Times(1)
+
+		assert.ErrorIs(t, service.RequestReauth(ctx), errs.ErrPasswordSet)
+	})
+
+	t.Run("fail rate limited", func(t *testing.T) {
+		_, _, mockRepo, service := setup(t)
+
+		mockRepo.EXPECT().FindUserByID(gomock.Any(), socialUser.ID).Return(socialUser, nil).Times(1)
+		mockRepo.EXPECT().CountUserTokensSince(gomock.Any(), socialUser.ID, user.TokenReauth, gomock.Any()).Return(defaultConfig.PasswordResetLimit, nil).Times(1)
+
+		assert.ErrorIs(t, service.RequestReauth(ctx), errs.ErrTooManyLoginAttempts)
+	})
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	type testCase struct {
+		name        string
+		claims      *jwttoken.UserClaims
+		userID      string
+		role        user.Role
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role)
+		revokedJTI  string // impersonation token the user issued
+		expectedErr error
+	}
+
+	adminClaims := &jwttoken.UserClaims{UserID: "mock-admin-uuid", Role: user.RoleAdmin}
+
+	testCases := []testCase{
+		{
+			name:   "success",
+			claims: adminClaims,
+			userID: "mock-uuid-1",
+			role:   user.RoleStaff,
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateUserRoleTx(gomock.Any(), nil, userID, role).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, userID).Return(int64(2), nil).Times(1)
+				mockRepo.EXPECT().FindActiveImpersonationsByActor(gomock.Any(), userID).Return(nil, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "success demoted admin loses impersonations",
+			claims: adminClaims,
+			userID: "mock-admin-2",
+			role:   user.RoleCustomer,
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateUserRoleTx(gomock.Any(), nil, userID, role).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, userID).Return(int64(1), nil).Times(1)
+				mockRepo.EXPECT().FindActiveImpersonationsByActor(gomock.Any(), userID).Return([]*user.Impersonation{
+					{ID: "mock-imp-jti", ActorID: userID, UserID: "mock-uuid-1", ExpiresAt: mockNow.Add(time.Hour)},
+				}, nil).Times(1)
+			},
+			revokedJTI:  "mock-imp-jti",
+			expectedErr: nil,
+		},
+		{
+			name:   "success promotion skips impersonations",
+			claims: adminClaims,
+			userID: "mock-uuid-1",
+			role:   user.RoleAdmin,
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateUserRoleTx(gomock.Any(), nil, userID, role).Return(nil).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), nil, userID).Return(int64(0), nil).Times(1)
+				mockRepo.EXPECT().FindActiveImpersonationsByActor(gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "fail not admin",
+			claims: &jwttoken.UserClaims{UserID: "mock-uuid-2", Role: user.RoleStaff},
+			userID: "mock-uuid-1",
+			role:   user.RoleAdmin,
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+			},
+			expectedErr: errs.ErrForbidden,
+		},
+		{
+			name:   "fail invalid role",
+			claims: adminClaims,
+			userID: "mock-uuid-1",
+			role:   user.Role("root"),
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+			},
+			expectedErr: errs.ErrInvalidRole,
+		},
+		{
+			name:   "fail change own role",
+			claims: adminClaims,
+			userID: adminClaims.UserID,
+			role:   user.RoleCustomer,
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+			},
+			expectedErr: errs.ErrCannotChangeOwnRole,
+		},
+		{
+			name:   "fail user not found",
+			claims: adminClaims,
+			userID: "mock-uuid-1",
+			role:   user.RoleStaff,
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, userID string, role user.Role) {
+				withTx(mockTx)
+				mockRepo.EXPECT().UpdateUserRoleTx(gomock.Any(), nil, userID, role).Return(errs.ErrUserNotFound).Times(1)
+				mockRepo.EXPECT().RevokeAllSessionsTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			expectedErr: errs.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		mockTx, mockRepo, service, deny := setupRevoke(t)
+
+		tc.mockFn(mockTx, mockRepo, tc.userID, tc.role)
+
+		ctx := auth.SetContextUserClaims(context.Background(), tc.claims)
+
+		err := service.UpdateUserRole(ctx, tc.userID, tc.role)
+
+		if tc.expectedErr != nil {
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assertUserTokensRevoked(t, deny, tc.userID, false)
+		} else {
+			assert.NoError(t, err)
+			assertUserTokensRevoked(t, deny, tc.userID, true)
+		}
+		if tc.revokedJTI != "" {
+			revoked, err := deny.Contains(context.Background(), tc.revokedJTI)
+			assert.NoError(t, err)
+			assert.True(t, revoked)
+		}
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	type testCase struct {
+		name        string
+		email       string
+		password    string
+		mockFn      func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, email string)
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "skip no email",
+			email:       "",
+			mockFn:      func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, email string) {},
+			expectedErr: nil,
+		},
+		{
+			name:  "skip admin exists",
+			email: "admin@mail.com",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, email string) {
+				mockRepo.EXPECT().CheckRoleExists(gomock.Any(), user.RoleAdmin).Return(true, nil).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:     "fail email taken by existing account",
+			email:    "admin@mail.com",
+			password: "admin_password",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, email string) {
+				mockRepo.EXPECT().CheckRoleExists(gomock.Any(), user.RoleAdmin).Return(false, nil).Times(1)
+
+				mockUser := &user.User{ID: "mock-uuid-1", Email: email, Role: user.RoleCustomer}
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(mockUser, nil).Times(1)
+			},
+			expectedErr: errors.New("admin email admin@mail.com belongs to an existing account, choose an unused address"),
+		},
+		{
+			name:     "success create admin",
+			email:    "admin@mail.com",
+			password: "admin_password",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, email string) {
+				mockRepo.EXPECT().CheckRoleExists(gomock.Any(), user.RoleAdmin).Return(false, nil).Times(1)
+
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, errs.ErrUserNotFound).Times(1)
+
+				mockTx.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(tx *sql.Tx) error) error {
+						return fn(nil)
+					},
+				).Times(1)
+
+				mockRepo.EXPECT().InsertUserTx(gomock.Any(), nil, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, tx *sql.Tx, u *user.User) error {
+						assert.Equal(t, user.RoleAdmin, u.Role)
+						return nil
+					},
+				).Times(1)
+			},
+			expectedErr: nil,
+		},
+		{
+			name:  "fail create admin without password",
+			email: "admin@mail.com",
+			mockFn: func(mockTx *database.MockTxManager, mockRepo *userrepository.MockUserRepository, email string) {
+				mockRepo.EXPECT().CheckRoleExists(gomock.Any(), user.RoleAdmin).Return(false, nil).Times(1)
+
+				mockRepo.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, errs.ErrUserNotFound).Times(1)
+			},
+			expectedErr: errors.New("admin password is required"),
+		},
+	}
+
+	for _, tc := range testCases {
+		_, mockTx, mockRepo, service := setup(t)
+
+		tc.mockFn(mockTx, mockRepo, tc.email)
+
+		err := service.BootstrapAdmin(context.Background(), tc.email, tc.password)
+
+		if tc.expectedErr != nil {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+var defaultConfig = userservice.Config{
+	AllowUnverifiedLogin:     true,
+	VerificationTTL:          time.Hour,
+	VerifyEmailURL:           "http://localhost:3000/verify-email",
+	ConfirmEmailURL:          "http://localhost:3000/confirm-email",
+	VerificationResendLimit:  3,
+	VerificationResendWindow: time.Hour,
+	PasswordResetTTL:         30 * time.Minute,
+	PasswordResetURL:         "http://localhost:3000/reset-password",
+	PasswordResetLimit:       3,
+	PasswordResetWindow:      time.Hour,
+	MFAIssuer:                "Go Starter Kit",
+	MFAChallengeTTL:          5 * time.Minute,
+	MFASecrets:               mfaSecrets,
+	Passwords:                bcryptPasswords,
+}
+
+// bcryptPasswords : the fixture hashes are bcrypt cost 10, so logins in most tests need no rehash
+var bcryptPasswords, _ = password.NewHasher(password.Config{Algorithm: password.AlgBcrypt, BcryptCost: 10})
+
+var loginPolicy = loginguard.Policy{
+	Window:       time.Hour,
+	AccountLimit: 3,
+	IPLimit:      10,
+	LockoutBase:  time.Minute,
+	LockoutMax:   30 * time.Minute,
+}
+
 func setup(t *testing.T) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService) {
+	mockToken, mockTx, mockRepo, service, _ := setupWithConfig(t, defaultConfig)
+	return mockToken, mockTx, mockRepo, service
+}
+
+func setupWithConfig(t *testing.T, cfg userservice.Config) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService, *mailer.MemoryMailer) {
+	return setupWithDenylist(t, cfg, denylist.NewMemoryStore())
+}
+
+func setupWithDenylist(t *testing.T, cfg userservice.Config, deny denylist.Store) (*jwttoken.MockJWTToken, *database.MockTxManager, *userrepository.MockUserRepository, userservice.UserService, *mailer.MemoryMailer) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockToken := jwttoken.NewMockJWTToken(ctrl)
 	mockTx := database.NewMockTxManager(ctrl)
 	mockRepo := userrepository.NewMockUserRepository(ctrl)
+	mail := mailer.NewMemoryMailer()
+	guard := loginguard.New(loginguard.NewMemoryStore(), loginPolicy)
 
-	service := userservice.NewUserService(mockTx, mockToken, mockRepo)
+	service := userservice.NewUserService(mockTx, mockToken, mockRepo, mail, guard, deny, cfg)
 
-	return mockToken, mockTx, mockRepo, service
+	return mockToken, mockTx, mockRepo, service, mail
 }
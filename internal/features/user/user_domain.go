@@ -1,20 +1,331 @@
 package user
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleStaff    Role = "staff"
+	RoleAdmin    Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermProductsWrite Permission = "products:write"
+	PermOrdersRead    Permission = "orders:read"
+	PermOrdersWrite   Permission = "orders:write"
+	// Search accounts, disable and log out customers
+	PermCustomersManage Permission = "customers:manage"
+	// Roles, and disabling staff or admin accounts
+	PermUsersManage Permission = "users:manage"
+)
+
+// rolePermissions : customer has no back-office permissions
+var rolePermissions = map[Role][]Permission{
+	RoleStaff: {PermProductsWrite, PermOrdersRead, PermOrdersWrite, PermCustomersManage},
+	RoleAdmin: {PermProductsWrite, PermOrdersRead, PermOrdersWrite, PermCustomersManage, PermUsersManage},
+}
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleCustomer, RoleStaff, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (r Role) HasPermission(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
 
 type User struct {
-	ID        string    `db:"id" json:"id"`
-	Email     string    `db:"email" json:"email"`
-	Password  string    `db:"password" json:"-"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID              string     `db:"id" json:"id"`
+	Email           string     `db:"email" json:"email"`
+	Name            string     `db:"name" json:"name"`
+	PendingEmail    *string    `db:"pending_email" json:"pending_email,omitempty"` // waiting for confirmation
+	Password        string     `db:"password" json:"-"`
+	Role            Role       `db:"role" json:"role"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"`
+	TOTPEnabledAt   *time.Time `db:"totp_enabled_at" json:"totp_enabled_at"`
+	DisabledAt      *time.Time `db:"disabled_at" json:"disabled_at,omitempty"` // set by staff, blocks login
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
 
-type RefreshToken struct {
+// UpdateProfileInput : nil fields are unchanged, email change needs CurrentPassword (or a reauth code)
+type UpdateProfileInput struct {
+	Name            *string
+	Email           *string
+	CurrentPassword string
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// HasPassword : false for accounts created by social login, they confirm changes with a reauth code
+func (u *User) HasPassword() bool {
+	return u.Password != ""
+}
+
+// IsMFAEnabled : login needs a TOTP or recovery code after the password
+func (u *User) IsMFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// UserFilter : admin search, zero values do not filter.
+// Email matches part of the address, CreatedTo is exclusive.
+type UserFilter struct {
+	Email       string
+	Role        Role
+	Verified    *bool
+	Locked      *bool // login lockout still running
+	Disabled    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// AdminUser : account as listed for staff
+type AdminUser struct {
+	*User
+	LockedUntil *time.Time `json:"locked_until"`
+}
+
+// AdminUserDetails : LifetimeSpend is paid orders minus refunds, cancelled orders excluded
+type AdminUserDetails struct {
+	*AdminUser
+	OrderCount    int64 `json:"order_count"`
+	LifetimeSpend int64 `json:"lifetime_spend"`
+}
+
+type UserListResponse struct {
+	Users       []*AdminUser `json:"users"`
+	TotalUsers  int64        `json:"total_users"`
+	Page        int          `json:"page"`
+	Limit       int          `json:"limit"`
+	TotalPage   int          `json:"total_page"`
+	HasNextPage bool         `json:"has_next_page"`
+	HasPrevPage bool         `json:"has_prev_page"`
+}
+
+// Impersonation : an admin acting as a customer, ID is the jti of the access token issued
+type Impersonation struct {
 	ID        string    `db:"id" json:"id"`
+	ActorID   string    `db:"actor_id" json:"actor_id"`
 	UserID    string    `db:"user_id" json:"user_id"`
-	Token     string    `db:"token" json:"token"`
+	Reason    string    `db:"reason" json:"reason"`
+	IPAddress string    `db:"ip_address" json:"ip_address"`
 	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
-	Revoked   bool      `db:"revoked" json:"revoked"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
+
+// ImpersonationRequest : audit row for one request made with an impersonation token.
+// Written before the request runs, Status is set when it completes.
+type ImpersonationRequest struct {
+	ID              int64  `db:"id"`
+	ImpersonationID string `db:"impersonation_id"`
+	Method          string `db:"method"`
+	Path            string `db:"path"`
+	Status          int    `db:"status"`
+	IPAddress       string `db:"ip_address"`
+}
+
+// TOTP : Secret is encrypted, empty when enrollment was never started.
+// LastStep is the last accepted time step, the same code works only once.
+type TOTP struct {
+	UserID    string     `db:"id"`
+	Secret    string     `db:"totp_secret"`
+	EnabledAt *time.Time `db:"totp_enabled_at"`
+	LastStep  int64      `db:"totp_last_step"`
+}
+
+// Identity : external OIDC account linked to a user, Subject is the provider's id for it
+type Identity struct {
+	ID        string    `db:"id" json:"id"`
+	UserID    string    `db:"user_id" json:"-"`
+	Provider  string    `db:"provider" json:"provider"`
+	Subject   string    `db:"subject" json:"-"`
+	Email     string    `db:"email" json:"email"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// OIDCState : social login waiting for the provider callback, only StateHash is stored
+type OIDCState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// apiKeyScopes : permissions a service-account key may hold, user management stays with people
+var apiKeyScopes = []Permission{PermProductsWrite, PermOrdersRead, PermOrdersWrite}
+
+func (p Permission) IsAPIKeyScope() bool {
+	for _, s := range apiKeyScopes {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyPrefix : start of every API key, lets secret scanners spot leaked keys
+const APIKeyPrefix = "gsk_"
+
+// APIKey : service-account key created by an admin, only Hash is stored.
+// Prefix is the start of the key, to tell keys apart in lists and logs.
+type APIKey struct {
+	ID         string       `db:"id" json:"id"`
+	Name       string       `db:"name" json:"name"`
+	Prefix     string       `db:"prefix" json:"prefix"`
+	Hash       string       `db:"key_hash" json:"-"`
+	Scopes     []Permission `db:"scopes" json:"scopes"`
+	CreatedBy  string       `db:"created_by" json:"created_by"`
+	ExpiresAt  *time.Time   `db:"expires_at" json:"expires_at"` // nil never expires
+	LastUsedAt *time.Time   `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  *time.Time   `db:"revoked_at" json:"revoked_at"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+	// Current role of CreatedBy, the key never has more than its creator
+	OwnerRole Role `db:"role" json:"-"`
+}
+
+func (k *APIKey) HasScope(perm Permission) bool {
+	for _, s := range k.Scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type TokenPurpose string
+
+const (
+	TokenEmailVerification TokenPurpose = "email_verification"
+	TokenPasswordReset     TokenPurpose = "password_reset"
+	TokenEmailChange       TokenPurpose = "email_change"
+	TokenMFAChallenge      TokenPurpose = "mfa_challenge" // password ok, waiting for the second factor
+	TokenReauth            TokenPurpose = "reauth"        // stands in for the password of an account without one
+)
+
+// UserToken : single-use token, only TokenHash is stored
+type UserToken struct {
+	ID        string       `db:"id" json:"id"`
+	UserID    string       `db:"user_id" json:"user_id"`
+	Purpose   TokenPurpose `db:"purpose" json:"purpose"`
+	TokenHash string       `db:"token_hash" json:"-"`
+	ExpiresAt time.Time    `db:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time   `db:"used_at" json:"used_at"`
+	Attempts  int          `db:"attempts" json:"attempts"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
+// RefreshToken : tokens rotated from the same login share FamilyID
+type RefreshToken struct {
+	ID          string     `db:"id" json:"id"`
+	UserID      string     `db:"user_id" json:"user_id"`
+	Token       string     `db:"token" json:"token"`
+	FamilyID    string     `db:"family_id" json:"family_id"`
+	ParentID    *string    `db:"parent_id" json:"parent_id"` // nil for the first token of a login
+	UserAgent   string     `db:"user_agent" json:"user_agent"`
+	IPAddress   string     `db:"ip_address" json:"ip_address"`
+	DeviceLabel string     `db:"device_label" json:"device_label"`
+	ExpiresAt   time.Time  `db:"expires_at" json:"expires_at"`
+	Revoked     bool       `db:"revoked" json:"revoked"`
+	RevokedAt   *time.Time `db:"revoked_at" json:"revoked_at"`
+	LastUsedAt  time.Time  `db:"last_used_at" json:"last_used_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Session : one token family, ID is the family id
+type Session struct {
+	ID          string    `json:"id"`
+	DeviceLabel string    `json:"device_label"`
+	UserAgent   string    `json:"user_agent"`
+	IPAddress   string    `json:"ip_address"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ClientInfo : request that opens or refreshes a session
+type ClientInfo struct {
+	UserAgent   string
+	IPAddress   string
+	DeviceLabel string // optional, from client
+}
+
+const maxDeviceLabel = 100
+
+// Label : DeviceLabel or "Browser on OS" from the user agent
+func (c ClientInfo) Label() string {
+	label := strings.TrimSpace(c.DeviceLabel)
+	if label == "" {
+		label = deviceFromUserAgent(c.UserAgent)
+	}
+	if len(label) > maxDeviceLabel {
+		label = label[:maxDeviceLabel]
+	}
+	return label
+}
+
+func deviceFromUserAgent(ua string) string {
+	if ua == "" {
+		return ""
+	}
+
+	// Order matters: Edge and Opera also send "Chrome", Chrome also sends "Safari"
+	browser := "Unknown browser"
+	for _, b := range [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(ua, b[0]) {
+			browser = b[1]
+			break
+		}
+	}
+
+	// iPhone/Android before macOS/Linux, their user agents contain both
+	os := "Unknown OS"
+	for _, o := range [][2]string{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o[0]) {
+			os = o[1]
+			break
+		}
+	}
+
+	if browser == "Unknown browser" && os == "Unknown OS" {
+		return "Unknown device"
+	}
+	return browser + " on " + os
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
+	"github.com/codepnw/go-starter-kit/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency : replay first response for a repeated Idempotency-Key.
+// Keys are scoped by user or API key, use after Authorized() on protected routes.
+func (m *Middleware) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.ResponseError(c, http.StatusBadRequest, errs.ErrIdempotencyKeyInvalid)
+			c.Abort()
+			return
+		}
+
+		// Read & Restore Body
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			response.ResponseError(c, http.StatusBadRequest, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := idempotencyScope(ctx)
+
+		rec := &idempotency.Record{
+			Scope:       scope,
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			ExpiresAt:   time.Now().Add(m.idemTTL),
+		}
+
+		acquired, err := m.idem.Acquire(ctx, rec)
+		if err != nil {
+			response.ResponseError(c, http.StatusInternalServerError, err)
+			c.Abort()
+			return
+		}
+
+		// Duplicate Request
+		if !acquired {
+			m.replay(c, rec)
+			return
+		}
+
+		// First Request: capture response
+		recorder := &bodyRecorder{ResponseWriter: c.Writer, body: new(bytes.Buffer)}
+		c.Writer = recorder
+
+		// Saved even when the client has gone away
+		saveCtx := context.WithoutCancel(ctx)
+
+		// Handler panic: key is released so a retry is not stuck in progress, Recovery answers 500
+		defer func() {
+			if r := recover(); r != nil {
+				m.releaseIdempotencyKey(saveCtx, scope, key)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			// Server error: allow client retry with same key
+			m.releaseIdempotencyKey(saveCtx, scope, key)
+			return
+		}
+
+		if err := m.idem.Complete(saveCtx, scope, key, status, recorder.body.Bytes()); err != nil {
+			slog.Error("save idempotency response failed", slog.String("key", key), slog.Any("error", err))
+		}
+	}
+}
+
+// idempotencyScope : API keys act as their creator, each key gets its own scope
+// so two keys of the same admin cannot replay each other's responses
+func idempotencyScope(ctx context.Context) string {
+	if key, ok := auth.GetAPIKeyFromContext(ctx); ok {
+		return "apikey:" + key.ID
+	}
+	userID, _ := auth.GetUserIDFromContext(ctx)
+	return userID
+}
+
+func (m *Middleware) releaseIdempotencyKey(ctx context.Context, scope, key string) {
+	if err := m.idem.Release(ctx, scope, key); err != nil {
+		slog.Error("release idempotency key failed", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+func (m *Middleware) replay(c *gin.Context, rec *idempotency.Record) {
+	existing, err := m.idem.Find(c.Request.Context(), rec.Scope, rec.Key)
+	if err != nil {
+		response.ResponseError(c, http.StatusInternalServerError, err)
+		c.Abort()
+		return
+	}
+
+	switch {
+	case existing.RequestHash != rec.RequestHash:
+		response.ResponseError(c, http.StatusUnprocessableEntity, errs.ErrIdempotencyKeyReused)
+	case !existing.Completed():
+		response.ResponseError(c, http.StatusConflict, errs.ErrIdempotencyKeyInProgress)
+	default:
+		c.Header(HeaderIdempotencyReplayed, "true")
+		c.Data(existing.StatusCode, gin.MIMEJSON+"; charset=utf-8", existing.Body)
+	}
+	c.Abort()
+}
+
+// fingerprint : same key must be reused with same endpoint and body
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type testCase struct {
+		name           string
+		key            string
+		handlerStatus  int
+		mockFn         func(mockStore *idempotency.MockStore)
+		expectedStatus int
+		expectedCalls  int
+		expectedReplay bool
+	}
+
+	const body = `{"address":"Bangkok"}`
+
+	testCases := []testCase{
+		{
+			name:           "no key",
+			key:            "",
+			handlerStatus:  http.StatusCreated,
+			mockFn:         func(mockStore *idempotency.MockStore) {},
+			expectedStatus: http.StatusCreated,
+			expectedCalls:  1,
+		},
+		{
+			name:          "first request",
+			key:           "key-1",
+			handlerStatus: http.StatusCreated,
+			mockFn: func(mockStore *idempotency.MockStore) {
+				mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
+				mockStore.EXPECT().Complete(gomock.Any(), "", "key-1", http.StatusCreated, gomock.Any()).Return(nil).Times(1)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedCalls:  1,
+		},
+		{
+			name:          "server error releases key",
+			key:           "key-1",
+			handlerStatus: http.StatusInternalServerError,
+			mockFn: func(mockStore *idempotency.MockStore) {
+				mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
+				mockStore.EXPECT().Release(gomock.Any(), "", "key-1").Return(nil).Times(1)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  1,
+		},
+		{
+			name:          "replay completed response",
+			key:           "key-1",
+			handlerStatus: http.StatusCreated,
+			mockFn: func(mockStore *idempotency.MockStore) {
+				mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(false, nil).Times(1)
+				mockStore.EXPECT().Find(gomock.Any(), "", "key-1").DoAndReturn(func(ctx context.Context, scope, key string) (*idempotency.Record, error) {
+					return &idempotency.Record{
+						Key:         key,
+						RequestHash: fingerprint(http.MethodPost, "/orders/checkout", []byte(body)),
+						StatusCode:  http.StatusCreated,
+						Body:        []byte(`{"order_id":1}`),
+					}, nil
+				}).Times(1)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedCalls:  0,
+			expectedReplay: true,
+		},
+		{
+			name:          "key reused with different body",
+			key:           "key-1",
+			handlerStatus: http.StatusCreated,
+			mockFn: func(mockStore *idempotency.MockStore) {
+				mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(false, nil).Times(1)
+				mockStore.EXPECT().Find(gomock.Any(), "", "key-1").Return(&idempotency.Record{
+					Key:         "key-1",
+					RequestHash: "other-hash",
+					StatusCode:  http.StatusCreated,
+				}, nil).Times(1)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCalls:  0,
+		},
+		{
+			name:          "first request still in progress",
+			key:           "key-1",
+			handlerStatus: http.StatusCreated,
+			mockFn: func(mockStore *idempotency.MockStore) {
+				mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(false, nil).Times(1)
+				mockStore.EXPECT().Find(gomock.Any(), "", "key-1").DoAndReturn(func(ctx context.Context, scope, key string) (*idempotency.Record, error) {
+					return &idempotency.Record{
+						Key:         key,
+						RequestHash: fingerprint(http.MethodPost, "/orders/checkout", []byte(body)),
+					}, nil
+				}).Times(1)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCalls:  0,
+		},
+		{
+			name:           "key too long",
+			key:            strings.Repeat("k", 256),
+			handlerStatus:  http.StatusCreated,
+			mockFn:         func(mockStore *idempotency.MockStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCalls:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := idempotency.NewMockStore(ctrl)
+			tc.mockFn(mockStore)
+
+			mid := InitMiddleware(nil, nil, nil, nil, mockStore, time.Hour)
+
+			calls := 0
+			r := gin.New()
+			r.POST("/orders/checkout", mid.Idempotency(), func(c *gin.Context) {
+				calls++
+				c.JSON(tc.handlerStatus, gin.H{"order_id": 1})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/orders/checkout", strings.NewReader(body))
+			if tc.key != "" {
+				req.Header.Set(HeaderIdempotencyKey, tc.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedCalls, calls)
+			if tc.expectedReplay {
+				assert.Equal(t, "true", w.Header().Get(HeaderIdempotencyReplayed))
+				assert.JSONEq(t, `{"order_id":1}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userCtx := auth.SetContextUserClaims(context.Background(), &jwttoken.UserClaims{UserID: "admin-uuid"})
+	keyCtx := auth.SetContextAPIKey(context.Background(), &user.APIKey{ID: "key-uuid", CreatedBy: "admin-uuid"})
+
+	type testCase struct {
+		name  string
+		ctx   context.Context
+		scope string
+	}
+
+	testCases := []testCase{
+		{name: "user", ctx: userCtx, scope: "admin-uuid"},
+		{name: "api key of the same admin", ctx: keyCtx, scope: "apikey:key-uuid"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := idempotency.NewMockStore(ctrl)
+			mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, rec *idempotency.Record) (bool, error) {
+					assert.Equal(t, tc.scope, rec.Scope)
+					return true, nil
+				},
+			).Times(1)
+			mockStore.EXPECT().Complete(gomock.Any(), tc.scope, "key-1", http.StatusCreated, gomock.Any()).Return(nil).Times(1)
+
+			mid := InitMiddleware(nil, nil, nil, nil, mockStore, time.Hour)
+
+			r := gin.New()
+			r.POST("/orders/checkout", mid.Idempotency(), func(c *gin.Context) {
+				c.JSON(http.StatusCreated, gin.H{"order_id": 1})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/orders/checkout", strings.NewReader(`{}`)).WithContext(tc.ctx)
+			req.Header.Set(HeaderIdempotencyKey, "key-1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+		})
+	}
+}
+
+func TestIdempotencyOutlivesRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRequest := func(ctx context.Context) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders/checkout", strings.NewReader(`{}`)).WithContext(ctx)
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		return req
+	}
+	notCancelled := func(ctx context.Context, scope, key string) error {
+		assert.NoError(t, ctx.Err())
+		return nil
+	}
+
+	t.Run("client gone still saves response", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStore := idempotency.NewMockStore(ctrl)
+		mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
+		mockStore.EXPECT().Complete(gomock.Any(), "", "key-1", http.StatusCreated, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, scope, key string, status int, body []byte) error {
+				return notCancelled(ctx, scope, key)
+			},
+		).Times(1)
+
+		mid := InitMiddleware(nil, nil, nil, nil, mockStore, time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		r := gin.New()
+		r.POST("/orders/checkout", mid.Idempotency(), func(c *gin.Context) {
+			cancel()
+			c.JSON(http.StatusCreated, gin.H{"order_id": 1})
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newRequest(ctx))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("handler panic releases key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockStore := idempotency.NewMockStore(ctrl)
+		mockStore.EXPECT().Acquire(gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
+		mockStore.EXPECT().Release(gomock.Any(), "", "key-1").DoAndReturn(notCancelled).Times(1)
+		mockStore.EXPECT().Complete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		mid := InitMiddleware(nil, nil, nil, nil, mockStore, time.Hour)
+
+		r := gin.New()
+		r.Use(gin.Recovery())
+		r.POST("/orders/checkout", mid.Idempotency(), func(c *gin.Context) {
+			panic("boom")
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, newRequest(context.Background()))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
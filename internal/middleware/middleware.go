@@ -8,22 +8,63 @@ import (
 	"strings"
 	"time"
 
-	"github.com/codepnw/go-starter-kit/internal/config"
+	"github.com/codepnw/go-starter-kit/internal/auth"
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
 	"github.com/codepnw/go-starter-kit/pkg/utils/response"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// HeaderAPIKey : service-account key, same as "Authorization: ApiKey <key>"
+	HeaderAPIKey = "X-API-Key"
+
+	schemeAPIKey = "ApiKey"
+)
+
+// APIKeyAuthenticator : returns the key with the scopes it may use now
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (*user.APIKey, error)
+}
+
+// ImpersonationAuditor : records a request made with an impersonation token before it runs,
+// then its response status
+type ImpersonationAuditor interface {
+	AuditImpersonation(ctx context.Context, req *user.ImpersonationRequest) error
+	CompleteImpersonationAudit(ctx context.Context, req *user.ImpersonationRequest) error
+}
+
 type Middleware struct {
-	token jwttoken.JWTToken
+	token    jwttoken.JWTToken
+	denylist denylist.Store
+	apiKeys  APIKeyAuthenticator
+	auditor  ImpersonationAuditor
+	idem     idempotency.Store
+	idemTTL  time.Duration
 }
 
-func InitMiddleware(token jwttoken.JWTToken) *Middleware {
-	return &Middleware{token: token}
+func InitMiddleware(token jwttoken.JWTToken, deny denylist.Store, apiKeys APIKeyAuthenticator, auditor ImpersonationAuditor, idem idempotency.Store, idemTTL time.Duration) *Middleware {
+	return &Middleware{
+		token:    token,
+		denylist: deny,
+		apiKeys:  apiKeys,
+		auditor:  auditor,
+		idem:     idem,
+		idemTTL:  idemTTL,
+	}
 }
 
+// Authorized : Bearer access token, or an API key in X-API-Key / "Authorization: ApiKey"
 func (m *Middleware) Authorized() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if rawKey := c.GetHeader(HeaderAPIKey); rawKey != "" {
+			m.authorizeAPIKey(c, rawKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			response.ResponseError(c, http.StatusUnauthorized, errors.New("header is misstion"))
@@ -32,6 +73,10 @@ func (m *Middleware) Authorized() gin.HandlerFunc {
 		}
 
 		args := strings.Fields(authHeader)
+		if len(args) == 2 && args[0] == schemeAPIKey {
+			m.authorizeAPIKey(c, args[1])
+			return
+		}
 		if len(args) != 2 || args[0] != "Bearer" {
 			response.ResponseError(c, http.StatusUnauthorized, errors.New("invalid token format"))
 			c.Abort()
@@ -45,15 +90,186 @@ func (m *Middleware) Authorized() gin.HandlerFunc {
 			return
 		}
 
-		ctx := c.Request.Context()
-		ctx = context.WithValue(ctx, config.ContextUserClaimsKey, claims)
-		ctx = context.WithValue(ctx, config.ContextUserIDKey, claims.UserID)
+		revoked, err := m.tokenRevoked(c.Request.Context(), claims)
+		if err != nil {
+			response.ResponseError(c, http.StatusInternalServerError, err)
+			c.Abort()
+			return
+		}
+		if revoked {
+			response.ResponseError(c, http.StatusUnauthorized, errs.ErrTokenRevoked)
+			c.Abort()
+			return
+		}
+
+		ctx := auth.SetContextUserClaims(c.Request.Context(), claims)
 
 		c.Request = c.Request.WithContext(ctx)
+		if claims.IsImpersonated() {
+			m.auditImpersonation(c, claims)
+			return
+		}
+		c.Next()
+	}
+}
+
+// tokenRevoked : the token itself or every token of its user was revoked before it expired
+func (m *Middleware) tokenRevoked(ctx context.Context, claims *jwttoken.UserClaims) (bool, error) {
+	// Logged out or password changed
+	revoked, err := m.denylist.Contains(ctx, claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	// Disabled, logged out by staff or role changed after it was issued
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return m.denylist.UserRevoked(ctx, claims.UserID, issuedAt)
+}
+
+// auditImpersonation : records the request before it runs and its status after.
+// No audit row = no request, without an auditor impersonation tokens are refused.
+func (m *Middleware) auditImpersonation(c *gin.Context, claims *jwttoken.UserClaims) {
+	if m.auditor == nil {
+		response.ResponseError(c, http.StatusUnauthorized, errs.ErrImpersonationNotAllowed)
+		c.Abort()
+		return
+	}
+
+	req := &user.ImpersonationRequest{
+		ImpersonationID: claims.ID,
+		Method:          c.Request.Method,
+		Path:            c.Request.URL.Path,
+		IPAddress:       c.ClientIP(),
+	}
+	if err := m.auditor.AuditImpersonation(c.Request.Context(), req); err != nil {
+		m.logAuditError("audit impersonated request failed", claims, req, err)
+		response.ResponseError(c, http.StatusServiceUnavailable, errs.ErrAuditUnavailable)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+
+	// Written even when the client has gone away
+	ctx := context.WithoutCancel(c.Request.Context())
+	req.Status = c.Writer.Status()
+	if err := m.auditor.CompleteImpersonationAudit(ctx, req); err != nil {
+		m.logAuditError("complete impersonated request audit failed", claims, req, err)
+	}
+}
+
+func (m *Middleware) logAuditError(msg string, claims *jwttoken.UserClaims, req *user.ImpersonationRequest, err error) {
+	slog.Error(msg,
+		slog.String("impersonation_id", claims.ID),
+		slog.String("actor_id", claims.Actor.UserID),
+		slog.String("method", req.Method),
+		slog.String("path", req.Path),
+		slog.Any("error", err),
+	)
+}
+
+func (m *Middleware) authorizeAPIKey(c *gin.Context, rawKey string) {
+	if m.apiKeys == nil {
+		response.ResponseError(c, http.StatusUnauthorized, errs.ErrInvalidAPIKey)
+		c.Abort()
+		return
+	}
+
+	key, err := m.apiKeys.AuthenticateAPIKey(c.Request.Context(), rawKey)
+	if err != nil {
+		switch err {
+		case errs.ErrInvalidAPIKey:
+			response.ResponseError(c, http.StatusUnauthorized, err)
+		default:
+			response.ResponseError(c, http.StatusInternalServerError, err)
+		}
+		c.Abort()
+		return
+	}
+
+	ctx := auth.SetContextAPIKey(c.Request.Context(), key)
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// RequireUserToken : use after Authorized(), account routes are for people, not API keys
+func (m *Middleware) RequireUserToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := auth.GetAPIKeyFromContext(c.Request.Context()); ok {
+			response.ResponseError(c, http.StatusForbidden, errs.ErrAPIKeyNotAllowed)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// BlockImpersonation : use after Authorized(), for account security and payment routes
+// only the customer may use
+func (m *Middleware) BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.IsImpersonated(c.Request.Context()) {
+			response.ResponseError(c, http.StatusForbidden, errs.ErrImpersonationNotAllowed)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole : use after Authorized()
+func (m *Middleware) RequireRole(roles ...user.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := auth.RequireRole(c.Request.Context(), roles...); err != nil {
+			abortWithAuthError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission : use after Authorized()
+func (m *Middleware) RequirePermission(perms ...user.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := auth.RequirePermission(c.Request.Context(), perms...); err != nil {
+			abortWithAuthError(c, err)
+			return
+		}
 		c.Next()
 	}
 }
 
+// RequireVerifiedEmail : use after Authorized(), reads the claim so verify then refresh the token
+func (m *Middleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := auth.GetUserFromContext(c.Request.Context())
+		if err != nil {
+			abortWithAuthError(c, errs.ErrUnauthorized)
+			return
+		}
+		if !claims.EmailVerified {
+			response.ResponseError(c, http.StatusForbidden, errs.ErrEmailNotVerified)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortWithAuthError(c *gin.Context, err error) {
+	switch err {
+	case errs.ErrForbidden:
+		response.ResponseError(c, http.StatusForbidden, err)
+	default:
+		response.ResponseError(c, http.StatusUnauthorized, err)
+	}
+	c.Abort()
+}
+
 func (m *Middleware) Logger() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		start := time.Now()
@@ -0,0 +1,354 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/errs"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
+	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	claims := &jwttoken.UserClaims{
+		UserID: "mock-uuid-1",
+		RegisteredClaims: &jwt.RegisteredClaims{
+			ID:        "mock-jti",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	type testCase struct {
+		name           string
+		header         string
+		revoked        bool
+		userRevoked    bool
+		mockFn         func(mockToken *jwttoken.MockJWTToken)
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{
+			name:   "success",
+			header: "Bearer mock-access-token",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken("mock-access-token").Return(claims, nil).Times(1)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "fail missing header",
+			header:         "",
+			mockFn:         func(mockToken *jwttoken.MockJWTToken) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "fail invalid token",
+			header: "Bearer mock-access-token",
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken("mock-access-token").Return(nil, errors.New("invalid")).Times(1)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "fail revoked token",
+			header:  "Bearer mock-access-token",
+			revoked: true,
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken("mock-access-token").Return(claims, nil).Times(1)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "fail user tokens revoked after issue",
+			header:      "Bearer mock-access-token",
+			userRevoked: true,
+			mockFn: func(mockToken *jwttoken.MockJWTToken) {
+				mockToken.EXPECT().VerifyAccessToken("mock-access-token").Return(claims, nil).Times(1)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockToken := jwttoken.NewMockJWTToken(ctrl)
+			tc.mockFn(mockToken)
+
+			deny := denylist.NewMemoryStore()
+			if tc.revoked {
+				assert.NoError(t, deny.Add(context.Background(), claims.ID, claims.ExpiresAt.Time))
+			}
+			if tc.userRevoked {
+				assert.NoError(t, deny.RevokeUser(context.Background(), claims.UserID, claims.IssuedAt.Add(time.Second), claims.ExpiresAt.Time))
+			}
+
+			mid := InitMiddleware(mockToken, deny, nil, nil, nil, time.Hour)
+
+			r := gin.New()
+			r.GET("/users/profile", mid.Authorized(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+type apiKeyFunc func(ctx context.Context, rawKey string) (*user.APIKey, error)
+
+func (f apiKeyFunc) AuthenticateAPIKey(ctx context.Context, rawKey string) (*user.APIKey, error) {
+	return f(ctx, rawKey)
+}
+
+func TestAuthorizedAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := apiKeyFunc(func(ctx context.Context, rawKey string) (*user.APIKey, error) {
+		switch rawKey {
+		case "gsk_valid":
+			return &user.APIKey{ID: "mock-key-1", CreatedBy: "mock-uuid-1", Scopes: []user.Permission{user.PermProductsWrite}}, nil
+		case "gsk_db_down":
+			return nil, errors.New("db down")
+		default:
+			return nil, errs.ErrInvalidAPIKey
+		}
+	})
+
+	type testCase struct {
+		name           string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{
+			name:           "success x-api-key",
+			path:           "/products/1/stock",
+			headers:        map[string]string{HeaderAPIKey: "gsk_valid"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "success authorization scheme",
+			path:           "/products/1/stock",
+			headers:        map[string]string{"Authorization": "ApiKey gsk_valid"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "fail invalid key",
+			path:           "/products/1/stock",
+			headers:        map[string]string{HeaderAPIKey: "gsk_unknown"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "fail lookup error",
+			path:           "/products/1/stock",
+			headers:        map[string]string{HeaderAPIKey: "gsk_db_down"},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "fail scope missing",
+			path:           "/admin/orders",
+			headers:        map[string]string{HeaderAPIKey: "gsk_valid"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "fail role required",
+			path:           "/admin/users",
+			headers:        map[string]string{HeaderAPIKey: "gsk_valid"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "fail account route",
+			path:           "/users/profile",
+			headers:        map[string]string{HeaderAPIKey: "gsk_valid"},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mid := InitMiddleware(nil, denylist.NewMemoryStore(), keys, nil, nil, time.Hour)
+
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			r := gin.New()
+			r.GET("/products/1/stock", mid.Authorized(), mid.RequirePermission(user.PermProductsWrite), ok)
+			r.GET("/admin/orders", mid.Authorized(), mid.RequirePermission(user.PermOrdersRead), ok)
+			r.GET("/admin/users", mid.Authorized(), mid.RequireRole(user.RoleAdmin), ok)
+			r.GET("/users/profile", mid.Authorized(), mid.RequireUserToken(), ok)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+// fakeAuditor : keeps the request as it was when audited and when completed
+type fakeAuditor struct {
+	auditErr    error
+	completeErr error
+	audited     *user.ImpersonationRequest
+	completed   *user.ImpersonationRequest
+}
+
+func (f *fakeAuditor) AuditImpersonation(ctx context.Context, req *user.ImpersonationRequest) error {
+	if f.auditErr != nil {
+		return f.auditErr
+	}
+	req.ID = 1
+	audited := *req
+	f.audited = &audited
+	return nil
+}
+
+func (f *fakeAuditor) CompleteImpersonationAudit(ctx context.Context, req *user.ImpersonationRequest) error {
+	completed := *req
+	f.completed = &completed
+	return f.completeErr
+}
+
+func TestAuthorizedImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	claims := &jwttoken.UserClaims{
+		UserID: "mock-uuid-1",
+		Role:   user.RoleCustomer,
+		Actor:  &jwttoken.Actor{UserID: "mock-admin-uuid"},
+		RegisteredClaims: &jwt.RegisteredClaims{
+			ID:        "mock-jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	type testCase struct {
+		name           string
+		method         string
+		path           string
+		noAuditor      bool
+		auditErr       error
+		completeErr    error
+		expectedStatus int
+		expectedAudit  bool
+		expectHandler  bool
+	}
+
+	testCases := []testCase{
+		{
+			name:           "success audited",
+			method:         http.MethodGet,
+			path:           "/cart",
+			expectedStatus: http.StatusOK,
+			expectedAudit:  true,
+			expectHandler:  true,
+		},
+		{
+			name:           "success status update failure does not fail the request",
+			method:         http.MethodGet,
+			path:           "/cart",
+			completeErr:    errors.New("db down"),
+			expectedStatus: http.StatusOK,
+			expectedAudit:  true,
+			expectHandler:  true,
+		},
+		{
+			name:           "fail blocked route is audited",
+			method:         http.MethodPost,
+			path:           "/orders/checkout",
+			expectedStatus: http.StatusForbidden,
+			expectedAudit:  true,
+		},
+		{
+			name:           "fail audit insert blocks the request",
+			method:         http.MethodGet,
+			path:           "/cart",
+			auditErr:       errors.New("db down"),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "fail no auditor",
+			method:         http.MethodGet,
+			path:           "/cart",
+			noAuditor:      true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockToken := jwttoken.NewMockJWTToken(ctrl)
+			mockToken.EXPECT().VerifyAccessToken("mock-access-token").Return(claims, nil).Times(1)
+
+			fake := &fakeAuditor{auditErr: tc.auditErr, completeErr: tc.completeErr}
+			var auditor ImpersonationAuditor
+			if !tc.noAuditor {
+				auditor = fake
+			}
+			mid := InitMiddleware(mockToken, denylist.NewMemoryStore(), nil, auditor, nil, time.Hour)
+
+			handled := false
+			ok := func(c *gin.Context) {
+				// The audit row exists before the handler runs
+				handled = fake.audited != nil && fake.completed == nil
+				c.Status(http.StatusOK)
+			}
+			r := gin.New()
+			r.GET("/cart", mid.Authorized(), ok)
+			r.POST("/orders/checkout", mid.Authorized(), mid.BlockImpersonation(), ok)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer mock-access-token")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectHandler, handled)
+			if !tc.expectedAudit {
+				assert.Nil(t, fake.audited)
+				assert.Nil(t, fake.completed)
+				return
+			}
+			expected := &user.ImpersonationRequest{
+				ID:              1,
+				ImpersonationID: "mock-jti",
+				Method:          tc.method,
+				Path:            tc.path,
+				IPAddress:       "192.0.2.1",
+			}
+			assert.Equal(t, expected, fake.audited)
+			expected.Status = tc.expectedStatus
+			assert.Equal(t, expected, fake.completed)
+		})
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"github.com/codepnw/go-starter-kit/pkg/utils/response"
 	"github.com/gin-gonic/gin"
 
+	addresshandler "github.com/codepnw/go-starter-kit/internal/features/address/handler"
 	orderhandler "github.com/codepnw/go-starter-kit/internal/features/order/handler"
+	paymenthandler "github.com/codepnw/go-starter-kit/internal/features/payment/handler"
 	producthandler "github.com/codepnw/go-starter-kit/internal/features/product/handler"
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	userhandler "github.com/codepnw/go-starter-kit/internal/features/user/handler"
 )
 
 // -------------------- HEALTH Routes -----------------------
@@ -18,6 +22,15 @@ func (s *Server) registerHealthRoutes(r *gin.RouterGroup) {
 	})
 }
 
+// -------------------- WELL-KNOWN Routes -----------------------
+// Outside the API prefix, JWKS clients expect the standard path and a bare key set
+func (s *Server) registerWellKnownRoutes(r *gin.Engine) {
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, s.token.JWKS())
+	})
+}
+
 // -------------------- USER Routes -----------------------
 func (s *Server) registerUserRoutes(r *gin.RouterGroup) {
 	handler := s.handlerUser
@@ -27,16 +40,88 @@ func (s *Server) registerUserRoutes(r *gin.RouterGroup) {
 	{
 		auth.POST("/register", handler.Register)
 		auth.POST("/login", handler.Login)
+		auth.POST("/login/mfa", handler.VerifyMFA)
 		auth.POST("/refresh-token", handler.RefreshToken)
+		auth.POST("/verify-email", handler.VerifyEmail)
+		auth.POST("/verify-email/resend", handler.ResendVerification)
+		auth.POST("/password/forgot", handler.ForgotPassword)
+		auth.POST("/password/reset", handler.ResetPassword)
+		auth.POST("/email/confirm", handler.ConfirmEmailChange)
+
+		// Social Login: start returns the provider url, callback gets code and state
+		paramProvider := fmt.Sprintf("/:%s", userhandler.ParamProvider)
+		auth.POST("/oidc"+paramProvider, handler.StartOIDC)
+		auth.POST("/oidc"+paramProvider+"/callback", handler.OIDCCallback)
 
 		// Authorized
-		auth.POST("/logout", handler.Logout, s.mid.Authorized())
+		auth.POST("/logout", s.mid.Authorized(), s.mid.RequireUserToken(), handler.Logout)
 	}
 
-	// Users Routes
-	users := r.Group("/users", s.mid.Authorized())
+	// Users Routes: people only, not API keys
+	users := r.Group("/users", s.mid.Authorized(), s.mid.RequireUserToken())
 	{
+		// Account security: the customer only, never an impersonating admin
+		ownerOnly := s.mid.BlockImpersonation()
+
 		users.GET("/profile", handler.GetProfile)
+		users.PATCH("/profile", ownerOnly, handler.UpdateProfile)
+		users.DELETE("/profile", ownerOnly, handler.DeleteAccount)
+		users.POST("/password", ownerOnly, handler.ChangePassword)
+		users.POST("/reauth", ownerOnly, handler.RequestReauth)
+		users.GET("/identities", handler.ListIdentities)
+
+		// Sessions: one per login device
+		users.GET("/sessions", handler.ListSessions)
+		users.DELETE("/sessions", ownerOnly, handler.RevokeAllSessions)
+		users.DELETE(fmt.Sprintf("/sessions/:%s", userhandler.ParamSessionID), ownerOnly, handler.RevokeSession)
+
+		// Two-Factor: enroll, then confirm with the first code
+		users.POST("/mfa/totp", ownerOnly, handler.EnrollTOTP)
+		users.POST("/mfa/totp/confirm", ownerOnly, handler.ConfirmTOTP)
+		users.DELETE("/mfa/totp", ownerOnly, handler.DisableTOTP)
+		users.POST("/mfa/recovery-codes", ownerOnly, handler.RegenerateRecoveryCodes)
+	}
+
+	// Address Book: own addresses only
+	addresses := r.Group("/users/addresses", s.mid.Authorized(), s.mid.RequireUserToken())
+	{
+		paramAddr := fmt.Sprintf("/:%s", addresshandler.ParamAddressID)
+
+		addresses.GET("/", s.handlerAddress.ListAddresses)
+		addresses.POST("/", s.handlerAddress.CreateAddress)
+		addresses.GET(paramAddr, s.handlerAddress.GetAddress)
+		addresses.PUT(paramAddr, s.handlerAddress.UpdateAddress)
+		addresses.DELETE(paramAddr, s.handlerAddress.DeleteAddress)
+	}
+
+	// Admin Routes
+	admin := r.Group("/admin/users", s.mid.Authorized())
+	{
+		paramUser := fmt.Sprintf("/:%s", userhandler.ParamUserID)
+		customers := s.mid.RequirePermission(user.PermCustomersManage)
+		adminOnly := s.mid.RequireRole(user.RoleAdmin)
+
+		// Staff: search and manage customers, staff accounts need users:manage
+		admin.GET("/", customers, handler.ListUsers)
+		admin.GET(paramUser, customers, handler.GetUser)
+		admin.POST(paramUser+"/disable", customers, handler.DisableUser)
+		admin.POST(paramUser+"/enable", customers, handler.EnableUser)
+		admin.POST(paramUser+"/logout", customers, handler.ForceLogout)
+
+		admin.PATCH(paramUser+"/role", adminOnly, handler.UpdateUserRole)
+		admin.POST(paramUser+"/unlock", adminOnly, handler.UnlockUser)
+		admin.GET(paramUser+"/login-failures", adminOnly, handler.ListLoginFailures)
+
+		// Support: short-lived customer token, every request made with it is audited
+		admin.POST(paramUser+"/impersonate", adminOnly, s.mid.RequireUserToken(), s.mid.BlockImpersonation(), handler.Impersonate)
+	}
+
+	// API Keys: service accounts for back-office integrations
+	apiKeys := r.Group("/admin/api-keys", s.mid.Authorized(), s.mid.RequireRole(user.RoleAdmin))
+	{
+		apiKeys.GET("/", handler.ListAPIKeys)
+		apiKeys.POST("/", handler.CreateAPIKey)
+		apiKeys.DELETE(fmt.Sprintf("/:%s", userhandler.ParamAPIKeyID), handler.RevokeAPIKey)
 	}
 }
 
@@ -52,8 +137,8 @@ func (s *Server) registerProductRoutes(r *gin.RouterGroup) {
 		public.GET(paramID, handler.GetProduct)
 	}
 
-	// Authorized Routes
-	authorized := r.Group("/products", s.mid.Authorized())
+	// Staff & Admin Routes
+	authorized := r.Group("/products", s.mid.Authorized(), s.mid.RequirePermission(user.PermProductsWrite))
 	{
 		authorized.POST("/", handler.CreateProduct)
 		authorized.PATCH(paramID, handler.UpdateProduct)
@@ -66,10 +151,10 @@ func (s *Server) registerProductRoutes(r *gin.RouterGroup) {
 func (s *Server) registerCartRoutes(r *gin.RouterGroup) {
 	handler := s.handlerCart
 
-	carts := r.Group("/cart", s.mid.Authorized())
+	carts := r.Group("/cart", s.mid.Authorized(), s.mid.RequireUserToken())
 	{
 		carts.GET("/", handler.GetCart)
-		carts.POST("/items", handler.AddItem)
+		carts.POST("/items", s.mid.Idempotency(), handler.AddItem)
 		carts.DELETE(fmt.Sprintf("/items/:%s", producthandler.ParamProductID), handler.RemoveItme)
 	}
 }
@@ -77,12 +162,61 @@ func (s *Server) registerCartRoutes(r *gin.RouterGroup) {
 // -------------------- ORDER Routes -----------------------
 func (s *Server) registerOrderRoutes(r *gin.RouterGroup) {
 	handler := s.handlerOrder
+	paramNo := fmt.Sprintf("/:%s", orderhandler.ParamOrderNo)
 	paramID := fmt.Sprintf("/:%s", orderhandler.ParamOrderID)
 
-	orders := r.Group("/orders", s.mid.Authorized())
+	// Customer Routes: own orders only
+	orders := r.Group("/orders", s.mid.Authorized(), s.mid.RequireUserToken())
 	{
 		orders.GET("/", handler.MyOrders)
-		orders.POST("/checkout", handler.CreateOrder)
-		orders.GET(paramID, handler.GetOrderDetails)
+		// Checkout: the customer only, never an impersonating admin
+		checkout := []gin.HandlerFunc{s.mid.BlockImpersonation(), s.mid.Idempotency(), handler.CreateOrder}
+		if !s.cfg.Auth.AllowUnverifiedCheckout {
+			checkout = append([]gin.HandlerFunc{s.mid.RequireVerifiedEmail()}, checkout...)
+		}
+		orders.POST("/checkout", checkout...)
+		orders.GET(paramNo, handler.GetOrderDetails)
+		orders.POST(paramNo+"/cancel", s.mid.Idempotency(), handler.CancelOrder)
+		orders.POST(paramNo+"/returns", s.mid.Idempotency(), handler.RequestReturn)
+		orders.GET(paramNo+"/returns", handler.MyOrderReturns)
+	}
+
+	// Back-Office Routes: returns review and inspection
+	returns := r.Group("/orders/returns", s.mid.Authorized())
+	{
+		paramReturn := fmt.Sprintf("/:%s", orderhandler.ParamReturnID)
+
+		returns.GET("/", s.mid.RequirePermission(user.PermOrdersRead), handler.AdminListReturns)
+		returns.POST(paramReturn+"/approve", s.mid.RequirePermission(user.PermOrdersWrite), handler.ApproveReturn)
+		returns.POST(paramReturn+"/reject", s.mid.RequirePermission(user.PermOrdersWrite), handler.RejectReturn)
+		returns.POST(paramReturn+"/receive", s.mid.RequirePermission(user.PermOrdersWrite), s.mid.Idempotency(), handler.ReceiveReturn)
+	}
+
+	// Back-Office Routes: all orders
+	admin := r.Group("/admin/orders", s.mid.Authorized())
+	{
+		admin.GET("/", s.mid.RequirePermission(user.PermOrdersRead), handler.AdminListOrders)
+		admin.GET(paramID, s.mid.RequirePermission(user.PermOrdersRead), handler.AdminGetOrderDetails)
+		admin.PATCH(paramID+"/status", s.mid.RequirePermission(user.PermOrdersWrite), handler.UpdateOrderStatus)
+		admin.POST(paramID+"/refunds", s.mid.RequirePermission(user.PermOrdersWrite), s.mid.Idempotency(), handler.RefundOrder)
+		admin.POST(paramID+"/shipments", s.mid.RequirePermission(user.PermOrdersWrite), s.mid.Idempotency(), handler.CreateShipment)
+		admin.POST(fmt.Sprintf("%s/shipments/:%s/deliver", paramID, orderhandler.ParamShipmentID), s.mid.RequirePermission(user.PermOrdersWrite), handler.DeliverShipment)
+	}
+}
+
+// -------------------- PAYMENT Routes -----------------------
+func (s *Server) registerPaymentRoutes(r *gin.RouterGroup) {
+	handler := s.handlerPayment
+
+	// Customer Routes: pay own order
+	orders := r.Group("/orders", s.mid.Authorized(), s.mid.RequireUserToken())
+	{
+		orders.POST(fmt.Sprintf("/:%s/payments", orderhandler.ParamOrderNo), s.mid.BlockImpersonation(), s.mid.Idempotency(), handler.CreatePayment)
+	}
+
+	// Provider Webhooks: authenticated by signature
+	payments := r.Group("/payments")
+	{
+		payments.POST(fmt.Sprintf("/webhooks/:%s", paymenthandler.ParamProvider), handler.Webhook)
 	}
 }
@@ -1,82 +1,134 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/codepnw/go-starter-kit/internal/config"
+	addresshandler "github.com/codepnw/go-starter-kit/internal/features/address/handler"
+	addressrepository "github.com/codepnw/go-starter-kit/internal/features/address/repository"
+	addressservice "github.com/codepnw/go-starter-kit/internal/features/address/service"
 	carthandler "github.com/codepnw/go-starter-kit/internal/features/cart/handler"
 	cartrepository "github.com/codepnw/go-starter-kit/internal/features/cart/repository"
 	cartservice "github.com/codepnw/go-starter-kit/internal/features/cart/service"
 	orderhandler "github.com/codepnw/go-starter-kit/internal/features/order/handler"
 	orderrepository "github.com/codepnw/go-starter-kit/internal/features/order/repository"
 	orderservice "github.com/codepnw/go-starter-kit/internal/features/order/service"
+	paymenthandler "github.com/codepnw/go-starter-kit/internal/features/payment/handler"
+	"github.com/codepnw/go-starter-kit/internal/features/payment/provider"
+	paymentrepository "github.com/codepnw/go-starter-kit/internal/features/payment/repository"
+	paymentservice "github.com/codepnw/go-starter-kit/internal/features/payment/service"
 	producthandler "github.com/codepnw/go-starter-kit/internal/features/product/handler"
 	productrepository "github.com/codepnw/go-starter-kit/internal/features/product/repository"
 	productservice "github.com/codepnw/go-starter-kit/internal/features/product/service"
 	userhandler "github.com/codepnw/go-starter-kit/internal/features/user/handler"
+	"github.com/codepnw/go-starter-kit/internal/features/user/oidc"
 	userrepository "github.com/codepnw/go-starter-kit/internal/features/user/repository"
 	userservice "github.com/codepnw/go-starter-kit/internal/features/user/service"
 	"github.com/codepnw/go-starter-kit/internal/middleware"
 	"github.com/codepnw/go-starter-kit/pkg/database"
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
+	"github.com/codepnw/go-starter-kit/pkg/idempotency"
 	jwttoken "github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/codepnw/go-starter-kit/pkg/loginguard"
+	"github.com/codepnw/go-starter-kit/pkg/mailer"
+	"github.com/codepnw/go-starter-kit/pkg/utils/password"
+	"github.com/codepnw/go-starter-kit/pkg/utils/secretbox"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
+	cfg    *config.EnvConfig
 	db     *sql.DB
 	router *gin.Engine
 	token  jwttoken.JWTToken
 	mid    *middleware.Middleware
 	tx     database.TxManager
+	idem   idempotency.Store
+	deny   denylist.Store
+	guard  *loginguard.Guard
+	// API key lookup and impersonation audit for the middleware
+	apiKeys middleware.APIKeyAuthenticator
+	auditor middleware.ImpersonationAuditor
 	// Handler Domain
 	handlerUser    *userhandler.UserHandler
+	handlerAddress *addresshandler.AddressHandler
 	handlerProduct *producthandler.ProductHandler
 	handlerCart    *carthandler.CartHandler
 	handlerOrder   *orderhandler.OrderHandler
+	handlerPayment *paymenthandler.PaymentHandler
+	// Local fake payment gateway, nil for real providers
+	fakeGateway *http.Server
+	// Local fake OIDC provider, nil when not configured
+	fakeOIDC *http.Server
 }
 
 func NewServer(cfg *config.EnvConfig, db *sql.DB) (*Server, error) {
 	r := gin.New()
 
 	// JWT Token
-	token, err := jwttoken.NewJWTToken(cfg.JWT.AppName, cfg.JWT.SecretKey, cfg.JWT.RefreshKey)
+	token, err := jwttoken.NewJWTToken(jwttoken.Config{
+		AppName:     cfg.JWT.AppName,
+		Algorithm:   cfg.JWT.Algorithm,
+		SecretKey:   cfg.JWT.SecretKey,
+		RefreshKey:  cfg.JWT.RefreshKey,
+		KeysDir:     cfg.JWT.KeysDir,
+		ActiveKeyID: cfg.JWT.ActiveKeyID,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Middleware
-	mid := middleware.InitMiddleware(token)
+	// Idempotency Keys
+	idem := idempotency.NewPostgresStore(db)
+
+	// Revoked Access Tokens
+	deny := denylist.NewCache(denylist.NewPostgresStore(db), cfg.Auth.DenylistRefresh)
 
 	// DB Transaction
 	tx := database.NewDBTransaction(db)
 
 	// Denpendency Injection
 	s := &Server{
+		cfg:    cfg,
 		db:     db,
 		router: r,
 		token:  token,
-		mid:    mid,
 		tx:     tx,
+		idem:   idem,
+		deny:   deny,
+	}
+
+	// Setup Domain Handler
+	if err := s.setupHandler(); err != nil {
+		return nil, err
 	}
 
+	// Middleware: API keys and impersonated requests are handled by the user service
+	s.mid = middleware.InitMiddleware(token, deny, s.apiKeys, s.auditor, idem, cfg.APP.IdempotencyTTL)
+
 	// Gin Middleware
 	s.ginMiddleware(r)
-	
-	// Setup Domain Handler
-	s.setupHandler()
 
 	// Prefix Default: /api/v1
 	prefix := s.router.Group(cfg.APP.Prefix)
 
 	// Register Routes
+	s.registerWellKnownRoutes(s.router)
 	s.registerHealthRoutes(prefix)
 	s.registerUserRoutes(prefix)
 	s.registerProductRoutes(prefix)
 	s.registerCartRoutes(prefix)
 	s.registerOrderRoutes(prefix)
+	s.registerPaymentRoutes(prefix)
 
 	return s, nil
 }
@@ -85,24 +137,121 @@ func (s *Server) Handler() http.Handler {
 	return s.router
 }
 
+// Shutdown : stop resources started by the server, http server is stopped by caller
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errList []error
+	if s.fakeGateway != nil {
+		errList = append(errList, s.fakeGateway.Shutdown(ctx))
+	}
+	if s.fakeOIDC != nil {
+		errList = append(errList, s.fakeOIDC.Shutdown(ctx))
+	}
+	return errors.Join(errList...)
+}
+
+// RunBackgroundJobs : periodic cleanup, stop when ctx is cancelled
+func (s *Server) RunBackgroundJobs(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runCleanup(ctx)
+		}
+	}
+}
+
+// runCleanup : a failing step is logged, the others still run
+func (s *Server) runCleanup(ctx context.Context) {
+	steps := []struct {
+		name string
+		run  func(ctx context.Context) (int64, error)
+	}{
+		{name: "expired idempotency keys", run: s.idem.DeleteExpired},
+		{name: "stale login throttles", run: s.guard.Cleanup},
+		{name: "expired revoked access tokens", run: s.deny.DeleteExpired},
+	}
+
+	for _, step := range steps {
+		count, err := step.run(ctx)
+		if err != nil {
+			slog.Error("delete "+step.name+" failed", slog.Any("error", err))
+		} else {
+			slog.Info("deleted "+step.name, slog.Int64("count", count))
+		}
+	}
+}
+
 func (s *Server) ginMiddleware(r *gin.Engine) {
 	r.Use(gin.Recovery())
 	r.Use(s.mid.Logger())
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.HeaderIdempotencyKey, middleware.HeaderAPIKey},
+		ExposeHeaders:    []string{"Content-Length", middleware.HeaderIdempotencyReplayed},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 }
 
-func (s *Server) setupHandler() {
+func (s *Server) setupHandler() error {
 	// User Handler Setup
 	userRepo := userrepository.NewUserRepository(s.db)
-	userService := userservice.NewUserService(s.tx, s.token, userRepo)
+	mail, err := s.newMailer()
+	if err != nil {
+		return err
+	}
+	mfaSecrets, err := s.newMFASecrets()
+	if err != nil {
+		return err
+	}
+	s.guard = loginguard.New(loginguard.NewPostgresStore(s.db), loginguard.Policy{
+		Window:       s.cfg.Auth.LoginWindow,
+		AccountLimit: s.cfg.Auth.LoginAccountLimit,
+		IPLimit:      s.cfg.Auth.LoginIPLimit,
+		LockoutBase:  s.cfg.Auth.LoginLockoutBase,
+		LockoutMax:   s.cfg.Auth.LoginLockoutMax,
+	})
+	oidcProviders, err := s.newOIDCProviders()
+	if err != nil {
+		return err
+	}
+	passwords, policy, err := s.newPasswords()
+	if err != nil {
+		return err
+	}
+	userService := userservice.NewUserService(s.tx, s.token, userRepo, mail, s.guard, s.deny, userservice.Config{
+		AllowUnverifiedLogin:     s.cfg.Auth.AllowUnverifiedLogin,
+		VerificationTTL:          s.cfg.Auth.VerificationTTL,
+		VerifyEmailURL:           s.cfg.Auth.VerifyEmailURL,
+		ConfirmEmailURL:          s.cfg.Auth.ConfirmEmailURL,
+		VerificationResendLimit:  s.cfg.Auth.VerificationResendLimit,
+		VerificationResendWindow: s.cfg.Auth.VerificationResendWindow,
+		PasswordResetTTL:         s.cfg.Auth.PasswordResetTTL,
+		PasswordResetURL:         s.cfg.Auth.PasswordResetURL,
+		PasswordResetLimit:       s.cfg.Auth.PasswordResetLimit,
+		PasswordResetWindow:      s.cfg.Auth.PasswordResetWindow,
+		MFAIssuer:                s.cfg.Auth.MFAIssuer,
+		MFAChallengeTTL:          s.cfg.Auth.MFAChallengeTTL,
+		MFASecrets:               mfaSecrets,
+		OIDCProviders:            oidcProviders,
+		OIDCStateTTL:             s.cfg.OIDC.StateTTL,
+		Passwords:                passwords,
+		PasswordPolicy:           policy,
+		ImpersonationTTL:         s.cfg.Auth.ImpersonationTTL,
+	})
 	s.handlerUser = userhandler.NewUserHandler(userService)
+	s.apiKeys = userService
+	s.auditor = userService
+
+	// Bootstrap First Admin
+	if err := userService.BootstrapAdmin(context.Background(), s.cfg.Admin.Email, s.cfg.Admin.Password); err != nil {
+		return fmt.Errorf("bootstrap admin failed: %w", err)
+	}
 
 	// Product Handler Setup
 	prodRepo := productrepository.NewProductRepository(s.db)
@@ -114,8 +263,154 @@ func (s *Server) setupHandler() {
 	cartSrv := cartservice.NewCartService(cartRepo, prodService)
 	s.handlerCart = carthandler.NewCartHandler(cartSrv)
 
+	// Address Handler Setup
+	addrRepo := addressrepository.NewAddressRepository(s.db)
+	addrService := addressservice.NewAddressService(s.tx, addrRepo)
+	s.handlerAddress = addresshandler.NewAddressHandler(addrService)
+
+	// Payment Provider
+	payProvider, err := s.newPaymentProvider()
+	if err != nil {
+		return err
+	}
+	payRepo := paymentrepository.NewPaymentRepository(s.db)
+
 	// Order Handler Setup
 	ordRepo := orderrepository.NewOrderRepository(s.db)
-	ordService := orderservice.NewOrderService(s.tx, ordRepo, prodRepo, cartRepo)
+	ordService := orderservice.NewOrderService(s.tx, ordRepo, prodRepo, cartRepo, payRepo, payProvider, addrService)
 	s.handlerOrder = orderhandler.NewOrderHandler(ordService)
+
+	// Payment Handler Setup
+	payService := paymentservice.NewPaymentService(s.tx, payRepo, ordRepo, ordService, payProvider, s.cfg.Payment.Currency)
+	s.handlerPayment = paymenthandler.NewPaymentHandler(payService)
+
+	return nil
+}
+
+func (s *Server) newMailer() (mailer.Mailer, error) {
+	cfg := s.cfg.Mail
+
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		slog.Info("mail messages are written to files", slog.String("dir", cfg.FileDir))
+		return mailer.NewFileMailer(cfg.FileDir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %q", cfg.Driver)
+	}
+}
+
+// newMFASecrets : without a key 2FA enrollment answers 503
+func (s *Server) newMFASecrets() (*secretbox.Box, error) {
+	if s.cfg.Auth.MFAEncryptionKey == "" {
+		slog.Warn("AUTH_MFA_ENCRYPTION_KEY is not set, two-factor authentication is disabled")
+		return nil, nil
+	}
+
+	box, err := secretbox.New(s.cfg.Auth.MFAEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("mfa encryption key: %w", err)
+	}
+	return box, nil
+}
+
+// newPasswords : bcrypt keeps its 72 byte limit in the policy
+func (s *Server) newPasswords() (password.Hasher, *password.Policy, error) {
+	cfg := s.cfg.Password
+	hasher, err := password.NewHasher(password.Config{
+		Algorithm:   cfg.Algorithm,
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+		BcryptCost:  cfg.BcryptCost,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy := &password.Policy{MinLength: cfg.MinLength, MaxLength: cfg.MaxLength}
+	if cfg.Algorithm == password.AlgBcrypt {
+		policy.MaxBytes = password.BcryptMaxBytes
+	}
+	if cfg.BreachedFile != "" {
+		if err := policy.LoadBreached(cfg.BreachedFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	return hasher, policy, nil
+}
+
+// newOIDCProviders : "fake" starts a local identity provider with a random client secret per process
+func (s *Server) newOIDCProviders() (map[string]oidc.Provider, error) {
+	providers := make(map[string]oidc.Provider, len(s.cfg.OIDC.Clients))
+
+	for _, c := range s.cfg.OIDC.Clients {
+		if _, ok := providers[c.Name]; ok {
+			return nil, fmt.Errorf("duplicate oidc provider: %q", c.Name)
+		}
+
+		if c.Name == oidc.FakeProviderName {
+			if s.fakeOIDC != nil {
+				return nil, fmt.Errorf("duplicate oidc provider: %q", c.Name)
+			}
+			if c.ClientID == "" {
+				c.ClientID = "go-starter-kit"
+			}
+			if c.ClientSecret == "" {
+				b := make([]byte, 32)
+				if _, err := rand.Read(b); err != nil {
+					return nil, err
+				}
+				c.ClientSecret = hex.EncodeToString(b)
+			}
+
+			srv, issuer, err := oidc.StartFakeProvider(s.cfg.OIDC.FakeAddr, c.ClientID, c.ClientSecret, c.RedirectURL)
+			if err != nil {
+				return nil, err
+			}
+			s.fakeOIDC = srv
+			c.Issuer = issuer
+			slog.Info("fake oidc provider started", slog.String("url", issuer))
+		}
+
+		providers[c.Name] = oidc.NewProvider(oidc.Config{
+			Name:         c.Name,
+			Issuer:       c.Issuer,
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  c.RedirectURL,
+			Scopes:       c.Scopes,
+		}, nil)
+	}
+	return providers, nil
+}
+
+func (s *Server) newPaymentProvider() (provider.PaymentProvider, error) {
+	cfg := s.cfg.Payment
+
+	switch cfg.Provider {
+	case provider.FakeProviderName:
+		secret := cfg.WebhookSecret
+		if secret == "" {
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				return nil, err
+			}
+			secret = hex.EncodeToString(b)
+		}
+
+		webhookURL := fmt.Sprintf("http://%s:%d%s/payments/webhooks/%s", s.cfg.APP.Host, s.cfg.APP.Port, s.cfg.APP.Prefix, provider.FakeProviderName)
+
+		gateway, baseURL, err := provider.StartFakeGateway(cfg.FakeGatewayAddr, secret, webhookURL)
+		if err != nil {
+			return nil, err
+		}
+		s.fakeGateway = gateway
+		slog.Info("fake payment gateway started", slog.String("url", baseURL))
+
+		return provider.NewFakeProvider(baseURL, secret, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %q", cfg.Provider)
+	}
 }
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status TEXT,                               -- NULL when order created
+    to_status TEXT NOT NULL,
+    changed_by UUID REFERENCES users(id),           -- NULL when changed by system
+    reason TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id);
+
+-- Backfill current status of existing orders
+INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason, created_at)
+SELECT id, NULL, status, NULL, 'backfill', created_at FROM orders;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_payments_order_open;
+DROP INDEX IF EXISTS idx_payments_order_id;
+
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id),
+    provider VARCHAR(50) NOT NULL,
+    provider_ref VARCHAR(255) NOT NULL,         -- payment intent id at provider
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    checkout_url TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT payments_status_check CHECK (status IN ('PENDING', 'CAPTURED', 'FAILED')),
+    CONSTRAINT payments_provider_ref_unique UNIQUE (provider, provider_ref)
+);
+
+CREATE INDEX idx_payments_order_id ON payments(order_id);
+
+-- 1 open payment per order, new attempt allowed after FAILED
+CREATE UNIQUE INDEX idx_payments_order_open ON payments(order_id) WHERE status IN ('PENDING', 'CAPTURED');
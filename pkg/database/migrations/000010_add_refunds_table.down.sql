@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_refund_items_order_item_id;
+DROP INDEX IF EXISTS idx_refund_items_refund_id;
+DROP TABLE IF EXISTS refund_items;
+
+DROP INDEX IF EXISTS idx_refunds_order_id;
+DROP TABLE IF EXISTS refunds;
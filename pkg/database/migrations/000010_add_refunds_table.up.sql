@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS refunds (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id),
+    payment_id BIGINT NOT NULL REFERENCES payments(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    provider_ref VARCHAR(255) NOT NULL,         -- refund id at provider
+    created_by UUID REFERENCES users(id),       -- NULL when created by system
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    id BIGSERIAL PRIMARY KEY,
+    refund_id BIGINT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount BIGINT NOT NULL CHECK (amount >= 0)
+);
+
+CREATE INDEX idx_refund_items_refund_id ON refund_items(refund_id);
+CREATE INDEX idx_refund_items_order_item_id ON refund_items(order_item_id);
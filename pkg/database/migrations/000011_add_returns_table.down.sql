@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_refunds_return_id;
+ALTER TABLE refunds DROP COLUMN IF EXISTS return_id;
+
+DROP TABLE IF EXISTS return_items;
+
+DROP INDEX IF EXISTS idx_returns_status;
+DROP INDEX IF EXISTS idx_returns_order_id;
+DROP TABLE IF EXISTS returns;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS returns (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'REQUESTED',
+    note TEXT,                                  -- customer note
+    admin_note TEXT,                            -- approval / inspection note
+    reviewed_by UUID REFERENCES users(id),
+    reviewed_at TIMESTAMPTZ,
+    received_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT returns_status_check CHECK (status IN ('REQUESTED', 'APPROVED', 'REJECTED', 'REFUNDED'))
+);
+
+CREATE INDEX idx_returns_order_id ON returns(order_id);
+CREATE INDEX idx_returns_status ON returns(status);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id BIGSERIAL PRIMARY KEY,
+    return_id BIGINT NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    reason_code VARCHAR(30) NOT NULL,
+    resolution VARCHAR(20),                     -- NULL until received
+
+    CONSTRAINT return_items_reason_check CHECK (reason_code IN ('DAMAGED', 'DEFECTIVE', 'WRONG_ITEM', 'NOT_AS_DESCRIBED', 'NO_LONGER_NEEDED', 'OTHER')),
+    CONSTRAINT return_items_resolution_check CHECK (resolution IN ('RESTOCK', 'WRITE_OFF')),
+    CONSTRAINT return_items_unique UNIQUE (return_id, order_item_id)
+);
+
+-- Refund issued when a return is received
+ALTER TABLE refunds ADD COLUMN return_id BIGINT REFERENCES returns(id);
+CREATE INDEX idx_refunds_return_id ON refunds(return_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_shipment_items_order_item_id;
+DROP TABLE IF EXISTS shipment_items;
+
+DROP INDEX IF EXISTS idx_shipments_order_id;
+DROP TABLE IF EXISTS shipments;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS shipments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id),
+    carrier VARCHAR(50) NOT NULL,
+    tracking_number VARCHAR(100) NOT NULL,
+    shipped_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ,                   -- NULL until delivered
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    CONSTRAINT shipments_tracking_unique UNIQUE (carrier, tracking_number)
+);
+
+CREATE INDEX idx_shipments_order_id ON shipments(order_id);
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    id BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+
+    CONSTRAINT shipment_items_unique UNIQUE (shipment_id, order_item_id)
+);
+
+CREATE INDEX idx_shipment_items_order_item_id ON shipment_items(order_item_id);
@@ -0,0 +1,6 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_address;
+
+DROP INDEX IF EXISTS idx_user_addresses_default_billing;
+DROP INDEX IF EXISTS idx_user_addresses_default_shipping;
+DROP INDEX IF EXISTS idx_user_addresses_user_id;
+DROP TABLE IF EXISTS user_addresses;
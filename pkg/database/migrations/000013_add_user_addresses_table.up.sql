@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS user_addresses (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label VARCHAR(50),
+    recipient_name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255),
+    city VARCHAR(100) NOT NULL,
+    province VARCHAR(100),
+    postal_code VARCHAR(20) NOT NULL,
+    country CHAR(2) NOT NULL,
+    is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default_billing BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_addresses_user_id ON user_addresses(user_id);
+
+-- At most one default of each kind per user
+CREATE UNIQUE INDEX idx_user_addresses_default_shipping ON user_addresses(user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX idx_user_addresses_default_billing ON user_addresses(user_id) WHERE is_default_billing;
+
+-- Snapshot of the structured address at checkout, NULL for older orders
+ALTER TABLE orders ADD COLUMN shipping_address JSONB;
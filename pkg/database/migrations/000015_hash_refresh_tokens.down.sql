@@ -0,0 +1,2 @@
+-- Hashes cannot be reversed: revoke every token, users log in again
+UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW() WHERE NOT revoked;
@@ -0,0 +1,2 @@
+-- Store SHA-256 (hex) of refresh tokens instead of the raw JWT
+UPDATE refresh_tokens SET token = encode(sha256(convert_to(token, 'UTF8')), 'hex');
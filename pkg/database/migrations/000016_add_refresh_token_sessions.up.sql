@@ -0,0 +1,8 @@
+-- Sessions: a token family is one login on one device
+ALTER TABLE refresh_tokens
+    ADD COLUMN user_agent TEXT,
+    ADD COLUMN ip_address VARCHAR(45),
+    ADD COLUMN device_label VARCHAR(100),
+    ADD COLUMN last_used_at TIMESTAMPTZ DEFAULT NOW();
+
+CREATE INDEX idx_refresh_tokens_user_active ON refresh_tokens(user_id) WHERE NOT revoked;
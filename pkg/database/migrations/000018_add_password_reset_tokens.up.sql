@@ -0,0 +1,7 @@
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+
+ALTER TABLE user_tokens
+ADD CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset'));
+
+-- Rate limit lookups: recent tokens of a user
+CREATE INDEX idx_user_tokens_created_at ON user_tokens(user_id, purpose, created_at);
@@ -0,0 +1,16 @@
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_user_id_fkey;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+
+DELETE FROM user_tokens WHERE purpose = 'email_change';
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+
+ALTER TABLE user_tokens
+ADD CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset'));
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS pending_email,
+    DROP COLUMN IF EXISTS name;
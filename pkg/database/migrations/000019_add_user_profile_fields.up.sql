@@ -0,0 +1,15 @@
+ALTER TABLE users
+    ADD COLUMN name VARCHAR(100),
+    ADD COLUMN pending_email VARCHAR(255),   -- new email waiting for confirmation
+    ADD COLUMN deleted_at TIMESTAMPTZ;       -- account deleted, row kept anonymized for orders
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+
+ALTER TABLE user_tokens
+ADD CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset', 'email_change'));
+
+-- Orders are accounting records: users are anonymized, never deleted while they have orders
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_user_id_fkey;
+
+ALTER TABLE orders
+ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
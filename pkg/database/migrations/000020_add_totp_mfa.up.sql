@@ -0,0 +1,24 @@
+-- TOTP secret is AES-GCM encrypted, last step blocks reusing a code
+ALTER TABLE users
+    ADD COLUMN totp_secret TEXT,
+    ADD COLUMN totp_enabled_at TIMESTAMPTZ,          -- NULL until the first code is confirmed
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- One-time recovery codes, only the SHA-256 hash is stored
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    UNIQUE (user_id, code_hash)
+);
+
+-- MFA login challenge: wrong codes are counted, too many burn the challenge
+ALTER TABLE user_tokens ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+
+ALTER TABLE user_tokens
+ADD CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset', 'email_change', 'mfa_challenge'));
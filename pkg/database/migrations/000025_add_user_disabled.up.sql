@@ -0,0 +1,6 @@
+-- Disabled by staff: no login or token refresh until enabled again
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
+
+-- Admin user search sorts by signup date, user details count orders
+CREATE INDEX idx_users_created_at ON users(created_at DESC);
+CREATE INDEX idx_orders_user_id ON orders(user_id);
@@ -0,0 +1,25 @@
+-- Support agents acting as a customer, id is the jti of the access token issued
+CREATE TABLE IF NOT EXISTS impersonations (
+    id VARCHAR(64) PRIMARY KEY,
+    actor_id UUID NOT NULL REFERENCES users(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    reason VARCHAR(500) NOT NULL,
+    ip_address VARCHAR(45),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Every request made with an impersonation token
+CREATE TABLE IF NOT EXISTS impersonation_requests (
+    id BIGSERIAL PRIMARY KEY,
+    impersonation_id VARCHAR(64) NOT NULL REFERENCES impersonations(id),
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(500) NOT NULL,
+    status INT NOT NULL,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_impersonations_user_id ON impersonations(user_id, created_at DESC);
+CREATE INDEX idx_impersonations_actor_id ON impersonations(actor_id, created_at DESC);
+CREATE INDEX idx_impersonation_requests_impersonation_id ON impersonation_requests(impersonation_id);
@@ -0,0 +1,4 @@
+UPDATE orders SET status = 'PARTIALLY_REFUNDED'
+WHERE refunded_amount > 0 AND status NOT IN ('REFUNDED', 'CANCELLED');
+
+ALTER TABLE orders DROP COLUMN IF EXISTS refunded_amount;
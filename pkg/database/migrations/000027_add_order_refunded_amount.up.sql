@@ -0,0 +1,20 @@
+-- Refund state is kept apart from fulfilment status, only a full refund changes the status
+ALTER TABLE orders ADD COLUMN refunded_amount BIGINT NOT NULL DEFAULT 0;
+
+UPDATE orders o SET refunded_amount = r.total
+FROM (SELECT order_id, SUM(amount) AS total FROM refunds GROUP BY order_id) r
+WHERE r.order_id = o.id;
+
+-- PARTIALLY_REFUNDED orders go back to their last fulfilment status
+WITH moved AS (
+    UPDATE orders o SET status = COALESCE((
+        SELECT h.to_status FROM order_status_history h
+        WHERE h.order_id = o.id AND h.to_status NOT IN ('PARTIALLY_REFUNDED', 'REFUNDED')
+        ORDER BY h.created_at DESC, h.id DESC
+        LIMIT 1
+    ), 'PAID'), updated_at = NOW()
+    WHERE o.status = 'PARTIALLY_REFUNDED'
+    RETURNING o.id, o.status
+)
+INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+SELECT id, 'PARTIALLY_REFUNDED', status, NULL, 'partial refund moved to refunded_amount' FROM moved;
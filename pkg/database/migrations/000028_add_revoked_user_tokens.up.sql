@@ -0,0 +1,10 @@
+-- Access tokens of a user issued before revoked_before are rejected (disabled, logged out by staff, role changed)
+CREATE TABLE IF NOT EXISTS revoked_user_tokens (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_user_tokens_created_at ON revoked_user_tokens(created_at);
+CREATE INDEX idx_revoked_user_tokens_expires_at ON revoked_user_tokens(expires_at);
@@ -0,0 +1,2 @@
+UPDATE impersonation_requests SET status = 0 WHERE status IS NULL;
+ALTER TABLE impersonation_requests ALTER COLUMN status SET NOT NULL;
@@ -0,0 +1,2 @@
+-- Request rows are written before the request runs, status is set when it completes
+ALTER TABLE impersonation_requests ALTER COLUMN status DROP NOT NULL;
@@ -0,0 +1,13 @@
+-- PARTIALLY_REFUNDED orders go back to their last fulfilment status
+WITH moved AS (
+    UPDATE orders o SET status = COALESCE((
+        SELECT h.to_status FROM order_status_history h
+        WHERE h.order_id = o.id AND h.to_status NOT IN ('PARTIALLY_REFUNDED', 'REFUNDED')
+        ORDER BY h.created_at DESC, h.id DESC
+        LIMIT 1
+    ), 'PAID'), updated_at = NOW()
+    WHERE o.status = 'PARTIALLY_REFUNDED'
+    RETURNING o.id, o.status
+)
+INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+SELECT id, 'PARTIALLY_REFUNDED', status, NULL, 'partial refund kept in refunded_amount' FROM moved;
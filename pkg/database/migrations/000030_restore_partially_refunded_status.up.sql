@@ -0,0 +1,11 @@
+-- Partial refunds set PARTIALLY_REFUNDED again, refunded_amount keeps the sum
+WITH moved AS (
+    UPDATE orders o SET status = 'PARTIALLY_REFUNDED', updated_at = NOW()
+    FROM orders prev
+    WHERE prev.id = o.id
+      AND o.refunded_amount > 0
+      AND o.status NOT IN ('PARTIALLY_REFUNDED', 'REFUNDED', 'CANCELLED')
+    RETURNING o.id, prev.status AS from_status
+)
+INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, reason)
+SELECT id, from_status, 'PARTIALLY_REFUNDED', NULL, 'partial refund status restored' FROM moved;
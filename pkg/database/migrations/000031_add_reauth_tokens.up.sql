@@ -0,0 +1,5 @@
+-- One-time codes that confirm a user without a password (social login) before account changes
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+
+ALTER TABLE user_tokens
+ADD CONSTRAINT user_tokens_purpose_check CHECK (purpose IN ('email_verification', 'password_reset', 'email_change', 'mfa_challenge', 'reauth'));
@@ -0,0 +1,215 @@
+package denylist
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Entry : a revoked access token id, kept until the token would expire anyway
+type Entry struct {
+	JTI       string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// UserEntry : every access token of a user issued before RevokedBefore is revoked,
+// kept until the last of those tokens would expire anyway
+type UserEntry struct {
+	UserID        string
+	RevokedBefore time.Time
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
+
+// Store : checked on every authenticated request
+type Store interface {
+	Add(ctx context.Context, jti string, expiresAt time.Time) error
+	Contains(ctx context.Context, jti string) (bool, error)
+	// RevokeUser : tokens of userID issued before revokedBefore, none of them outlives expiresAt
+	RevokeUser(ctx context.Context, userID string, revokedBefore, expiresAt time.Time) error
+	UserRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// Source : shared storage behind Cache, Since and UsersSince return entries added after cursor
+type Source interface {
+	Add(ctx context.Context, jti string, expiresAt time.Time) error
+	Since(ctx context.Context, cursor time.Time) ([]Entry, error)
+	RevokeUser(ctx context.Context, userID string, revokedBefore, expiresAt time.Time) error
+	UsersSince(ctx context.Context, cursor time.Time) ([]UserEntry, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// revokedBy : iat has second precision, a token issued in the same second as the cut-off is revoked too
+func (e UserEntry) revokedBy(issuedAt, now time.Time) bool {
+	return now.Before(e.ExpiresAt) && issuedAt.Before(e.RevokedBefore)
+}
+
+// merge : a later cut-off covers an earlier one
+func (e UserEntry) merge(other UserEntry) UserEntry {
+	e.UserID = other.UserID
+	if other.RevokedBefore.After(e.RevokedBefore) {
+		e.RevokedBefore = other.RevokedBefore
+	}
+	if other.ExpiresAt.After(e.ExpiresAt) {
+		e.ExpiresAt = other.ExpiresAt
+	}
+	if other.CreatedAt.After(e.CreatedAt) {
+		e.CreatedAt = other.CreatedAt
+	}
+	return e
+}
+
+// Rows committed out of created_at order are picked up by re-reading this much
+const syncOverlap = time.Minute
+
+// Cache : in-memory copy of Source, refreshed at most every refresh interval.
+// Lookups never hit the database between refreshes, so a token revoked on
+// another instance is rejected here within one interval.
+type Cache struct {
+	src     Source
+	refresh time.Duration
+	now     func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]time.Time // jti -> expires at
+	users      map[string]UserEntry // user id -> cut-off
+	cursor     time.Time
+	userCursor time.Time
+	syncedAt   time.Time
+}
+
+func NewCache(src Source, refresh time.Duration) *Cache {
+	return &Cache{
+		src:     src,
+		refresh: refresh,
+		now:     time.Now,
+		entries: make(map[string]time.Time),
+		users:   make(map[string]UserEntry),
+	}
+}
+
+// WithClock : fixed time in tests
+func (c *Cache) WithClock(now func() time.Time) *Cache {
+	c.now = now
+	return c
+}
+
+func (c *Cache) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := c.src.Add(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[jti] = expiresAt
+	return nil
+}
+
+func (c *Cache) Contains(ctx context.Context, jti string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if err := c.syncIfStale(ctx, now); err != nil {
+		return false, err
+	}
+
+	expiresAt, ok := c.entries[jti]
+	return ok && now.Before(expiresAt), nil
+}
+
+func (c *Cache) RevokeUser(ctx context.Context, userID string, revokedBefore, expiresAt time.Time) error {
+	if err := c.src.RevokeUser(ctx, userID, revokedBefore, expiresAt); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.users[userID] = c.users[userID].merge(UserEntry{UserID: userID, RevokedBefore: revokedBefore, ExpiresAt: expiresAt})
+	return nil
+}
+
+func (c *Cache) UserRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if err := c.syncIfStale(ctx, now); err != nil {
+		return false, err
+	}
+
+	e, ok := c.users[userID]
+	return ok && e.revokedBy(issuedAt, now), nil
+}
+
+func (c *Cache) DeleteExpired(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	now := c.now()
+	for jti, expiresAt := range c.entries {
+		if !now.Before(expiresAt) {
+			delete(c.entries, jti)
+		}
+	}
+	for userID, e := range c.users {
+		if !now.Before(e.ExpiresAt) {
+			delete(c.users, userID)
+		}
+	}
+	c.mu.Unlock()
+
+	return c.src.DeleteExpired(ctx)
+}
+
+// syncIfStale : caller holds mu
+func (c *Cache) syncIfStale(ctx context.Context, now time.Time) error {
+	if !c.syncedAt.IsZero() && now.Sub(c.syncedAt) < c.refresh {
+		return nil
+	}
+	return c.sync(ctx, now)
+}
+
+// sync : caller holds mu
+func (c *Cache) sync(ctx context.Context, now time.Time) error {
+	entries, err := c.src.Since(ctx, c.cursor)
+	if err != nil {
+		return err
+	}
+	users, err := c.src.UsersSince(ctx, c.userCursor)
+	if err != nil {
+		return err
+	}
+
+	latest := c.cursor
+	for _, e := range entries {
+		c.entries[e.JTI] = e.ExpiresAt
+		if e.CreatedAt.After(latest) {
+			latest = e.CreatedAt
+		}
+	}
+
+	if next := latest.Add(-syncOverlap); next.After(c.cursor) {
+		c.cursor = next
+	}
+
+	latest = c.userCursor
+	for _, e := range users {
+		c.users[e.UserID] = c.users[e.UserID].merge(e)
+		if e.CreatedAt.After(latest) {
+			latest = e.CreatedAt
+		}
+	}
+	if next := latest.Add(-syncOverlap); next.After(c.userCursor) {
+		c.userCursor = next
+	}
+	c.syncedAt = now
+
+	if len(entries) > 0 || len(users) > 0 {
+		slog.Debug("access token denylist synced", slog.Int("entries", len(entries)), slog.Int("users", len(users)))
+	}
+	return nil
+}
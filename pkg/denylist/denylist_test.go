@@ -0,0 +1,104 @@
+package denylist_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/denylist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	// Two instances sharing one database
+	shared := denylist.NewMemoryStore().WithClock(clock)
+	a := denylist.NewCache(shared, 5*time.Second).WithClock(clock)
+	b := denylist.NewCache(shared, 5*time.Second).WithClock(clock)
+
+	revoked, err := b.Contains(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Seen at once by the instance that revoked it
+	assert.NoError(t, a.Add(ctx, "jti-1", now.Add(time.Minute)))
+	revoked, _ = a.Contains(ctx, "jti-1")
+	assert.True(t, revoked)
+
+	// Other instance picks it up on the next refresh
+	revoked, _ = b.Contains(ctx, "jti-1")
+	assert.False(t, revoked)
+
+	now = now.Add(5 * time.Second)
+	revoked, _ = b.Contains(ctx, "jti-1")
+	assert.True(t, revoked)
+
+	// Token expired anyway: no longer listed, cleanup removes it
+	now = now.Add(time.Minute)
+	revoked, _ = b.Contains(ctx, "jti-1")
+	assert.False(t, revoked)
+
+	deleted, err := a.DeleteExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestCacheLoadsExistingEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	shared := denylist.NewMemoryStore().WithClock(clock)
+	assert.NoError(t, shared.Add(ctx, "jti-1", now.Add(time.Minute)))
+
+	// New instance after a restart
+	now = now.Add(10 * time.Second)
+	cache := denylist.NewCache(shared, time.Hour).WithClock(clock)
+
+	revoked, err := cache.Contains(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestCacheRevokeUser(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	shared := denylist.NewMemoryStore().WithClock(clock)
+	a := denylist.NewCache(shared, 5*time.Second).WithClock(clock)
+	b := denylist.NewCache(shared, 5*time.Second).WithClock(clock)
+
+	issued := now.Add(-time.Minute)
+	revoked, err := b.UserRevoked(ctx, "user-1", issued)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Every token issued before the cut-off, seen at once by the instance that revoked
+	assert.NoError(t, a.RevokeUser(ctx, "user-1", now, now.Add(30*time.Minute)))
+	revoked, _ = a.UserRevoked(ctx, "user-1", issued)
+	assert.True(t, revoked)
+
+	// Token issued after the cut-off still works, other users are not affected
+	revoked, _ = a.UserRevoked(ctx, "user-1", now.Add(time.Second))
+	assert.False(t, revoked)
+	revoked, _ = a.UserRevoked(ctx, "user-2", issued)
+	assert.False(t, revoked)
+
+	// Other instance picks it up on the next refresh
+	now = now.Add(5 * time.Second)
+	revoked, _ = b.UserRevoked(ctx, "user-1", issued)
+	assert.True(t, revoked)
+
+	// Every token issued before the cut-off expired: cleanup removes it
+	now = now.Add(30 * time.Minute)
+	revoked, _ = b.UserRevoked(ctx, "user-1", issued)
+	assert.False(t, revoked)
+
+	deleted, err := a.DeleteExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
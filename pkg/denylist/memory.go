@@ -0,0 +1,108 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore : single process Store and Source, for tests
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+	users   map[string]UserEntry
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry), users: make(map[string]UserEntry), now: time.Now}
+}
+
+// WithClock : fixed time in tests, also used as CreatedAt
+func (m *MemoryStore) WithClock(now func() time.Time) *MemoryStore {
+	m.now = now
+	return m
+}
+
+func (m *MemoryStore) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entries[jti]; !ok {
+		m.entries[jti] = Entry{JTI: jti, ExpiresAt: expiresAt, CreatedAt: m.now()}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Contains(ctx context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[jti]
+	return ok && m.now().Before(e.ExpiresAt), nil
+}
+
+func (m *MemoryStore) Since(ctx context.Context, cursor time.Time) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var entries []Entry
+	for _, e := range m.entries {
+		if e.CreatedAt.After(cursor) && now.Before(e.ExpiresAt) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (m *MemoryStore) RevokeUser(ctx context.Context, userID string, revokedBefore, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users[userID] = m.users[userID].merge(UserEntry{UserID: userID, RevokedBefore: revokedBefore, ExpiresAt: expiresAt, CreatedAt: m.now()})
+	return nil
+}
+
+func (m *MemoryStore) UserRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.users[userID]
+	return ok && e.revokedBy(issuedAt, m.now()), nil
+}
+
+func (m *MemoryStore) UsersSince(ctx context.Context, cursor time.Time) ([]UserEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var users []UserEntry
+	for _, e := range m.users {
+		if e.CreatedAt.After(cursor) && now.Before(e.ExpiresAt) {
+			users = append(users, e)
+		}
+	}
+	return users, nil
+}
+
+func (m *MemoryStore) DeleteExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	now := m.now()
+	for jti, e := range m.entries {
+		if !now.Before(e.ExpiresAt) {
+			delete(m.entries, jti)
+			deleted++
+		}
+	}
+	for userID, e := range m.users {
+		if !now.Before(e.ExpiresAt) {
+			delete(m.users, userID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
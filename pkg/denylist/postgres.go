@@ -0,0 +1,100 @@
+package denylist
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore : shared by every instance, wrap it in a Cache
+func NewPostgresStore(db *sql.DB) Source {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err := s.db.ExecContext(ctx, query, jti, expiresAt)
+	return err
+}
+
+func (s *postgresStore) Since(ctx context.Context, cursor time.Time) ([]Entry, error) {
+	query := `
+		SELECT jti, expires_at, created_at FROM revoked_access_tokens
+		WHERE created_at > $1 AND expires_at > NOW()
+	`
+	rows, err := s.db.QueryContext(ctx, query, cursor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.JTI, &e.ExpiresAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// RevokeUser : keeps the latest cut-off, created_at moves so other instances pick it up
+func (s *postgresStore) RevokeUser(ctx context.Context, userID string, revokedBefore, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_user_tokens (user_id, revoked_before, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			revoked_before = GREATEST(revoked_user_tokens.revoked_before, EXCLUDED.revoked_before),
+			expires_at = GREATEST(revoked_user_tokens.expires_at, EXCLUDED.expires_at),
+			created_at = NOW()
+	`
+	_, err := s.db.ExecContext(ctx, query, userID, revokedBefore, expiresAt)
+	return err
+}
+
+func (s *postgresStore) UsersSince(ctx context.Context, cursor time.Time) ([]UserEntry, error) {
+	query := `
+		SELECT user_id, revoked_before, expires_at, created_at FROM revoked_user_tokens
+		WHERE created_at > $1 AND expires_at > NOW()
+	`
+	rows, err := s.db.QueryContext(ctx, query, cursor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []UserEntry
+	for rows.Next() {
+		var e UserEntry
+		if err := rows.Scan(&e.UserID, &e.RevokedBefore, &e.ExpiresAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, e)
+	}
+	return users, rows.Err()
+}
+
+func (s *postgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err = s.db.ExecContext(ctx, `DELETE FROM revoked_user_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return deleted, err
+	}
+	users, err := res.RowsAffected()
+	return deleted + users, err
+}
@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Record struct {
+	Scope       string // user id, empty for anonymous
+	Key         string
+	Method      string
+	Path        string
+	RequestHash string
+	StatusCode  int // 0 = first request still in progress
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (r *Record) Completed() bool {
+	return r.StatusCode != 0
+}
+
+//go:generate mockgen -source=idempotency.go -destination=idempotency_mock.go -package=idempotency
+type Store interface {
+	// Acquire : insert new key or take over an expired one, false = key in use
+	Acquire(ctx context.Context, rec *Record) (bool, error)
+	Find(ctx context.Context, scope, key string) (*Record, error)
+	Complete(ctx context.Context, scope, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+var ErrRecordNotFound = errors.New("idempotency record not found")
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Acquire(ctx context.Context, rec *Record) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, key, method, path, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			method = EXCLUDED.method,
+			path = EXCLUDED.path,
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_body = NULL,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+	`
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		rec.Scope,
+		rec.Key,
+		rec.Method,
+		rec.Path,
+		rec.RequestHash,
+		rec.ExpiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (s *postgresStore) Find(ctx context.Context, scope, key string) (*Record, error) {
+	query := `
+		SELECT scope, key, method, path, request_hash, COALESCE(status_code, 0), response_body, expires_at
+		FROM idempotency_keys WHERE scope = $1 AND key = $2
+	`
+	rec := new(Record)
+
+	err := s.db.QueryRowContext(ctx, query, scope, key).Scan(
+		&rec.Scope,
+		&rec.Key,
+		&rec.Method,
+		&rec.Path,
+		&rec.RequestHash,
+		&rec.StatusCode,
+		&rec.Body,
+		&rec.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *postgresStore) Complete(ctx context.Context, scope, key string, statusCode int, body []byte) error {
+	query := `
+		UPDATE idempotency_keys SET status_code = $1, response_body = $2
+		WHERE scope = $3 AND key = $4
+	`
+	_, err := s.db.ExecContext(ctx, query, statusCode, body, scope, key)
+	return err
+}
+
+// Release : remove unfinished key so client can retry after server error
+func (s *postgresStore) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL`
+	_, err := s.db.ExecContext(ctx, query, scope, key)
+	return err
+}
+
+func (s *postgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < NOW()`
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
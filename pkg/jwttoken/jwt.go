@@ -7,6 +7,7 @@ import (
 
 	"github.com/codepnw/go-starter-kit/internal/config"
 	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/utils/securetoken"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -14,77 +15,146 @@ import (
 type JWTToken interface {
 	GenerateAccessToken(u *user.User) (string, error)
 	GenerateRefreshToken(u *user.User) (string, error)
+	// GenerateImpersonationToken : access token for u used by actorID, no refresh token
+	GenerateImpersonationToken(u *user.User, actorID string, ttl time.Duration) (string, *UserClaims, error)
 	VerifyAccessToken(tokenStr string) (*UserClaims, error)
 	VerifyRefreshToken(tokenStr string) (*UserClaims, error)
+	// JWKS : public keys for other services to verify access tokens
+	JWKS() *JWKSet
+}
+
+// Config : access tokens use Algorithm, refresh tokens always use HS256 with
+// RefreshKey since only this service reads them
+type Config struct {
+	AppName    string
+	Algorithm  string // HS256 (default), RS256 or EdDSA
+	SecretKey  string // HS256 only
+	RefreshKey string
+	// RS256/EdDSA: <kid>.pem signs, <kid>.pub.pem only verifies (retired keys)
+	KeysDir     string
+	ActiveKeyID string
 }
 
 type token struct {
-	appName    string
-	secretKey  string
-	refreshKey string
+	appName string
+	access  *keySet
+	refresh *keySet
 }
 
-func NewJWTToken(appName, secretKey, refreshKey string) (JWTToken, error) {
-	if secretKey == "" || refreshKey == "" {
-		return nil, errors.New("secret & refresh key is required")
+func NewJWTToken(cfg Config) (JWTToken, error) {
+	if cfg.RefreshKey == "" {
+		return nil, errors.New("refresh key is required")
+	}
+
+	var (
+		access *keySet
+		err    error
+	)
+	switch cfg.Algorithm {
+	case "", AlgHS256:
+		access, err = newHMACKeySet(cfg.SecretKey)
+	default:
+		access, err = loadKeySet(cfg.Algorithm, cfg.KeysDir, cfg.ActiveKeyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := newHMACKeySet(cfg.RefreshKey)
+	if err != nil {
+		return nil, err
 	}
+
 	return &token{
-		appName:    appName,
-		secretKey:  secretKey,
-		refreshKey: refreshKey,
+		appName: cfg.AppName,
+		access:  access,
+		refresh: refresh,
 	}, nil
 }
 
 type UserClaims struct {
-	UserID string
-	Email  string
+	UserID        string
+	Email         string
+	Role          user.Role
+	EmailVerified bool
+	// Actor : set when someone else acts as UserID (RFC 8693 "act")
+	Actor *Actor `json:"act,omitempty"`
 	*jwt.RegisteredClaims
 }
 
+// Actor : the support agent behind an impersonation token
+type Actor struct {
+	UserID string `json:"sub"`
+}
+
+func (c *UserClaims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
 // ------------- Generate Token ----------------
 
 func (j *token) GenerateAccessToken(u *user.User) (string, error) {
-	return j.generateToken(j.secretKey, u, config.AccessTokenDuration)
+	ss, _, err := j.generateToken(j.access, u, nil, config.AccessTokenDuration)
+	return ss, err
 }
 
 func (j *token) GenerateRefreshToken(u *user.User) (string, error) {
-	return j.generateToken(j.refreshKey, u, config.RefreshTokenDuration)
+	ss, _, err := j.generateToken(j.refresh, u, nil, config.RefreshTokenDuration)
+	return ss, err
+}
+
+func (j *token) GenerateImpersonationToken(u *user.User, actorID string, ttl time.Duration) (string, *UserClaims, error) {
+	if actorID == "" {
+		return "", nil, errors.New("actor is required")
+	}
+	return j.generateToken(j.access, u, &Actor{UserID: actorID}, ttl)
 }
 
-func (j *token) generateToken(key string, u *user.User, duration time.Duration) (string, error) {
+func (j *token) generateToken(keys *keySet, u *user.User, actor *Actor, duration time.Duration) (string, *UserClaims, error) {
+	// Unique ID: two tokens issued in the same second must differ
+	jti, err := securetoken.Generate(16)
+	if err != nil {
+		return "", nil, fmt.Errorf("generate token id failed: %w", err)
+	}
+
 	claims := &UserClaims{
-		UserID: u.ID,
-		Email:  u.Email,
+		UserID:        u.ID,
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.IsEmailVerified(),
+		Actor:         actor,
 		RegisteredClaims: &jwt.RegisteredClaims{
+			ID:        jti,
 			Subject:   u.ID,
 			Issuer:    j.appName,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	ss, err := token.SignedString([]byte(key))
+	ss, err := keys.sign(claims)
 	if err != nil {
-		return "", fmt.Errorf("sign token failed: %w", err)
+		return "", nil, fmt.Errorf("sign token failed: %w", err)
 	}
-	return ss, nil
+	return ss, claims, nil
 }
 
 // ------------- Verify Token ----------------
 
 func (j *token) VerifyAccessToken(tokenStr string) (*UserClaims, error) {
-	return j.verifyToken(j.secretKey, tokenStr)
+	return j.verifyToken(j.access, tokenStr)
 }
 
 func (j *token) VerifyRefreshToken(tokenStr string) (*UserClaims, error) {
-	return j.verifyToken(j.refreshKey, tokenStr)
+	return j.verifyToken(j.refresh, tokenStr)
 }
 
-func (j *token) verifyToken(key, tokenStr string) (*UserClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &UserClaims{}, func(t *jwt.Token) (any, error) {
-		return []byte(key), nil
-	})
+func (j *token) verifyToken(keys *keySet, tokenStr string) (*UserClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &UserClaims{}, keys.keyFunc,
+		jwt.WithValidMethods([]string{keys.method.Alg()}),
+		jwt.WithIssuer(j.appName),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("parse token failed: %w", err)
 	}
@@ -95,3 +165,9 @@ func (j *token) verifyToken(key, tokenStr string) (*UserClaims, error) {
 	}
 	return claims, nil
 }
+
+// ------------- JWKS ----------------
+
+func (j *token) JWKS() *JWKSet {
+	return j.access.jwks()
+}
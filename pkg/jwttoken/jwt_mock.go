@@ -6,6 +6,7 @@ package jwttoken
 
 import (
 	reflect "reflect"
+	time "time"
 
 	user "github.com/codepnw/go-starter-kit/internal/features/user"
 	gomock "github.com/golang/mock/gomock"
@@ -49,6 +50,22 @@ func (mr *MockJWTTokenMockRecorder) GenerateAccessToken(u interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateAccessToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateAccessToken), u)
 }
 
+// GenerateImpersonationToken mocks base method.
+func (m *MockJWTToken) GenerateImpersonationToken(u *user.User, actorID string, ttl time.Duration) (string, *UserClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateImpersonationToken", u, actorID, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*UserClaims)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GenerateImpersonationToken indicates an expected call of GenerateImpersonationToken.
+func (mr *MockJWTTokenMockRecorder) GenerateImpersonationToken(u, actorID, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateImpersonationToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateImpersonationToken), u, actorID, ttl)
+}
+
 // GenerateRefreshToken mocks base method.
 func (m *MockJWTToken) GenerateRefreshToken(u *user.User) (string, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +81,20 @@ func (mr *MockJWTTokenMockRecorder) GenerateRefreshToken(u interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockJWTToken)(nil).GenerateRefreshToken), u)
 }
 
+// JWKS mocks base method.
+func (m *MockJWTToken) JWKS() *JWKSet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(*JWKSet)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockJWTTokenMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockJWTToken)(nil).JWKS))
+}
+
 // VerifyAccessToken mocks base method.
 func (m *MockJWTToken) VerifyAccessToken(tokenStr string) (*UserClaims, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,249 @@
+package jwttoken_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/internal/features/user"
+	"github.com/codepnw/go-starter-kit/pkg/jwttoken"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUser = &user.User{ID: "user-1", Email: "a@mail.com", Role: user.RoleCustomer}
+
+func writePrivate(t *testing.T, dir, kid string, key crypto.Signer) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, kid+".pem"), "PRIVATE KEY", der)
+}
+
+func writePublic(t *testing.T, dir, kid string, key crypto.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, kid+".pub.pem"), "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600)
+	require.NoError(t, err)
+}
+
+func newToken(t *testing.T, alg, dir, active string) jwttoken.JWTToken {
+	tk, err := jwttoken.NewJWTToken(jwttoken.Config{
+		AppName:     "test",
+		Algorithm:   alg,
+		SecretKey:   "secret",
+		RefreshKey:  "refresh",
+		KeysDir:     dir,
+		ActiveKeyID: active,
+	})
+	require.NoError(t, err)
+	return tk
+}
+
+func TestAsymmetricRoundTrip(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		alg  string
+		key  crypto.Signer
+		kty  string
+	}{
+		{"EdDSA", jwttoken.AlgEdDSA, edKey, "OKP"},
+		{"RS256", jwttoken.AlgRS256, rsaKey, "RSA"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writePrivate(t, dir, "k1", tc.key)
+			tk := newToken(t, tc.alg, dir, "")
+
+			access, err := tk.GenerateAccessToken(testUser)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(access, &jwttoken.UserClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, parsed.Method.Alg())
+			assert.Equal(t, "k1", parsed.Header["kid"])
+
+			claims, err := tk.VerifyAccessToken(access)
+			require.NoError(t, err)
+			assert.Equal(t, testUser.ID, claims.UserID)
+
+			// Refresh tokens stay HS256 and are not accepted as access tokens
+			refresh, err := tk.GenerateRefreshToken(testUser)
+			require.NoError(t, err)
+			_, err = tk.VerifyRefreshToken(refresh)
+			assert.NoError(t, err)
+			_, err = tk.VerifyAccessToken(refresh)
+			assert.Error(t, err)
+
+			jwks := tk.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "k1", jwks.Keys[0].Kid)
+			assert.Equal(t, tc.kty, jwks.Keys[0].Kty)
+			assert.Equal(t, tc.alg, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	dir := t.TempDir()
+	writePrivate(t, dir, "2026-01", oldKey)
+	oldToken, err := newToken(t, jwttoken.AlgEdDSA, dir, "").GenerateAccessToken(testUser)
+	require.NoError(t, err)
+
+	// Rotate: new key signs, old key is kept public-only
+	require.NoError(t, os.Remove(filepath.Join(dir, "2026-01.pem")))
+	writePublic(t, dir, "2026-01", oldKey.Public())
+	writePrivate(t, dir, "2026-02", newKey)
+
+	tk := newToken(t, jwttoken.AlgEdDSA, dir, "2026-02")
+
+	_, err = tk.VerifyAccessToken(oldToken)
+	assert.NoError(t, err)
+
+	fresh, err := tk.GenerateAccessToken(testUser)
+	require.NoError(t, err)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(fresh, &jwttoken.UserClaims{})
+	assert.Equal(t, "2026-02", parsed.Header["kid"])
+
+	assert.Len(t, tk.JWKS().Keys, 2)
+
+	// Old key dropped: its tokens stop working
+	require.NoError(t, os.Remove(filepath.Join(dir, "2026-01.pub.pem")))
+	tk = newToken(t, jwttoken.AlgEdDSA, dir, "2026-02")
+	_, err = tk.VerifyAccessToken(oldToken)
+	assert.ErrorIs(t, err, jwttoken.ErrUnknownKey)
+}
+
+func TestRejectUnexpectedAlgorithm(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	writePrivate(t, dir, "k1", edKey)
+	tk := newToken(t, jwttoken.AlgEdDSA, dir, "")
+
+	claims := &jwttoken.UserClaims{
+		UserID: testUser.ID,
+		RegisteredClaims: &jwt.RegisteredClaims{
+			Issuer:    "test",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	t.Run("HS256 signed with the public key", func(t *testing.T) {
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		forged.Header["kid"] = "k1"
+		ss, err := forged.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+		require.NoError(t, err)
+
+		_, err = tk.VerifyAccessToken(ss)
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		forged := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+		ss, err := forged.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = tk.VerifyAccessToken(ss)
+		assert.Error(t, err)
+	})
+
+	t.Run("HS256 config rejects EdDSA", func(t *testing.T) {
+		ss, err := tk.GenerateAccessToken(testUser)
+		require.NoError(t, err)
+
+		_, err = newToken(t, jwttoken.AlgHS256, "", "").VerifyAccessToken(ss)
+		assert.Error(t, err)
+	})
+}
+
+func TestHMACPublishesNoKeys(t *testing.T) {
+	tk := newToken(t, jwttoken.AlgHS256, "", "")
+
+	access, err := tk.GenerateAccessToken(testUser)
+	require.NoError(t, err)
+	_, err = tk.VerifyAccessToken(access)
+	assert.NoError(t, err)
+
+	assert.Empty(t, tk.JWKS().Keys)
+}
+
+func TestImpersonationToken(t *testing.T) {
+	tk := newToken(t, jwttoken.AlgHS256, "", "")
+
+	ss, issued, err := tk.GenerateImpersonationToken(testUser, "admin-1", 15*time.Minute)
+	require.NoError(t, err)
+	assert.True(t, issued.IsImpersonated())
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), issued.ExpiresAt.Time, time.Minute)
+
+	claims, err := tk.VerifyAccessToken(ss)
+	require.NoError(t, err)
+	assert.Equal(t, testUser.ID, claims.UserID)
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, "admin-1", claims.Actor.UserID)
+	assert.Equal(t, issued.ID, claims.ID)
+
+	access, err := tk.GenerateAccessToken(testUser)
+	require.NoError(t, err)
+	claims, err = tk.VerifyAccessToken(access)
+	require.NoError(t, err)
+	assert.False(t, claims.IsImpersonated())
+
+	_, _, err = tk.GenerateImpersonationToken(testUser, "", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestLoadKeysErrors(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	t.Run("key does not match algorithm", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivate(t, dir, "k1", edKey)
+		_, err := jwttoken.NewJWTToken(jwttoken.Config{Algorithm: jwttoken.AlgRS256, RefreshKey: "r", KeysDir: dir})
+		assert.Error(t, err)
+	})
+
+	t.Run("weak RSA key", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivate(t, dir, "k1", weakRSA)
+		_, err := jwttoken.NewJWTToken(jwttoken.Config{Algorithm: jwttoken.AlgRS256, RefreshKey: "r", KeysDir: dir})
+		assert.Error(t, err)
+	})
+
+	t.Run("several private keys without active id", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivate(t, dir, "k1", edKey)
+		writePrivate(t, dir, "k2", edKey)
+		_, err := jwttoken.NewJWTToken(jwttoken.Config{Algorithm: jwttoken.AlgEdDSA, RefreshKey: "r", KeysDir: dir})
+		assert.Error(t, err)
+	})
+
+	t.Run("active id is public only", func(t *testing.T) {
+		dir := t.TempDir()
+		writePrivate(t, dir, "k1", edKey)
+		writePublic(t, dir, "k0", edKey.Public())
+		_, err := jwttoken.NewJWTToken(jwttoken.Config{Algorithm: jwttoken.AlgEdDSA, RefreshKey: "r", KeysDir: dir, ActiveKeyID: "k0"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,209 @@
+package jwttoken
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported access token algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const minRSABits = 2048
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// signingKey : Private is nil for retired keys that only verify
+type signingKey struct {
+	ID      string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// keySet : one key signs, every key verifies. Tokens carry the kid of their key.
+type keySet struct {
+	method  jwt.SigningMethod
+	active  string
+	keys    map[string]*signingKey
+	ordered []string // kids, stable JWKS order
+	secret  []byte   // HS256 only
+}
+
+func newHMACKeySet(secret string) (*keySet, error) {
+	if secret == "" {
+		return nil, errors.New("hmac secret is required")
+	}
+	return &keySet{method: jwt.SigningMethodHS256, secret: []byte(secret)}, nil
+}
+
+// loadKeySet : <kid>.pem private keys (PKCS#8, or PKCS#1 for RSA) and
+// <kid>.pub.pem public keys of retired signers. activeKID signs; empty is
+// allowed when there is exactly one private key.
+func loadKeySet(alg, dir, activeKID string) (*keySet, error) {
+	var method jwt.SigningMethod
+	switch alg {
+	case AlgRS256:
+		method = jwt.SigningMethodRS256
+	case AlgEdDSA:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", alg)
+	}
+
+	if dir == "" {
+		return nil, fmt.Errorf("%s needs a keys directory", alg)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	ks := &keySet{method: method, keys: make(map[string]*signingKey)}
+	var private []string
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		publicOnly := strings.HasSuffix(name, ".pub.pem")
+		kid := strings.TrimSuffix(strings.TrimSuffix(name, ".pem"), ".pub")
+
+		if _, dup := ks.keys[kid]; dup {
+			return nil, fmt.Errorf("duplicate jwt key id %q", kid)
+		}
+
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parseKey(kid, raw, publicOnly, alg)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", name, err)
+		}
+
+		ks.keys[kid] = key
+		ks.ordered = append(ks.ordered, kid)
+		if key.Private != nil {
+			private = append(private, kid)
+		}
+	}
+
+	switch {
+	case activeKID != "":
+		key, ok := ks.keys[activeKID]
+		if !ok || key.Private == nil {
+			return nil, fmt.Errorf("active jwt key %q has no private key in %s", activeKID, dir)
+		}
+		ks.active = activeKID
+	case len(private) == 1:
+		ks.active = private[0]
+	default:
+		return nil, fmt.Errorf("found %d private jwt keys in %s, set the active key id", len(private), dir)
+	}
+
+	return ks, nil
+}
+
+func parseKey(kid string, raw []byte, publicOnly bool, alg string) (*signingKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	key := &signingKey{ID: kid}
+
+	if publicOnly {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.Public = pub
+	} else {
+		priv, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		key.Private = priv
+		key.Public = priv.Public()
+	}
+
+	// Key type must match the algorithm
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		if alg != AlgRS256 {
+			return nil, fmt.Errorf("RSA key used with %s", alg)
+		}
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA {
+			return nil, fmt.Errorf("Ed25519 key used with %s", alg)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", pub)
+	}
+	return key, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// sign : active key, kid header so verifiers pick the right key
+func (ks *keySet) sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(ks.method, claims)
+
+	if ks.secret != nil {
+		return t.SignedString(ks.secret)
+	}
+
+	t.Header["kid"] = ks.active
+	return t.SignedString(ks.keys[ks.active].Private)
+}
+
+// keyFunc : only the configured algorithm is accepted, alg comes from the token
+// and must not choose the verification method
+func (ks *keySet) keyFunc(t *jwt.Token) (any, error) {
+	if t.Method.Alg() != ks.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+
+	if ks.secret != nil {
+		return ks.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key.Public, nil
+}
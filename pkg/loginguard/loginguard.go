@@ -0,0 +1,196 @@
+// Package loginguard : failed login tracking per account and per IP,
+// with temporary lockouts that grow on every failure past the limit.
+package loginguard
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Counter : failures of one key, account:<email> or ip:<address>
+type Counter struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// Failure : audit record of a failed login
+type Failure struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	UserID    string    `json:"user_id,omitempty"` // empty for unknown emails
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Failure reasons
+const (
+	ReasonUnknownEmail = "unknown_email"
+	ReasonBadPassword  = "bad_password"
+	ReasonBadMFACode   = "bad_mfa_code"
+)
+
+// Store : Postgres in production, MemoryStore in tests
+type Store interface {
+	// Get : zero Counter when the key has no failures
+	Get(ctx context.Context, key string) (*Counter, error)
+	// Fail : count one failure, counting restarts when the last one is older than window
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (*Counter, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+
+	LogFailure(ctx context.Context, f *Failure) error
+	ListFailures(ctx context.Context, email string, limit int) ([]*Failure, error)
+}
+
+// Policy : Limit failures within Window lock the key for LockoutBase,
+// doubled for each further failure, at most LockoutMax. Limit 0 disables the key type.
+type Policy struct {
+	Window       time.Duration
+	AccountLimit int
+	IPLimit      int
+	LockoutBase  time.Duration
+	LockoutMax   time.Duration
+}
+
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func New(store Store, policy Policy) *Guard {
+	return &Guard{store: store, policy: policy, now: time.Now}
+}
+
+// WithClock : tests control lockout expiry
+func (g *Guard) WithClock(now func() time.Time) *Guard {
+	g.now = now
+	return g
+}
+
+// Check : time left on the longest lock of the account or IP, 0 = allowed
+func (g *Guard) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	now := g.now()
+
+	var wait time.Duration
+	for _, key := range g.keys(email, ip) {
+		c, err := g.store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if c.LockedUntil != nil && c.LockedUntil.After(now) {
+			if d := c.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait, nil
+}
+
+// Fail : audit the failure and count it for the account and the IP
+func (g *Guard) Fail(ctx context.Context, f Failure) error {
+	now := g.now()
+	f.Email = NormalizeEmail(f.Email)
+	f.CreatedAt = now
+
+	if err := g.store.LogFailure(ctx, &f); err != nil {
+		return err
+	}
+	slog.Warn("login failed",
+		slog.String("event", "login_failed"),
+		slog.String("email", f.Email),
+		slog.String("user_id", f.UserID),
+		slog.String("ip", f.IPAddress),
+		slog.String("reason", f.Reason),
+	)
+
+	for _, key := range g.keys(f.Email, f.IPAddress) {
+		c, err := g.store.Fail(ctx, key, now, g.policy.Window)
+		if err != nil {
+			return err
+		}
+
+		over := c.Failures - g.limit(key)
+		if over < 0 {
+			continue
+		}
+
+		until := now.Add(g.lockout(over))
+		if err := g.store.Lock(ctx, key, until); err != nil {
+			return err
+		}
+		slog.Warn("login locked",
+			slog.String("event", "login_locked"),
+			slog.String("key", key),
+			slog.Int("failures", c.Failures),
+			slog.Time("locked_until", until),
+		)
+	}
+	return nil
+}
+
+// Succeed : clear the account counter. The IP counter stays, one valid
+// account must not reset an IP that is guessing others.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+// Unlock : admin clears the account lock and counter
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+func (g *Guard) Failures(ctx context.Context, email string, limit int) ([]*Failure, error) {
+	return g.store.ListFailures(ctx, NormalizeEmail(email), limit)
+}
+
+// Cleanup : drop counters with no recent failure and no active lock
+func (g *Guard) Cleanup(ctx context.Context) (int64, error) {
+	return g.store.DeleteStale(ctx, g.now().Add(-g.policy.Window))
+}
+
+// NormalizeEmail : same account for any case or surrounding spaces
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (g *Guard) keys(email, ip string) []string {
+	keys := make([]string, 0, 2)
+	if g.policy.AccountLimit > 0 && email != "" {
+		keys = append(keys, accountKey(email))
+	}
+	if g.policy.IPLimit > 0 && ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+func (g *Guard) limit(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return g.policy.IPLimit
+	}
+	return g.policy.AccountLimit
+}
+
+// lockout : LockoutBase doubled per failure over the limit, capped at LockoutMax
+func (g *Guard) lockout(over int) time.Duration {
+	d := g.policy.LockoutBase
+	for i := 0; i < over && d < g.policy.LockoutMax; i++ {
+		d *= 2
+	}
+	if d > g.policy.LockoutMax {
+		return g.policy.LockoutMax
+	}
+	return d
+}
+
+func accountKey(email string) string {
+	return "account:" + NormalizeEmail(email)
+}
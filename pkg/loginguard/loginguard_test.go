@@ -0,0 +1,110 @@
+package loginguard_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/loginguard"
+	"github.com/stretchr/testify/assert"
+)
+
+var policy = loginguard.Policy{
+	Window:       time.Hour,
+	AccountLimit: 3,
+	IPLimit:      5,
+	LockoutBase:  time.Minute,
+	LockoutMax:   10 * time.Minute,
+}
+
+func setup() (*loginguard.Guard, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard := loginguard.New(loginguard.NewMemoryStore(), policy).WithClock(func() time.Time { return now })
+	return guard, &now
+}
+
+func fail(t *testing.T, guard *loginguard.Guard, email, ip string, n int) {
+	for i := 0; i < n; i++ {
+		err := guard.Fail(context.Background(), loginguard.Failure{Email: email, IPAddress: ip, Reason: loginguard.ReasonBadPassword})
+		assert.NoError(t, err)
+	}
+}
+
+func TestAccountLockout(t *testing.T) {
+	ctx := context.Background()
+	guard, now := setup()
+
+	fail(t, guard, "a@mail.com", "10.0.0.1", 2)
+	wait, err := guard.Check(ctx, "a@mail.com", "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// Limit reached: locked for the base duration, from any IP, any case
+	fail(t, guard, "A@mail.com ", "10.0.0.1", 1)
+	wait, _ = guard.Check(ctx, "a@mail.com", "10.0.0.2")
+	assert.Equal(t, time.Minute, wait)
+
+	// Lock expired, next failure doubles it
+	*now = now.Add(time.Minute)
+	wait, _ = guard.Check(ctx, "a@mail.com", "")
+	assert.Zero(t, wait)
+
+	fail(t, guard, "a@mail.com", "10.0.0.1", 1)
+	wait, _ = guard.Check(ctx, "a@mail.com", "")
+	assert.Equal(t, 2*time.Minute, wait)
+
+	// Capped at LockoutMax
+	fail(t, guard, "a@mail.com", "10.0.0.1", 10)
+	wait, _ = guard.Check(ctx, "a@mail.com", "")
+	assert.Equal(t, 10*time.Minute, wait)
+
+	// Admin unlock
+	assert.NoError(t, guard.Unlock(ctx, "a@mail.com"))
+	wait, _ = guard.Check(ctx, "a@mail.com", "")
+	assert.Zero(t, wait)
+}
+
+func TestWindowRestartsCount(t *testing.T) {
+	ctx := context.Background()
+	guard, now := setup()
+
+	fail(t, guard, "a@mail.com", "", 2)
+	*now = now.Add(policy.Window + time.Second)
+	fail(t, guard, "a@mail.com", "", 2)
+
+	wait, _ := guard.Check(ctx, "a@mail.com", "")
+	assert.Zero(t, wait)
+}
+
+func TestIPLockout(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := setup()
+
+	// Spraying different accounts from one IP
+	for _, email := range []string{"a@mail.com", "b@mail.com", "c@mail.com", "d@mail.com", "e@mail.com"} {
+		fail(t, guard, email, "10.0.0.1", 1)
+	}
+
+	wait, _ := guard.Check(ctx, "f@mail.com", "10.0.0.1")
+	assert.Equal(t, time.Minute, wait)
+
+	wait, _ = guard.Check(ctx, "f@mail.com", "10.0.0.2")
+	assert.Zero(t, wait)
+
+	// Success on one account does not clear the IP
+	assert.NoError(t, guard.Succeed(ctx, "a@mail.com"))
+	wait, _ = guard.Check(ctx, "a@mail.com", "10.0.0.1")
+	assert.Equal(t, time.Minute, wait)
+}
+
+func TestFailures(t *testing.T) {
+	guard, _ := setup()
+
+	fail(t, guard, "a@mail.com", "10.0.0.1", 2)
+	fail(t, guard, "b@mail.com", "10.0.0.1", 1)
+
+	failures, err := guard.Failures(context.Background(), "A@mail.com", 10)
+	assert.NoError(t, err)
+	assert.Len(t, failures, 2)
+	assert.Equal(t, loginguard.ReasonBadPassword, failures[0].Reason)
+}
@@ -0,0 +1,100 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore : same rules as the Postgres store, for tests
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]Counter
+	failures []Failure
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]Counter)}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (*Counter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok {
+		return &Counter{Key: key}, nil
+	}
+	return &c, nil
+}
+
+func (m *MemoryStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (*Counter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok || c.LastFailureAt.Before(now.Add(-window)) {
+		c = Counter{Key: key, LockedUntil: c.LockedUntil}
+	}
+	c.Failures++
+	c.LastFailureAt = now
+
+	m.counters[key] = c
+	return &c, nil
+}
+
+func (m *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.counters[key]; ok {
+		c.LockedUntil = &until
+		m.counters[key] = c
+	}
+	return nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.counters, key)
+	return nil
+}
+
+func (m *MemoryStore) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, c := range m.counters {
+		if c.LastFailureAt.Before(before) && (c.LockedUntil == nil || c.LockedUntil.Before(time.Now())) {
+			delete(m.counters, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryStore) LogFailure(ctx context.Context, f *Failure) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f.ID = int64(len(m.failures) + 1)
+	m.failures = append(m.failures, *f)
+	return nil
+}
+
+func (m *MemoryStore) ListFailures(ctx context.Context, email string, limit int) ([]*Failure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []*Failure
+	for i := len(m.failures) - 1; i >= 0 && len(out) < limit; i-- {
+		if m.failures[i].Email == email {
+			f := m.failures[i]
+			out = append(out, &f)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package loginguard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Get(ctx context.Context, key string) (*Counter, error) {
+	c := &Counter{Key: key}
+
+	query := `SELECT failures, last_failure_at, locked_until FROM login_throttles WHERE key = $1`
+	err := s.db.QueryRowContext(ctx, query, key).Scan(&c.Failures, &c.LastFailureAt, &c.LockedUntil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *postgresStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (*Counter, error) {
+	c := &Counter{Key: key}
+
+	query := `
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < $3 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures, last_failure_at, locked_until
+	`
+	if err := s.db.QueryRowContext(ctx, query, key, now, now.Add(-window)).Scan(
+		&c.Failures,
+		&c.LastFailureAt,
+		&c.LockedUntil,
+	); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *postgresStore) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $2 WHERE key = $1`
+	_, err := s.db.ExecContext(ctx, query, key, until)
+	return err
+}
+
+func (s *postgresStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM login_throttles WHERE key = $1`, key)
+	return err
+}
+
+func (s *postgresStore) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_throttles
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < NOW())
+	`
+	res, err := s.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *postgresStore) LogFailure(ctx context.Context, f *Failure) error {
+	query := `
+		INSERT INTO login_failures (email, user_id, ip_address, user_agent, reason, created_at)
+		VALUES ($1, NULLIF($2, '')::UUID, $3, $4, $5, $6)
+		RETURNING id
+	`
+	return s.db.QueryRowContext(ctx, query, f.Email, f.UserID, f.IPAddress, f.UserAgent, f.Reason, f.CreatedAt).Scan(&f.ID)
+}
+
+// ListFailures : newest first
+func (s *postgresStore) ListFailures(ctx context.Context, email string, limit int) ([]*Failure, error) {
+	query := `
+		SELECT id, email, COALESCE(user_id::TEXT, ''), ip_address, user_agent, reason, created_at
+		FROM login_failures
+		WHERE email = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, email, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []*Failure
+	for rows.Next() {
+		f := new(Failure)
+		if err := rows.Scan(
+			&f.ID,
+			&f.Email,
+			&f.UserID,
+			&f.IPAddress,
+			&f.UserAgent,
+			&f.Reason,
+			&f.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
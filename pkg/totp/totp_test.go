@@ -0,0 +1,70 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codepnw/go-starter-kit/pkg/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 Appendix B, SHA1 seed, last 6 digits
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := totp.Code(rfcSecret, time.Unix(tc.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, code, "time %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := totp.Code(rfcSecret, now)
+	assert.NoError(t, err)
+
+	// Same window
+	step, ok := totp.Validate(rfcSecret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// One window late is within skew
+	step, ok = totp.Validate(rfcSecret, code, now.Add(totp.Period), 1)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// Two windows late is not
+	_, ok = totp.Validate(rfcSecret, code, now.Add(2*totp.Period), 1)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	_, err = totp.Code(secret, time.Now())
+	assert.NoError(t, err)
+
+	uri := totp.URI("Go Starter Kit", "a@mail.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Go%20Starter%20Kit:a@mail.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+}
@@ -1,16 +1,168 @@
 package password
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-func GenerateHashPassword(pwd string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
-	if err != nil {
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+
+	// BcryptMaxBytes : bcrypt refuses longer passwords
+	BcryptMaxBytes = 72
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Hasher : new hashes use the configured algorithm, bcrypt and argon2id hashes both verify
+type Hasher interface {
+	Hash(pwd string) (string, error)
+	// Verify : rehash is true when the hash was made with another algorithm or other parameters
+	Verify(hashed, pwd string) (ok, rehash bool)
+}
+
+// Config : Memory is in KiB, defaults follow the OWASP minimum for argon2id
+type Config struct {
+	Algorithm   string
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	BcryptCost  int
+}
+
+var DefaultConfig = Config{
+	Algorithm:   AlgArgon2id,
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	BcryptCost:  bcrypt.DefaultCost,
+}
+
+type hasher struct {
+	cfg Config
+}
+
+func NewHasher(cfg Config) (Hasher, error) {
+	switch cfg.Algorithm {
+	case AlgArgon2id:
+		if cfg.Memory < 8*uint32(cfg.Parallelism) || cfg.Iterations < 1 || cfg.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d", cfg.Memory, cfg.Iterations, cfg.Parallelism)
+		}
+	case AlgBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", cfg.BcryptCost)
+		}
+	default:
+		return nil, fmt.Errorf("unknown password algorithm: %q", cfg.Algorithm)
+	}
+	return &hasher{cfg: cfg}, nil
+}
+
+// Default : argon2id with DefaultConfig
+func Default() Hasher {
+	return &hasher{cfg: DefaultConfig}
+}
+
+func (h *hasher) Hash(pwd string) (string, error) {
+	if h.cfg.Algorithm == AlgBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(pwd), h.cfg.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashed), nil
+	p := argon2Params{memory: h.cfg.Memory, iterations: h.cfg.Iterations, parallelism: h.cfg.Parallelism}
+	key := argon2.IDKey([]byte(pwd), salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+	return p.encode(salt, key), nil
 }
 
-func CompareHashedPassword(hashed, pwd string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(pwd))
-	return err == nil
-}
\ No newline at end of file
+func (h *hasher) Verify(hashed, pwd string) (bool, bool) {
+	switch {
+	case strings.HasPrefix(hashed, "$argon2id$"):
+		p, salt, key, err := decodeArgon2(hashed)
+		if err != nil {
+			return false, false
+		}
+		other := argon2.IDKey([]byte(pwd), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false
+		}
+		stale := h.cfg.Algorithm != AlgArgon2id ||
+			p.memory != h.cfg.Memory || p.iterations != h.cfg.Iterations || p.parallelism != h.cfg.Parallelism ||
+			len(salt) != argon2SaltLength || len(key) != argon2KeyLength
+		return true, stale
+
+	case strings.HasPrefix(hashed, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(pwd)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(hashed))
+		return true, err != nil || h.cfg.Algorithm != AlgBcrypt || cost != h.cfg.BcryptCost
+
+	default:
+		// Empty for accounts without a password (social login only)
+		return false, false
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// encode : PHC string format, $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func (p argon2Params) encode(salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2(hashed string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != AlgArgon2id {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if p.iterations < 1 || p.parallelism < 1 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,127 @@
+package password_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codepnw/go-starter-kit/pkg/utils/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bcrypt cost 10 of "test_password"
+const bcryptHash = "$2y$10$WsTQ3C0XLFoAWJNA3kY0AOOkSzZwXF20KVRtjSR18FkS5d20OYwp2"
+
+func TestArgon2id(t *testing.T) {
+	h := password.Default()
+
+	hashed, err := h.Hash("test_password")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+	ok, rehash := h.Verify(hashed, "test_password")
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _ = h.Verify(hashed, "wrong_password")
+	assert.False(t, ok)
+
+	again, err := h.Hash("test_password")
+	require.NoError(t, err)
+	assert.NotEqual(t, hashed, again, "salt is random")
+}
+
+func TestVerifyRehash(t *testing.T) {
+	old, err := password.NewHasher(password.Config{Algorithm: password.AlgArgon2id, Memory: 8 * 1024, Iterations: 1, Parallelism: 1})
+	require.NoError(t, err)
+	oldHash, err := old.Hash("test_password")
+	require.NoError(t, err)
+
+	bcryptCost12, err := password.NewHasher(password.Config{Algorithm: password.AlgBcrypt, BcryptCost: 12})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		hasher password.Hasher
+		hashed string
+		rehash bool
+	}{
+		{name: "bcrypt to argon2id", hasher: password.Default(), hashed: bcryptHash, rehash: true},
+		{name: "argon2id parameters raised", hasher: password.Default(), hashed: oldHash, rehash: true},
+		{name: "bcrypt cost raised", hasher: bcryptCost12, hashed: bcryptHash, rehash: true},
+		{name: "argon2id back to bcrypt", hasher: bcryptCost12, hashed: oldHash, rehash: true},
+		{name: "current parameters", hasher: old, hashed: oldHash, rehash: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash := tc.hasher.Verify(tc.hashed, "test_password")
+			assert.True(t, ok)
+			assert.Equal(t, tc.rehash, rehash)
+
+			ok, rehash = tc.hasher.Verify(tc.hashed, "wrong_password")
+			assert.False(t, ok)
+			assert.False(t, rehash)
+		})
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	h := password.Default()
+
+	for _, hashed := range []string{
+		"",
+		"plain_password",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!!$a2V5",
+	} {
+		ok, rehash := h.Verify(hashed, "test_password")
+		assert.False(t, ok, hashed)
+		assert.False(t, rehash, hashed)
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	_, err := password.NewHasher(password.Config{Algorithm: "md5"})
+	assert.Error(t, err)
+
+	_, err = password.NewHasher(password.Config{Algorithm: password.AlgArgon2id, Memory: 19 * 1024, Iterations: 0, Parallelism: 1})
+	assert.Error(t, err)
+
+	_, err = password.NewHasher(password.Config{Algorithm: password.AlgBcrypt, BcryptCost: 2})
+	assert.Error(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte("# common\n  Password123 \n\nletmein!\n"), 0o600))
+
+	p := &password.Policy{MinLength: 8, MaxLength: 16, MaxBytes: password.BcryptMaxBytes}
+	require.NoError(t, p.LoadBreached(list))
+
+	testCases := []struct {
+		password string
+		expected error
+	}{
+		{"correct horse", nil},
+		{"รหัสผ่านยาว", nil}, // 11 characters, 33 bytes
+		{"short", password.ErrTooShort},
+		{strings.Repeat("a", 17), password.ErrTooLong},
+		{"PASSWORD123", password.ErrBreached},
+		{"letmein!", password.ErrBreached},
+		{"# common", nil},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, p.Check(tc.password), tc.password)
+	}
+
+	bytes := &password.Policy{MinLength: 8, MaxBytes: password.BcryptMaxBytes}
+	assert.Equal(t, password.ErrTooLong, bytes.Check(strings.Repeat("ก", 25)), "75 bytes")
+
+	assert.Error(t, p.LoadBreached(filepath.Join(t.TempDir(), "missing.txt")))
+}
@@ -0,0 +1,65 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort = errors.New("password too short")
+	ErrTooLong  = errors.New("password too long")
+	ErrBreached = errors.New("password found in a data breach")
+)
+
+// Policy : lengths count characters, MaxBytes (0 = no limit) is for bcrypt.
+// Breached passwords are compared case-insensitively.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	MaxBytes  int
+	breached  map[string]struct{}
+}
+
+// LoadBreached : one password per line, empty lines and lines starting with # are skipped
+func (p *Policy) LoadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open breached password list failed: %w", err)
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read breached password list failed: %w", err)
+	}
+
+	p.breached = breached
+	return nil
+}
+
+// Check : for new passwords only, login accepts whatever was allowed before
+func (p *Policy) Check(pwd string) error {
+	n := utf8.RuneCountInString(pwd)
+	if n < p.MinLength {
+		return ErrTooShort
+	}
+	if (p.MaxLength > 0 && n > p.MaxLength) || (p.MaxBytes > 0 && len(pwd) > p.MaxBytes) {
+		return ErrTooLong
+	}
+	if _, ok := p.breached[strings.ToLower(pwd)]; ok {
+		return ErrBreached
+	}
+	return nil
+}